@@ -0,0 +1,292 @@
+package abuse
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/metrics"
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is how long an unused rate limiter is kept before it is dropped
+const idleTimeout = 10 * time.Minute
+
+// Config configures command rate limits and temporary bans. Zero values disable a limit.
+type Config struct {
+	ChatCommandsPerMinute  int
+	UserCommandsPerMinute  int
+	InlineQueriesPerMinute int           // Inline queries a user may send per minute, apart from commands
+	BanAfterViolations     int           // Per-user rate limit violations within BanDuration before a user is banned
+	BanDuration            time.Duration // How long a ban lasts
+}
+
+// Verdict is the outcome of checking a command
+type Verdict int
+
+const (
+	Allowed     Verdict = iota // The command may be handled
+	RateLimited                // The chat or user sent too many commands
+	Banned                     // The user is banned
+)
+
+// Decision tells the caller what to do with a command
+type Decision struct {
+	Verdict Verdict
+	Notify  bool // Whether the user should be told, so replies are not rate limited themselves
+}
+
+// Guard rate limits commands per chat and per user, and temporarily bans users who
+// keep exceeding the limits
+type Guard struct {
+	cfg  Config
+	bans db.BanStore
+
+	mu         sync.Mutex
+	chats      map[int64]*limiter
+	users      map[int64]*limiter
+	inline     map[int64]*limiter
+	violations map[int64][]time.Time
+	banned     map[int64]time.Time
+	lastSweep  time.Time
+}
+
+type limiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+	notified bool // Whether the current run of rejections has been reported
+}
+
+func NewGuard(cfg Config, bans db.BanStore) *Guard {
+	return &Guard{
+		cfg:        cfg,
+		bans:       bans,
+		chats:      make(map[int64]*limiter),
+		users:      make(map[int64]*limiter),
+		inline:     make(map[int64]*limiter),
+		violations: make(map[int64][]time.Time),
+		banned:     make(map[int64]time.Time),
+	}
+}
+
+// Load restores bans that are still active from the database
+func (g *Guard) Load(ctx context.Context) error {
+	bans, err := g.bans.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, ban := range bans {
+		g.banned[ban.UserID] = ban.ExpiresAt
+	}
+	metrics.ActiveBans.Set(int64(len(g.banned)))
+
+	return nil
+}
+
+// Check decides whether a command from a user in a chat may be handled
+func (g *Guard) Check(ctx context.Context, chatID, userID int64, now time.Time) Decision {
+	decision, ban := g.check(chatID, userID, now)
+	if ban != nil {
+		g.storeBan(ctx, ban)
+	}
+	return decision
+}
+
+// check decides on a command, returning the ban to store if the user was just banned
+func (g *Guard) check(chatID, userID int64, now time.Time) (Decision, *db.Ban) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+
+	if g.isBanned(userID, now) {
+		metrics.BannedCommands.Add(1)
+		return Decision{Verdict: Banned}, nil
+	}
+
+	user := g.limiter(g.users, userID, g.cfg.UserCommandsPerMinute, now)
+	chat := g.limiter(g.chats, chatID, g.cfg.ChatCommandsPerMinute, now)
+
+	// The chat's limit is checked first, so a busy group doesn't cost its members their own
+	// tokens. Only the user's own flooding counts towards a ban, not other members'.
+	var chatReservation *rate.Reservation
+	if chat != nil {
+		chatReservation = chat.limiter.ReserveN(now, 1)
+		if !chatReservation.OK() || chatReservation.DelayFrom(now) > 0 {
+			chatReservation.CancelAt(now)
+			metrics.RateLimited.Add("chat", 1)
+			return reject(chat), nil
+		}
+	}
+
+	if user != nil && !user.limiter.AllowN(now, 1) {
+		// The command isn't handled, so it doesn't use up the chat's token
+		if chatReservation != nil {
+			chatReservation.CancelAt(now)
+		}
+		metrics.RateLimited.Add("user", 1)
+
+		if g.recordViolation(userID, now) {
+			ban := g.ban(userID, "repeatedly exceeded the command rate limit", now.Add(g.cfg.BanDuration))
+			return Decision{Verdict: Banned, Notify: true}, ban
+		}
+		return reject(user), nil
+	}
+
+	if user != nil {
+		user.notified = false
+	}
+	if chat != nil {
+		chat.notified = false
+	}
+	return Decision{Verdict: Allowed}, nil
+}
+
+// reject returns the decision for a command rejected by a limiter, telling the user only
+// about the first rejection in a run. Callers must hold g.mu.
+func reject(rejectedBy *limiter) Decision {
+	notify := !rejectedBy.notified
+	rejectedBy.notified = true
+	return Decision{Verdict: RateLimited, Notify: notify}
+}
+
+// AllowInline reports whether an inline query from a user may be answered. Telegram
+// sends one per keystroke, so inline queries have their own limit, and exceeding it
+// is not a violation counted towards a ban.
+func (g *Guard) AllowInline(userID int64, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+
+	if g.isBanned(userID, now) {
+		return false
+	}
+
+	l := g.limiter(g.inline, userID, g.cfg.InlineQueriesPerMinute, now)
+	if l != nil && !l.limiter.AllowN(now, 1) {
+		metrics.RateLimited.Add("inline", 1)
+		return false
+	}
+	return true
+}
+
+// Ban bans a user for a duration
+func (g *Guard) Ban(ctx context.Context, userID int64, reason string, duration time.Duration) {
+	g.mu.Lock()
+	ban := g.ban(userID, reason, time.Now().Add(duration))
+	g.mu.Unlock()
+
+	g.storeBan(ctx, ban)
+}
+
+// Unban lifts a user's ban
+func (g *Guard) Unban(ctx context.Context, userID int64) error {
+	if err := g.bans.Unban(ctx, userID); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.banned, userID)
+	delete(g.violations, userID)
+	metrics.ActiveBans.Set(int64(len(g.banned)))
+
+	return nil
+}
+
+// isBanned reports whether a user is banned. Callers must hold g.mu.
+func (g *Guard) isBanned(userID int64, now time.Time) bool {
+	until, ok := g.banned[userID]
+	return ok && now.Before(until)
+}
+
+// ban records a ban in memory and returns it, to be stored with storeBan once
+// g.mu is released. Callers must hold g.mu.
+func (g *Guard) ban(userID int64, reason string, until time.Time) *db.Ban {
+	g.banned[userID] = until
+	delete(g.violations, userID)
+	metrics.Bans.Add(1)
+	metrics.ActiveBans.Set(int64(len(g.banned)))
+	log.Printf("Banned user %d until %s: %s", userID, until.Format(time.RFC3339), reason)
+
+	return &db.Ban{UserID: userID, Reason: reason, ExpiresAt: until}
+}
+
+// storeBan stores a ban in the database. Callers must not hold g.mu, so other
+// users' commands aren't held up by the write.
+func (g *Guard) storeBan(ctx context.Context, ban *db.Ban) {
+	// The ban still applies until restart if it can't be stored
+	if err := g.bans.Ban(ctx, ban); err != nil {
+		log.Printf("Error storing ban for user %d: %v", ban.UserID, err)
+	}
+}
+
+// limiter returns the rate limiter for an ID, creating it if needed, or nil if the
+// limit is disabled. Callers must hold g.mu.
+func (g *Guard) limiter(limiters map[int64]*limiter, id int64, perMinute int, now time.Time) *limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+
+	l, ok := limiters[id]
+	if !ok {
+		l = &limiter{limiter: rate.NewLimiter(rate.Limit(float64(perMinute)/60), perMinute)}
+		limiters[id] = l
+	}
+	l.lastSeen = now
+	return l
+}
+
+// recordViolation records a rate limit violation and reports whether the user
+// should now be banned. Callers must hold g.mu.
+func (g *Guard) recordViolation(userID int64, now time.Time) bool {
+	if g.cfg.BanAfterViolations <= 0 {
+		return false
+	}
+
+	recent := g.violations[userID][:0]
+	for _, at := range g.violations[userID] {
+		if now.Sub(at) < g.cfg.BanDuration {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	g.violations[userID] = recent
+
+	return len(recent) >= g.cfg.BanAfterViolations
+}
+
+// sweep drops idle limiters, old violations and expired bans. Callers must hold g.mu.
+func (g *Guard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < idleTimeout {
+		return
+	}
+	g.lastSweep = now
+
+	for _, limiters := range []map[int64]*limiter{g.chats, g.users, g.inline} {
+		for id, l := range limiters {
+			if now.Sub(l.lastSeen) > idleTimeout {
+				delete(limiters, id)
+			}
+		}
+	}
+
+	for userID, at := range g.violations {
+		if len(at) == 0 || now.Sub(at[len(at)-1]) > g.cfg.BanDuration {
+			delete(g.violations, userID)
+		}
+	}
+
+	for userID, until := range g.banned {
+		if !now.Before(until) {
+			delete(g.banned, userID)
+		}
+	}
+	metrics.ActiveBans.Set(int64(len(g.banned)))
+}
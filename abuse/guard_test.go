@@ -0,0 +1,214 @@
+package abuse
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+)
+
+// banStore records bans in memory. If block is set, Ban signals stored and waits
+// for block to be closed before returning.
+type banStore struct {
+	mu     sync.Mutex
+	bans   []*db.Ban
+	block  chan struct{}
+	stored chan struct{}
+}
+
+func (s *banStore) Ban(ctx context.Context, ban *db.Ban) error {
+	if s.block != nil {
+		close(s.stored)
+		<-s.block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bans = append(s.bans, ban)
+	return nil
+}
+
+func (s *banStore) Unban(ctx context.Context, userID int64) error { return nil }
+
+func (s *banStore) ListActive(ctx context.Context) ([]*db.Ban, error) { return nil, nil }
+
+func (s *banStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bans)
+}
+
+func TestCommandFloodBans(t *testing.T) {
+	ctx := context.Background()
+	store := &banStore{}
+	g := NewGuard(Config{UserCommandsPerMinute: 1, BanAfterViolations: 2, BanDuration: time.Hour}, store)
+	now := time.Now()
+
+	steps := []Decision{
+		{Verdict: Allowed},
+		{Verdict: RateLimited, Notify: true},
+		{Verdict: Banned, Notify: true},
+		{Verdict: Banned},
+	}
+	for i, want := range steps {
+		if got := g.Check(ctx, 1, 1, now); got != want {
+			t.Errorf("command %d: Check = %+v, want %+v", i+1, got, want)
+		}
+	}
+
+	if store.count() != 1 {
+		t.Errorf("%d bans stored, want 1", store.count())
+	}
+	if g.AllowInline(1, now) {
+		t.Error("a banned user's inline query was allowed")
+	}
+	if got := g.Check(ctx, 1, 1, now.Add(2*time.Hour)); got.Verdict != Allowed {
+		t.Errorf("Check after the ban expired = %+v, want Allowed", got)
+	}
+}
+
+func TestInlineQueriesDontBan(t *testing.T) {
+	ctx := context.Background()
+	store := &banStore{}
+	g := NewGuard(Config{
+		UserCommandsPerMinute:  2,
+		InlineQueriesPerMinute: 5,
+		BanAfterViolations:     2,
+		BanDuration:            time.Hour,
+	}, store)
+	now := time.Now()
+
+	// Typing a long query sends many more inline queries than the limit allows
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		if g.AllowInline(1, now) {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Errorf("%d inline queries allowed, want 5", allowed)
+	}
+
+	if got := g.Check(ctx, 1, 1, now); got.Verdict != Allowed {
+		t.Errorf("command after an inline flood: Check = %+v, want Allowed", got)
+	}
+	if store.count() != 0 {
+		t.Errorf("%d bans stored for inline queries, want none", store.count())
+	}
+
+	// The inline limit refills like the command limits
+	if !g.AllowInline(1, now.Add(time.Minute)) {
+		t.Error("inline query a minute later was not allowed")
+	}
+}
+
+func TestInlineLimitDisabled(t *testing.T) {
+	g := NewGuard(Config{UserCommandsPerMinute: 1}, &banStore{})
+	for i := 0; i < 100; i++ {
+		if !g.AllowInline(1, time.Now()) {
+			t.Fatalf("inline query %d was rejected without an inline limit", i+1)
+		}
+	}
+}
+
+func TestBanIsStoredWithoutHoldingTheLock(t *testing.T) {
+	ctx := context.Background()
+	store := &banStore{block: make(chan struct{}), stored: make(chan struct{})}
+	g := NewGuard(Config{UserCommandsPerMinute: 1, BanAfterViolations: 1, BanDuration: time.Hour}, store)
+	now := time.Now()
+
+	g.Check(ctx, 1, 1, now)
+	banned := make(chan Decision)
+	go func() {
+		banned <- g.Check(ctx, 1, 1, now)
+	}()
+	<-store.stored
+
+	// While the ban is being written, other users' commands are still checked
+	checked := make(chan Decision)
+	go func() {
+		checked <- g.Check(ctx, 2, 2, now)
+	}()
+	select {
+	case got := <-checked:
+		if got.Verdict != Allowed {
+			t.Errorf("other user's Check = %+v, want Allowed", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Check blocked while a ban was being stored")
+	}
+
+	// The ban applies before it is stored
+	if got := g.Check(ctx, 3, 1, now); got.Verdict != Banned {
+		t.Errorf("banned user's Check while storing = %+v, want Banned", got)
+	}
+
+	close(store.block)
+	if got := <-banned; got.Verdict != Banned || !got.Notify {
+		t.Errorf("Check that banned = %+v, want Banned and notified", got)
+	}
+	if store.count() != 1 {
+		t.Errorf("%d bans stored, want 1", store.count())
+	}
+}
+
+func TestBusyChatDoesntBanMembers(t *testing.T) {
+	ctx := context.Background()
+	store := &banStore{}
+	g := NewGuard(Config{
+		ChatCommandsPerMinute: 2,
+		UserCommandsPerMinute: 2,
+		BanAfterViolations:    2,
+		BanDuration:           time.Hour,
+	}, store)
+	now := time.Now()
+	const group = -100
+
+	// Two members use up the group's limit between them
+	for userID := int64(1); userID <= 2; userID++ {
+		if got := g.Check(ctx, group, userID, now); got.Verdict != Allowed {
+			t.Fatalf("user %d's first command: Check = %+v, want Allowed", userID, got)
+		}
+	}
+
+	// A third member is limited by the group, which is not their violation
+	steps := []Decision{
+		{Verdict: RateLimited, Notify: true},
+		{Verdict: RateLimited},
+		{Verdict: RateLimited},
+	}
+	for i, want := range steps {
+		if got := g.Check(ctx, group, 3, now); got != want {
+			t.Errorf("command %d in the busy group: Check = %+v, want %+v", i+1, got, want)
+		}
+	}
+	if store.count() != 0 {
+		t.Errorf("%d bans stored for a busy group, want none", store.count())
+	}
+
+	// Rejections by the group didn't use up the member's own tokens
+	for i := 0; i < 2; i++ {
+		if got := g.Check(ctx, 3, 3, now); got.Verdict != Allowed {
+			t.Errorf("command %d in a private chat: Check = %+v, want Allowed", i+1, got)
+		}
+	}
+}
+
+func TestUserLimitDoesntUseChatTokens(t *testing.T) {
+	ctx := context.Background()
+	g := NewGuard(Config{ChatCommandsPerMinute: 2, UserCommandsPerMinute: 1}, &banStore{})
+	now := time.Now()
+	const group = -100
+
+	g.Check(ctx, group, 1, now)
+	if got := g.Check(ctx, group, 1, now); got.Verdict != RateLimited {
+		t.Fatalf("flooding user's Check = %+v, want RateLimited", got)
+	}
+
+	// The flooding user's rejected command left the group's second token for others
+	if got := g.Check(ctx, group, 2, now); got.Verdict != Allowed {
+		t.Errorf("other member's Check = %+v, want Allowed", got)
+	}
+}
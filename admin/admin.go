@@ -0,0 +1,173 @@
+// Package admin serves an authenticated HTTP API for operators to inspect and change
+// the bot's state, recording every request in an audit log
+package admin
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+// Prefix is the path every admin endpoint is served under
+const Prefix = "/admin/"
+
+// maxBodySize limits request bodies, which are all small JSON objects
+const maxBodySize = 64 << 10
+
+// Bot sends alerts to chats and answers commands
+type Bot interface {
+	// SendProposal sends a proposal alert to one chat, whatever its subscriptions
+	SendProposal(chatID int64, alert *notify.ProposalAlert) error
+	// SetMaintenance turns maintenance mode on or off
+	SetMaintenance(enabled bool, message string)
+}
+
+// Replayer processes a kept block again
+type Replayer interface {
+	// ReplayHeight returns db.ErrBlockNotFound if the block at the height wasn't kept
+	ReplayHeight(ctx context.Context, height uint64) error
+}
+
+// Services are the stores and helpers the admin API works on
+type Services struct {
+	Subscriptions *db.SubscriptionRepository
+	Proposals     *db.ProposalRepository
+	Webhooks      *db.WebhookRepository
+	Maintenance   *db.MaintenanceRepository
+	Audit         db.AuditStore
+	Bot           Bot
+	Replayer      Replayer
+}
+
+// API serves the admin API
+type API struct {
+	tokens   map[string]string // Token by the name it is audited as
+	services Services
+	mux      *http.ServeMux
+}
+
+// New creates the admin API accepting the given bearer tokens, keyed by the name
+// requests made with them are audited as
+func New(tokens map[string]string, services Services) *API {
+	a := &API{tokens: tokens, services: services, mux: http.NewServeMux()}
+
+	a.mux.HandleFunc("GET "+Prefix+"chats", a.handleChats)
+	a.mux.HandleFunc("GET "+Prefix+"chats/{chatID}/subscriptions", a.handleSubscriptions)
+	a.mux.HandleFunc("POST "+Prefix+"chats/{chatID}/subscriptions", a.handleSubscribe)
+	a.mux.HandleFunc("DELETE "+Prefix+"chats/{chatID}/subscriptions/{symbol}", a.handleUnsubscribe)
+	a.mux.HandleFunc("POST "+Prefix+"chats/{chatID}/resend", a.handleResend)
+	a.mux.HandleFunc("POST "+Prefix+"blocks/{height}/replay", a.handleReplay)
+	a.mux.HandleFunc("GET "+Prefix+"webhooks/deliveries/failed", a.handleFailedDeliveries)
+	a.mux.HandleFunc("GET "+Prefix+"maintenance", a.handleMaintenance)
+	a.mux.HandleFunc("PUT "+Prefix+"maintenance", a.handleSetMaintenance)
+	a.mux.HandleFunc("GET "+Prefix+"audit", a.handleAudit)
+	a.mux.HandleFunc(Prefix, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+
+	return a
+}
+
+// ServeHTTP authenticates a request, serves it and records it in the audit log
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	actor, ok := a.authenticate(r)
+	if !ok {
+		// Rejected requests are only logged, so clients without a token can't fill the audit log
+		log.Printf("Unauthorized admin request from %s: %s %s", r.RemoteAddr, r.Method, r.URL.Path)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized", "a valid bearer token is required")
+		return
+	}
+
+	// The body is read up front so it can be audited
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "body_too_large", "the request body is too large")
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	a.mux.ServeHTTP(rec, r)
+
+	entry := &db.AuditEntry{
+		Actor:      actor,
+		Action:     r.Pattern,
+		Path:       r.URL.RequestURI(),
+		Details:    auditDetails(body),
+		Status:     rec.status,
+		RemoteAddr: r.RemoteAddr,
+	}
+	if entry.Action == "" {
+		entry.Action = r.Method + " " + r.URL.Path
+	}
+	if err := a.services.Audit.Record(context.Background(), entry); err != nil {
+		log.Printf("Failed to audit admin request by %s to %s: %v", actor, entry.Action, err)
+	}
+}
+
+// authenticate returns the name of the token a request bears
+func (a *API) authenticate(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+
+	// Every token is compared so timing doesn't tell which one came close
+	actor := ""
+	for name, expected := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			actor = name
+		}
+	}
+	return actor, actor != ""
+}
+
+// auditDetails returns a request body as JSON for the audit log, nil if it is empty
+func auditDetails(body []byte) json.RawMessage {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+
+	// Invalid bodies are kept as a string
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// statusRecorder remembers the status of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Admin API error encoding response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, &ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
@@ -0,0 +1,396 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/admin"
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+// tokens are accepted by every test API
+var tokens = map[string]string{"alice": "s3cret", "bob": "t0ken"}
+
+// auditLog keeps the audit log in memory
+type auditLog struct {
+	mu      sync.Mutex
+	entries []*db.AuditEntry
+}
+
+func (l *auditLog) Record(ctx context.Context, e *db.AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e.ID = int64(len(l.entries) + 1)
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+func (l *auditLog) List(ctx context.Context, beforeID int64, limit int) ([]*db.AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []*db.AuditEntry
+	for i := len(l.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		if e := l.entries[i]; beforeID == 0 || e.ID < beforeID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// last returns the latest entry, failing the test if there is none
+func (l *auditLog) last(t *testing.T) *db.AuditEntry {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == 0 {
+		t.Fatal("nothing was audited")
+	}
+	return l.entries[len(l.entries)-1]
+}
+
+func (l *auditLog) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// bot records what the API asks of the bot, failing sends if sendErr is set
+type bot struct {
+	sent        []int64
+	sendErr     error
+	maintenance *bool
+}
+
+func (b *bot) SendProposal(chatID int64, alert *notify.ProposalAlert) error {
+	if b.sendErr != nil {
+		return b.sendErr
+	}
+	b.sent = append(b.sent, chatID)
+	return nil
+}
+
+func (b *bot) SetMaintenance(enabled bool, message string) { b.maintenance = &enabled }
+
+// replayer replays heights it has, returning db.ErrBlockNotFound for others
+type replayer struct {
+	heights  map[uint64]bool
+	replayed []uint64
+}
+
+func (r *replayer) ReplayHeight(ctx context.Context, height uint64) error {
+	if !r.heights[height] {
+		return db.ErrBlockNotFound
+	}
+	r.replayed = append(r.replayed, height)
+	return nil
+}
+
+// testAPI is the admin API with the fakes it was built on
+type testAPI struct {
+	*admin.API
+	audit    *auditLog
+	bot      *bot
+	replayer *replayer
+	services admin.Services
+}
+
+// newTestAPI builds the admin API on the given stores, with the audit log, bot and
+// replayer faked
+func newTestAPI(services admin.Services) *testAPI {
+	a := &testAPI{audit: &auditLog{}, bot: &bot{}, replayer: &replayer{heights: map[uint64]bool{100: true}}}
+	services.Audit, services.Bot, services.Replayer = a.audit, a.bot, a.replayer
+	a.services = services
+	a.API = admin.New(tokens, services)
+	return a
+}
+
+// newDatabaseAPI builds the admin API on the test database
+func newDatabaseAPI(t *testing.T) *testAPI {
+	t.Helper()
+	database := pgtest.Open(t)
+
+	return newTestAPI(admin.Services{
+		Subscriptions: db.NewSubscriptionRepository(database),
+		Proposals:     db.NewProposalRepository(database),
+		Webhooks:      db.NewWebhookRepository(database),
+		Maintenance:   db.NewMaintenanceRepository(database),
+	})
+}
+
+// do sends a request with a token, which is left out if empty
+func (a *testAPI) do(method, path, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	return w
+}
+
+// expect sends a request as alice, checks its status and decodes the response into v
+// unless v is nil
+func (a *testAPI) expect(t *testing.T, method, path, body string, status int, v interface{}) {
+	t.Helper()
+
+	w := a.do(method, path, tokens["alice"], body)
+	if w.Code != status {
+		t.Fatalf("%s %s: status %d, want %d: %s", method, path, w.Code, status, w.Body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: failed to decode response %q: %v", method, path, w.Body, err)
+		}
+	}
+}
+
+// expectError sends a request as alice and checks the error it is refused with
+func (a *testAPI) expectError(t *testing.T, method, path, body string, status int, code string) {
+	t.Helper()
+
+	var resp admin.ErrorResponse
+	a.expect(t, method, path, body, status, &resp)
+	if resp.Error.Code != code {
+		t.Errorf("%s %s: error %q, want %q", method, path, resp.Error.Code, code)
+	}
+}
+
+func TestBearerAuthentication(t *testing.T) {
+	api := newTestAPI(admin.Services{})
+
+	refused := map[string]func(*http.Request){
+		"no token":    func(r *http.Request) {},
+		"empty token": func(r *http.Request) { r.Header.Set("Authorization", "Bearer ") },
+		"wrong token": func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cre") },
+		"basic auth":  func(r *http.Request) { r.SetBasicAuth("alice", "s3cret") },
+		"token name":  func(r *http.Request) { r.Header.Set("Authorization", "Bearer alice") },
+	}
+	for name, authorize := range refused {
+		r := httptest.NewRequest(http.MethodPut, "/admin/maintenance", strings.NewReader(`{"enabled": true}`))
+		authorize(r)
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, want 401", name, w.Code)
+		}
+		if got := w.Header().Get("WWW-Authenticate"); got != `Bearer realm="admin"` {
+			t.Errorf("%s: WWW-Authenticate = %q, want the bearer realm", name, got)
+		}
+	}
+
+	// Refused requests aren't audited, and the maintenance mode wasn't touched
+	if api.audit.count() != 0 {
+		t.Errorf("%d refused requests audited, want none", api.audit.count())
+	}
+	if api.bot.maintenance != nil {
+		t.Error("a refused request changed the maintenance mode")
+	}
+
+	// Each token is audited under its own name
+	for name, token := range tokens {
+		if w := api.do(http.MethodGet, "/admin/audit", token, ""); w.Code != http.StatusOK {
+			t.Errorf("%s's request: status %d, want 200: %s", name, w.Code, w.Body)
+		}
+		if got := api.audit.last(t).Actor; got != name {
+			t.Errorf("request with %s's token audited as %q", name, got)
+		}
+	}
+}
+
+func TestAuditRecordsRequests(t *testing.T) {
+	api := newTestAPI(admin.Services{})
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		action             string
+		details            string
+	}{
+		{http.MethodPost, "/admin/blocks/100/replay", "", http.StatusNoContent, "POST /admin/blocks/{height}/replay", ""},
+		{http.MethodPost, "/admin/blocks/7/replay", "", http.StatusNotFound, "POST /admin/blocks/{height}/replay", ""},
+		{http.MethodPost, "/admin/blocks/tip/replay", "", http.StatusBadRequest, "POST /admin/blocks/{height}/replay", ""},
+		{http.MethodPost, "/admin/chats/0/subscriptions", `{"symbol": "$ZRA+0000"}`, http.StatusBadRequest, "POST /admin/chats/{chatID}/subscriptions", `{"symbol": "$ZRA+0000"}`},
+		{http.MethodPut, "/admin/maintenance", "not json", http.StatusBadRequest, "PUT /admin/maintenance", `"not json"`},
+		{http.MethodGet, "/admin/chats?limit=0", "", http.StatusBadRequest, "GET /admin/chats", ""},
+		{http.MethodGet, "/admin/deliveries/failed", "", http.StatusNotFound, "/admin/", ""},
+	}
+	for _, tt := range tests {
+		w := api.do(tt.method, tt.path, tokens["bob"], tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d: %s", tt.method, tt.path, w.Code, tt.status, w.Body)
+			continue
+		}
+
+		e := api.audit.last(t)
+		if e.Actor != "bob" || e.Action != tt.action || e.Path != tt.path || e.Status != tt.status {
+			t.Errorf("%s %s audited as %+v, want bob's %s with status %d", tt.method, tt.path, e, tt.action, tt.status)
+		}
+		if string(e.Details) != tt.details {
+			t.Errorf("%s %s: audited details %q, want %q", tt.method, tt.path, e.Details, tt.details)
+		}
+	}
+
+	if got := api.replayer.replayed; len(got) != 1 || got[0] != 100 {
+		t.Errorf("replayed %v, want block #100 only", got)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	api := newTestAPI(admin.Services{})
+
+	api.expectError(t, http.MethodGet, "/admin/chats?limit=501", "", http.StatusBadRequest, "invalid_limit")
+	api.expectError(t, http.MethodGet, "/admin/chats?after=first", "", http.StatusBadRequest, "invalid_after")
+	api.expectError(t, http.MethodGet, "/admin/audit?before=x", "", http.StatusBadRequest, "invalid_before")
+	api.expectError(t, http.MethodGet, "/admin/chats/abc/subscriptions", "", http.StatusBadRequest, "invalid_chat_id")
+	api.expectError(t, http.MethodPost, "/admin/chats/1/subscriptions", `{"symbol": "$ZRA+12"}`, http.StatusBadRequest, "invalid_symbol")
+	api.expectError(t, http.MethodPost, "/admin/chats/1/subscriptions", `{"symbol": "$ZRA+0000", "chat": 2}`, http.StatusBadRequest, "invalid_body")
+	api.expectError(t, http.MethodDelete, "/admin/chats/1/subscriptions/ZRA", "", http.StatusBadRequest, "invalid_symbol")
+	api.expectError(t, http.MethodPut, "/admin/maintenance", `{"message": "Back soon"}`, http.StatusBadRequest, "invalid_body")
+	api.expectError(t, http.MethodPost, "/admin/blocks/-1/replay", "", http.StatusBadRequest, "invalid_height")
+	api.expectError(t, http.MethodGet, "/admin/nothing", "", http.StatusNotFound, "not_found")
+
+	big := `{"symbol": "` + strings.Repeat("x", 64<<10) + `"}`
+	api.expectError(t, http.MethodPost, "/admin/chats/1/subscriptions", big, http.StatusRequestEntityTooLarge, "body_too_large")
+}
+
+func TestAuditPagination(t *testing.T) {
+	api := newTestAPI(admin.Services{})
+	for i := 0; i < 3; i++ {
+		api.do(http.MethodPost, "/admin/blocks/100/replay", tokens["alice"], "")
+	}
+
+	// Listing the log is audited as well, after the page is read
+	var first admin.AuditLog
+	api.expect(t, http.MethodGet, "/admin/audit?limit=2", "", http.StatusOK, &first)
+	if len(first.Data) != 2 || first.Data[0].ID != 3 || first.Data[1].ID != 2 || first.NextBefore != 2 {
+		t.Fatalf("first page = %+v, want entries 3 and 2 and the next page before 2", first)
+	}
+	if first.Data[0].Action != "POST /admin/blocks/{height}/replay" || first.Data[0].Status != http.StatusNoContent {
+		t.Errorf("first entry = %+v, want the replay and its status", first.Data[0])
+	}
+
+	var second admin.AuditLog
+	api.expect(t, http.MethodGet, "/admin/audit?limit=2&before=2", "", http.StatusOK, &second)
+	if len(second.Data) != 1 || second.Data[0].ID != 1 || second.NextBefore != 0 {
+		t.Errorf("last page = %+v, want entry 1 and no next page", second)
+	}
+}
+
+func TestChatEndpoints(t *testing.T) {
+	api := newDatabaseAPI(t)
+
+	var sub admin.Subscription
+	api.expect(t, http.MethodPost, "/admin/chats/1/subscriptions", `{"symbol": "$zra+0000"}`, http.StatusCreated, &sub)
+	if sub.Symbol != "$ZRA+0000" {
+		t.Errorf("subscribed to %q, want $ZRA+0000", sub.Symbol)
+	}
+	api.expect(t, http.MethodPost, "/admin/chats/1/subscriptions", `{"symbol": "ALL"}`, http.StatusCreated, nil)
+	api.expect(t, http.MethodPost, "/admin/chats/2/subscriptions", `{"symbol": "$ZIP+*"}`, http.StatusCreated, nil)
+
+	var subs admin.SubscriptionList
+	api.expect(t, http.MethodGet, "/admin/chats/1/subscriptions", "", http.StatusOK, &subs)
+	if len(subs.Data) != 2 {
+		t.Errorf("chat 1 has %d subscriptions, want 2: %+v", len(subs.Data), subs.Data)
+	}
+
+	var first admin.ChatList
+	api.expect(t, http.MethodGet, "/admin/chats?limit=1", "", http.StatusOK, &first)
+	if len(first.Data) != 1 || first.Data[0].ChatID != 1 || first.Data[0].Subscriptions != 2 || first.NextAfter != 1 {
+		t.Fatalf("first page of chats = %+v, want chat 1 with 2 subscriptions", first)
+	}
+	var second admin.ChatList
+	api.expect(t, http.MethodGet, "/admin/chats?limit=1&after=1", "", http.StatusOK, &second)
+	if len(second.Data) != 1 || second.Data[0].ChatID != 2 {
+		t.Errorf("second page of chats = %+v, want chat 2", second)
+	}
+
+	api.expect(t, http.MethodDelete, "/admin/chats/2/subscriptions/$ZIP+*", "", http.StatusNoContent, nil)
+	api.expectError(t, http.MethodDelete, "/admin/chats/2/subscriptions/$ZIP+*", "", http.StatusNotFound, "subscription_not_found")
+}
+
+func TestResend(t *testing.T) {
+	api := newDatabaseAPI(t)
+
+	api.expectError(t, http.MethodPost, "/admin/chats/1/resend", `{"proposal_id": "abc"}`, http.StatusNotFound, "proposal_not_found")
+
+	proposal := &db.Proposal{ProposalID: "abc", ContractID: "$ZRA+0000", Title: "Fund the treasury", Types: []string{}}
+	if err := api.services.Proposals.Save(context.Background(), proposal); err != nil {
+		t.Fatalf("failed to save proposal: %v", err)
+	}
+
+	api.expect(t, http.MethodPost, "/admin/chats/1/resend", `{"proposal_id": "abc"}`, http.StatusNoContent, nil)
+	if len(api.bot.sent) != 1 || api.bot.sent[0] != 1 {
+		t.Errorf("sent to %v, want chat 1", api.bot.sent)
+	}
+
+	api.bot.sendErr = errors.New("Forbidden: bot was blocked by the user")
+	api.expectError(t, http.MethodPost, "/admin/chats/1/resend", `{"proposal_id": "abc"}`, http.StatusBadGateway, "send_failed")
+}
+
+func TestMaintenanceEndpoints(t *testing.T) {
+	api := newDatabaseAPI(t)
+
+	var m admin.Maintenance
+	api.expect(t, http.MethodPut, "/admin/maintenance", `{"enabled": true, "message": " Back soon "}`, http.StatusOK, &m)
+	if !m.Enabled || m.Message != "Back soon" {
+		t.Errorf("set maintenance = %+v, want enabled with the trimmed message", m)
+	}
+	if api.bot.maintenance == nil || !*api.bot.maintenance {
+		t.Error("the bot wasn't put in maintenance mode")
+	}
+
+	var got admin.Maintenance
+	api.expect(t, http.MethodGet, "/admin/maintenance", "", http.StatusOK, &got)
+	if !got.Enabled || got.Message != "Back soon" {
+		t.Errorf("maintenance = %+v, want what was set", got)
+	}
+}
+
+func TestFailedWebhookDeliveries(t *testing.T) {
+	api := newDatabaseAPI(t)
+	ctx := context.Background()
+	webhooks := api.services.Webhooks
+
+	var empty admin.FailedDeliveryList
+	api.expect(t, http.MethodGet, "/admin/webhooks/deliveries/failed", "", http.StatusOK, &empty)
+	if empty.Data == nil || len(empty.Data) != 0 {
+		t.Errorf("failed deliveries = %+v, want an empty list", empty.Data)
+	}
+
+	hook := &db.Webhook{ChatID: 1, URL: "https://example.com/hook", Secret: "secret"}
+	if err := webhooks.Create(ctx, hook); err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	delivery := &db.WebhookDelivery{WebhookID: hook.ID, EventID: "proposal.created:abc", EventType: "proposal.created", Payload: []byte(`{}`)}
+	if err := webhooks.EnqueueDelivery(ctx, delivery); err != nil {
+		t.Fatalf("failed to enqueue delivery: %v", err)
+	}
+	due, err := webhooks.DueDeliveries(ctx, time.Now().Add(time.Minute), 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("DueDeliveries = %v, %v, want the delivery", due, err)
+	}
+	if err := webhooks.MarkAttemptFailed(ctx, due[0].ID, "503 Service Unavailable", time.Time{}); err != nil {
+		t.Fatalf("failed to give up on the delivery: %v", err)
+	}
+
+	var list admin.FailedDeliveryList
+	api.expect(t, http.MethodGet, "/admin/webhooks/deliveries/failed", "", http.StatusOK, &list)
+	if len(list.Data) != 1 {
+		t.Fatalf("got %d failed deliveries, want 1", len(list.Data))
+	}
+	if d := list.Data[0]; d.WebhookID != hook.ID || d.ChatID != 1 || d.URL != hook.URL || d.Attempts != 1 || d.LastError != "503 Service Unavailable" {
+		t.Errorf("failed delivery = %+v, want the given up delivery to %s", d, hook.URL)
+	}
+
+	api.expectError(t, http.MethodGet, "/admin/webhooks/deliveries/failed?limit=x", "", http.StatusBadRequest, "invalid_limit")
+}
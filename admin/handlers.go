@@ -0,0 +1,363 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/symbol"
+)
+
+// Page sizes
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// handleChats lists the Telegram chats with subscriptions, by chat ID
+func (a *API) handleChats(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	after, ok := parseInt64Param(w, r, "after")
+	if !ok {
+		return
+	}
+
+	chats, err := a.services.Subscriptions.ListChats(r.Context(), after, limit)
+	if err != nil {
+		internalError(w, "list chats", err)
+		return
+	}
+
+	resp := &ChatList{Data: make([]*Chat, 0, len(chats))}
+	for _, c := range chats {
+		resp.Data = append(resp.Data, &Chat{ChatID: c.ChatID, Subscriptions: c.Subscriptions, LastSubscribedAt: c.LastSubscribedAt.UTC()})
+	}
+	if len(chats) == limit {
+		resp.NextAfter = chats[len(chats)-1].ChatID
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSubscriptions lists a chat's subscriptions
+func (a *API) handleSubscriptions(w http.ResponseWriter, r *http.Request) {
+	chatID, ok := parseChatID(w, r)
+	if !ok {
+		return
+	}
+
+	subs, err := a.services.Subscriptions.GetUserSubscriptions(r.Context(), chatID)
+	if err != nil {
+		internalError(w, "list subscriptions", err)
+		return
+	}
+
+	resp := &SubscriptionList{Data: make([]*Subscription, 0, len(subs))}
+	for _, sub := range subs {
+		resp.Data = append(resp.Data, newSubscription(sub))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSubscribe subscribes a chat to a symbol, a pattern or "all", ignoring the
+// per-chat limit and whether the contract has been seen
+func (a *API) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	chatID, ok := parseChatID(w, r)
+	if !ok {
+		return
+	}
+	var req SubscribeRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	sym, ok := parseSymbol(w, req.Symbol)
+	if !ok {
+		return
+	}
+
+	repo := a.services.Subscriptions.ForChannel(db.TelegramChannel)
+	repo.SetMaxPerChat(0)
+	sub, err := repo.Subscribe(r.Context(), chatID, db.ProposalType, sym)
+	if err != nil {
+		internalError(w, "subscribe", err)
+		return
+	}
+
+	log.Printf("Admin subscribed chat %d to %s", chatID, sym)
+	writeJSON(w, http.StatusCreated, newSubscription(sub))
+}
+
+// handleUnsubscribe removes a chat's subscription
+func (a *API) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	chatID, ok := parseChatID(w, r)
+	if !ok {
+		return
+	}
+	sym, ok := parseSymbol(w, r.PathValue("symbol"))
+	if !ok {
+		return
+	}
+
+	err := a.services.Subscriptions.Unsubscribe(r.Context(), chatID, db.ProposalType, sym)
+	if errors.Is(err, db.ErrSubscriptionNotFound) {
+		writeError(w, http.StatusNotFound, "subscription_not_found", fmt.Sprintf("chat %d is not subscribed to %s", chatID, sym))
+		return
+	}
+	if err != nil {
+		internalError(w, "unsubscribe", err)
+		return
+	}
+
+	log.Printf("Admin unsubscribed chat %d from %s", chatID, sym)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResend sends a stored proposal's alert to a chat
+func (a *API) handleResend(w http.ResponseWriter, r *http.Request) {
+	chatID, ok := parseChatID(w, r)
+	if !ok {
+		return
+	}
+	var req ResendRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	p, err := a.services.Proposals.Get(r.Context(), req.ProposalID)
+	if errors.Is(err, db.ErrProposalNotFound) {
+		writeError(w, http.StatusNotFound, "proposal_not_found", "no such proposal has been seen on chain")
+		return
+	}
+	if err != nil {
+		internalError(w, "get proposal", err)
+		return
+	}
+
+	alert := &notify.ProposalAlert{
+		Symbol:     p.ContractID,
+		ProposalID: p.ProposalID,
+		Title:      p.Title,
+		Synopsis:   p.Synopsis,
+		Types:      p.Types,
+		Proposer:   p.Proposer,
+	}
+	if err := a.services.Bot.SendProposal(chatID, alert); err != nil {
+		log.Printf("Admin API failed to resend proposal %s to chat %d: %v", p.ProposalID, chatID, err)
+		writeError(w, http.StatusBadGateway, "send_failed", err.Error())
+		return
+	}
+
+	log.Printf("Admin resent proposal %s to chat %d", p.ProposalID, chatID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReplay processes a kept block again, notifying subscribers of its proposals
+func (a *API) handleReplay(w http.ResponseWriter, r *http.Request) {
+	height, err := strconv.ParseUint(r.PathValue("height"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_height", "height must be a block height")
+		return
+	}
+
+	err = a.services.Replayer.ReplayHeight(r.Context(), height)
+	if errors.Is(err, db.ErrBlockNotFound) {
+		writeError(w, http.StatusNotFound, "block_not_found", fmt.Sprintf("block #%d has no contracts or proposals, or wasn't received", height))
+		return
+	}
+	if err != nil {
+		internalError(w, "replay block", err)
+		return
+	}
+
+	log.Printf("Admin replayed block #%d", height)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleFailedDeliveries lists the webhook deliveries that were given up on. Only webhooks
+// queue their deliveries; failed Telegram, sink and email sends are logged instead.
+func (a *API) handleFailedDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := a.services.Webhooks.FailedDeliveries(r.Context(), limit)
+	if err != nil {
+		internalError(w, "list failed deliveries", err)
+		return
+	}
+
+	resp := &FailedDeliveryList{Data: make([]*FailedDelivery, 0, len(deliveries))}
+	for _, d := range deliveries {
+		resp.Data = append(resp.Data, &FailedDelivery{
+			ID:        d.ID,
+			WebhookID: d.WebhookID,
+			ChatID:    d.ChatID,
+			URL:       d.URL,
+			EventID:   d.EventID,
+			EventType: d.EventType,
+			Attempts:  d.Attempts,
+			LastError: d.LastError,
+			CreatedAt: d.CreatedAt.UTC(),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleMaintenance serves the maintenance mode
+func (a *API) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	m, err := a.services.Maintenance.Get(r.Context())
+	if err != nil {
+		internalError(w, "get maintenance mode", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newMaintenance(m))
+}
+
+// handleSetMaintenance turns maintenance mode on or off
+func (a *API) handleSetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req MaintenanceRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "enabled is required")
+		return
+	}
+
+	m, err := a.services.Maintenance.Set(r.Context(), *req.Enabled, strings.TrimSpace(req.Message))
+	if err != nil {
+		internalError(w, "set maintenance mode", err)
+		return
+	}
+	a.services.Bot.SetMaintenance(m.Enabled, m.Message)
+
+	log.Printf("Admin turned maintenance mode %s", map[bool]string{true: "on", false: "off"}[m.Enabled])
+	writeJSON(w, http.StatusOK, newMaintenance(m))
+}
+
+// handleAudit serves the audit log, newest first
+func (a *API) handleAudit(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	before, ok := parseInt64Param(w, r, "before")
+	if !ok {
+		return
+	}
+
+	entries, err := a.services.Audit.List(r.Context(), before, limit)
+	if err != nil {
+		internalError(w, "list audit log", err)
+		return
+	}
+
+	resp := &AuditLog{Data: make([]*AuditEntry, 0, len(entries))}
+	for _, e := range entries {
+		resp.Data = append(resp.Data, &AuditEntry{
+			ID:         e.ID,
+			Actor:      e.Actor,
+			Action:     e.Action,
+			Path:       e.Path,
+			Details:    e.Details,
+			Status:     e.Status,
+			RemoteAddr: e.RemoteAddr,
+			CreatedAt:  e.CreatedAt.UTC(),
+		})
+	}
+	if len(entries) == limit {
+		resp.NextBefore = entries[len(entries)-1].ID
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseChatID parses the chat ID in the path, writing an error if it is invalid
+func parseChatID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	chatID, err := strconv.ParseInt(r.PathValue("chatID"), 10, 64)
+	if err != nil || chatID == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_chat_id", "chat ID must be a Telegram chat ID")
+		return 0, false
+	}
+	return chatID, true
+}
+
+// parseSymbol normalizes a subscription's symbol, writing an error if it is invalid
+func parseSymbol(w http.ResponseWriter, input string) (string, bool) {
+	if strings.EqualFold(strings.TrimSpace(input), "all") {
+		return "all", true
+	}
+
+	sym := symbol.Normalize(input)
+	if !symbol.IsValid(sym) && !symbol.IsPattern(sym) {
+		writeError(w, http.StatusBadRequest, "invalid_symbol", "symbols look like $ZRA+0000, patterns like $ZRA+* or 'all'")
+		return "", false
+	}
+	return sym, true
+}
+
+// parseLimit parses the limit parameter, writing an error if it is invalid
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultLimit, true
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxLimit {
+		writeError(w, http.StatusBadRequest, "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		return 0, false
+	}
+	return limit, true
+}
+
+// parseInt64Param parses an optional integer parameter, writing an error if it is invalid
+func parseInt64Param(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, true
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_"+name, name+" must be an integer")
+		return 0, false
+	}
+	return n, true
+}
+
+// decodeBody decodes a JSON request body, writing an error if it is invalid
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "the body must be a JSON object: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// internalError logs an error and writes a generic error response
+func internalError(w http.ResponseWriter, action string, err error) {
+	log.Printf("Admin API failed to %s: %v", action, err)
+	writeError(w, http.StatusInternalServerError, "internal_error", "failed to "+action)
+}
+
+// newSubscription converts a stored subscription
+func newSubscription(sub *db.Subscription) *Subscription {
+	return &Subscription{ID: sub.ID, Symbol: sub.Symbol, Filtered: sub.Filter != nil, CreatedAt: sub.CreatedAt}
+}
+
+// newMaintenance converts the stored maintenance mode
+func newMaintenance(m *db.Maintenance) *Maintenance {
+	return &Maintenance{Enabled: m.Enabled, Message: m.Message, UpdatedAt: m.UpdatedAt.UTC()}
+}
@@ -0,0 +1,104 @@
+package admin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Chat is a Telegram chat with subscriptions
+type Chat struct {
+	ChatID           int64     `json:"chat_id"`
+	Subscriptions    int       `json:"subscriptions"`
+	LastSubscribedAt time.Time `json:"last_subscribed_at"`
+}
+
+// ChatList is a page of chats. NextAfter is passed as the after parameter to get
+// the next page and is left out on the last one.
+type ChatList struct {
+	Data      []*Chat `json:"data"`
+	NextAfter int64   `json:"next_after,omitempty"`
+}
+
+// Subscription is a chat's subscription to a symbol, pattern or "all"
+type Subscription struct {
+	ID        string `json:"id"`
+	Symbol    string `json:"symbol"`
+	Filtered  bool   `json:"filtered"` // Whether a filter narrows the proposals it matches
+	CreatedAt string `json:"created_at"`
+}
+
+// SubscriptionList lists a chat's subscriptions
+type SubscriptionList struct {
+	Data []*Subscription `json:"data"`
+}
+
+// SubscribeRequest is the body of force-subscribe requests
+type SubscribeRequest struct {
+	Symbol string `json:"symbol"`
+}
+
+// ResendRequest is the body of resend requests
+type ResendRequest struct {
+	ProposalID string `json:"proposal_id"`
+}
+
+// FailedDelivery is a webhook delivery that was given up on
+type FailedDelivery struct {
+	ID        int64     `json:"id"`
+	WebhookID int64     `json:"webhook_id"`
+	ChatID    int64     `json:"chat_id"`
+	URL       string    `json:"url"`
+	EventID   string    `json:"event_id"`
+	EventType string    `json:"event_type"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FailedDeliveryList lists failed deliveries, newest first
+type FailedDeliveryList struct {
+	Data []*FailedDelivery `json:"data"`
+}
+
+// Maintenance is the bot's maintenance mode
+type Maintenance struct {
+	Enabled   bool      `json:"enabled"`
+	Message   string    `json:"message"` // Shown to users along with the maintenance notice
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// AuditEntry is a request recorded in the audit log
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	Path       string          `json:"path"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	Status     int             `json:"status"`
+	RemoteAddr string          `json:"remote_addr"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditLog is a page of the audit log, newest first. NextBefore is passed as the
+// before parameter to get the next page and is left out on the last one.
+type AuditLog struct {
+	Data       []*AuditEntry `json:"data"`
+	NextBefore int64         `json:"next_before,omitempty"`
+}
+
+// ErrorResponse is the body of error responses
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes an error with a stable code and a human readable message
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// MaintenanceRequest is the body of requests changing maintenance mode
+type MaintenanceRequest struct {
+	Enabled *bool  `json:"enabled"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,371 @@
+// Package api serves a versioned, read-only JSON API over the proposals and
+// contracts the bot has seen on chain, and a stream of live events, for dashboards and websites
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/events"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/symbol"
+)
+
+// Prefix is the path every version 1 endpoint is served under
+const Prefix = "/api/v1/"
+
+// Page sizes
+const (
+	DefaultLimit = 25
+	MaxLimit     = 100
+)
+
+// Config configures the API
+type Config struct {
+	AllowedOrigins []string      // Origins allowed by CORS, "*" for any
+	MaxAge         time.Duration // How long clients and caches may reuse responses
+	StatsTTL       time.Duration // How long stats are cached before they are counted again
+	EventHeartbeat time.Duration // How often idle event streams get a comment to keep them open
+	EventRetry     time.Duration // How long clients wait before reconnecting to the event stream
+}
+
+// DefaultConfig returns a configuration allowing any origin
+func DefaultConfig() Config {
+	return Config{
+		AllowedOrigins: []string{"*"},
+		MaxAge:         15 * time.Second,
+		StatsTTL:       time.Minute,
+		EventHeartbeat: 15 * time.Second,
+		EventRetry:     5 * time.Second,
+	}
+}
+
+// API serves the JSON API
+type API struct {
+	cfg           Config
+	proposals     *db.ProposalRepository
+	contracts     *db.ContractRepository
+	subscriptions *db.SubscriptionRepository
+	events        *events.Stream
+	mux           *http.ServeMux
+
+	mu         sync.Mutex
+	stats      *Stats
+	statsUntil time.Time
+}
+
+// New creates the API
+func New(cfg Config, proposals *db.ProposalRepository, contracts *db.ContractRepository, subscriptions *db.SubscriptionRepository, stream *events.Stream) *API {
+	a := &API{
+		cfg:           cfg,
+		proposals:     proposals,
+		contracts:     contracts,
+		subscriptions: subscriptions,
+		events:        stream,
+		mux:           http.NewServeMux(),
+	}
+
+	a.mux.HandleFunc("GET "+Prefix+"proposals", a.handleProposals)
+	a.mux.HandleFunc("GET "+Prefix+"proposals/{id}", a.handleProposal)
+	a.mux.HandleFunc("GET "+Prefix+"symbols/{symbol}/proposals", a.handleSymbolProposals)
+	a.mux.HandleFunc("GET "+Prefix+"stats", a.handleStats)
+	a.mux.HandleFunc("GET "+Prefix+"events", a.handleEvents)
+	a.mux.HandleFunc(Prefix, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+
+	return a
+}
+
+// ServeHTTP serves the API with CORS headers. It only answers GET, HEAD and CORS preflight requests.
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.setCORSHeaders(w, r)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		a.mux.ServeHTTP(w, r)
+	case http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "If-None-Match, Last-Event-ID")
+		w.Header().Set("Access-Control-Max-Age", "86400")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "the API is read-only")
+	}
+}
+
+// setCORSHeaders allows the request's origin if it is configured
+func (a *API) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	allowOrigin := ""
+	origin := r.Header.Get("Origin")
+	if slices.Contains(a.cfg.AllowedOrigins, "*") {
+		allowOrigin = "*"
+	} else {
+		// Responses differ by origin, so caches must not serve one origin's response to another,
+		// including one to a request without an Origin
+		w.Header().Add("Vary", "Origin")
+		for _, allowed := range a.cfg.AllowedOrigins {
+			if strings.EqualFold(allowed, origin) {
+				allowOrigin = origin
+				break
+			}
+		}
+	}
+
+	if origin == "" || allowOrigin == "" {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+	w.Header().Set("Access-Control-Expose-Headers", "ETag, Link")
+}
+
+// handleProposals lists all proposals, newest first
+func (a *API) handleProposals(w http.ResponseWriter, r *http.Request) {
+	a.listProposals(w, r, "")
+}
+
+// handleSymbolProposals lists the proposals of one contract, newest first
+func (a *API) handleSymbolProposals(w http.ResponseWriter, r *http.Request) {
+	contractID := symbol.Normalize(r.PathValue("symbol"))
+	if !symbol.IsValid(contractID) {
+		writeError(w, http.StatusBadRequest, "invalid_symbol", "symbols look like $ZRA+0000")
+		return
+	}
+
+	a.listProposals(w, r, contractID)
+}
+
+// listProposals serves a page of proposals, of one contract if contractID is set
+func (a *API) listProposals(w http.ResponseWriter, r *http.Request, contractID string) {
+	limit := DefaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+			return
+		}
+		limit = n
+	}
+
+	page := db.ProposalPage{ContractID: contractID, Limit: limit + 1}
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		before, beforeID, err := decodeCursor(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_cursor", "cursor must be a next_cursor returned by the API")
+			return
+		}
+		page.Before, page.BeforeID = before, beforeID
+	}
+
+	proposals, err := a.proposals.List(r.Context(), page)
+	if err != nil {
+		log.Printf("API error listing proposals: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "failed to list proposals")
+		return
+	}
+
+	// One more than the limit was fetched to tell whether there is a next page
+	resp := &ProposalList{Data: make([]*Proposal, 0, len(proposals))}
+	if len(proposals) > limit {
+		proposals = proposals[:limit]
+		last := proposals[limit-1]
+		resp.NextCursor = encodeCursor(last.CreatedAt, last.ProposalID)
+	}
+	for _, p := range proposals {
+		resp.Data = append(resp.Data, newProposal(p))
+	}
+
+	if resp.NextCursor != "" {
+		next := *r.URL
+		query := next.Query()
+		query.Set("cursor", resp.NextCursor)
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	}
+
+	a.writeJSON(w, r, resp)
+}
+
+// handleProposal serves one proposal
+func (a *API) handleProposal(w http.ResponseWriter, r *http.Request) {
+	p, err := a.proposals.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, db.ErrProposalNotFound) {
+		writeError(w, http.StatusNotFound, "proposal_not_found", "no such proposal has been seen on chain")
+		return
+	}
+	if err != nil {
+		log.Printf("API error getting proposal: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "failed to get proposal")
+		return
+	}
+
+	a.writeJSON(w, r, newProposal(p))
+}
+
+// handleStats serves proposal, contract and subscription counts
+func (a *API) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := a.Stats(r.Context(), time.Now())
+	if err != nil {
+		log.Printf("API error getting stats: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "failed to get stats")
+		return
+	}
+
+	a.writeJSON(w, r, stats)
+}
+
+// Stats returns the current stats, counted at most once every StatsTTL
+func (a *API) Stats(ctx context.Context, now time.Time) (*Stats, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stats != nil && now.Before(a.statsUntil) {
+		return a.stats, nil
+	}
+
+	proposals, err := a.proposals.Stats(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	top, err := a.proposals.TopContracts(ctx, now.AddDate(0, 0, -30), 10)
+	if err != nil {
+		return nil, err
+	}
+	contracts, err := a.contracts.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chats, subscriptions, err := a.subscriptions.Counts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		Proposals: ProposalCounts{
+			Total:   proposals.Total,
+			Last24h: proposals.Last24h,
+			Last7d:  proposals.Last7d,
+			Last30d: proposals.Last30d,
+		},
+		Contracts: contracts,
+		Subscriptions: SubscriptionCounts{
+			Chats: chats,
+			Total: subscriptions,
+		},
+		TopSymbols30d: make([]SymbolCount, 0, len(top)),
+		GeneratedAt:   now.UTC(),
+	}
+	for _, count := range top {
+		stats.TopSymbols30d = append(stats.TopSymbols30d, SymbolCount{Symbol: count.ContractID, Proposals: count.Proposals})
+	}
+
+	a.stats, a.statsUntil = stats, now.Add(a.cfg.StatsTTL)
+	return stats, nil
+}
+
+// writeJSON writes a response with an ETag of its body, or 304 Not Modified if
+// the client already has it
+func (a *API) writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("API error encoding response: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "failed to encode response")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(a.cfg.MaxAge.Seconds())))
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, compared weakly
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// encodeCursor encodes the position after a proposal as an opaque cursor
+func encodeCursor(createdAt time.Time, proposalID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + proposalID))
+}
+
+// decodeCursor decodes a cursor from encodeCursor
+func decodeCursor(cursor string) (time.Time, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	nanos, proposalID, ok := strings.Cut(string(data), ":")
+	if !ok {
+		return time.Time{}, "", errors.New("malformed cursor")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	return time.Unix(0, n), proposalID, nil
+}
+
+// proposalURL returns the API URL of a proposal
+func proposalURL(proposalID string) string {
+	return Prefix + "proposals/" + url.PathEscape(proposalID)
+}
+
+// newProposal converts a stored proposal to its JSON form
+func newProposal(p *db.Proposal) *Proposal {
+	types := p.Types
+	if types == nil {
+		types = []string{}
+	}
+
+	return &Proposal{
+		ID:          p.ProposalID,
+		Symbol:      p.ContractID,
+		Title:       p.Title,
+		Synopsis:    p.Synopsis,
+		Types:       types,
+		Proposer:    p.Proposer,
+		BlockHeight: p.BlockHeight,
+		CreatedAt:   p.CreatedAt.UTC(),
+		URL:         proposalURL(p.ProposalID),
+		ExplorerURL: notify.ExplorerProposalURL + p.ProposalID,
+	}
+}
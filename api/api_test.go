@@ -0,0 +1,332 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+)
+
+// newCachedAPI returns an API whose stats are cached, so they are served without a database
+func newCachedAPI() *API {
+	a := New(DefaultConfig(), nil, nil, nil, nil)
+	a.stats = &Stats{
+		Proposals:     ProposalCounts{Total: 3, Last24h: 1, Last7d: 2, Last30d: 3},
+		Contracts:     2,
+		TopSymbols30d: []SymbolCount{{Symbol: "$ZRA+0000", Proposals: 3}},
+		GeneratedAt:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	a.statsUntil = time.Now().Add(time.Hour)
+	return a
+}
+
+func TestConditionalGet(t *testing.T) {
+	a := newCachedAPI()
+
+	first := httptest.NewRecorder()
+	a.ServeHTTP(first, httptest.NewRequest(http.MethodGet, Prefix+"stats", nil))
+	if first.Code != http.StatusOK {
+		t.Fatalf("GET stats = %d, want 200", first.Code)
+	}
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET stats has no ETag")
+	}
+
+	cases := []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{"NoHeader", "", http.StatusOK},
+		{"SameETag", etag, http.StatusNotModified},
+		{"WeakETag", "W/" + etag, http.StatusNotModified},
+		{"ListedETag", `"other", ` + etag, http.StatusNotModified},
+		{"Wildcard", "*", http.StatusNotModified},
+		{"OtherETag", `"other"`, http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, Prefix+"stats", nil)
+			if c.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", c.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, r)
+
+			if w.Code != c.want {
+				t.Fatalf("status = %d, want %d", w.Code, c.want)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %s, want %s", got, etag)
+			}
+			if c.want == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 response has a body: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestPreflightAllowsConditionalHeaders(t *testing.T) {
+	a := newCachedAPI()
+
+	r := httptest.NewRequest(http.MethodOptions, Prefix+"stats", nil)
+	r.Header.Set("Origin", "https://dashboard.example.com")
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("OPTIONS stats = %d, want 204", w.Code)
+	}
+	if got, want := w.Header().Get("Access-Control-Allow-Headers"), "If-None-Match, Last-Event-ID"; got != want {
+		t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, want)
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	const dashboard = "https://dashboard.example.com"
+
+	cases := []struct {
+		name    string
+		allowed []string
+		origin  string
+		allow   string // Expected Access-Control-Allow-Origin, empty for none
+		vary    bool
+	}{
+		{"AnyWithoutOrigin", []string{"*"}, "", "", false},
+		{"Any", []string{"*"}, dashboard, "*", false},
+		{"AnyInList", []string{dashboard, "*"}, "https://other.example.com", "*", false},
+		{"Listed", []string{"https://site.example.com", dashboard}, dashboard, dashboard, true},
+		{"ListedOtherCase", []string{"https://Dashboard.Example.com"}, dashboard, dashboard, true},
+		{"NotListed", []string{dashboard}, "https://evil.example.com", "", true},
+		{"ListWithoutOrigin", []string{dashboard}, "", "", true},
+		{"NoneAllowed", nil, dashboard, "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := newCachedAPI()
+			a.cfg.AllowedOrigins = c.allowed
+
+			for _, method := range []string{http.MethodGet, http.MethodOptions} {
+				r := httptest.NewRequest(method, Prefix+"stats", nil)
+				if c.origin != "" {
+					r.Header.Set("Origin", c.origin)
+				}
+				w := httptest.NewRecorder()
+				a.ServeHTTP(w, r)
+
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != c.allow {
+					t.Errorf("%s: Access-Control-Allow-Origin = %q, want %q", method, got, c.allow)
+				}
+				wantVary := ""
+				if c.vary {
+					wantVary = "Origin"
+				}
+				if got := strings.Join(w.Header().Values("Vary"), ", "); got != wantVary {
+					t.Errorf("%s: Vary = %q, want %q", method, got, wantVary)
+				}
+				// Headers are only exposed to origins that may read the response
+				wantExpose := ""
+				if c.allow != "" {
+					wantExpose = "ETag, Link"
+				}
+				if got := w.Header().Get("Access-Control-Expose-Headers"); got != wantExpose {
+					t.Errorf("%s: Access-Control-Expose-Headers = %q, want %q", method, got, wantExpose)
+				}
+			}
+		})
+	}
+}
+
+// get serves a GET request, sending If-None-Match if etag is set
+func get(a *API, target, etag string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	return w
+}
+
+// decode decodes a JSON response, failing the test if it doesn't parse
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode %q: %v", w.Body, err)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	a := New(DefaultConfig(), nil, nil, nil, nil)
+
+	cases := []struct {
+		name   string
+		method string
+		target string
+		status int
+		code   string
+	}{
+		{"LimitTooSmall", http.MethodGet, Prefix + "proposals?limit=0", http.StatusBadRequest, "invalid_limit"},
+		{"LimitTooLarge", http.MethodGet, Prefix + "proposals?limit=101", http.StatusBadRequest, "invalid_limit"},
+		{"LimitNotANumber", http.MethodGet, Prefix + "symbols/$ZRA+0000/proposals?limit=ten", http.StatusBadRequest, "invalid_limit"},
+		{"CursorNotBase64", http.MethodGet, Prefix + "proposals?cursor=%21%21", http.StatusBadRequest, "invalid_cursor"},
+		{"CursorMalformed", http.MethodGet, Prefix + "proposals?cursor=" + base64.RawURLEncoding.EncodeToString([]byte("yesterday")), http.StatusBadRequest, "invalid_cursor"},
+		{"CursorBadTime", http.MethodGet, Prefix + "proposals?cursor=" + base64.RawURLEncoding.EncodeToString([]byte("noon:abc")), http.StatusBadRequest, "invalid_cursor"},
+		{"InvalidSymbol", http.MethodGet, Prefix + "symbols/ZRA/proposals", http.StatusBadRequest, "invalid_symbol"},
+		{"UnknownEndpoint", http.MethodGet, Prefix + "votes", http.StatusNotFound, "not_found"},
+		{"ReadOnly", http.MethodPost, Prefix + "proposals", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, httptest.NewRequest(c.method, c.target, nil))
+
+			if w.Code != c.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, c.status, w.Body)
+			}
+			var resp ErrorResponse
+			decode(t, w, &resp)
+			if resp.Error.Code != c.code {
+				t.Errorf("error code = %q, want %q", resp.Error.Code, c.code)
+			}
+
+			// Errors aren't cached, so a fixed request isn't answered with a stale error
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+			if w.Header().Get("ETag") != "" {
+				t.Error("error response has an ETag")
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC)
+
+	before, proposalID, err := decodeCursor(encodeCursor(createdAt, "abc:def"))
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if !before.Equal(createdAt) || proposalID != "abc:def" {
+		t.Errorf("decoded %s and %q, want %s and abc:def", before, proposalID, createdAt)
+	}
+}
+
+// saveProposals stores proposals of $ZRA+0000 and $ZIP+0001, created a minute
+// apart except for the last two, which share a creation time
+func saveProposals(t *testing.T, database *db.Database) []string {
+	t.Helper()
+	ctx := context.Background()
+	repo := db.NewProposalRepository(database)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 5; i++ {
+		p := &db.Proposal{ProposalID: fmt.Sprintf("p%d", i), ContractID: "$ZRA+0000", Title: fmt.Sprintf("Proposal %d", i)}
+		if i%2 == 1 {
+			p.ContractID = "$ZIP+0001"
+		}
+		if err := repo.Save(ctx, p); err != nil {
+			t.Fatalf("failed to save proposal: %v", err)
+		}
+
+		createdAt := start.Add(time.Duration(min(i, 3)) * time.Minute)
+		pgtest.Exec(t, database, `UPDATE proposals SET created_at = $2 WHERE proposal_id = $1`, p.ProposalID, createdAt)
+		ids = append(ids, p.ProposalID)
+	}
+	return ids
+}
+
+func TestProposalPagination(t *testing.T) {
+	database := pgtest.Open(t)
+	saveProposals(t, database)
+	a := New(DefaultConfig(), db.NewProposalRepository(database), nil, nil, nil)
+
+	// Newest first, with the tie broken by proposal ID
+	want := []string{"p4", "p3", "p2", "p1", "p0"}
+	var got []string
+	target := Prefix + "proposals?limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages == 3 {
+			t.Fatalf("more than 3 pages of 2 for 5 proposals")
+		}
+
+		w := get(a, target, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", target, w.Code, w.Body)
+		}
+		var page ProposalList
+		decode(t, w, &page)
+		for _, p := range page.Data {
+			got = append(got, p.ID)
+		}
+
+		target = ""
+		if page.NextCursor != "" {
+			link := w.Header().Get("Link")
+			if !strings.Contains(link, "cursor="+page.NextCursor) || !strings.Contains(link, "limit=2") || !strings.HasSuffix(link, `rel="next"`) {
+				t.Errorf("Link = %q, want the next page keeping the limit", link)
+			}
+			target = Prefix + "proposals?limit=2&cursor=" + page.NextCursor
+		} else if w.Header().Get("Link") != "" {
+			t.Errorf("last page has a Link header: %s", w.Header().Get("Link"))
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("paged through %v, want %v", got, want)
+	}
+
+	// A symbol's proposals are paged the same way
+	var zip ProposalList
+	decode(t, get(a, Prefix+"symbols/$zip+0001/proposals?limit=1", ""), &zip)
+	if len(zip.Data) != 1 || zip.Data[0].ID != "p3" || zip.NextCursor == "" {
+		t.Fatalf("first page of $ZIP+0001 = %+v, want p3 and a next page", zip)
+	}
+	var rest ProposalList
+	decode(t, get(a, Prefix+"symbols/$ZIP+0001/proposals?cursor="+zip.NextCursor, ""), &rest)
+	if len(rest.Data) != 1 || rest.Data[0].ID != "p1" || rest.NextCursor != "" {
+		t.Errorf("rest of $ZIP+0001 = %+v, want p1 only", rest)
+	}
+}
+
+func TestProposal(t *testing.T) {
+	database := pgtest.Open(t)
+	saveProposals(t, database)
+	a := New(DefaultConfig(), db.NewProposalRepository(database), nil, nil, nil)
+
+	w := get(a, Prefix+"proposals/p2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET p2 = %d: %s", w.Code, w.Body)
+	}
+	var p Proposal
+	decode(t, w, &p)
+	if p.ID != "p2" || p.Symbol != "$ZRA+0000" || p.URL != Prefix+"proposals/p2" || p.Types == nil {
+		t.Errorf("proposal = %+v, want p2 of $ZRA+0000", p)
+	}
+
+	// The same proposal isn't sent again to a client that has it
+	etag := w.Header().Get("ETag")
+	if cached := get(a, Prefix+"proposals/p2", etag); cached.Code != http.StatusNotModified || cached.Body.Len() != 0 {
+		t.Errorf("GET p2 with its ETag = %d with %d bytes, want 304 without a body", cached.Code, cached.Body.Len())
+	}
+	if other := get(a, Prefix+"proposals/p1", etag); other.Code != http.StatusOK {
+		t.Errorf("GET p1 with p2's ETag = %d, want 200", other.Code)
+	}
+
+	missing := get(a, Prefix+"proposals/nope", "")
+	var resp ErrorResponse
+	decode(t, missing, &resp)
+	if missing.Code != http.StatusNotFound || resp.Error.Code != "proposal_not_found" {
+		t.Errorf("GET a missing proposal = %d %q, want 404 proposal_not_found", missing.Code, resp.Error.Code)
+	}
+}
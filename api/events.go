@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/events"
+	"github.com/ZeraVision/ZeraBot/symbol"
+)
+
+// replayBatch is how many logged events are read at a time when a client resumes
+const replayBatch = 500
+
+// handleEvents streams live events as Server-Sent Events. Clients resuming with
+// Last-Event-ID (or the last_event_id parameter) first get the events they missed
+// from the log. The types and symbol parameters filter the stream.
+func (a *API) handleEvents(w http.ResponseWriter, r *http.Request) {
+	filter := events.Filter{}
+	if value := r.URL.Query().Get("types"); value != "" {
+		for _, t := range strings.Split(value, ",") {
+			if !events.IsEventType(t) {
+				writeError(w, http.StatusBadRequest, "invalid_type", fmt.Sprintf("event types are %s", strings.Join(events.EventTypes, ", ")))
+				return
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+	if value := r.URL.Query().Get("symbol"); value != "" {
+		filter.Symbol = symbol.Normalize(value)
+		if !symbol.IsValid(filter.Symbol) {
+			writeError(w, http.StatusBadRequest, "invalid_symbol", "symbols look like $ZRA+0000")
+			return
+		}
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	resume := lastID != ""
+	var sent int64
+	if resume {
+		n, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_last_event_id", "Last-Event-ID must be the id of an event")
+			return
+		}
+		sent = n
+	}
+
+	// Subscribe before reading the log so no event falls between the two
+	sub, err := a.events.Subscribe(filter)
+	if errors.Is(err, events.ErrTooManyClients) || errors.Is(err, events.ErrClosed) {
+		w.Header().Set("Retry-After", "30")
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "the event stream is not accepting clients, retry later")
+		return
+	}
+	if err != nil {
+		log.Printf("API error subscribing to events: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "failed to subscribe to events")
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Don't let proxies buffer the stream
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", a.cfg.EventRetry.Milliseconds()); err != nil {
+		return
+	}
+
+	for resume {
+		missed, err := a.events.Since(r.Context(), filter, sent, replayBatch)
+		if err != nil {
+			log.Printf("API error replaying events: %v", err)
+			return
+		}
+		for _, e := range missed {
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			sent = e.ID
+		}
+		resume = len(missed) == replayBatch
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(a.cfg.EventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			// Already sent from the log
+			if e.ID <= sent {
+				continue
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			sent = e.ID
+		case <-heartbeat.C:
+			// Comments keep proxies from closing idle connections
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes an event in the Server-Sent Events format
+func writeEvent(w http.ResponseWriter, e *db.Event) error {
+	data, err := json.Marshal(events.NewMessage(e))
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/memstore"
+	"github.com/ZeraVision/ZeraBot/events"
+)
+
+// hookedEvents is an event log calling beforeList before each read, to publish
+// events while a client is being replayed the log
+type hookedEvents struct {
+	*memstore.EventStore
+	beforeList func()
+}
+
+func (h *hookedEvents) List(ctx context.Context, eq db.EventQuery) ([]*db.Event, error) {
+	if h.beforeList != nil {
+		h.beforeList()
+	}
+	return h.EventStore.List(ctx, eq)
+}
+
+// sseEvent is an event as read from a stream
+type sseEvent struct {
+	id, event, data string
+}
+
+// eventStream is a client connected to the event stream
+type eventStream struct {
+	resp   *http.Response
+	reader *bufio.Reader
+}
+
+// newEventServer serves the API with a stream of events logged in store
+func newEventServer(t *testing.T, cfg events.Config, store db.EventStore) (*httptest.Server, *events.Stream) {
+	t.Helper()
+	stream := events.NewStream(cfg, store)
+	server := httptest.NewServer(New(DefaultConfig(), nil, nil, nil, stream))
+	t.Cleanup(server.Close)
+	return server, stream
+}
+
+// connect opens the event stream at a query with an optional Last-Event-ID
+func connect(t *testing.T, server *httptest.Server, query, lastEventID string) *eventStream {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+Prefix+"events"+query, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET events: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET events = %d %s, want a 200 event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	s := &eventStream{resp: resp, reader: bufio.NewReader(resp.Body)}
+	if retry := s.frame(t); retry == nil || !strings.HasPrefix(retry[0], "retry: ") {
+		t.Fatalf("stream started with %q, want the retry interval", retry)
+	}
+	return s
+}
+
+// frame reads the lines up to the next blank line, or nil at the end of the stream
+func (s *eventStream) frame(t *testing.T) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if len(lines) > 0 {
+				t.Fatalf("stream ended inside an event: %q", lines)
+			}
+			return nil
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+// next reads the next event, skipping comments, or returns nil at the end of the stream
+func (s *eventStream) next(t *testing.T) *sseEvent {
+	t.Helper()
+	for {
+		lines := s.frame(t)
+		if lines == nil {
+			return nil
+		}
+		if strings.HasPrefix(lines[0], ":") {
+			continue
+		}
+
+		e := &sseEvent{}
+		for _, line := range lines {
+			field, value, _ := strings.Cut(line, ": ")
+			switch field {
+			case "id":
+				e.id = value
+			case "event":
+				e.event = value
+			case "data":
+				e.data = value
+			}
+		}
+		return e
+	}
+}
+
+// expectIDs reads events and checks their IDs
+func (s *eventStream) expectIDs(t *testing.T, from, to int) {
+	t.Helper()
+	for id := from; id <= to; id++ {
+		e := s.next(t)
+		if e == nil {
+			t.Fatalf("stream ended, want event %d", id)
+		}
+		if e.id != fmt.Sprint(id) {
+			t.Fatalf("got event %s, want %d", e.id, id)
+		}
+	}
+}
+
+// publishBlocks publishes block events at heights from to to
+func publishBlocks(stream *events.Stream, from, to int) {
+	for height := from; height <= to; height++ {
+		stream.PublishBlock(uint64(height), "")
+	}
+}
+
+func TestEventStreamLive(t *testing.T) {
+	server, stream := newEventServer(t, events.DefaultConfig(), memstore.NewEventStore())
+	s := connect(t, server, "?types=block.processed", "")
+
+	stream.PublishBlock(7, "ab")
+	e := s.next(t)
+	if e == nil || e.id != "1" || e.event != events.EventBlockProcessed {
+		t.Fatalf("first event = %+v, want block event 1", e)
+	}
+	if !strings.Contains(e.data, `"data":{"height":7,"hash":"ab"}`) {
+		t.Errorf("event data = %s, want the block", e.data)
+	}
+}
+
+func TestEventStreamResume(t *testing.T) {
+	server, stream := newEventServer(t, events.DefaultConfig(), memstore.NewEventStore())
+	publishBlocks(stream, 1, 5)
+
+	// Missed events come from the log, then live ones follow
+	s := connect(t, server, "", "2")
+	s.expectIDs(t, 3, 5)
+	publishBlocks(stream, 6, 6)
+	s.expectIDs(t, 6, 6)
+
+	// The last_event_id parameter resumes like the header
+	s = connect(t, server, "?last_event_id=4", "")
+	s.expectIDs(t, 5, 6)
+}
+
+func TestEventStreamReplaysInBatches(t *testing.T) {
+	server, stream := newEventServer(t, events.DefaultConfig(), memstore.NewEventStore())
+	total := 2*replayBatch + 3
+	publishBlocks(stream, 1, total)
+
+	s := connect(t, server, "", "0")
+	s.expectIDs(t, 1, total)
+	publishBlocks(stream, total+1, total+1)
+	s.expectIDs(t, total+1, total+1)
+}
+
+func TestEventStreamSkipsEventsReplayedFromLog(t *testing.T) {
+	store := &hookedEvents{EventStore: memstore.NewEventStore()}
+	server, stream := newEventServer(t, events.DefaultConfig(), store)
+	publishBlocks(stream, 1, 1)
+
+	// Published after the client subscribed but before the log is read, so the
+	// event is both replayed and received live
+	published := false
+	store.beforeList = func() {
+		if !published {
+			published = true
+			publishBlocks(stream, 2, 2)
+		}
+	}
+
+	s := connect(t, server, "", "0")
+	s.expectIDs(t, 1, 2)
+	publishBlocks(stream, 3, 3)
+	s.expectIDs(t, 3, 3) // Event 2 is not sent twice
+}
+
+func TestEventStreamDisconnectsSlowClient(t *testing.T) {
+	cfg := events.DefaultConfig()
+	cfg.BufferSize = 2
+	store := &hookedEvents{EventStore: memstore.NewEventStore()}
+	server, stream := newEventServer(t, cfg, store)
+
+	// Overflow the client's buffer while it is being replayed the log
+	published := false
+	store.beforeList = func() {
+		if !published {
+			published = true
+			publishBlocks(stream, 1, 4)
+		}
+	}
+
+	s := connect(t, server, "", "0")
+	s.expectIDs(t, 1, 4)
+	if e := s.next(t); e != nil {
+		t.Fatalf("got event %s, want the slow client disconnected", e.id)
+	}
+
+	// The client catches up from the log when it reconnects
+	store.beforeList = nil
+	publishBlocks(stream, 5, 5)
+	s = connect(t, server, "", "4")
+	s.expectIDs(t, 5, 5)
+}
+
+func TestEventStreamUnavailable(t *testing.T) {
+	cfg := events.DefaultConfig()
+	cfg.MaxClients = 1
+	server, stream := newEventServer(t, cfg, memstore.NewEventStore())
+
+	expect503 := func(name string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + Prefix + "events")
+		if err != nil {
+			t.Fatalf("%s: GET events: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+			t.Errorf("%s: GET events = %d with Retry-After %q, want 503 with Retry-After", name, resp.StatusCode, resp.Header.Get("Retry-After"))
+		}
+	}
+
+	s := connect(t, server, "", "")
+	expect503("TooManyClients")
+
+	// Stopping the stream ends connected clients and refuses new ones
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stream.Run(ctx, time.Hour)
+	if e := s.next(t); e != nil {
+		t.Fatalf("got event %s, want the stream ended", e.id)
+	}
+	expect503("Closed")
+}
+
+func TestEventStreamRequestErrors(t *testing.T) {
+	a := New(DefaultConfig(), nil, nil, nil, nil)
+
+	cases := []struct {
+		name   string
+		target string
+		header string
+		code   string
+	}{
+		{"UnknownType", "?types=proposal.created,proposal.vote", "", "invalid_type"},
+		{"InvalidSymbol", "?symbol=ZRA", "", "invalid_symbol"},
+		{"LastEventIDNotANumber", "", "latest", "invalid_last_event_id"},
+		{"NegativeLastEventID", "?last_event_id=-1", "", "invalid_last_event_id"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, Prefix+"events"+c.target, nil)
+			if c.header != "" {
+				r.Header.Set("Last-Event-ID", c.header)
+			}
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+			}
+			var resp ErrorResponse
+			decode(t, w, &resp)
+			if resp.Error.Code != c.code {
+				t.Errorf("error code = %q, want %q", resp.Error.Code, c.code)
+			}
+		})
+	}
+}
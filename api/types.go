@@ -0,0 +1,64 @@
+package api
+
+import "time"
+
+// Proposal is a governance proposal as served by the API
+type Proposal struct {
+	ID          string    `json:"id"`
+	Symbol      string    `json:"symbol"`
+	Title       string    `json:"title"`
+	Synopsis    string    `json:"synopsis"`
+	Types       []string  `json:"types"` // yesno or options, plus executable
+	Proposer    string    `json:"proposer"`
+	BlockHeight uint64    `json:"block_height"`
+	CreatedAt   time.Time `json:"created_at"` // When the bot saw the proposal
+	URL         string    `json:"url"`
+	ExplorerURL string    `json:"explorer_url"`
+}
+
+// ProposalList is a page of proposals. NextCursor is passed as the cursor
+// parameter to get the next page and is empty on the last one.
+type ProposalList struct {
+	Data       []*Proposal `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// Stats are counts of what the bot has seen and serves
+type Stats struct {
+	Proposals     ProposalCounts     `json:"proposals"`
+	Contracts     int                `json:"contracts"`
+	Subscriptions SubscriptionCounts `json:"subscriptions"`
+	TopSymbols30d []SymbolCount      `json:"top_symbols_30d"`
+	GeneratedAt   time.Time          `json:"generated_at"`
+}
+
+// ProposalCounts counts all proposals and recent ones
+type ProposalCounts struct {
+	Total   int `json:"total"`
+	Last24h int `json:"last_24h"`
+	Last7d  int `json:"last_7d"`
+	Last30d int `json:"last_30d"`
+}
+
+// SubscriptionCounts counts Telegram chats with subscriptions and their subscriptions
+type SubscriptionCounts struct {
+	Chats int `json:"chats"`
+	Total int `json:"total"`
+}
+
+// SymbolCount is the number of proposals of a symbol
+type SymbolCount struct {
+	Symbol    string `json:"symbol"`
+	Proposals int    `json:"proposals"`
+}
+
+// ErrorResponse is the body of error responses
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes an error with a stable code and a human readable message
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
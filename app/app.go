@@ -0,0 +1,298 @@
+// Package app wires configuration, storage, the Telegram bot and block ingestion together
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/abuse"
+	"github.com/ZeraVision/ZeraBot/admin"
+	"github.com/ZeraVision/ZeraBot/api"
+	"github.com/ZeraVision/ZeraBot/config"
+	"github.com/ZeraVision/ZeraBot/contract"
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/migrations"
+	"github.com/ZeraVision/ZeraBot/email"
+	"github.com/ZeraVision/ZeraBot/events"
+	"github.com/ZeraVision/ZeraBot/grpc"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/proposal"
+	"github.com/ZeraVision/ZeraBot/query"
+	"github.com/ZeraVision/ZeraBot/registry"
+	"github.com/ZeraVision/ZeraBot/server"
+	"github.com/ZeraVision/ZeraBot/sink"
+	"github.com/ZeraVision/ZeraBot/telegram"
+	"github.com/ZeraVision/ZeraBot/webhook"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// App is one instance of the bot with everything it depends on.
+// Several apps can run in one process, e.g. in tests.
+type App struct {
+	Config   *config.Config
+	Database *db.Database
+
+	Subscriptions *db.SubscriptionRepository
+	Settings      *db.ChatSettingsRepository
+	PendingAlerts *db.PendingAlertRepository
+	Proposals     *db.ProposalRepository
+	Destinations  *db.DestinationRepository
+	Registry      *registry.Registry
+	Guard         *abuse.Guard
+	Webhooks      *webhook.Service
+	Email         *email.Service // Nil if SMTP isn't configured
+	Events        *events.Stream
+	Sinks         []*sink.Channel // Channels to destinations outside Telegram
+
+	Bot      *telegram.Bot
+	Notifier proposal.Notifier
+	API      *api.API
+	Ingest   *grpc.Ingest
+	Server   *server.Server
+	Query    *query.Server // Nil if QUERY_GRPC_ADDRESS isn't set
+	Admin    *admin.API    // Nil if ADMIN_TOKENS isn't set
+}
+
+// Open connects to the database and Telegram as configured and builds the app
+func Open(cfg *config.Config) (*App, error) {
+	database, err := db.NewDatabase(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := migrations.RunMigrations(context.Background(), database.DB()); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	botAPI, err := telegram.NewBotAPI(cfg.BotToken, cfg.APIEndpoint, cfg.Env == "development")
+	if err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to initialize bot: %w", err)
+	}
+
+	a, err := New(cfg, database, botAPI)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// New builds the app around an open database and a Telegram Bot API client
+func New(cfg *config.Config, database *db.Database, botAPI *tgbotapi.BotAPI) (*App, error) {
+	a := &App{
+		Config:        cfg,
+		Database:      database,
+		Subscriptions: db.NewSubscriptionRepository(database),
+		Settings:      db.NewChatSettingsRepository(database),
+		PendingAlerts: db.NewPendingAlertRepository(database),
+		Proposals:     db.NewProposalRepository(database),
+		Destinations:  db.NewDestinationRepository(database),
+		Registry:      registry.NewRegistry(db.NewContractRepository(database)),
+		Guard: abuse.NewGuard(abuse.Config{
+			ChatCommandsPerMinute:  cfg.ChatCommandsPerMinute,
+			UserCommandsPerMinute:  cfg.UserCommandsPerMinute,
+			InlineQueriesPerMinute: cfg.InlineQueriesPerMinute,
+			BanAfterViolations:     cfg.BanAfterViolations,
+			BanDuration:            cfg.BanDuration,
+		}, db.NewBanRepository(database)),
+	}
+	a.Subscriptions.SetMaxPerChat(cfg.MaxSubscriptionsPerChat)
+
+	webhookConfig := webhook.DefaultConfig()
+	webhookConfig.MaxPerChat = cfg.MaxWebhooksPerChat
+	webhookConfig.AllowPrivate = cfg.WebhooksAllowPrivate
+	a.Webhooks = webhook.NewService(webhookConfig, db.NewWebhookRepository(database))
+	a.Events = events.NewStream(events.DefaultConfig(), db.NewEventRepository(database))
+
+	if cfg.SMTPHost != "" {
+		emailConfig := email.DefaultConfig()
+		emailConfig.From = cfg.SMTPFrom
+		emailConfig.BaseURL = cfg.PublicURL
+		if cfg.DevMode {
+			emailConfig.OnlyChatID = cfg.TestChatID
+		}
+		a.Email = email.NewService(emailConfig, db.NewEmailRepository(database), a.Subscriptions, a.Settings,
+			email.NewSMTPSender(email.SMTPConfig{
+				Host:     cfg.SMTPHost,
+				Port:     cfg.SMTPPort,
+				Username: cfg.SMTPUsername,
+				Password: cfg.SMTPPassword,
+			}))
+	}
+
+	// The registry only knows the contracts seen since deploy, so symbols it doesn't know are
+	// only warned about
+	if err := a.Registry.Load(context.Background()); err != nil {
+		log.Printf("Failed to load contract registry: %v", err)
+	}
+
+	if err := a.Guard.Load(context.Background()); err != nil {
+		log.Printf("Failed to load bans: %v", err)
+	}
+
+	opts := telegram.Options{}
+	if cfg.DevMode {
+		opts.OnlyChatID = cfg.TestChatID
+	}
+
+	a.Bot = telegram.NewBot(botAPI, telegram.Services{
+		Database:      database,
+		Subscriptions: a.Subscriptions,
+		Settings:      a.Settings,
+		PendingAlerts: a.PendingAlerts,
+		Proposals:     a.Proposals,
+		Registry:      a.Registry,
+		Guard:         a.Guard,
+		Webhooks:      a.Webhooks,
+		Email:         a.Email,
+	}, opts)
+	a.Webhooks.OnDisabled = a.Bot.NotifyWebhookDisabled
+
+	// Maintenance mode set through the admin API survives restarts
+	maintenance := db.NewMaintenanceRepository(database)
+	if m, err := maintenance.Get(context.Background()); err != nil {
+		log.Printf("Failed to load maintenance mode: %v", err)
+	} else {
+		a.Bot.SetMaintenance(m.Enabled, m.Message)
+	}
+
+	// Alerts go to Telegram, to the destinations operators registered on other
+	// channels, to the webhooks and email addresses users registered and to API event streams
+	fanout := notify.NewFanout(a.Bot, a.Webhooks, a.Events)
+	if a.Email != nil {
+		a.Email.OnConfirmed = a.Bot.NotifyEmailConfirmed
+		a.Email.OnUnsubscribed = a.Bot.NotifyEmailUnsubscribed
+		fanout.Add(a.Email)
+	}
+	for channel, sender := range sink.Senders(&http.Client{}) {
+		sinkChannel := sink.NewChannel(channel, a.Subscriptions.ForChannel(channel), a.Destinations, sender)
+		a.Sinks = append(a.Sinks, sinkChannel)
+		fanout.Add(sinkChannel)
+	}
+	a.Notifier = fanout
+
+	a.Ingest = grpc.NewIngest(grpc.IngestConfig{
+		TrustedDomain: cfg.GRPCAddress,
+		SecretAuth:    cfg.SecretAuth,
+		TrustAll:      cfg.Env == "development",
+	}, contract.NewProcessor(a.Registry), proposal.NewProcessor(a.Notifier, a.Proposals), db.NewBlockRepository(database))
+	a.Ingest.OnProcessed = a.Events.PublishBlock
+
+	apiConfig := api.DefaultConfig()
+	apiConfig.AllowedOrigins = cfg.APIAllowedOrigins
+	a.API = api.New(apiConfig, a.Proposals, db.NewContractRepository(database), a.Subscriptions, a.Events)
+
+	// The HTTP server also serves the JSON API, the admin API and email confirmation and unsubscribe links
+	handlers := map[string]http.Handler{api.Prefix: a.API}
+	if len(cfg.AdminTokens) > 0 {
+		a.Admin = admin.New(cfg.AdminTokens, admin.Services{
+			Subscriptions: a.Subscriptions,
+			Proposals:     a.Proposals,
+			Webhooks:      db.NewWebhookRepository(database),
+			Maintenance:   maintenance,
+			Audit:         db.NewAuditRepository(database),
+			Bot:           a.Bot,
+			Replayer:      a.Ingest,
+		})
+		handlers[admin.Prefix] = a.Admin
+	}
+	if a.Email != nil {
+		emailLinks := a.Email.Handler()
+		handlers[email.ConfirmPath] = emailLinks
+		handlers[email.UnsubscribePath] = emailLinks
+	}
+
+	srv, err := server.New(a.Bot, server.Config{
+		Domain:       cfg.Domain,
+		WebhookPath:  cfg.WebhookPath,
+		Production:   cfg.Env == "production",
+		NgrokURL:     cfg.NgrokURL,
+		MetricsToken: cfg.MetricsToken,
+		Handlers:     handlers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+	a.Server = srv
+
+	if cfg.QueryGRPCAddress != "" {
+		a.Query = query.NewServer(query.Config{
+			Address:      cfg.QueryGRPCAddress,
+			Tokens:       cfg.QueryGRPCTokens,
+			ManageTokens: cfg.QueryGRPCManageTokens,
+		}, a.Proposals, a.Subscriptions, a.Destinations, a.Events)
+	}
+
+	return a, nil
+}
+
+// Run sets up the webhook and serves updates, block broadcasts and digests until ctx is done
+func (a *App) Run(ctx context.Context) error {
+	// Set up webhook // TODO this service seems to die after a short time (not get new webhooks at entry)
+	if err := a.Bot.SetupWebhook(a.Server.WebhookURL()); err != nil {
+		return fmt.Errorf("failed to set up webhook: %w", err)
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		if err := a.Server.Start(); err != nil && err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+
+	if a.Query != nil {
+		go func() {
+			if err := a.Query.Start(); err != nil {
+				serverErr <- fmt.Errorf("gRPC query service: %w", err)
+			}
+		}()
+	}
+
+	go a.Ingest.InitialHookups()
+
+	// Send hourly and daily digests
+	go a.Bot.RunDigestScheduler(ctx, time.Minute)
+
+	// Deliver and retry outbound webhook events
+	go a.Webhooks.Run(ctx, 15*time.Second)
+
+	// Send alerts to Discord, Slack and Matrix destinations
+	for _, sinkChannel := range a.Sinks {
+		go sinkChannel.Run(ctx)
+	}
+
+	// Prune the event log, and end event streams on shutdown
+	go a.Events.Run(ctx, time.Hour)
+
+	// Send email alerts and daily digests
+	if a.Email != nil {
+		go a.Email.Run(ctx, time.Minute)
+	}
+
+	// Send a startup notification to the test channel
+	a.Bot.SendMessage(a.Config.TestChatID, "🤖 *Bot started successfully!*")
+
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+
+	// Shutdown servers
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if a.Query != nil {
+		a.Query.Stop(shutdownCtx)
+	}
+	return a.Server.Shutdown(shutdownCtx)
+}
+
+// Close closes the database
+func (a *App) Close() error {
+	return a.Database.Close()
+}
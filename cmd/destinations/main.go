@@ -0,0 +1,208 @@
+// Command destinations lets operators route proposal alerts to channels outside Telegram:
+//
+//	go run ./cmd/destinations add discord community url=https://discord.com/api/webhooks/...
+//	go run ./cmd/destinations subscribe community '$ZRA+0000' '$ZRA+*'
+//	go run ./cmd/destinations subscriptions community
+//	go run ./cmd/destinations unsubscribe community '$ZRA+*'
+//	go run ./cmd/destinations list
+//	go run ./cmd/destinations remove community
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/migrations"
+	"github.com/ZeraVision/ZeraBot/sink"
+	"github.com/ZeraVision/ZeraBot/symbol"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+const usage = `Usage: destinations COMMAND [ARGS]
+
+Commands:
+  add CHANNEL NAME KEY=VALUE...   register a destination, e.g. add discord community url=https://...
+  list                            list destinations
+  remove NAME                     remove a destination and its subscriptions
+  subscribe NAME SYMBOL...        route alerts for symbols ($ZRA+0000, $ZRA+*, all) to a destination
+  unsubscribe NAME SYMBOL...      stop routing alerts for symbols to a destination
+  subscriptions NAME              list the subscriptions of a destination
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	godotenv.Load(".env")
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable must be set")
+	}
+
+	database, err := db.NewDatabase(databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	if err := migrations.RunMigrations(ctx, database.DB()); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	if err := run(ctx, database, os.Args[1], os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, database *db.Database, command string, args []string) error {
+	destinations := db.NewDestinationRepository(database)
+	senders := sink.Senders(http.DefaultClient)
+
+	switch command {
+	case "add":
+		if len(args) < 2 {
+			return errors.New("usage: add CHANNEL NAME KEY=VALUE...")
+		}
+
+		channel := db.Channel(strings.ToLower(args[0]))
+		sender, ok := senders[channel]
+		if !ok {
+			return fmt.Errorf("unknown channel %q (available: %s)", channel, channelNames(senders))
+		}
+
+		settings := make(map[string]string)
+		for _, arg := range args[2:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf("invalid setting %q, want KEY=VALUE", arg)
+			}
+			settings[key] = value
+		}
+		if err := sender.Validate(settings); err != nil {
+			return err
+		}
+
+		d := &db.Destination{Channel: channel, Name: args[1], Settings: settings}
+		if err := destinations.Create(ctx, d); err != nil {
+			return err
+		}
+		fmt.Printf("Added %s destination %s\n", d.Channel, d.Name)
+
+	case "list":
+		list, err := destinations.List(ctx, "")
+		if err != nil {
+			return err
+		}
+		for _, d := range list {
+			fmt.Printf("%s\t%s\tcreated %s\n", d.Name, d.Channel, d.CreatedAt)
+		}
+
+	case "remove":
+		if len(args) != 1 {
+			return errors.New("usage: remove NAME")
+		}
+		if err := destinations.Delete(ctx, args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", args[0])
+
+	case "subscribe", "unsubscribe":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: %s NAME SYMBOL...", command)
+		}
+
+		d, subs, err := destinationSubscriptions(ctx, database, destinations, args[0])
+		if err != nil {
+			return err
+		}
+
+		symbols, err := parseSymbols(args[1:])
+		if err != nil {
+			return err
+		}
+
+		var results []*db.SymbolResult
+		if command == "subscribe" {
+			results, err = subs.SubscribeMany(ctx, d.ID, db.ProposalType, symbols)
+		} else {
+			results, err = subs.UnsubscribeMany(ctx, d.ID, db.ProposalType, symbols)
+		}
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			fmt.Printf("%s\t%s\n", result.Symbol, result.Outcome)
+		}
+
+	case "subscriptions":
+		if len(args) != 1 {
+			return errors.New("usage: subscriptions NAME")
+		}
+
+		d, subs, err := destinationSubscriptions(ctx, database, destinations, args[0])
+		if err != nil {
+			return err
+		}
+
+		list, err := subs.GetUserSubscriptions(ctx, d.ID)
+		if err != nil {
+			return err
+		}
+		for _, sub := range list {
+			fmt.Printf("%s\t%s\n", sub.Symbol, sub.Filter.String())
+		}
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+// destinationSubscriptions looks up a destination and returns the subscriptions of its channel
+func destinationSubscriptions(ctx context.Context, database *db.Database, destinations *db.DestinationRepository, name string) (*db.Destination, *db.SubscriptionRepository, error) {
+	d, err := destinations.GetByName(ctx, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, db.NewSubscriptionRepository(database).ForChannel(d.Channel), nil
+}
+
+// parseSymbols normalizes symbols and checks that they can be subscribed to
+func parseSymbols(args []string) ([]string, error) {
+	symbols := make([]string, 0, len(args))
+	for _, arg := range args {
+		if strings.EqualFold(arg, "all") {
+			symbols = append(symbols, "all")
+			continue
+		}
+
+		s := symbol.Normalize(arg)
+		if !symbol.IsValid(s) && !symbol.IsPattern(s) {
+			return nil, fmt.Errorf("invalid symbol %q, expected a contract ID such as $ZRA+0000 or a pattern such as $ZRA+*", arg)
+		}
+		symbols = append(symbols, s)
+	}
+	return symbols, nil
+}
+
+func channelNames(senders map[db.Channel]sink.Sender) string {
+	var names []string
+	for channel := range senders {
+		names = append(names, string(channel))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
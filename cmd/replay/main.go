@@ -0,0 +1,120 @@
+// Command replay feeds block fixtures through the proposal pipeline and prints the
+// Telegram messages the bot would have sent, without contacting Telegram:
+//
+//	go run ./cmd/replay -subscribe -100123 replay/testdata/proposal_ok.json
+//	go run ./cmd/replay -subscribe -100123 -expect '-100123=Fund the fixture' replay/testdata/*.json
+//
+// Contracts and proposals are recorded in DATABASE_URL, so point it at a scratch database.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/memstore"
+	"github.com/ZeraVision/ZeraBot/db/migrations"
+	"github.com/ZeraVision/ZeraBot/internal/telegramtest"
+	"github.com/ZeraVision/ZeraBot/replay"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+// listFlag collects a flag given several times
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	var subscribe, expect listFlag
+	flag.Var(&subscribe, "subscribe", "chat ID to subscribe to all proposals in an in-memory store instead of using stored subscriptions (repeatable)")
+	flag.Var(&expect, "expect", "CHATID=TEXT: fail unless a message containing TEXT was sent to CHATID (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] FIXTURE...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	godotenv.Load(".env")
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable must be set")
+	}
+
+	database, err := db.NewDatabase(databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	if err := migrations.RunMigrations(context.Background(), database.DB()); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	harness, err := replay.NewHarness(database)
+	if err != nil {
+		log.Fatalf("Failed to start replay harness: %v", err)
+	}
+	defer harness.Close()
+
+	if len(subscribe) > 0 {
+		store := memstore.NewSubscriptionStore()
+		for _, value := range subscribe {
+			chatID, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid chat ID %q", value)
+			}
+			if _, err := store.Subscribe(context.Background(), chatID, db.ProposalType, "all"); err != nil {
+				log.Fatalf("Failed to subscribe chat %d: %v", chatID, err)
+			}
+		}
+		harness.Bot.SetSubscriptionStore(store)
+	}
+
+	var sent []telegramtest.Message
+	for _, path := range flag.Args() {
+		messages, err := harness.ReplayFile(path)
+		if err != nil {
+			log.Fatalf("Failed to replay %s: %v", path, err)
+		}
+
+		fmt.Printf("== %s: %d message(s)\n", path, len(messages))
+		for _, msg := range messages {
+			fmt.Printf("-- to %d\n%s\n", msg.ChatID, msg.Text)
+		}
+		sent = append(sent, messages...)
+	}
+
+	failed := false
+	for _, value := range expect {
+		chat, text, ok := strings.Cut(value, "=")
+		chatID, err := strconv.ParseInt(chat, 10, 64)
+		if !ok || err != nil {
+			log.Fatalf("Invalid expectation %q, want CHATID=TEXT", value)
+		}
+		if err := replay.ExpectMessage(sent, chatID, text); err != nil {
+			log.Printf("FAIL: %v", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,37 @@
+// Command smtpsink runs a local SMTP server that prints the email it receives
+// instead of delivering it, for trying email alerts in development:
+//
+//	go run ./cmd/smtpsink -addr 127.0.0.1:1025
+//	SMTP_HOST=127.0.0.1 SMTP_PORT=1025 go run .
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ZeraVision/ZeraBot/internal/smtptest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:1025", "address to listen on")
+	flag.Parse()
+
+	sink, err := smtptest.NewServer(*addr)
+	if err != nil {
+		log.Fatalf("Failed to start SMTP sink: %v", err)
+	}
+	defer sink.Close()
+
+	sink.OnMessage(func(m smtptest.Message) {
+		log.Printf("Email from %s to %s: %s\n%s", m.From, strings.Join(m.To, ", "), m.Subject, m.Text)
+	})
+	log.Printf("SMTP sink listening on %s", sink.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+}
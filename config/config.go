@@ -3,15 +3,50 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	BotToken   string
-	Domain     string
-	WebhookURL string
-	Email      string
-	Env        string
+	BotToken    string
+	Domain      string
+	WebhookURL  string
+	Email       string
+	Env         string
 	DatabaseURL string
+	APIEndpoint string // Telegram Bot API endpoint format, empty for api.telegram.org
+	WebhookPath string // Path Telegram posts updates to
+	NgrokURL    string // Public URL used for the webhook in development, if set
+	GRPCAddress string // Domain of the network node allowed to broadcast blocks
+	SecretAuth  string // Block hash that authenticates broadcasts from other addresses
+	DevMode     bool   // Only send proposal alerts to TestChatID
+	TestChatID  int64  // Chat notified on startup and, in dev mode, of proposals
+
+	MaxSubscriptionsPerChat int           // 0 disables the limit
+	ChatCommandsPerMinute   int           // Commands a chat may send per minute, 0 disables the limit
+	UserCommandsPerMinute   int           // Commands a user may send per minute, 0 disables the limit
+	InlineQueriesPerMinute  int           // Inline queries a user may send per minute, 0 disables the limit
+	BanAfterViolations      int           // Rate limit violations within BanDuration before a user is banned, 0 disables bans
+	BanDuration             time.Duration // How long a temporary ban lasts
+	MetricsToken            string        // Bearer token for /debug/vars, metrics are not served if empty
+	MaxWebhooksPerChat      int           // Outbound webhooks a chat may register, 0 disables the limit
+	WebhooksAllowPrivate    bool          // Allow webhooks to http and private addresses (development only)
+
+	SMTPHost     string // Email alerts are disabled if empty
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string // Sender of email alerts, e.g. "ZeraBot <alerts@example.com>"
+	PublicURL    string // Base URL of the HTTP server in links, e.g. email confirmation links
+
+	APIAllowedOrigins []string // Origins allowed to call the JSON API from browsers, "*" for any
+
+	QueryGRPCAddress      string   // Address the gRPC query service listens on, disabled if empty
+	QueryGRPCTokens       []string // Bearer tokens accepted by the gRPC query service
+	QueryGRPCManageTokens []string // Bearer tokens that may also change any chat's subscriptions over gRPC
+
+	AdminTokens map[string]string // Bearer tokens of the admin API by operator name, disabled if empty
 }
 
 // Load loads configuration from environment variables
@@ -31,12 +66,164 @@ func Load() (*Config, error) {
 
 	webhookURL := fmt.Sprintf("https://%s/%s", domain, webhookSecret)
 
-	return &Config{
-		BotToken:   botToken,
-		Domain:     domain,
-		WebhookURL: webhookURL,
-		Email:      os.Getenv("EMAIL"),
-		Env:        env,
-		DatabaseURL: databaseURL,
-	}, nil
+	cfg := &Config{
+		BotToken:     botToken,
+		Domain:       domain,
+		WebhookURL:   webhookURL,
+		Email:        os.Getenv("EMAIL"),
+		Env:          env,
+		DatabaseURL:  databaseURL,
+		APIEndpoint:  os.Getenv("TELEGRAM_API_ENDPOINT"),
+		WebhookPath:  "/" + webhookSecret,
+		NgrokURL:     os.Getenv("NGROK_URL"),
+		GRPCAddress:  os.Getenv("GRPC_ADDRESS"),
+		SecretAuth:   os.Getenv("SECRET_AUTH"),
+		DevMode:      os.Getenv("DEV") == "TRUE",
+		MetricsToken: os.Getenv("METRICS_TOKEN"),
+
+		WebhooksAllowPrivate: os.Getenv("WEBHOOKS_ALLOW_PRIVATE") == "true",
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     os.Getenv("SMTP_FROM"),
+		PublicURL:    os.Getenv("PUBLIC_URL"),
+
+		QueryGRPCAddress: os.Getenv("QUERY_GRPC_ADDRESS"),
+	}
+
+	var err error
+	if cfg.TestChatID, err = int64Env("TEST_CHAT_ID", -4897181115); err != nil {
+		return nil, err
+	}
+	if cfg.MaxSubscriptionsPerChat, err = intEnv("MAX_SUBSCRIPTIONS_PER_CHAT", 100); err != nil {
+		return nil, err
+	}
+	if cfg.ChatCommandsPerMinute, err = intEnv("CHAT_COMMANDS_PER_MINUTE", 30); err != nil {
+		return nil, err
+	}
+	if cfg.UserCommandsPerMinute, err = intEnv("USER_COMMANDS_PER_MINUTE", 20); err != nil {
+		return nil, err
+	}
+	if cfg.InlineQueriesPerMinute, err = intEnv("INLINE_QUERIES_PER_MINUTE", 120); err != nil {
+		return nil, err
+	}
+	if cfg.BanAfterViolations, err = intEnv("BAN_AFTER_VIOLATIONS", 10); err != nil {
+		return nil, err
+	}
+	if cfg.BanDuration, err = durationEnv("BAN_DURATION", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.MaxWebhooksPerChat, err = intEnv("MAX_WEBHOOKS_PER_CHAT", 5); err != nil {
+		return nil, err
+	}
+	if cfg.SMTPPort, err = intEnv("SMTP_PORT", 587); err != nil {
+		return nil, err
+	}
+
+	// Links point at the domain the bot is served on unless configured otherwise
+	if cfg.PublicURL == "" {
+		switch {
+		case env == "production":
+			cfg.PublicURL = "https://" + domain
+		case cfg.NgrokURL != "":
+			cfg.PublicURL = cfg.NgrokURL
+		default:
+			cfg.PublicURL = "http://localhost:8080"
+		}
+	}
+	cfg.APIAllowedOrigins = []string{"*"}
+	if origins := os.Getenv("API_ALLOWED_ORIGINS"); origins != "" {
+		cfg.APIAllowedOrigins = strings.Split(strings.ReplaceAll(origins, " ", ""), ",")
+	}
+
+	if cfg.SMTPHost != "" && cfg.SMTPFrom == "" {
+		return nil, fmt.Errorf("SMTP_FROM must be set when SMTP_HOST is")
+	}
+
+	cfg.QueryGRPCTokens = listEnv("QUERY_GRPC_TOKENS")
+	cfg.QueryGRPCManageTokens = listEnv("QUERY_GRPC_MANAGE_TOKENS")
+	if cfg.QueryGRPCAddress != "" && len(cfg.QueryGRPCTokens) == 0 && len(cfg.QueryGRPCManageTokens) == 0 {
+		return nil, fmt.Errorf("QUERY_GRPC_TOKENS or QUERY_GRPC_MANAGE_TOKENS must be set when QUERY_GRPC_ADDRESS is")
+	}
+
+	if cfg.AdminTokens, err = adminTokensEnv("ADMIN_TOKENS"); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// listEnv reads a comma separated list, leaving out empty items
+func listEnv(name string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// adminTokensEnv reads comma separated name:token pairs, e.g. "alice:s3cret,bob:t0ken"
+func adminTokensEnv(name string) (map[string]string, error) {
+	tokens := map[string]string{}
+	for _, entry := range strings.Split(os.Getenv(name), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		operator, token, ok := strings.Cut(entry, ":")
+		operator, token = strings.TrimSpace(operator), strings.TrimSpace(token)
+		if !ok || operator == "" || token == "" {
+			return nil, fmt.Errorf("%s entries must look like name:token, got %q", name, entry)
+		}
+		if _, exists := tokens[operator]; exists {
+			return nil, fmt.Errorf("%s has more than one token for %q", name, operator)
+		}
+		tokens[operator] = token
+	}
+	return tokens, nil
+}
+
+// intEnv reads a non-negative integer environment variable, using def if it is unset
+func intEnv(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", name, value)
+	}
+	return n, nil
+}
+
+// int64Env reads an integer environment variable such as a chat ID, using def if it is unset
+func int64Env(name string, def int64) (int64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %q", name, value)
+	}
+	return n, nil
+}
+
+// durationEnv reads a positive duration environment variable such as "1h", using def if it is unset
+func durationEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 1h, got %q", name, value)
+	}
+	return d, nil
 }
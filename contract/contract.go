@@ -0,0 +1,68 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/registry"
+	"github.com/ZeraVision/ZeraBot/txnstatus"
+	zera_protobuf "github.com/ZeraVision/go-zera-network/grpc/protobuf"
+)
+
+// Processor records contracts seen on chain
+type Processor struct {
+	registry *registry.Registry
+}
+
+// NewProcessor creates a processor recording contracts in a registry
+func NewProcessor(reg *registry.Registry) *Processor {
+	return &Processor{registry: reg}
+}
+
+// ProcessContracts records the instrument contracts created in a block, along with
+// contracts referenced by its proposals, in the contract registry
+func (p *Processor) ProcessContracts(block *zera_protobuf.Block) error {
+	height := block.GetBlockHeader().GetBlockHeight()
+	var contracts []*db.Contract
+
+	for _, contract := range block.Transactions.ContractTxns {
+		status, err := txnstatus.GetStatus(contract.GetBase().GetHash(), block.Transactions.TxnFeesAndStatus)
+		if err != nil {
+			log.Printf("Error getting status for contract %s: %v", contract.ContractId, err)
+			continue
+		}
+
+		// If not STATUS_OK - ignore
+		if status != zera_protobuf.TXN_STATUS_OK {
+			continue
+		}
+
+		contracts = append(contracts, &db.Contract{
+			ContractID:     contract.ContractId,
+			Symbol:         contract.Symbol,
+			Name:           contract.Name,
+			FirstSeenBlock: height,
+		})
+	}
+
+	// Accepted proposals can only be made against existing contracts
+	for _, proposal := range block.Transactions.GovernanceProposals {
+		status, err := txnstatus.GetStatus(proposal.GetBase().GetHash(), block.Transactions.TxnFeesAndStatus)
+		if err != nil || status != zera_protobuf.TXN_STATUS_OK {
+			continue
+		}
+
+		contracts = append(contracts, &db.Contract{
+			ContractID:     proposal.ContractId,
+			FirstSeenBlock: height,
+		})
+	}
+
+	if err := p.registry.Add(context.Background(), contracts...); err != nil {
+		return fmt.Errorf("failed to record contracts: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditEntry records a request to the admin API
+type AuditEntry struct {
+	ID         int64
+	Actor      string // Name of the admin token used
+	Action     string // Route, e.g. "POST /admin/blocks/{height}/replay"
+	Path       string
+	Details    json.RawMessage // Request body of changes, nil for reads
+	Status     int             // HTTP status of the response
+	RemoteAddr string
+	CreatedAt  time.Time
+}
+
+// AuditStore stores the admin audit log. AuditRepository implements it on Postgres.
+type AuditStore interface {
+	// Record appends an entry to the audit log and sets its ID and creation time
+	Record(ctx context.Context, e *AuditEntry) error
+	// List returns up to limit entries before an ID, or the latest if beforeID is 0, newest first
+	List(ctx context.Context, beforeID int64, limit int) ([]*AuditEntry, error)
+}
+
+var _ AuditStore = (*AuditRepository)(nil)
+
+// AuditRepository handles database operations for the admin audit log
+type AuditRepository struct {
+	q Querier
+}
+
+func NewAuditRepository(q Querier) *AuditRepository {
+	return &AuditRepository{q: q}
+}
+
+// Record appends an entry to the audit log and sets its ID and creation time
+func (r *AuditRepository) Record(ctx context.Context, e *AuditEntry) error {
+	const query = `
+		INSERT INTO admin_audit_log (actor, action, path, details, status, remote_addr)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	var details interface{}
+	if e.Details != nil {
+		details = []byte(e.Details)
+	}
+
+	err := r.q.QueryRowContext(ctx, query, e.Actor, e.Action, e.Path, details, e.Status, e.RemoteAddr).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// List returns up to limit entries before an ID, or the latest if beforeID is 0, newest first
+func (r *AuditRepository) List(ctx context.Context, beforeID int64, limit int) ([]*AuditEntry, error) {
+	const query = `
+		SELECT id, actor, action, path, details, status, remote_addr, created_at
+		FROM admin_audit_log
+		WHERE $1::bigint = 0 OR id < $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		e := &AuditEntry{}
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Path, &details, &e.Status, &e.RemoteAddr, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if details != nil {
+			e.Details = details
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit log: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Ban represents a user temporarily blocked from using the bot
+type Ban struct {
+	UserID    int64
+	Reason    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// BanStore stores bans. BanRepository implements it on Postgres.
+type BanStore interface {
+	// Ban bans a user until a point in time, replacing any existing ban
+	Ban(ctx context.Context, ban *Ban) error
+	// Unban lifts a user's ban
+	Unban(ctx context.Context, userID int64) error
+	// ListActive returns the bans that haven't expired
+	ListActive(ctx context.Context) ([]*Ban, error)
+}
+
+var _ BanStore = (*BanRepository)(nil)
+
+// BanRepository handles database operations for bans
+type BanRepository struct {
+	q Querier
+}
+
+func NewBanRepository(q Querier) *BanRepository {
+	return &BanRepository{q: q}
+}
+
+// Ban bans a user until a point in time, replacing any existing ban
+func (r *BanRepository) Ban(ctx context.Context, ban *Ban) error {
+	const query = `
+		INSERT INTO bans (user_id, reason, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			reason = EXCLUDED.reason,
+			expires_at = EXCLUDED.expires_at,
+			created_at = NOW()
+	`
+
+	if _, err := r.q.ExecContext(ctx, query, ban.UserID, ban.Reason, ban.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to ban user: %w", err)
+	}
+
+	return nil
+}
+
+// Unban lifts a user's ban
+func (r *BanRepository) Unban(ctx context.Context, userID int64) error {
+	const query = `DELETE FROM bans WHERE user_id = $1`
+
+	if _, err := r.q.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to unban user: %w", err)
+	}
+
+	return nil
+}
+
+// ListActive returns all bans that have not expired, removing expired ones
+func (r *BanRepository) ListActive(ctx context.Context) ([]*Ban, error) {
+	if _, err := r.q.ExecContext(ctx, `DELETE FROM bans WHERE expires_at <= NOW()`); err != nil {
+		return nil, fmt.Errorf("failed to remove expired bans: %w", err)
+	}
+
+	const query = `
+		SELECT user_id, reason, expires_at, created_at
+		FROM bans
+		ORDER BY expires_at
+	`
+
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+	defer rows.Close()
+
+	var bans []*Ban
+	for rows.Next() {
+		ban := &Ban{}
+		if err := rows.Scan(&ban.UserID, &ban.Reason, &ban.ExpiresAt, &ban.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ban: %w", err)
+		}
+		bans = append(bans, ban)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating bans: %w", err)
+	}
+
+	return bans, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrBlockNotFound is returned when a block at a height wasn't kept
+var ErrBlockNotFound = errors.New("block not found")
+
+// BlockRepository keeps received blocks so they can be processed again
+type BlockRepository struct {
+	q Querier
+}
+
+func NewBlockRepository(q Querier) *BlockRepository {
+	return &BlockRepository{q: q}
+}
+
+// Save keeps an encoded block, replacing one kept at the same height
+func (r *BlockRepository) Save(ctx context.Context, height uint64, hash string, data []byte) error {
+	const query = `
+		INSERT INTO blocks (height, hash, data)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (height) DO UPDATE SET
+			hash = EXCLUDED.hash,
+			data = EXCLUDED.data,
+			received_at = NOW()
+	`
+
+	if _, err := r.q.ExecContext(ctx, query, int64(height), hash, data); err != nil {
+		return fmt.Errorf("failed to save block: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the encoded block at a height, or ErrBlockNotFound
+func (r *BlockRepository) Get(ctx context.Context, height uint64) ([]byte, error) {
+	var data []byte
+	err := r.q.QueryRowContext(ctx, `SELECT data FROM blocks WHERE height = $1`, int64(height)).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBlockNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block: %w", err)
+	}
+
+	return data, nil
+}
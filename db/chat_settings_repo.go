@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MessageFormat controls how much detail proposal alerts include
+type MessageFormat string
+
+const (
+	// FullFormat includes the title, synopsis, and proposal ID
+	FullFormat MessageFormat = "full"
+	// CompactFormat includes only the symbol, title, and explorer link
+	CompactFormat MessageFormat = "compact"
+)
+
+// DeliveryMode controls whether proposal alerts are sent immediately or batched into digests
+type DeliveryMode string
+
+const (
+	// InstantDelivery sends each alert as soon as the proposal is seen
+	InstantDelivery DeliveryMode = "instant"
+	// HourlyDigest sends one summary at the start of every hour
+	HourlyDigest DeliveryMode = "hourly"
+	// DailyDigest sends one summary a day at the chat's digest hour
+	DailyDigest DeliveryMode = "daily"
+)
+
+// QuietMode controls what happens to alerts during quiet hours or a snooze
+type QuietMode string
+
+const (
+	// HoldAlerts queues alerts and delivers them once the quiet period ends
+	HoldAlerts QuietMode = "hold"
+	// SilentAlerts delivers alerts right away without a notification sound
+	SilentAlerts QuietMode = "silent"
+)
+
+// ChatSettings represents the preferences of a single chat
+type ChatSettings struct {
+	ChatID        int64
+	Language      string // Empty when the chat has not chosen a language
+	Timezone      string
+	Muted         bool
+	MessageFormat MessageFormat
+	LinkPreviews  bool
+	AdminOnly     bool
+	DeliveryMode  DeliveryMode
+	DigestHour    int       // Local hour (0-23) at which daily digests are sent
+	LastDigestAt  time.Time // Zero if no digest was sent yet
+	QuietStart    int       // Minutes after local midnight; quiet hours are off if equal to QuietEnd
+	QuietEnd      int
+	QuietMode     QuietMode
+	SnoozedUntil  time.Time // Zero if the chat is not snoozed
+	CreatedAt     string
+	UpdatedAt     string
+}
+
+// DefaultChatSettings returns the settings used for chats that have not changed anything
+func DefaultChatSettings(chatID int64) *ChatSettings {
+	return &ChatSettings{
+		ChatID:        chatID,
+		Timezone:      "UTC",
+		MessageFormat: FullFormat,
+		LinkPreviews:  true,
+		AdminOnly:     true,
+		DeliveryMode:  InstantDelivery,
+		DigestHour:    9,
+		QuietMode:     HoldAlerts,
+	}
+}
+
+// ChatSettingsRepository handles database operations for per-chat preferences
+type ChatSettingsRepository struct {
+	q Querier
+}
+
+func NewChatSettingsRepository(q Querier) *ChatSettingsRepository {
+	return &ChatSettingsRepository{q: q}
+}
+
+// Get returns the settings for a chat, or the defaults if none were stored
+func (r *ChatSettingsRepository) Get(ctx context.Context, chatID int64) (*ChatSettings, error) {
+	const query = `
+		SELECT chat_id, language, timezone, muted, message_format, link_previews, admin_only,
+			delivery_mode, digest_hour, last_digest_at, quiet_start, quiet_end, quiet_mode, snoozed_until,
+			created_at, updated_at
+		FROM chat_settings
+		WHERE chat_id = $1
+	`
+
+	settings := &ChatSettings{}
+	var language sql.NullString
+	var lastDigestAt, snoozedUntil sql.NullTime
+	err := r.q.QueryRowContext(ctx, query, chatID).Scan(
+		&settings.ChatID,
+		&language,
+		&settings.Timezone,
+		&settings.Muted,
+		&settings.MessageFormat,
+		&settings.LinkPreviews,
+		&settings.AdminOnly,
+		&settings.DeliveryMode,
+		&settings.DigestHour,
+		&lastDigestAt,
+		&settings.QuietStart,
+		&settings.QuietEnd,
+		&settings.QuietMode,
+		&snoozedUntil,
+		&settings.CreatedAt,
+		&settings.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultChatSettings(chatID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat settings: %w", err)
+	}
+
+	settings.Language = language.String
+	settings.LastDigestAt = lastDigestAt.Time
+	settings.SnoozedUntil = snoozedUntil.Time
+	return settings, nil
+}
+
+// Save inserts or updates the user-editable settings for a chat
+func (r *ChatSettingsRepository) Save(ctx context.Context, settings *ChatSettings) error {
+	const query = `
+		INSERT INTO chat_settings (chat_id, language, timezone, muted, message_format, link_previews, admin_only,
+			delivery_mode, digest_hour, quiet_start, quiet_end, quiet_mode, snoozed_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET language = EXCLUDED.language,
+			timezone = EXCLUDED.timezone,
+			muted = EXCLUDED.muted,
+			message_format = EXCLUDED.message_format,
+			link_previews = EXCLUDED.link_previews,
+			admin_only = EXCLUDED.admin_only,
+			delivery_mode = EXCLUDED.delivery_mode,
+			digest_hour = EXCLUDED.digest_hour,
+			quiet_start = EXCLUDED.quiet_start,
+			quiet_end = EXCLUDED.quiet_end,
+			quiet_mode = EXCLUDED.quiet_mode,
+			snoozed_until = EXCLUDED.snoozed_until
+		RETURNING created_at, updated_at
+	`
+
+	var language sql.NullString
+	if settings.Language != "" {
+		language = sql.NullString{String: settings.Language, Valid: true}
+	}
+
+	var snoozedUntil sql.NullTime
+	if !settings.SnoozedUntil.IsZero() {
+		snoozedUntil = sql.NullTime{Time: settings.SnoozedUntil, Valid: true}
+	}
+
+	err := r.q.QueryRowContext(
+		ctx,
+		query,
+		settings.ChatID,
+		language,
+		settings.Timezone,
+		settings.Muted,
+		settings.MessageFormat,
+		settings.LinkPreviews,
+		settings.AdminOnly,
+		settings.DeliveryMode,
+		settings.DigestHour,
+		settings.QuietStart,
+		settings.QuietEnd,
+		settings.QuietMode,
+		snoozedUntil,
+	).Scan(&settings.CreatedAt, &settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save chat settings: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDigestSent records when the last digest was sent to a chat
+func (r *ChatSettingsRepository) MarkDigestSent(ctx context.Context, chatID int64, sentAt time.Time) error {
+	const query = `
+		INSERT INTO chat_settings (chat_id, last_digest_at)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET last_digest_at = EXCLUDED.last_digest_at
+	`
+
+	if _, err := r.q.ExecContext(ctx, query, chatID, sentAt); err != nil {
+		return fmt.Errorf("failed to mark digest sent: %w", err)
+	}
+
+	return nil
+}
+
+// GetLanguage returns the language chosen for a chat, or an empty string if none was set
+func (r *ChatSettingsRepository) GetLanguage(ctx context.Context, chatID int64) (string, error) {
+	const query = `SELECT language FROM chat_settings WHERE chat_id = $1`
+
+	var language sql.NullString
+	err := r.q.QueryRowContext(ctx, query, chatID).Scan(&language)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get chat language: %w", err)
+	}
+
+	return language.String, nil
+}
+
+// SetLanguage stores the language for a chat
+func (r *ChatSettingsRepository) SetLanguage(ctx context.Context, chatID int64, language string) error {
+	const query = `
+		INSERT INTO chat_settings (chat_id, language)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET language = EXCLUDED.language
+	`
+
+	if _, err := r.q.ExecContext(ctx, query, chatID, language); err != nil {
+		return fmt.Errorf("failed to set chat language: %w", err)
+	}
+
+	return nil
+}
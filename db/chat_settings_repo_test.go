@@ -0,0 +1,107 @@
+package db_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+)
+
+func TestDefaultChatSettings(t *testing.T) {
+	want := &db.ChatSettings{
+		ChatID:        7,
+		Timezone:      "UTC",
+		MessageFormat: db.FullFormat,
+		LinkPreviews:  true,
+		AdminOnly:     true,
+		DeliveryMode:  db.InstantDelivery,
+		DigestHour:    9,
+		QuietMode:     db.HoldAlerts,
+	}
+	if got := db.DefaultChatSettings(7); !reflect.DeepEqual(got, want) {
+		t.Errorf("DefaultChatSettings = %+v, want %+v", got, want)
+	}
+}
+
+// stored clears the timestamps a chat settings row gets from the database
+func stored(settings *db.ChatSettings) db.ChatSettings {
+	s := *settings
+	s.CreatedAt, s.UpdatedAt = "", ""
+	return s
+}
+
+func TestChatSettingsRoundTrip(t *testing.T) {
+	repo := db.NewChatSettingsRepository(pgtest.Open(t))
+	ctx := context.Background()
+
+	// Chats without a row get the defaults
+	got, err := repo.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !reflect.DeepEqual(got, db.DefaultChatSettings(1)) {
+		t.Errorf("settings of a new chat = %+v, want the defaults", got)
+	}
+
+	changed := &db.ChatSettings{
+		ChatID:        1,
+		Language:      "es",
+		Timezone:      "America/New_York",
+		Muted:         true,
+		MessageFormat: db.CompactFormat,
+		LinkPreviews:  false,
+		AdminOnly:     false,
+		DeliveryMode:  db.DailyDigest,
+		DigestHour:    18,
+		QuietStart:    23 * 60,
+		QuietEnd:      7*60 + 30,
+		QuietMode:     db.SilentAlerts,
+		SnoozedUntil:  time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC),
+	}
+	if err := repo.Save(ctx, changed); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if changed.CreatedAt == "" || changed.UpdatedAt == "" {
+		t.Error("Save didn't set the timestamps")
+	}
+	sentAt := time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)
+	if err := repo.MarkDigestSent(ctx, 1, sentAt); err != nil {
+		t.Fatalf("MarkDigestSent: %v", err)
+	}
+
+	got, err = repo.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	want := stored(changed)
+	want.LastDigestAt = sentAt
+	if !got.SnoozedUntil.Equal(want.SnoozedUntil) || !got.LastDigestAt.Equal(want.LastDigestAt) {
+		t.Errorf("stored snooze %s and last digest %s, want %s and %s", got.SnoozedUntil, got.LastDigestAt, want.SnoozedUntil, want.LastDigestAt)
+	}
+	got.SnoozedUntil, got.LastDigestAt = want.SnoozedUntil, want.LastDigestAt // Compared above, the location may differ
+	if stored(got) != want {
+		t.Errorf("stored %+v, want %+v", stored(got), want)
+	}
+
+	// Saving user settings keeps the digest period, and clearing the language and snooze stores them as unset
+	changed.Language = ""
+	changed.SnoozedUntil = time.Time{}
+	if err := repo.Save(ctx, changed); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err = repo.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Language != "" || !got.SnoozedUntil.IsZero() || !got.LastDigestAt.Equal(sentAt) {
+		t.Errorf("after clearing, language %q, snooze %s and last digest %s, want unset, unset and %s", got.Language, got.SnoozedUntil, got.LastDigestAt, sentAt)
+	}
+
+	// Only the chat's own row changes
+	if other, err := repo.Get(ctx, 2); err != nil || !reflect.DeepEqual(other, db.DefaultChatSettings(2)) {
+		t.Errorf("settings of another chat = %+v, %v, want the defaults", other, err)
+	}
+}
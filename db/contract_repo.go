@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// Contract represents an instrument contract seen on chain
+type Contract struct {
+	ContractID     string // e.g. $ZRA+0000
+	Symbol         string
+	Name           string
+	FirstSeenBlock uint64
+	CreatedAt      string
+}
+
+// ContractStore stores known contracts. ContractRepository implements it on Postgres.
+type ContractStore interface {
+	// Save records a contract, keeping known details that the contract leaves empty
+	Save(ctx context.Context, contract *Contract) error
+	// List returns all known contracts ordered by contract ID
+	List(ctx context.Context) ([]*Contract, error)
+}
+
+var _ ContractStore = (*ContractRepository)(nil)
+
+// ContractRepository handles database operations for known contracts
+type ContractRepository struct {
+	q Querier
+}
+
+func NewContractRepository(q Querier) *ContractRepository {
+	return &ContractRepository{q: q}
+}
+
+// Save records a contract. Known contracts keep their first seen block, while an
+// empty symbol or name never overwrites a known one.
+func (r *ContractRepository) Save(ctx context.Context, contract *Contract) error {
+	const query = `
+		INSERT INTO contracts (contract_id, symbol, name, first_seen_block)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (contract_id) DO UPDATE SET
+			symbol = COALESCE(NULLIF(EXCLUDED.symbol, ''), contracts.symbol),
+			name = COALESCE(NULLIF(EXCLUDED.name, ''), contracts.name)
+		WHERE (EXCLUDED.symbol <> '' AND EXCLUDED.symbol <> contracts.symbol)
+			OR (EXCLUDED.name <> '' AND EXCLUDED.name <> contracts.name)
+	`
+
+	_, err := r.q.ExecContext(
+		ctx,
+		query,
+		contract.ContractID,
+		contract.Symbol,
+		contract.Name,
+		int64(contract.FirstSeenBlock),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save contract: %w", err)
+	}
+
+	return nil
+}
+
+// List returns all known contracts ordered by contract ID
+func (r *ContractRepository) List(ctx context.Context) ([]*Contract, error) {
+	const query = `
+		SELECT contract_id, symbol, name, first_seen_block, created_at
+		FROM contracts
+		ORDER BY contract_id
+	`
+
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contracts: %w", err)
+	}
+	defer rows.Close()
+
+	var contracts []*Contract
+	for rows.Next() {
+		contract := &Contract{}
+		var firstSeen int64
+		if err := rows.Scan(&contract.ContractID, &contract.Symbol, &contract.Name, &firstSeen, &contract.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan contract: %w", err)
+		}
+		contract.FirstSeenBlock = uint64(firstSeen)
+		contracts = append(contracts, contract)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contracts: %w", err)
+	}
+
+	return contracts, nil
+}
+
+// Count returns how many contracts are known
+func (r *ContractRepository) Count(ctx context.Context) (int, error) {
+	const query = `SELECT COUNT(*) FROM contracts`
+
+	var count int
+	if err := r.q.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count contracts: %w", err)
+	}
+	return count, nil
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -47,9 +48,49 @@ func (d *Database) DB() *sql.DB {
 	return d.db
 }
 
-// WithTransaction executes a function within a transaction
+// Querier runs queries. It is implemented by *sql.DB, *sql.Tx and *Database, so
+// repositories created with a transaction take part in it.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// txBeginner starts transactions, like *sql.DB and *Database
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// ExecContext executes a query without returning rows
+func (d *Database) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext executes a query that returns rows
+func (d *Database) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query that returns at most one row
+func (d *Database) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction
+func (d *Database) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return d.db.BeginTx(ctx, opts)
+}
+
+// WithTransaction executes a function within a transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise; commit and rollback
+// failures are returned too. Repositories created with the transaction, e.g.
+// NewSubscriptionRepository(tx), can be combined into one atomic change.
 func (d *Database) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
-	tx, err := d.db.BeginTx(ctx, nil)
+	return withTransaction(ctx, d.db, fn)
+}
+
+func withTransaction(ctx context.Context, beginner txBeginner, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := beginner.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -58,13 +99,34 @@ func (d *Database) WithTransaction(ctx context.Context, fn func(tx *sql.Tx) erro
 		if p := recover(); p != nil {
 			tx.Rollback()
 			panic(p) // Re-throw panic after rollback
-		} else if err != nil {
-			tx.Rollback()
-		} else {
-			err = tx.Commit()
 		}
 	}()
 
-	err = fn(tx)
-	return err
+	if err := fn(tx); err != nil {
+		// ErrTxDone means the transaction was already rolled back, e.g. as ctx was cancelled
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rollbackErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// inTransaction runs fn in q if it already is a transaction, or in a new transaction otherwise
+func inTransaction(ctx context.Context, q Querier, fn func(tx *sql.Tx) error) error {
+	if tx, ok := q.(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	beginner, ok := q.(txBeginner)
+	if !ok {
+		return errors.New("querier cannot begin transactions")
+	}
+
+	return withTransaction(ctx, beginner, fn)
 }
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeConnector opens connections whose transactions fail as configured and
+// counts how transactions end. Statements are not supported.
+type fakeConnector struct {
+	beginErr    error
+	commitErr   error
+	rollbackErr error
+
+	mu        sync.Mutex
+	commits   int
+	rollbacks int
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{c: c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return nil }
+
+func (c *fakeConnector) counts() (commits, rollbacks int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commits, c.rollbacks
+}
+
+type fakeConn struct{ c *fakeConnector }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	if c.c.beginErr != nil {
+		return nil, c.c.beginErr
+	}
+	return &fakeTx{c: c.c}, nil
+}
+
+type fakeTx struct{ c *fakeConnector }
+
+func (tx *fakeTx) Commit() error {
+	tx.c.mu.Lock()
+	defer tx.c.mu.Unlock()
+	tx.c.commits++
+	return tx.c.commitErr
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.c.mu.Lock()
+	defer tx.c.mu.Unlock()
+	tx.c.rollbacks++
+	return tx.c.rollbackErr
+}
+
+func TestWithTransaction(t *testing.T) {
+	errBegin := errors.New("connection refused")
+	errCommit := errors.New("could not serialize access")
+	errRollback := errors.New("connection reset")
+	errFn := errors.New("constraint violated")
+
+	cases := []struct {
+		name          string
+		connector     *fakeConnector
+		fnErr         error
+		wantErrs      []error // Errors the result must wrap, none for success
+		wantMessage   string  // Text the error must contain
+		wantCommits   int
+		wantRollbacks int
+	}{
+		{
+			name:        "Commit",
+			connector:   &fakeConnector{},
+			wantCommits: 1,
+		},
+		{
+			name:        "CommitFails",
+			connector:   &fakeConnector{commitErr: errCommit},
+			wantErrs:    []error{errCommit},
+			wantMessage: "failed to commit transaction",
+			wantCommits: 1,
+		},
+		{
+			name:          "Rollback",
+			connector:     &fakeConnector{},
+			fnErr:         errFn,
+			wantErrs:      []error{errFn},
+			wantRollbacks: 1,
+		},
+		{
+			name:          "RollbackFails",
+			connector:     &fakeConnector{rollbackErr: errRollback},
+			fnErr:         errFn,
+			wantErrs:      []error{errFn, errRollback},
+			wantMessage:   "failed to roll back transaction",
+			wantRollbacks: 1,
+		},
+		{
+			name:          "RollbackAfterTxDone",
+			connector:     &fakeConnector{rollbackErr: sql.ErrTxDone},
+			fnErr:         errFn,
+			wantErrs:      []error{errFn},
+			wantRollbacks: 1,
+		},
+		{
+			name:        "BeginFails",
+			connector:   &fakeConnector{beginErr: errBegin},
+			wantErrs:    []error{errBegin},
+			wantMessage: "failed to begin transaction",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sqlDB := sql.OpenDB(c.connector)
+			defer sqlDB.Close()
+			database := &Database{db: sqlDB}
+
+			called := false
+			err := database.WithTransaction(context.Background(), func(tx *sql.Tx) error {
+				called = true
+				return c.fnErr
+			})
+
+			if len(c.wantErrs) == 0 && err != nil {
+				t.Fatalf("WithTransaction = %v, want nil", err)
+			}
+			if len(c.wantErrs) > 0 && err == nil {
+				t.Fatalf("WithTransaction = nil, want an error wrapping %v", c.wantErrs)
+			}
+			for _, want := range c.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("WithTransaction = %v, want it to wrap %v", err, want)
+				}
+			}
+			if err != nil && !strings.Contains(err.Error(), c.wantMessage) {
+				t.Errorf("WithTransaction = %q, want it to contain %q", err, c.wantMessage)
+			}
+
+			if called != (c.connector.beginErr == nil) {
+				t.Errorf("fn called = %v with begin error %v", called, c.connector.beginErr)
+			}
+			commits, rollbacks := c.connector.counts()
+			if commits != c.wantCommits || rollbacks != c.wantRollbacks {
+				t.Errorf("%d commits and %d rollbacks, want %d and %d", commits, rollbacks, c.wantCommits, c.wantRollbacks)
+			}
+		})
+	}
+}
+
+func TestWithTransactionRollsBackOnPanic(t *testing.T) {
+	connector := &fakeConnector{}
+	sqlDB := sql.OpenDB(connector)
+	defer sqlDB.Close()
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the panic to be re-raised", p)
+		}
+		if commits, rollbacks := connector.counts(); commits != 0 || rollbacks != 1 {
+			t.Errorf("%d commits and %d rollbacks after a panic, want 0 and 1", commits, rollbacks)
+		}
+	}()
+
+	withTransaction(context.Background(), sqlDB, func(tx *sql.Tx) error {
+		panic("boom")
+	})
+}
+
+func TestInTransactionJoinsExistingTransaction(t *testing.T) {
+	ctx := context.Background()
+	connector := &fakeConnector{}
+	sqlDB := sql.OpenDB(connector)
+	defer sqlDB.Close()
+
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+
+	var got *sql.Tx
+	if err := inTransaction(ctx, tx, func(inner *sql.Tx) error {
+		got = inner
+		return nil
+	}); err != nil {
+		t.Fatalf("inTransaction: %v", err)
+	}
+	if got != tx {
+		t.Error("inTransaction started a new transaction inside an existing one")
+	}
+	if commits, rollbacks := connector.counts(); commits != 0 || rollbacks != 0 {
+		t.Errorf("inTransaction ended the caller's transaction: %d commits, %d rollbacks", commits, rollbacks)
+	}
+
+	// Errors are left to the caller, who owns the transaction
+	errFn := errors.New("failed")
+	if err := inTransaction(ctx, tx, func(*sql.Tx) error { return errFn }); !errors.Is(err, errFn) {
+		t.Errorf("inTransaction = %v, want %v", err, errFn)
+	}
+	if _, rollbacks := connector.counts(); rollbacks != 0 {
+		t.Error("inTransaction rolled back the caller's transaction")
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// A new transaction is committed by inTransaction itself
+	if err := inTransaction(ctx, sqlDB, func(*sql.Tx) error { return nil }); err != nil {
+		t.Fatalf("inTransaction: %v", err)
+	}
+	if commits, _ := connector.counts(); commits != 2 {
+		t.Errorf("%d commits, want 2", commits)
+	}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrDestinationNotFound is returned when a destination doesn't exist
+var ErrDestinationNotFound = errors.New("destination not found")
+
+// Destination is a place outside Telegram that alerts are delivered to, such as a Discord webhook
+type Destination struct {
+	ID        int64 // Used as the chat ID of the destination's subscriptions
+	Channel   Channel
+	Name      string            // Unique name chosen by the operator
+	Settings  map[string]string // Channel specific settings, e.g. "url" for webhooks
+	CreatedAt string
+}
+
+// DestinationStore looks up destinations. DestinationRepository implements it on Postgres.
+type DestinationStore interface {
+	// Get returns a destination by ID, or ErrDestinationNotFound
+	Get(ctx context.Context, id int64) (*Destination, error)
+}
+
+var _ DestinationStore = (*DestinationRepository)(nil)
+
+// DestinationRepository handles database operations for destinations
+type DestinationRepository struct {
+	q Querier
+}
+
+func NewDestinationRepository(q Querier) *DestinationRepository {
+	return &DestinationRepository{q: q}
+}
+
+// Create registers a destination and sets its ID
+func (r *DestinationRepository) Create(ctx context.Context, d *Destination) error {
+	settings, err := json.Marshal(d.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode destination settings: %w", err)
+	}
+
+	const query = `
+		INSERT INTO destinations (channel, name, settings)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	if err := r.q.QueryRowContext(ctx, query, d.Channel, d.Name, string(settings)).Scan(&d.ID, &d.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create destination: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a destination by ID, or ErrDestinationNotFound
+func (r *DestinationRepository) Get(ctx context.Context, id int64) (*Destination, error) {
+	const query = `SELECT id, channel, name, settings, created_at FROM destinations WHERE id = $1`
+	return scanDestination(r.q.QueryRowContext(ctx, query, id))
+}
+
+// GetByName returns a destination by name, or ErrDestinationNotFound
+func (r *DestinationRepository) GetByName(ctx context.Context, name string) (*Destination, error) {
+	const query = `SELECT id, channel, name, settings, created_at FROM destinations WHERE name = $1`
+	return scanDestination(r.q.QueryRowContext(ctx, query, name))
+}
+
+// List returns the destinations of a channel, or of every channel if channel is empty, ordered by name
+func (r *DestinationRepository) List(ctx context.Context, channel Channel) ([]*Destination, error) {
+	const query = `
+		SELECT id, channel, name, settings, created_at
+		FROM destinations
+		WHERE $1::text = '' OR channel = $1
+		ORDER BY name
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list destinations: %w", err)
+	}
+	defer rows.Close()
+
+	var destinations []*Destination
+	for rows.Next() {
+		d, err := scanDestination(rows)
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating destinations: %w", err)
+	}
+
+	return destinations, nil
+}
+
+// Delete removes a destination together with its subscriptions
+func (r *DestinationRepository) Delete(ctx context.Context, name string) error {
+	return inTransaction(ctx, r.q, func(tx *sql.Tx) error {
+		var id int64
+		var channel Channel
+		err := tx.QueryRowContext(ctx, `DELETE FROM destinations WHERE name = $1 RETURNING id, channel`, name).Scan(&id, &channel)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDestinationNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete destination: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE chat_id = $1 AND channel = $2`, id, channel); err != nil {
+			return fmt.Errorf("failed to delete destination subscriptions: %w", err)
+		}
+		return nil
+	})
+}
+
+// scanDestination reads a destination selected as id, channel, name, settings, created_at
+func scanDestination(row interface{ Scan(...any) error }) (*Destination, error) {
+	d := &Destination{}
+	var settings []byte
+	err := row.Scan(&d.ID, &d.Channel, &d.Name, &settings, &d.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDestinationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan destination: %w", err)
+	}
+
+	if err := json.Unmarshal(settings, &d.Settings); err != nil {
+		return nil, fmt.Errorf("failed to decode settings of destination %s: %w", d.Name, err)
+	}
+
+	return d, nil
+}
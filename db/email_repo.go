@@ -0,0 +1,383 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrEmailNotFound is returned when an email recipient doesn't exist or belongs to another chat
+	ErrEmailNotFound = errors.New("email recipient not found")
+	// ErrEmailExists is returned when a chat registers an address it already registered
+	ErrEmailExists = errors.New("email address already registered")
+	// ErrEmailTokenInvalid is returned for unknown or expired confirmation and unsubscribe tokens
+	ErrEmailTokenInvalid = errors.New("invalid or expired email token")
+)
+
+// EmailRecipient is an email address receiving a chat's proposal alerts
+type EmailRecipient struct {
+	ID                 int64
+	ChatID             int64 // Chat whose subscriptions the address receives
+	Address            string
+	ConfirmToken       string
+	UnsubscribeToken   string
+	ConfirmationSentAt time.Time
+	ConfirmedAt        time.Time // Zero until the address is confirmed
+	DailyDigest        bool
+	LastDigestAt       time.Time // Zero if no digest was sent yet
+	CreatedAt          time.Time
+}
+
+// Confirmed reports whether the address was confirmed through its confirmation link
+func (e *EmailRecipient) Confirmed() bool {
+	return !e.ConfirmedAt.IsZero()
+}
+
+// EmailRepository handles database operations for email recipients and their queued digest alerts
+type EmailRepository struct {
+	q Querier
+}
+
+func NewEmailRepository(q Querier) *EmailRepository {
+	return &EmailRepository{q: q}
+}
+
+// emailColumns are the columns scanned by scanEmailRecipients
+const emailColumns = `id, chat_id, address, confirm_token, unsubscribe_token, confirmation_sent_at,
+	confirmed_at, daily_digest, last_digest_at, created_at`
+
+// Create registers an unconfirmed address for a chat and sets its ID.
+// It returns ErrEmailExists if the chat already registered the address.
+func (r *EmailRepository) Create(ctx context.Context, e *EmailRecipient) error {
+	const query = `
+		INSERT INTO email_recipients (chat_id, address, confirm_token, unsubscribe_token)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chat_id, address) DO NOTHING
+		RETURNING id, confirmation_sent_at, created_at
+	`
+
+	err := r.q.QueryRowContext(ctx, query, e.ChatID, e.Address, e.ConfirmToken, e.UnsubscribeToken).
+		Scan(&e.ID, &e.ConfirmationSentAt, &e.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEmailExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create email recipient: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a recipient by ID, or ErrEmailNotFound
+func (r *EmailRepository) Get(ctx context.Context, id int64) (*EmailRecipient, error) {
+	query := `SELECT ` + emailColumns + ` FROM email_recipients WHERE id = $1`
+	return r.getOne(ctx, "get email recipient", query, id)
+}
+
+// GetByAddress returns a chat's recipient with the given address, or ErrEmailNotFound
+func (r *EmailRepository) GetByAddress(ctx context.Context, chatID int64, address string) (*EmailRecipient, error) {
+	query := `SELECT ` + emailColumns + ` FROM email_recipients WHERE chat_id = $1 AND address = $2`
+	return r.getOne(ctx, "get email recipient", query, chatID, address)
+}
+
+// GetByUnsubscribeToken returns the recipient an unsubscribe link belongs to, or ErrEmailTokenInvalid
+func (r *EmailRepository) GetByUnsubscribeToken(ctx context.Context, token string) (*EmailRecipient, error) {
+	query := `SELECT ` + emailColumns + ` FROM email_recipients WHERE unsubscribe_token = $1`
+	e, err := r.getOne(ctx, "get email recipient", query, token)
+	if errors.Is(err, ErrEmailNotFound) {
+		return nil, ErrEmailTokenInvalid
+	}
+	return e, err
+}
+
+// List returns the recipients of a chat, or of every chat if chatID is 0, ordered by ID
+func (r *EmailRepository) List(ctx context.Context, chatID int64) ([]*EmailRecipient, error) {
+	query := `SELECT ` + emailColumns + ` FROM email_recipients WHERE $1::bigint = 0 OR chat_id = $1 ORDER BY id`
+
+	rows, err := r.q.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list email recipients: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEmailRecipients(rows)
+}
+
+// ListConfirmed returns the confirmed recipients of the given chats
+func (r *EmailRepository) ListConfirmed(ctx context.Context, chatIDs []int64) ([]*EmailRecipient, error) {
+	query := `SELECT ` + emailColumns + ` FROM email_recipients
+		WHERE chat_id = ANY($1) AND confirmed_at IS NOT NULL
+		ORDER BY id`
+
+	rows, err := r.q.QueryContext(ctx, query, pq.Array(chatIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list confirmed email recipients: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEmailRecipients(rows)
+}
+
+// CountByChat returns how many addresses a chat registered
+func (r *EmailRepository) CountByChat(ctx context.Context, chatID int64) (int, error) {
+	const query = `SELECT COUNT(*) FROM email_recipients WHERE chat_id = $1`
+
+	var count int
+	if err := r.q.QueryRowContext(ctx, query, chatID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count email recipients: %w", err)
+	}
+	return count, nil
+}
+
+// Delete removes one of a chat's recipients along with its queued alerts
+func (r *EmailRepository) Delete(ctx context.Context, chatID, id int64) error {
+	const query = `DELETE FROM email_recipients WHERE chat_id = $1 AND id = $2`
+	return r.execOne(ctx, "delete email recipient", query, chatID, id)
+}
+
+// DeleteByUnsubscribeToken removes the recipient an unsubscribe link belongs to
+// and returns it, or ErrEmailTokenInvalid
+func (r *EmailRepository) DeleteByUnsubscribeToken(ctx context.Context, token string) (*EmailRecipient, error) {
+	query := `DELETE FROM email_recipients WHERE unsubscribe_token = $1 RETURNING ` + emailColumns
+	e, err := r.getOne(ctx, "delete email recipient", query, token)
+	if errors.Is(err, ErrEmailNotFound) {
+		return nil, ErrEmailTokenInvalid
+	}
+	return e, err
+}
+
+// SetDailyDigest switches one of a chat's recipients between an email per alert and
+// a daily digest. The first digest period starts when it is switched on.
+func (r *EmailRepository) SetDailyDigest(ctx context.Context, chatID, id int64, daily bool) error {
+	const query = `
+		UPDATE email_recipients
+		SET daily_digest = $3,
+			last_digest_at = CASE WHEN $3 AND NOT daily_digest THEN NOW() ELSE last_digest_at END
+		WHERE chat_id = $1 AND id = $2
+	`
+	return r.execOne(ctx, "update email recipient", query, chatID, id, daily)
+}
+
+// RefreshConfirmation replaces an unconfirmed recipient's confirmation token
+// before the confirmation email is sent again
+func (r *EmailRepository) RefreshConfirmation(ctx context.Context, id int64, token string, now time.Time) error {
+	const query = `
+		UPDATE email_recipients
+		SET confirm_token = $2, confirmation_sent_at = $3
+		WHERE id = $1 AND confirmed_at IS NULL
+	`
+	return r.execOne(ctx, "refresh email confirmation", query, id, token, now)
+}
+
+// Confirm confirms the recipient a confirmation link belongs to and returns it.
+// Tokens sent before sentAfter have expired; confirming twice is not an error.
+func (r *EmailRepository) Confirm(ctx context.Context, token string, now, sentAfter time.Time) (*EmailRecipient, error) {
+	query := `
+		UPDATE email_recipients
+		SET confirmed_at = COALESCE(confirmed_at, $2)
+		WHERE confirm_token = $1 AND (confirmed_at IS NOT NULL OR confirmation_sent_at > $3)
+		RETURNING ` + emailColumns
+
+	e, err := r.getOne(ctx, "confirm email recipient", query, token, now, sentAfter)
+	if errors.Is(err, ErrEmailNotFound) {
+		return nil, ErrEmailTokenInvalid
+	}
+	return e, err
+}
+
+// EnqueueAlert queues an alert to be emailed to a recipient, on its own or in its
+// next digest. Alerts for a proposal already queued for the recipient are ignored.
+func (r *EmailRepository) EnqueueAlert(ctx context.Context, recipientID int64, alert *PendingAlert) error {
+	const query = `
+		INSERT INTO email_pending_alerts (recipient_id, symbol, proposal_id, title, synopsis)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (recipient_id, proposal_id) DO NOTHING
+	`
+
+	_, err := r.q.ExecContext(ctx, query, recipientID, alert.Symbol, alert.ProposalID, alert.Title, alert.Synopsis)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue email alert: %w", err)
+	}
+	return nil
+}
+
+// DueRecipients returns the confirmed recipients with queued alerts that are due: those
+// getting an email per alert, and those whose last digest was sent before sentBefore
+func (r *EmailRepository) DueRecipients(ctx context.Context, sentBefore time.Time) ([]*EmailRecipient, error) {
+	query := `SELECT ` + emailColumns + ` FROM email_recipients r
+		WHERE confirmed_at IS NOT NULL
+			AND EXISTS (SELECT 1 FROM email_pending_alerts a WHERE a.recipient_id = r.id)
+			AND (NOT daily_digest OR COALESCE(last_digest_at, created_at) <= $1)
+		ORDER BY id`
+
+	rows, err := r.q.QueryContext(ctx, query, sentBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due email digests: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEmailRecipients(rows)
+}
+
+// QueuedAlerts returns the alerts queued for a recipient, oldest first. Their ChatID
+// is the recipient's chat.
+func (r *EmailRepository) QueuedAlerts(ctx context.Context, recipientID int64) ([]*PendingAlert, error) {
+	const query = `
+		SELECT a.id, r.chat_id, a.symbol, a.proposal_id, a.title, a.synopsis, a.created_at
+		FROM email_pending_alerts a
+		JOIN email_recipients r ON r.id = a.recipient_id
+		WHERE a.recipient_id = $1
+		ORDER BY a.id
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, recipientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []*PendingAlert
+	for rows.Next() {
+		alert := &PendingAlert{}
+		err := rows.Scan(
+			&alert.ID,
+			&alert.ChatID,
+			&alert.Symbol,
+			&alert.ProposalID,
+			&alert.Title,
+			&alert.Synopsis,
+			&alert.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating email alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// MarkDigestSent removes a recipient's queued alerts up to and including maxAlertID
+// and starts the next digest period, in one statement
+func (r *EmailRepository) MarkDigestSent(ctx context.Context, recipientID, maxAlertID int64, sentAt time.Time) error {
+	const query = `
+		WITH sent AS (
+			DELETE FROM email_pending_alerts WHERE recipient_id = $1 AND id <= $2
+		)
+		UPDATE email_recipients SET last_digest_at = $3 WHERE id = $1
+	`
+
+	if _, err := r.q.ExecContext(ctx, query, recipientID, maxAlertID, sentAt); err != nil {
+		return fmt.Errorf("failed to mark email digest sent: %w", err)
+	}
+	return nil
+}
+
+// RemoveAlerts removes a recipient's queued alerts up to and including maxAlertID
+func (r *EmailRepository) RemoveAlerts(ctx context.Context, recipientID, maxAlertID int64) error {
+	const query = `DELETE FROM email_pending_alerts WHERE recipient_id = $1 AND id <= $2`
+
+	if _, err := r.q.ExecContext(ctx, query, recipientID, maxAlertID); err != nil {
+		return fmt.Errorf("failed to remove email alerts: %w", err)
+	}
+	return nil
+}
+
+// DropStaleAlerts removes the alerts queued before a time for recipients getting an
+// email per alert, and returns how many were removed. Digest alerts are kept.
+func (r *EmailRepository) DropStaleAlerts(ctx context.Context, queuedBefore time.Time) (int64, error) {
+	const query = `
+		DELETE FROM email_pending_alerts a
+		USING email_recipients r
+		WHERE r.id = a.recipient_id AND NOT r.daily_digest AND a.created_at < $1
+	`
+
+	result, err := r.q.ExecContext(ctx, query, queuedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to drop stale email alerts: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count dropped email alerts: %w", err)
+	}
+	return n, nil
+}
+
+// getOne runs a query selecting emailColumns of at most one recipient
+func (r *EmailRepository) getOne(ctx context.Context, action, query string, args ...interface{}) (*EmailRecipient, error) {
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer rows.Close()
+
+	recipients, err := scanEmailRecipients(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, ErrEmailNotFound
+	}
+	return recipients[0], nil
+}
+
+// execOne runs a statement that must affect exactly one recipient
+func (r *EmailRepository) execOne(ctx context.Context, action, query string, args ...interface{}) error {
+	result, err := r.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrEmailNotFound
+	}
+	return nil
+}
+
+// scanEmailRecipients reads recipients selected as emailColumns
+func scanEmailRecipients(rows *sql.Rows) ([]*EmailRecipient, error) {
+	var recipients []*EmailRecipient
+	for rows.Next() {
+		e := &EmailRecipient{}
+		var confirmedAt, lastDigestAt sql.NullTime
+		err := rows.Scan(
+			&e.ID,
+			&e.ChatID,
+			&e.Address,
+			&e.ConfirmToken,
+			&e.UnsubscribeToken,
+			&e.ConfirmationSentAt,
+			&confirmedAt,
+			&e.DailyDigest,
+			&lastDigestAt,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email recipient: %w", err)
+		}
+		e.ConfirmedAt = confirmedAt.Time
+		e.LastDigestAt = lastDigestAt.Time
+		recipients = append(recipients, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating email recipients: %w", err)
+	}
+
+	return recipients, nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Event is an entry of the event log streamed to API clients
+type Event struct {
+	ID        int64
+	Type      string
+	Symbol    string          // Contract the event is about, empty for block events
+	Data      json.RawMessage // JSON payload
+	CreatedAt time.Time
+}
+
+// EventQuery selects events from the log
+type EventQuery struct {
+	AfterID int64    // Only events after this ID
+	Types   []string // Only these event types, every type if empty
+	Symbol  string   // Only events about this contract or no contract, every event if empty
+	Limit   int
+}
+
+// EventStore stores the event log
+type EventStore interface {
+	// Append adds an event to the log and sets its ID and creation time
+	Append(ctx context.Context, e *Event) error
+	// List returns the events matching a query, oldest first
+	List(ctx context.Context, eq EventQuery) ([]*Event, error)
+	// DeleteBefore deletes events created before a time and returns how many were deleted
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+var _ EventStore = (*EventRepository)(nil)
+
+// EventRepository handles database operations for the event log
+type EventRepository struct {
+	q Querier
+}
+
+func NewEventRepository(q Querier) *EventRepository {
+	return &EventRepository{q: q}
+}
+
+// Append adds an event to the log and sets its ID and creation time
+func (r *EventRepository) Append(ctx context.Context, e *Event) error {
+	const query = `
+		INSERT INTO events (type, symbol, data)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	if err := r.q.QueryRowContext(ctx, query, e.Type, e.Symbol, []byte(e.Data)).Scan(&e.ID, &e.CreatedAt); err != nil {
+		return fmt.Errorf("failed to append event: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the events matching a query, oldest first
+func (r *EventRepository) List(ctx context.Context, eq EventQuery) ([]*Event, error) {
+	const query = `
+		SELECT id, type, symbol, data, created_at
+		FROM events
+		WHERE id > $1
+		  AND (cardinality($2::text[]) = 0 OR type = ANY($2))
+		  AND ($3::text = '' OR symbol = '' OR symbol = $3)
+		ORDER BY id
+		LIMIT $4
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, eq.AfterID, pq.Array(nonNil(eq.Types)), eq.Symbol, eq.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		e := &Event{}
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.Symbol, &data, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		e.Data = data
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate events: %w", err)
+	}
+
+	return events, nil
+}
+
+// DeleteBefore deletes events created before a time and returns how many were deleted
+func (r *EventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.q.ExecContext(ctx, `DELETE FROM events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old events: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted events: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Maintenance is the bot's maintenance mode
+type Maintenance struct {
+	Enabled   bool
+	Message   string // Shown to users along with the maintenance notice
+	UpdatedAt time.Time
+}
+
+// MaintenanceRepository stores the maintenance mode
+type MaintenanceRepository struct {
+	q Querier
+}
+
+func NewMaintenanceRepository(q Querier) *MaintenanceRepository {
+	return &MaintenanceRepository{q: q}
+}
+
+// Get returns the maintenance mode
+func (r *MaintenanceRepository) Get(ctx context.Context) (*Maintenance, error) {
+	m := &Maintenance{}
+	err := r.q.QueryRowContext(ctx, `SELECT enabled, message, updated_at FROM maintenance`).Scan(&m.Enabled, &m.Message, &m.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance mode: %w", err)
+	}
+
+	return m, nil
+}
+
+// Set turns maintenance mode on or off
+func (r *MaintenanceRepository) Set(ctx context.Context, enabled bool, message string) (*Maintenance, error) {
+	const query = `
+		UPDATE maintenance
+		SET enabled = $1, message = $2, updated_at = NOW()
+		RETURNING enabled, message, updated_at
+	`
+
+	m := &Maintenance{}
+	if err := r.q.QueryRowContext(ctx, query, enabled, message).Scan(&m.Enabled, &m.Message, &m.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to set maintenance mode: %w", err)
+	}
+
+	return m, nil
+}
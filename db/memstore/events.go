@@ -0,0 +1,88 @@
+package memstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+)
+
+// EventStore is an in-memory db.EventStore with the same semantics as the Postgres
+// EventRepository, for tests and local development
+type EventStore struct {
+	mu     sync.Mutex
+	events []*db.Event
+	nextID int64
+	now    func() time.Time
+}
+
+var _ db.EventStore = (*EventStore)(nil)
+
+func NewEventStore() *EventStore {
+	return &EventStore{nextID: 1, now: time.Now}
+}
+
+// SetClock sets the function giving the creation time of appended events
+func (s *EventStore) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// Append adds an event to the log and sets its ID and creation time
+func (s *EventStore) Append(ctx context.Context, e *db.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.ID, e.CreatedAt = s.nextID, s.now()
+	s.nextID++
+	stored := *e
+	s.events = append(s.events, &stored)
+	return nil
+}
+
+// List returns the events matching a query, oldest first
+func (s *EventStore) List(ctx context.Context, eq db.EventQuery) ([]*db.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []*db.Event
+	for _, e := range s.events {
+		if len(events) == eq.Limit {
+			break
+		}
+		if e.ID <= eq.AfterID || (len(eq.Types) > 0 && !contains(eq.Types, e.Type)) ||
+			(eq.Symbol != "" && e.Symbol != "" && e.Symbol != eq.Symbol) {
+			continue
+		}
+		found := *e
+		events = append(events, &found)
+	}
+	return events, nil
+}
+
+// DeleteBefore deletes events created before a time and returns how many were deleted
+func (s *EventStore) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.events[:0]
+	for _, e := range s.events {
+		if !e.CreatedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	n := int64(len(s.events) - len(kept))
+	s.events = kept
+	return n, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
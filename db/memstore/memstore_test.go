@@ -0,0 +1,17 @@
+package memstore_test
+
+import (
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/memstore"
+	"github.com/ZeraVision/ZeraBot/db/storetest"
+)
+
+func TestSubscriptionStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T, maxPerChat int) db.SubscriptionStore {
+		store := memstore.NewSubscriptionStore()
+		store.SetMaxPerChat(maxPerChat)
+		return store
+	})
+}
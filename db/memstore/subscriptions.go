@@ -0,0 +1,385 @@
+package memstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/filter"
+)
+
+// SubscriptionStore is an in-memory db.SubscriptionStore with the same semantics as
+// the Postgres SubscriptionRepository, for tests and local development
+type SubscriptionStore struct {
+	mu            sync.Mutex
+	subscriptions map[key]*entry
+	maxPerChat    int
+}
+
+type key struct {
+	chatID  int64
+	subType db.SubscriptionType
+	symbol  string
+}
+
+type entry struct {
+	sub       db.Subscription
+	createdAt time.Time
+}
+
+var _ db.SubscriptionStore = (*SubscriptionStore)(nil)
+
+func NewSubscriptionStore() *SubscriptionStore {
+	return &SubscriptionStore{subscriptions: make(map[key]*entry)}
+}
+
+// SetMaxPerChat limits how many subscriptions a chat can have. 0 removes the limit.
+func (s *SubscriptionStore) SetMaxPerChat(max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxPerChat = max
+}
+
+// MaxPerChat returns the maximum number of subscriptions per chat, 0 if unlimited
+func (s *SubscriptionStore) MaxPerChat() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxPerChat
+}
+
+// Subscribe adds a new subscription or refreshes an existing one
+func (s *SubscriptionStore) Subscribe(ctx context.Context, chatID int64, subType db.SubscriptionType, symbol string) (*db.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{chatID, subType, symbol}
+	if _, ok := s.subscriptions[k]; !ok && s.atLimit(chatID) {
+		return nil, db.ErrSubscriptionLimit
+	}
+
+	return copySubscription(&s.upsert(k, time.Now()).sub), nil
+}
+
+// Unsubscribe removes a subscription
+func (s *SubscriptionStore) Unsubscribe(ctx context.Context, chatID int64, subType db.SubscriptionType, symbol string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{chatID, subType, symbol}
+	if _, ok := s.subscriptions[k]; !ok {
+		return db.ErrSubscriptionNotFound
+	}
+	delete(s.subscriptions, k)
+
+	return nil
+}
+
+// UnsubscribeAll removes all subscriptions of a type for a chat
+func (s *SubscriptionStore) UnsubscribeAll(ctx context.Context, chatID int64, subType db.SubscriptionType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k := range s.subscriptions {
+		if k.chatID == chatID && k.subType == subType {
+			delete(s.subscriptions, k)
+		}
+	}
+
+	return nil
+}
+
+// SubscribeMany subscribes a chat to several symbols at once
+func (s *SubscriptionStore) SubscribeMany(ctx context.Context, chatID int64, subType db.SubscriptionType, symbols []string) ([]*db.SymbolResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.subscribeMany(chatID, subType, uniqueSymbols(symbols)), nil
+}
+
+// UnsubscribeMany removes a chat's subscriptions to several symbols at once
+func (s *SubscriptionStore) UnsubscribeMany(ctx context.Context, chatID int64, subType db.SubscriptionType, symbols []string) ([]*db.SymbolResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbols = uniqueSymbols(symbols)
+	results := make([]*db.SymbolResult, len(symbols))
+	for i, symbol := range symbols {
+		results[i] = &db.SymbolResult{Symbol: symbol, Outcome: db.NotSubscribed}
+
+		k := key{chatID, subType, symbol}
+		if _, ok := s.subscriptions[k]; ok {
+			delete(s.subscriptions, k)
+			results[i].Outcome = db.Unsubscribed
+		}
+	}
+
+	return results, nil
+}
+
+// ReplaceAll replaces all of a chat's subscriptions of a type with subscriptions to the given symbols
+func (s *SubscriptionStore) ReplaceAll(ctx context.Context, chatID int64, subType db.SubscriptionType, symbols []string) ([]*db.SymbolResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbols = uniqueSymbols(symbols)
+	keep := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		keep[symbol] = true
+	}
+
+	for k := range s.subscriptions {
+		if k.chatID == chatID && k.subType == subType && !keep[k.symbol] {
+			delete(s.subscriptions, k)
+		}
+	}
+
+	return s.subscribeMany(chatID, subType, symbols), nil
+}
+
+// ResolveSubscribers returns each chat subscribed to a symbol exactly once, with the
+// subscriptions that matched and why
+func (s *SubscriptionStore) ResolveSubscribers(ctx context.Context, symbol string, subType db.SubscriptionType) ([]*db.Subscriber, error) {
+	s.mu.Lock()
+	var matches []*db.SubscriptionMatch
+	for k, e := range s.subscriptions {
+		if k.subType != subType {
+			continue
+		}
+
+		var reason db.MatchReason
+		switch {
+		case k.symbol == symbol:
+			reason = db.MatchExact
+		case k.symbol == "all":
+			reason = db.MatchAll
+		case strings.Contains(k.symbol, "*") && like(symbol, k.symbol):
+			reason = db.MatchPattern
+		default:
+			continue
+		}
+		matches = append(matches, &db.SubscriptionMatch{Subscription: copySubscription(&e.sub), Reason: reason})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Subscription.ChatID != b.Subscription.ChatID {
+			return a.Subscription.ChatID < b.Subscription.ChatID
+		}
+		if reasonRank(a.Reason) != reasonRank(b.Reason) {
+			return reasonRank(a.Reason) < reasonRank(b.Reason)
+		}
+		return a.Subscription.Symbol < b.Subscription.Symbol
+	})
+
+	var subscribers []*db.Subscriber
+	for _, match := range matches {
+		if len(subscribers) == 0 || subscribers[len(subscribers)-1].ChatID != match.Subscription.ChatID {
+			subscribers = append(subscribers, &db.Subscriber{ChatID: match.Subscription.ChatID})
+		}
+		last := subscribers[len(subscribers)-1]
+		last.Matches = append(last.Matches, match)
+	}
+
+	return subscribers, nil
+}
+
+// GetUserSubscriptions returns all subscriptions for a chat, newest first
+func (s *SubscriptionStore) GetUserSubscriptions(ctx context.Context, chatID int64) ([]*db.Subscription, error) {
+	s.mu.Lock()
+	var entries []*entry
+	for k, e := range s.subscriptions {
+		if k.chatID == chatID {
+			entries = append(entries, e)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.After(b.createdAt)
+		}
+		if a.sub.Type != b.sub.Type {
+			return a.sub.Type < b.sub.Type
+		}
+		return a.sub.Symbol < b.sub.Symbol
+	})
+
+	var subscriptions []*db.Subscription
+	for _, e := range entries {
+		subscriptions = append(subscriptions, copySubscription(&e.sub))
+	}
+
+	return subscriptions, nil
+}
+
+// SetFilter replaces the filter of an existing subscription. A nil filter removes it.
+func (s *SubscriptionStore) SetFilter(ctx context.Context, chatID int64, subType db.SubscriptionType, symbol string, f *filter.Filter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.subscriptions[key{chatID, subType, symbol}]
+	if !ok {
+		return db.ErrSubscriptionNotFound
+	}
+
+	e.sub.Filter = nil
+	if !f.IsEmpty() {
+		copied, err := copyFilter(f)
+		if err != nil {
+			return fmt.Errorf("failed to encode filter: %w", err)
+		}
+		e.sub.Filter = copied
+	}
+	e.sub.UpdatedAt = formatTime(time.Now())
+
+	return nil
+}
+
+// subscribeMany subscribes to the symbols that fit within the chat's limit. All
+// subscriptions made in one call share a timestamp, like rows inserted in one
+// Postgres transaction. Callers must hold s.mu.
+func (s *SubscriptionStore) subscribeMany(chatID int64, subType db.SubscriptionType, symbols []string) []*db.SymbolResult {
+	now := time.Now()
+	results := make([]*db.SymbolResult, len(symbols))
+	for i, symbol := range symbols {
+		k := key{chatID, subType, symbol}
+		results[i] = &db.SymbolResult{Symbol: symbol, Outcome: db.AlreadySubscribed}
+		if _, ok := s.subscriptions[k]; !ok {
+			if s.atLimit(chatID) {
+				results[i].Outcome = db.LimitReached
+				continue
+			}
+			results[i].Outcome = db.Subscribed
+		}
+		s.upsert(k, now)
+	}
+
+	return results
+}
+
+// upsert creates a subscription or refreshes its update time. Callers must hold s.mu.
+func (s *SubscriptionStore) upsert(k key, now time.Time) *entry {
+	if e, ok := s.subscriptions[k]; ok {
+		e.sub.UpdatedAt = formatTime(now)
+		return e
+	}
+
+	e := &entry{
+		sub: db.Subscription{
+			ID:        newID(),
+			ChatID:    k.chatID,
+			Symbol:    k.symbol,
+			Type:      k.subType,
+			CreatedAt: formatTime(now),
+			UpdatedAt: formatTime(now),
+		},
+		createdAt: now,
+	}
+	s.subscriptions[k] = e
+	return e
+}
+
+// atLimit reports whether a chat has as many subscriptions as allowed. Callers must hold s.mu.
+func (s *SubscriptionStore) atLimit(chatID int64) bool {
+	if s.maxPerChat <= 0 {
+		return false
+	}
+
+	total := 0
+	for k := range s.subscriptions {
+		if k.chatID == chatID {
+			total++
+		}
+	}
+	return total >= s.maxPerChat
+}
+
+// like reports whether s matches a pattern where * matches any run of characters,
+// as Postgres does for `s LIKE replace(pattern, '*', '%')`
+func like(s, pattern string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+
+	return strings.HasSuffix(s, parts[last])
+}
+
+func reasonRank(reason db.MatchReason) int {
+	switch reason {
+	case db.MatchExact:
+		return 0
+	case db.MatchPattern:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// copySubscription returns a copy that callers can't use to modify the store
+func copySubscription(sub *db.Subscription) *db.Subscription {
+	copied := *sub
+	if sub.Filter != nil {
+		copied.Filter, _ = copyFilter(sub.Filter)
+	}
+	return &copied
+}
+
+// copyFilter copies a filter through JSON, as storing it in Postgres does
+func copyFilter(f *filter.Filter) (*filter.Filter, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := &filter.Filter{}
+	if err := json.Unmarshal(data, copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// uniqueSymbols removes duplicate symbols, keeping the first occurrence
+func uniqueSymbols(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	unique := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		if !seen[symbol] {
+			seen[symbol] = true
+			unique = append(unique, symbol)
+		}
+	}
+	return unique
+}
+
+// formatTime formats a timestamp the way database/sql scans a timestamptz into a string
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// newID returns a random version 4 UUID
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
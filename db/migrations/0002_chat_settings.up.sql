@@ -0,0 +1,13 @@
+-- Create chat settings table for per-chat preferences
+CREATE TABLE chat_settings (
+    chat_id BIGINT PRIMARY KEY,
+    language TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create trigger to automatically update updated_at
+CREATE TRIGGER update_chat_settings_updated_at
+BEFORE UPDATE ON chat_settings
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,7 @@
+-- Add chat-level preferences to chat settings
+ALTER TABLE chat_settings
+    ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC',
+    ADD COLUMN muted BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN message_format TEXT NOT NULL DEFAULT 'full' CHECK (message_format IN ('full', 'compact')),
+    ADD COLUMN link_previews BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN admin_only BOOLEAN NOT NULL DEFAULT TRUE;
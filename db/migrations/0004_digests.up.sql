@@ -0,0 +1,19 @@
+-- Add digest delivery preferences to chat settings
+ALTER TABLE chat_settings
+    ADD COLUMN delivery_mode TEXT NOT NULL DEFAULT 'instant' CHECK (delivery_mode IN ('instant', 'hourly', 'daily')),
+    ADD COLUMN digest_hour SMALLINT NOT NULL DEFAULT 9 CHECK (digest_hour BETWEEN 0 AND 23),
+    ADD COLUMN last_digest_at TIMESTAMPTZ;
+
+-- Create pending alerts table for alerts waiting to be sent in a digest
+CREATE TABLE pending_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    symbol TEXT NOT NULL,
+    proposal_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    synopsis TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(chat_id, proposal_id)
+);
+
+CREATE INDEX idx_pending_alerts_chat_id ON pending_alerts(chat_id);
@@ -0,0 +1,7 @@
+-- Add quiet hours and snooze to chat settings. Quiet hours are stored as minutes
+-- after local midnight; an empty window (start = end) means quiet hours are off.
+ALTER TABLE chat_settings
+    ADD COLUMN quiet_start SMALLINT NOT NULL DEFAULT 0 CHECK (quiet_start BETWEEN 0 AND 1439),
+    ADD COLUMN quiet_end SMALLINT NOT NULL DEFAULT 0 CHECK (quiet_end BETWEEN 0 AND 1439),
+    ADD COLUMN quiet_mode TEXT NOT NULL DEFAULT 'hold' CHECK (quiet_mode IN ('hold', 'silent')),
+    ADD COLUMN snoozed_until TIMESTAMPTZ;
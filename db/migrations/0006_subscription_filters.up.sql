@@ -0,0 +1,2 @@
+-- Add optional content filters to subscriptions
+ALTER TABLE subscriptions ADD COLUMN filter JSONB;
@@ -0,0 +1,2 @@
+-- Index wildcard subscriptions (e.g. $ZRA+*) so they can be matched without scanning every row
+CREATE INDEX idx_subscriptions_patterns ON subscriptions(type) WHERE position('*' IN symbol) > 0;
@@ -0,0 +1,14 @@
+-- Create contracts table caching instrument contracts observed on chain
+CREATE TABLE contracts (
+    contract_id TEXT PRIMARY KEY,
+    symbol TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    first_seen_block BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_contracts_updated_at
+BEFORE UPDATE ON contracts
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
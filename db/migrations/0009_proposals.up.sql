@@ -0,0 +1,12 @@
+-- Create proposals table recording governance proposals seen on chain
+CREATE TABLE proposals (
+    proposal_id TEXT PRIMARY KEY,
+    contract_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    synopsis TEXT NOT NULL,
+    proposer TEXT NOT NULL DEFAULT '',
+    block_height BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_proposals_contract_id_created_at ON proposals(contract_id, created_at);
@@ -0,0 +1,9 @@
+-- Create bans table for users temporarily blocked from using the bot
+CREATE TABLE bans (
+    user_id BIGINT PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_bans_expires_at ON bans(expires_at);
@@ -0,0 +1,23 @@
+-- Subscriptions can be delivered through channels other than Telegram. For those,
+-- chat_id holds the ID of the destination alerts are sent to.
+ALTER TABLE subscriptions ADD COLUMN channel TEXT NOT NULL DEFAULT 'telegram';
+
+ALTER TABLE subscriptions DROP CONSTRAINT subscriptions_chat_id_symbol_type_key;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_channel_chat_id_symbol_type_key
+    UNIQUE (channel, chat_id, symbol, type);
+
+-- Create destinations table for places outside Telegram that operators route alerts to,
+-- such as Discord webhooks. Settings hold channel specific values like the webhook URL.
+CREATE TABLE destinations (
+    id BIGSERIAL PRIMARY KEY,
+    channel TEXT NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    settings JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_destinations_updated_at
+BEFORE UPDATE ON destinations
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,34 @@
+-- Create email recipients table for addresses receiving a chat's alerts by email
+CREATE TABLE email_recipients (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL, -- Chat whose subscriptions the address receives
+    address TEXT NOT NULL,
+    confirm_token TEXT NOT NULL UNIQUE,
+    unsubscribe_token TEXT NOT NULL UNIQUE,
+    confirmation_sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMPTZ, -- NULL until the address is confirmed (double opt-in)
+    daily_digest BOOLEAN NOT NULL DEFAULT FALSE, -- Send one summary a day instead of an email per alert
+    last_digest_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(chat_id, address)
+);
+
+CREATE INDEX idx_email_recipients_chat_id ON email_recipients(chat_id);
+
+CREATE TRIGGER update_email_recipients_updated_at
+BEFORE UPDATE ON email_recipients
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Create email pending alerts table holding alerts for the next daily digest
+CREATE TABLE email_pending_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    recipient_id BIGINT NOT NULL REFERENCES email_recipients(id) ON DELETE CASCADE,
+    symbol TEXT NOT NULL,
+    proposal_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    synopsis TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(recipient_id, proposal_id)
+);
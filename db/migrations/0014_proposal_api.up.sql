@@ -0,0 +1,5 @@
+-- Record the proposal types alerts are filtered on, so the API can serve them too
+ALTER TABLE proposals ADD COLUMN types TEXT[] NOT NULL DEFAULT '{}';
+
+-- Index listing proposals newest first, with the proposal ID breaking ties for paging
+CREATE INDEX idx_proposals_created_at_proposal_id ON proposals(created_at, proposal_id);
@@ -0,0 +1,10 @@
+-- Create events table logging the live events streamed by the API, so clients can resume after a disconnect
+CREATE TABLE events (
+    id BIGSERIAL PRIMARY KEY, -- Sent as the SSE event ID and resumed from with Last-Event-ID
+    type TEXT NOT NULL,
+    symbol TEXT NOT NULL DEFAULT '', -- Contract the event is about, empty for block events
+    data JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_created_at ON events(created_at);
@@ -0,0 +1,31 @@
+-- Create blocks table keeping the blocks with contracts or proposals, so operators can replay them
+CREATE TABLE blocks (
+    height BIGINT PRIMARY KEY,
+    hash TEXT NOT NULL,
+    data BYTEA NOT NULL, -- Block encoded as protobuf
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create maintenance table holding the single row of the bot's maintenance mode
+CREATE TABLE maintenance (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT NOT NULL DEFAULT '', -- Shown to users along with the maintenance notice
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO maintenance (id) VALUES (TRUE);
+
+-- Create admin audit log recording every request to the admin API
+CREATE TABLE admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL, -- Name of the admin token used
+    action TEXT NOT NULL, -- Route, e.g. "POST /admin/blocks/{height}/replay"
+    path TEXT NOT NULL,
+    details JSONB, -- Request body of changes
+    status INTEGER NOT NULL, -- HTTP status of the response
+    remote_addr TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_audit_log_created_at ON admin_audit_log(created_at);
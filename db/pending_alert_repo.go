@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// PendingAlert represents a proposal alert waiting to be delivered to a chat
+type PendingAlert struct {
+	ID         int64
+	ChatID     int64
+	Symbol     string
+	ProposalID string
+	Title      string
+	Synopsis   string
+	CreatedAt  string
+}
+
+// PendingAlertRepository handles database operations for alerts held for later delivery
+type PendingAlertRepository struct {
+	q Querier
+}
+
+func NewPendingAlertRepository(q Querier) *PendingAlertRepository {
+	return &PendingAlertRepository{q: q}
+}
+
+// Enqueue stores an alert for a chat. Alerts for a proposal already queued for the chat are ignored.
+func (r *PendingAlertRepository) Enqueue(ctx context.Context, alert *PendingAlert) error {
+	const query = `
+		INSERT INTO pending_alerts (chat_id, symbol, proposal_id, title, synopsis)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chat_id, proposal_id) DO NOTHING
+	`
+
+	_, err := r.q.ExecContext(
+		ctx,
+		query,
+		alert.ChatID,
+		alert.Symbol,
+		alert.ProposalID,
+		alert.Title,
+		alert.Synopsis,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue alert: %w", err)
+	}
+
+	return nil
+}
+
+// GetChatsWithPending returns the IDs of all chats that have queued alerts
+func (r *PendingAlertRepository) GetChatsWithPending(ctx context.Context) ([]int64, error) {
+	const query = `SELECT DISTINCT chat_id FROM pending_alerts`
+
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chats with pending alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var chatIDs []int64
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			return nil, fmt.Errorf("failed to scan chat ID: %w", err)
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return chatIDs, nil
+}
+
+// List returns the queued alerts for a chat, oldest first
+func (r *PendingAlertRepository) List(ctx context.Context, chatID int64) ([]*PendingAlert, error) {
+	const query = `
+		SELECT id, chat_id, symbol, proposal_id, title, synopsis, created_at
+		FROM pending_alerts
+		WHERE chat_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []*PendingAlert
+	for rows.Next() {
+		alert := &PendingAlert{}
+		err := rows.Scan(
+			&alert.ID,
+			&alert.ChatID,
+			&alert.Symbol,
+			&alert.ProposalID,
+			&alert.Title,
+			&alert.Synopsis,
+			&alert.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// DeleteUpTo removes the queued alerts for a chat with an ID up to and including maxID,
+// leaving alerts queued after a digest was built for the next one
+func (r *PendingAlertRepository) DeleteUpTo(ctx context.Context, chatID int64, maxID int64) error {
+	const query = `DELETE FROM pending_alerts WHERE chat_id = $1 AND id <= $2`
+
+	if _, err := r.q.ExecContext(ctx, query, chatID, maxID); err != nil {
+		return fmt.Errorf("failed to delete pending alerts: %w", err)
+	}
+
+	return nil
+}
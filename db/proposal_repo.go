@@ -0,0 +1,249 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Proposal represents a governance proposal seen on chain
+type Proposal struct {
+	ProposalID  string
+	ContractID  string
+	Title       string
+	Synopsis    string
+	Proposer    string
+	Types       []string // Proposal types as defined by the filter package
+	BlockHeight uint64
+	CreatedAt   time.Time
+}
+
+// ErrProposalNotFound is returned when a proposal hasn't been seen on chain
+var ErrProposalNotFound = errors.New("proposal not found")
+
+// ProposalPage selects a page of proposals, newest first
+type ProposalPage struct {
+	ContractID string    // Only proposals for this contract, all if empty
+	Before     time.Time // Only proposals older than this one, the first page if zero
+	BeforeID   string    // Proposal ID of Before, breaking ties between proposals created at the same time
+	Limit      int
+}
+
+// ProposalStats counts the proposals seen on chain
+type ProposalStats struct {
+	Total   int
+	Last24h int
+	Last7d  int
+	Last30d int
+}
+
+// ContractCount is the number of proposals a contract received
+type ContractCount struct {
+	ContractID string
+	Proposals  int
+}
+
+// ProposalRepository handles database operations for proposals
+type ProposalRepository struct {
+	q Querier
+}
+
+func NewProposalRepository(q Querier) *ProposalRepository {
+	return &ProposalRepository{q: q}
+}
+
+// Save records a proposal. Proposals already recorded are ignored.
+func (r *ProposalRepository) Save(ctx context.Context, proposal *Proposal) error {
+	const query = `
+		INSERT INTO proposals (proposal_id, contract_id, title, synopsis, proposer, types, block_height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (proposal_id) DO NOTHING
+	`
+
+	_, err := r.q.ExecContext(
+		ctx,
+		query,
+		proposal.ProposalID,
+		proposal.ContractID,
+		proposal.Title,
+		proposal.Synopsis,
+		proposal.Proposer,
+		pq.Array(nonNil(proposal.Types)),
+		int64(proposal.BlockHeight),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save proposal: %w", err)
+	}
+
+	return nil
+}
+
+// CountSince returns how many proposals each of the given contracts received since a
+// point in time. Contracts without proposals are left out.
+func (r *ProposalRepository) CountSince(ctx context.Context, contractIDs []string, since time.Time) (map[string]int, error) {
+	const query = `
+		SELECT contract_id, COUNT(*)
+		FROM proposals
+		WHERE contract_id = ANY($1) AND created_at >= $2
+		GROUP BY contract_id
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, pq.Array(contractIDs), since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count proposals: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var contractID string
+		var count int
+		if err := rows.Scan(&contractID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan proposal count: %w", err)
+		}
+		counts[contractID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating proposal counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// Get returns a proposal by ID, or ErrProposalNotFound
+func (r *ProposalRepository) Get(ctx context.Context, proposalID string) (*Proposal, error) {
+	const query = `
+		SELECT proposal_id, contract_id, title, synopsis, proposer, types, block_height, created_at
+		FROM proposals
+		WHERE proposal_id = $1
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, proposalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proposal: %w", err)
+	}
+	defer rows.Close()
+
+	proposals, err := scanProposals(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(proposals) == 0 {
+		return nil, ErrProposalNotFound
+	}
+	return proposals[0], nil
+}
+
+// List returns a page of proposals, newest first
+func (r *ProposalRepository) List(ctx context.Context, page ProposalPage) ([]*Proposal, error) {
+	const query = `
+		SELECT proposal_id, contract_id, title, synopsis, proposer, types, block_height, created_at
+		FROM proposals
+		WHERE ($1 = '' OR contract_id = $1)
+			AND ($2::timestamptz IS NULL OR (created_at, proposal_id) < ($2, $3))
+		ORDER BY created_at DESC, proposal_id DESC
+		LIMIT $4
+	`
+
+	var before *time.Time
+	if !page.Before.IsZero() {
+		before = &page.Before
+	}
+
+	rows, err := r.q.QueryContext(ctx, query, page.ContractID, before, page.BeforeID, page.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proposals: %w", err)
+	}
+	defer rows.Close()
+
+	return scanProposals(rows)
+}
+
+// Stats counts all proposals and those created in the last day, week and 30 days before now
+func (r *ProposalRepository) Stats(ctx context.Context, now time.Time) (*ProposalStats, error) {
+	const query = `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE created_at >= $1),
+			COUNT(*) FILTER (WHERE created_at >= $2),
+			COUNT(*) FILTER (WHERE created_at >= $3)
+		FROM proposals
+	`
+
+	stats := &ProposalStats{}
+	err := r.q.QueryRowContext(ctx, query, now.Add(-24*time.Hour), now.AddDate(0, 0, -7), now.AddDate(0, 0, -30)).
+		Scan(&stats.Total, &stats.Last24h, &stats.Last7d, &stats.Last30d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count proposals: %w", err)
+	}
+
+	return stats, nil
+}
+
+// TopContracts returns the contracts with the most proposals since a point in time, most first
+func (r *ProposalRepository) TopContracts(ctx context.Context, since time.Time, limit int) ([]*ContractCount, error) {
+	const query = `
+		SELECT contract_id, COUNT(*) AS proposals
+		FROM proposals
+		WHERE created_at >= $1
+		GROUP BY contract_id
+		ORDER BY proposals DESC, contract_id
+		LIMIT $2
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top contracts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []*ContractCount
+	for rows.Next() {
+		count := &ContractCount{}
+		if err := rows.Scan(&count.ContractID, &count.Proposals); err != nil {
+			return nil, fmt.Errorf("failed to scan contract count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contract counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// scanProposals reads proposals selected as proposal_id, contract_id, title,
+// synopsis, proposer, types, block_height, created_at
+func scanProposals(rows *sql.Rows) ([]*Proposal, error) {
+	var proposals []*Proposal
+	for rows.Next() {
+		proposal := &Proposal{}
+		var blockHeight int64
+		err := rows.Scan(
+			&proposal.ProposalID,
+			&proposal.ContractID,
+			&proposal.Title,
+			&proposal.Synopsis,
+			&proposal.Proposer,
+			pq.Array(&proposal.Types),
+			&blockHeight,
+			&proposal.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan proposal: %w", err)
+		}
+		proposal.BlockHeight = uint64(blockHeight)
+		proposals = append(proposals, proposal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating proposals: %w", err)
+	}
+
+	return proposals, nil
+}
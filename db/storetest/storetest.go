@@ -0,0 +1,462 @@
+// Package storetest is a conformance suite for db.SubscriptionStore implementations.
+// Run it from a test of each implementation:
+//
+//	func TestSubscriptionStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T, maxPerChat int) db.SubscriptionStore {
+//			store := memstore.NewSubscriptionStore()
+//			store.SetMaxPerChat(maxPerChat)
+//			return store
+//		})
+//	}
+//
+// Each case asks for a new, empty store. A Postgres factory should therefore
+// truncate the subscriptions table before returning the repository.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/filter"
+)
+
+// Factory returns a new, empty store limited to maxPerChat subscriptions per chat (0 for no limit)
+type Factory func(t *testing.T, maxPerChat int) db.SubscriptionStore
+
+const (
+	chatA int64 = 1001
+	chatB int64 = 1002
+	chatC int64 = -1003 // Group chats have negative IDs
+)
+
+// Run runs every conformance case against stores created by newStore
+func Run(t *testing.T, newStore Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, newStore Factory)
+	}{
+		{"SubscribeIsIdempotent", testSubscribeIsIdempotent},
+		{"SubscriptionLimit", testSubscriptionLimit},
+		{"Unsubscribe", testUnsubscribe},
+		{"UnsubscribeAll", testUnsubscribeAll},
+		{"SubscribeMany", testSubscribeMany},
+		{"UnsubscribeMany", testUnsubscribeMany},
+		{"ReplaceAll", testReplaceAll},
+		{"ResolveSubscribers", testResolveSubscribers},
+		{"Filters", testFilters},
+		{"UserSubscriptionOrder", testUserSubscriptionOrder},
+		{"ResultsAreCopies", testResultsAreCopies},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newStore)
+		})
+	}
+}
+
+func testSubscribeIsIdempotent(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 0)
+
+	first := subscribe(t, store, chatA, "$ZRA+0000")
+	second := subscribe(t, store, chatA, "$ZRA+0000")
+	if first.ID != second.ID {
+		t.Errorf("resubscribing created a new subscription: %s, then %s", first.ID, second.ID)
+	}
+	if first.ChatID != chatA || first.Symbol != "$ZRA+0000" || first.Type != db.ProposalType {
+		t.Errorf("unexpected subscription %+v", first)
+	}
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbols(t, subs, "$ZRA+0000")
+}
+
+func testSubscriptionLimit(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 2)
+
+	if got := store.MaxPerChat(); got != 2 {
+		t.Errorf("MaxPerChat() = %d, want 2", got)
+	}
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+	subscribe(t, store, chatA, "$ZIP+0000")
+
+	if _, err := store.Subscribe(ctx, chatA, db.ProposalType, "$ABC+0000"); !errors.Is(err, db.ErrSubscriptionLimit) {
+		t.Errorf("subscribing past the limit returned %v, want ErrSubscriptionLimit", err)
+	}
+
+	// Existing subscriptions can still be refreshed, and other chats are unaffected
+	subscribe(t, store, chatA, "$ZRA+0000")
+	subscribe(t, store, chatB, "$ABC+0000")
+}
+
+func testUnsubscribe(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 0)
+
+	if err := store.Unsubscribe(ctx, chatA, db.ProposalType, "$ZRA+0000"); !errors.Is(err, db.ErrSubscriptionNotFound) {
+		t.Errorf("unsubscribing without a subscription returned %v, want ErrSubscriptionNotFound", err)
+	}
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+	subscribe(t, store, chatA, "$ZIP+0000")
+	if err := store.Unsubscribe(ctx, chatA, db.ProposalType, "$ZRA+0000"); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbols(t, subs, "$ZIP+0000")
+}
+
+func testUnsubscribeAll(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 0)
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+	subscribe(t, store, chatA, "all")
+	subscribe(t, store, chatB, "$ZRA+0000")
+
+	if err := store.UnsubscribeAll(ctx, chatA, db.ProposalType); err != nil {
+		t.Fatalf("UnsubscribeAll: %v", err)
+	}
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbols(t, subs)
+
+	subs, err = store.GetUserSubscriptions(ctx, chatB)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbols(t, subs, "$ZRA+0000")
+}
+
+func testSubscribeMany(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 2)
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+
+	results, err := store.SubscribeMany(ctx, chatA, db.ProposalType, []string{"$ZRA+0000", "$ZIP+0000", "$ZIP+0000", "$ABC+0000"})
+	if err != nil {
+		t.Fatalf("SubscribeMany: %v", err)
+	}
+	assertResults(t, results, map[string]db.BulkOutcome{
+		"$ZRA+0000": db.AlreadySubscribed,
+		"$ZIP+0000": db.Subscribed,
+		"$ABC+0000": db.LimitReached,
+	}, "$ZRA+0000", "$ZIP+0000", "$ABC+0000")
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbolSet(t, subs, "$ZRA+0000", "$ZIP+0000")
+}
+
+func testUnsubscribeMany(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 0)
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+	subscribe(t, store, chatA, "$ZIP+0000")
+
+	results, err := store.UnsubscribeMany(ctx, chatA, db.ProposalType, []string{"$ZRA+0000", "$ABC+0000", "$ZRA+0000"})
+	if err != nil {
+		t.Fatalf("UnsubscribeMany: %v", err)
+	}
+	assertResults(t, results, map[string]db.BulkOutcome{
+		"$ZRA+0000": db.Unsubscribed,
+		"$ABC+0000": db.NotSubscribed,
+	}, "$ZRA+0000", "$ABC+0000")
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbols(t, subs, "$ZIP+0000")
+}
+
+func testReplaceAll(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 2)
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+	subscribe(t, store, chatA, "$ZIP+0000")
+
+	// Replaced subscriptions don't count towards the limit
+	results, err := store.ReplaceAll(ctx, chatA, db.ProposalType, []string{"$ZIP+0000", "all"})
+	if err != nil {
+		t.Fatalf("ReplaceAll: %v", err)
+	}
+	assertResults(t, results, map[string]db.BulkOutcome{
+		"$ZIP+0000": db.AlreadySubscribed,
+		"all":       db.Subscribed,
+	}, "$ZIP+0000", "all")
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbolSet(t, subs, "$ZIP+0000", "all")
+
+	if _, err := store.ReplaceAll(ctx, chatA, db.ProposalType, nil); err != nil {
+		t.Fatalf("ReplaceAll: %v", err)
+	}
+	subs, err = store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbols(t, subs)
+}
+
+func testResolveSubscribers(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 0)
+
+	subscribe(t, store, chatB, "all")
+	subscribe(t, store, chatB, "$ZRA+*")
+	subscribe(t, store, chatB, "$ZRA+0000")
+	subscribe(t, store, chatA, "$Z*+0000")
+	subscribe(t, store, chatA, "$*+0000")
+	subscribe(t, store, chatC, "$ZIP+0000")
+	subscribe(t, store, chatC, "$ZRA+0001")
+	subscribe(t, store, chatC, "$ZI*+0000")
+
+	tests := []struct {
+		symbol string
+		want   []matchedChat
+	}{
+		{"$ZRA+0000", []matchedChat{
+			{chatA, []match{{"$*+0000", db.MatchPattern}, {"$Z*+0000", db.MatchPattern}}},
+			{chatB, []match{{"$ZRA+0000", db.MatchExact}, {"$ZRA+*", db.MatchPattern}, {"all", db.MatchAll}}},
+		}},
+		{"$ZIP+0000", []matchedChat{
+			{chatC, []match{{"$ZIP+0000", db.MatchExact}, {"$ZI*+0000", db.MatchPattern}}},
+			{chatA, []match{{"$*+0000", db.MatchPattern}, {"$Z*+0000", db.MatchPattern}}},
+			{chatB, []match{{"all", db.MatchAll}}},
+		}},
+		{"$ABC+0001", []matchedChat{
+			{chatB, []match{{"all", db.MatchAll}}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			subscribers, err := store.ResolveSubscribers(ctx, tt.symbol, db.ProposalType)
+			if err != nil {
+				t.Fatalf("ResolveSubscribers: %v", err)
+			}
+
+			var got []matchedChat
+			for _, subscriber := range subscribers {
+				chat := matchedChat{chatID: subscriber.ChatID}
+				for _, m := range subscriber.Matches {
+					if m.Subscription.ChatID != subscriber.ChatID {
+						t.Errorf("chat %d has a match from chat %d", subscriber.ChatID, m.Subscription.ChatID)
+					}
+					chat.matches = append(chat.matches, match{m.Subscription.Symbol, m.Reason})
+				}
+				got = append(got, chat)
+			}
+
+			// Chats are ordered by ID
+			want := append([]matchedChat(nil), tt.want...)
+			sortChats(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ResolveSubscribers(%s) = %+v, want %+v", tt.symbol, got, want)
+			}
+		})
+	}
+}
+
+func testFilters(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 0)
+
+	f, err := filter.Parse(`treasury -test +/fee.*cut/ type:yesno`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if err := store.SetFilter(ctx, chatA, db.ProposalType, "$ZRA+0000", f); !errors.Is(err, db.ErrSubscriptionNotFound) {
+		t.Errorf("setting a filter without a subscription returned %v, want ErrSubscriptionNotFound", err)
+	}
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+	if err := store.SetFilter(ctx, chatA, db.ProposalType, "$ZRA+0000", f); err != nil {
+		t.Fatalf("SetFilter: %v", err)
+	}
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	if len(subs) != 1 || !reflect.DeepEqual(subs[0].Filter, f) {
+		t.Fatalf("stored filter = %+v, want %+v", subs, f)
+	}
+
+	// Resubscribing keeps the filter
+	sub := subscribe(t, store, chatA, "$ZRA+0000")
+	if !reflect.DeepEqual(sub.Filter, f) {
+		t.Errorf("resubscribing returned filter %+v, want %+v", sub.Filter, f)
+	}
+
+	subscribers, err := store.ResolveSubscribers(ctx, "$ZRA+0000", db.ProposalType)
+	if err != nil {
+		t.Fatalf("ResolveSubscribers: %v", err)
+	}
+	if len(subscribers) != 1 || !reflect.DeepEqual(subscribers[0].Matches[0].Subscription.Filter, f) {
+		t.Errorf("resolved subscribers don't carry the filter: %+v", subscribers)
+	}
+
+	// An empty filter removes it
+	if err := store.SetFilter(ctx, chatA, db.ProposalType, "$ZRA+0000", &filter.Filter{}); err != nil {
+		t.Fatalf("SetFilter: %v", err)
+	}
+	subs, err = store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	if subs[0].Filter != nil {
+		t.Errorf("filter after clearing = %+v, want nil", subs[0].Filter)
+	}
+}
+
+func testUserSubscriptionOrder(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 0)
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+	time.Sleep(5 * time.Millisecond)
+	subscribe(t, store, chatA, "$ZIP+0000")
+	time.Sleep(5 * time.Millisecond)
+
+	// Subscriptions made together are ordered by symbol
+	if _, err := store.SubscribeMany(ctx, chatA, db.ProposalType, []string{"$DEF+0000", "$ABC+0000"}); err != nil {
+		t.Fatalf("SubscribeMany: %v", err)
+	}
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	assertSymbols(t, subs, "$ABC+0000", "$DEF+0000", "$ZIP+0000", "$ZRA+0000")
+}
+
+func testResultsAreCopies(t *testing.T, newStore Factory) {
+	ctx := context.Background()
+	store := newStore(t, 0)
+
+	subscribe(t, store, chatA, "$ZRA+0000")
+	if err := store.SetFilter(ctx, chatA, db.ProposalType, "$ZRA+0000", &filter.Filter{Include: []string{"treasury"}}); err != nil {
+		t.Fatalf("SetFilter: %v", err)
+	}
+
+	subs, err := store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	subs[0].Symbol = "$ZIP+0000"
+	subs[0].Filter.Include[0] = "changed"
+
+	subs, err = store.GetUserSubscriptions(ctx, chatA)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	if subs[0].Symbol != "$ZRA+0000" || subs[0].Filter.Include[0] != "treasury" {
+		t.Errorf("modifying a returned subscription changed the store: %+v", subs[0])
+	}
+}
+
+type match struct {
+	symbol string
+	reason db.MatchReason
+}
+
+type matchedChat struct {
+	chatID  int64
+	matches []match
+}
+
+func sortChats(chats []matchedChat) {
+	for i := 1; i < len(chats); i++ {
+		for j := i; j > 0 && chats[j].chatID < chats[j-1].chatID; j-- {
+			chats[j], chats[j-1] = chats[j-1], chats[j]
+		}
+	}
+}
+
+func subscribe(t *testing.T, store db.SubscriptionStore, chatID int64, symbol string) *db.Subscription {
+	t.Helper()
+
+	sub, err := store.Subscribe(context.Background(), chatID, db.ProposalType, symbol)
+	if err != nil {
+		t.Fatalf("Subscribe(%d, %s): %v", chatID, symbol, err)
+	}
+	return sub
+}
+
+// assertSymbols checks the symbols of subscriptions, in order
+func assertSymbols(t *testing.T, subs []*db.Subscription, want ...string) {
+	t.Helper()
+
+	got := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		got = append(got, sub.Symbol)
+	}
+	if len(want) == 0 {
+		want = []string{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("subscriptions = %v, want %v", got, want)
+	}
+}
+
+// assertSymbolSet checks the symbols of subscriptions, in any order
+func assertSymbolSet(t *testing.T, subs []*db.Subscription, want ...string) {
+	t.Helper()
+
+	got := make(map[string]bool, len(subs))
+	for _, sub := range subs {
+		got[sub.Symbol] = true
+	}
+	wantSet := make(map[string]bool, len(want))
+	for _, symbol := range want {
+		wantSet[symbol] = true
+	}
+	if len(subs) != len(want) || !reflect.DeepEqual(got, wantSet) {
+		t.Errorf("subscriptions = %v, want %v", got, want)
+	}
+}
+
+// assertResults checks bulk outcomes and that results follow the order of the unique input symbols
+func assertResults(t *testing.T, results []*db.SymbolResult, want map[string]db.BulkOutcome, order ...string) {
+	t.Helper()
+
+	if len(results) != len(order) {
+		t.Fatalf("got %d results, want %d", len(results), len(order))
+	}
+	for i, result := range results {
+		if result.Symbol != order[i] {
+			t.Errorf("result %d is for %s, want %s", i, result.Symbol, order[i])
+		}
+		if result.Outcome != want[result.Symbol] {
+			t.Errorf("outcome for %s = %s, want %s", result.Symbol, result.Outcome, want[result.Symbol])
+		}
+	}
+}
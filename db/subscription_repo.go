@@ -2,8 +2,14 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/filter"
+	"github.com/lib/pq"
 )
 
 // SubscriptionType represents the type of subscription
@@ -14,63 +20,134 @@ const (
 	ProposalType SubscriptionType = "proposal"
 )
 
+// Channel is where a subscription's alerts are delivered. For Telegram the
+// subscription's chat ID is a Telegram chat, for other channels it is the ID of a Destination.
+type Channel string
+
+const (
+	TelegramChannel Channel = "telegram" // Telegram chats, the default
+	DiscordChannel  Channel = "discord"  // Discord incoming webhooks
+	SlackChannel    Channel = "slack"    // Slack incoming webhooks
+	MatrixChannel   Channel = "matrix"   // Matrix rooms
+)
+
 // Subscription represents a user's subscription to a specific symbol and type
 type Subscription struct {
 	ID        string
 	ChatID    int64
 	Symbol    string
 	Type      SubscriptionType
+	Filter    *filter.Filter // Nil if the subscription matches every proposal
 	CreatedAt string
 	UpdatedAt string
 }
 
-// SubscriptionRepository handles database operations for subscriptions
+var (
+	// ErrSubscriptionLimit is returned when a chat already has the maximum number of subscriptions
+	ErrSubscriptionLimit = errors.New("subscription limit reached")
+	// ErrSubscriptionNotFound is returned when changing a subscription that doesn't exist
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+// SubscriptionRepository handles database operations for the subscriptions of one channel
 type SubscriptionRepository struct {
-	db *Database
+	q          Querier
+	channel    Channel
+	maxPerChat int
+}
+
+// NewSubscriptionRepository creates a repository for Telegram subscriptions
+func NewSubscriptionRepository(q Querier) *SubscriptionRepository {
+	return &SubscriptionRepository{q: q, channel: TelegramChannel}
 }
 
-func NewSubscriptionRepository(db *Database) *SubscriptionRepository {
-	return &SubscriptionRepository{db: db}
+// ForChannel returns a repository for the subscriptions of another channel, sharing
+// the querier and subscription limit
+func (r *SubscriptionRepository) ForChannel(channel Channel) *SubscriptionRepository {
+	return &SubscriptionRepository{q: r.q, channel: channel, maxPerChat: r.maxPerChat}
 }
 
-// Subscribe adds a new subscription or returns existing one
+// Channel returns the channel whose subscriptions the repository handles
+func (r *SubscriptionRepository) Channel() Channel {
+	return r.channel
+}
+
+// SetMaxPerChat limits how many subscriptions a chat can have. 0 removes the limit.
+func (r *SubscriptionRepository) SetMaxPerChat(max int) {
+	r.maxPerChat = max
+}
+
+// MaxPerChat returns the maximum number of subscriptions per chat, 0 if unlimited
+func (r *SubscriptionRepository) MaxPerChat() int {
+	return r.maxPerChat
+}
+
+// Subscribe adds a new subscription or returns existing one. It returns
+// ErrSubscriptionLimit if the chat is at its subscription limit.
 func (r *SubscriptionRepository) Subscribe(ctx context.Context, chatID int64, subType SubscriptionType, symbol string) (*Subscription, error) {
+	// Existing subscriptions are always refreshed, new ones only inserted below the limit
 	const query = `
-		INSERT INTO subscriptions (chat_id, symbol, type)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (chat_id, symbol, type) DO UPDATE
+		WITH existing AS (
+			SELECT COUNT(*) AS total, COALESCE(bool_or(symbol = $2 AND type = $3), false) AS subscribed
+			FROM subscriptions
+			WHERE chat_id = $1 AND channel = $5
+		)
+		INSERT INTO subscriptions (chat_id, symbol, type, channel)
+		SELECT $1, $2, $3, $5
+		FROM existing
+		WHERE $4 <= 0 OR existing.total < $4 OR existing.subscribed
+		ON CONFLICT (channel, chat_id, symbol, type) DO UPDATE
 		SET updated_at = NOW()
-		RETURNING id, chat_id, symbol, type, created_at, updated_at
+		RETURNING id, chat_id, symbol, type, filter, created_at, updated_at
 	`
 
 	sub := &Subscription{}
-	err := r.db.DB().QueryRowContext(
-		ctx,
-		query,
-		chatID,
-		symbol,
-		subType,
-	).Scan(
-		&sub.ID,
-		&sub.ChatID,
-		&sub.Symbol,
-		&sub.Type,
-		&sub.CreatedAt,
-		&sub.UpdatedAt,
-	)
+	var filterData []byte
+	err := inTransaction(ctx, r.q, func(tx *sql.Tx) error {
+		// The count above doesn't see rows inserted by concurrent transactions, so the
+		// chat's lock keeps them from pushing it past its limit together
+		if err := r.lockChat(ctx, tx, chatID); err != nil {
+			return err
+		}
 
+		return tx.QueryRowContext(
+			ctx,
+			query,
+			chatID,
+			symbol,
+			subType,
+			r.maxPerChat,
+			r.channel,
+		).Scan(
+			&sub.ID,
+			&sub.ChatID,
+			&sub.Symbol,
+			&sub.Type,
+			&filterData,
+			&sub.CreatedAt,
+			&sub.UpdatedAt,
+		)
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSubscriptionLimit
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe: %w", err)
 	}
 
+	if sub.Filter, err = decodeFilter(filterData); err != nil {
+		return nil, fmt.Errorf("failed to decode filter of subscription %s: %w", sub.ID, err)
+	}
+
 	return sub, nil
 }
 
 // Unsubscribe removes a subscription
 func (r *SubscriptionRepository) Unsubscribe(ctx context.Context, chatID int64, subType SubscriptionType, symbol string) error {
-	const query = `DELETE FROM subscriptions WHERE chat_id = $1 AND symbol = $2 AND type = $3`
+	const query = `DELETE FROM subscriptions WHERE chat_id = $1 AND symbol = $2 AND type = $3 AND channel = $4`
 
-	result, err := r.db.DB().ExecContext(ctx, query, chatID, symbol, subType)
+	result, err := r.q.ExecContext(ctx, query, chatID, symbol, subType, r.channel)
 	if err != nil {
 		return fmt.Errorf("failed to unsubscribe: %w", err)
 	}
@@ -81,88 +158,462 @@ func (r *SubscriptionRepository) Unsubscribe(ctx context.Context, chatID int64,
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("subscription not found")
+		return ErrSubscriptionNotFound
 	}
 
 	return nil
 }
 
-// GetSubscribers returns all chat IDs subscribed to a specific symbol and type
-func (r *SubscriptionRepository) GetSubscribers(ctx context.Context, symbol string, subType SubscriptionType) ([]int64, error) {
-	const query = `SELECT chat_id FROM subscriptions WHERE symbol = $1 OR symbol = 'all' AND type = $2`
+// MatchReason explains why a subscription matched a symbol
+type MatchReason string
+
+const (
+	// MatchExact is a subscription to exactly the symbol
+	MatchExact MatchReason = "exact"
+	// MatchPattern is a wildcard subscription such as $ZRA+* covering the symbol
+	MatchPattern MatchReason = "pattern"
+	// MatchAll is a subscription to all symbols
+	MatchAll MatchReason = "all"
+)
+
+// SubscriptionMatch is a subscription that matched a symbol
+type SubscriptionMatch struct {
+	Subscription *Subscription
+	Reason       MatchReason
+}
+
+// Subscriber is a chat together with every one of its subscriptions that matched a symbol
+type Subscriber struct {
+	ChatID  int64
+	Matches []*SubscriptionMatch // Most specific first: exact, pattern, then all
+}
+
+// ResolveSubscribers returns each chat subscribed to a symbol exactly once, with the
+// subscriptions that matched and why. Only subscriptions of the given type are
+// considered, whether they are exact, wildcard, or 'all' subscriptions.
+func (r *SubscriptionRepository) ResolveSubscribers(ctx context.Context, symbol string, subType SubscriptionType) ([]*Subscriber, error) {
+	// Patterns only contain letters, digits, $, + and *, so turning * into %
+	// gives an equivalent LIKE pattern
+	const query = `
+		SELECT id, chat_id, symbol, type, filter, created_at, updated_at, reason
+		FROM (
+			SELECT id, chat_id, symbol, type, filter, created_at, updated_at,
+				CASE
+					WHEN symbol = $1 THEN 'exact'
+					WHEN symbol = 'all' THEN 'all'
+					ELSE 'pattern'
+				END AS reason
+			FROM subscriptions
+			WHERE type = $2 AND channel = $3
+			AND (
+				symbol = $1
+				OR symbol = 'all'
+				OR (position('*' IN symbol) > 0 AND $1 LIKE replace(symbol, '*', '%'))
+			)
+		) matched
+		ORDER BY chat_id,
+			CASE reason WHEN 'exact' THEN 0 WHEN 'pattern' THEN 1 ELSE 2 END,
+			symbol
+	`
 
-	rows, err := r.db.DB().QueryContext(ctx, query, symbol, subType)
+	rows, err := r.q.QueryContext(ctx, query, symbol, subType, r.channel)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get subscribers: %w", err)
+		return nil, fmt.Errorf("failed to resolve subscribers: %w", err)
 	}
 	defer rows.Close()
 
-	var chatIDs []int64
+	var subscribers []*Subscriber
 	for rows.Next() {
-		var chatID int64
-		if err := rows.Scan(&chatID); err != nil {
-			return nil, fmt.Errorf("failed to scan chat ID: %w", err)
+		sub := &Subscription{}
+		var filterData []byte
+		var reason MatchReason
+		err := rows.Scan(
+			&sub.ID,
+			&sub.ChatID,
+			&sub.Symbol,
+			&sub.Type,
+			&filterData,
+			&sub.CreatedAt,
+			&sub.UpdatedAt,
+			&reason,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscriber: %w", err)
 		}
-		chatIDs = append(chatIDs, chatID)
+
+		if sub.Filter, err = decodeFilter(filterData); err != nil {
+			return nil, fmt.Errorf("failed to decode filter of subscription %s: %w", sub.ID, err)
+		}
+
+		// Rows are ordered by chat, so a new chat starts a new subscriber
+		if len(subscribers) == 0 || subscribers[len(subscribers)-1].ChatID != sub.ChatID {
+			subscribers = append(subscribers, &Subscriber{ChatID: sub.ChatID})
+		}
+		last := subscribers[len(subscribers)-1]
+		last.Matches = append(last.Matches, &SubscriptionMatch{Subscription: sub, Reason: reason})
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		return nil, fmt.Errorf("error iterating subscribers: %w", err)
 	}
 
-	return chatIDs, nil
+	return subscribers, nil
 }
 
 // GetUserSubscriptions returns all subscriptions for a specific chat ID
 func (r *SubscriptionRepository) GetUserSubscriptions(ctx context.Context, chatID int64) ([]*Subscription, error) {
 	const query = `
-		SELECT id, chat_id, symbol, type, created_at, updated_at
+		SELECT id, chat_id, symbol, type, filter, created_at, updated_at
 		FROM subscriptions
-		WHERE chat_id = $1
-		ORDER BY created_at DESC
+		WHERE chat_id = $1 AND channel = $2
+		ORDER BY created_at DESC, type, symbol
 	`
 
-	rows, err := r.db.DB().QueryContext(ctx, query, chatID)
+	rows, err := r.q.QueryContext(ctx, query, chatID, r.channel)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user subscriptions: %w", err)
 	}
 	defer rows.Close()
 
+	return scanSubscriptions(rows)
+}
+
+// SetFilter replaces the filter of an existing subscription. A nil filter removes it.
+func (r *SubscriptionRepository) SetFilter(ctx context.Context, chatID int64, subType SubscriptionType, symbol string, f *filter.Filter) error {
+	const query = `UPDATE subscriptions SET filter = $4 WHERE chat_id = $1 AND symbol = $2 AND type = $3 AND channel = $5`
+
+	var data sql.NullString
+	if !f.IsEmpty() {
+		encoded, err := json.Marshal(f)
+		if err != nil {
+			return fmt.Errorf("failed to encode filter: %w", err)
+		}
+		data = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	result, err := r.q.ExecContext(ctx, query, chatID, symbol, subType, data, r.channel)
+	if err != nil {
+		return fmt.Errorf("failed to set filter: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// scanSubscriptions reads subscriptions selected as id, chat_id, symbol, type, filter, created_at, updated_at
+func scanSubscriptions(rows *sql.Rows) ([]*Subscription, error) {
 	var subscriptions []*Subscription
 	for rows.Next() {
 		sub := &Subscription{}
+		var filterData []byte
 		err := rows.Scan(
 			&sub.ID,
 			&sub.ChatID,
 			&sub.Symbol,
 			&sub.Type,
+			&filterData,
 			&sub.CreatedAt,
 			&sub.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan subscription: %w", err)
 		}
+
+		if sub.Filter, err = decodeFilter(filterData); err != nil {
+			return nil, fmt.Errorf("failed to decode filter of subscription %s: %w", sub.ID, err)
+		}
+
 		subscriptions = append(subscriptions, sub)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating subscriptions: %w", err)
 	}
 
 	return subscriptions, nil
 }
 
+// Counts returns how many chats have subscriptions on the repository's channel and
+// how many subscriptions they have in total
+func (r *SubscriptionRepository) Counts(ctx context.Context) (chats, subscriptions int, err error) {
+	const query = `SELECT COUNT(DISTINCT chat_id), COUNT(*) FROM subscriptions WHERE channel = $1`
+
+	if err := r.q.QueryRowContext(ctx, query, r.channel).Scan(&chats, &subscriptions); err != nil {
+		return 0, 0, fmt.Errorf("failed to count subscriptions: %w", err)
+	}
+	return chats, subscriptions, nil
+}
+
+// ChatSummary counts the subscriptions of a chat
+type ChatSummary struct {
+	ChatID           int64
+	Subscriptions    int
+	LastSubscribedAt time.Time
+}
+
+// ListChats returns up to limit chats with subscriptions on the repository's channel
+// after a chat ID, ordered by chat ID
+func (r *SubscriptionRepository) ListChats(ctx context.Context, afterChatID int64, limit int) ([]*ChatSummary, error) {
+	const query = `
+		SELECT chat_id, COUNT(*), MAX(created_at)
+		FROM subscriptions
+		WHERE channel = $1 AND chat_id > $2
+		GROUP BY chat_id
+		ORDER BY chat_id
+		LIMIT $3
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, r.channel, afterChatID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chats: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []*ChatSummary
+	for rows.Next() {
+		c := &ChatSummary{}
+		if err := rows.Scan(&c.ChatID, &c.Subscriptions, &c.LastSubscribedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat: %w", err)
+		}
+		chats = append(chats, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chats: %w", err)
+	}
+
+	return chats, nil
+}
+
 // UnsubscribeAll removes all subscriptions for a specific chat ID and subscription type
 func (r *SubscriptionRepository) UnsubscribeAll(ctx context.Context, chatID int64, subType SubscriptionType) error {
 	const query = `
 		DELETE FROM subscriptions
-		WHERE chat_id = $1 AND type = $2
+		WHERE chat_id = $1 AND type = $2 AND channel = $3
 	`
 
-	_, err := r.db.DB().ExecContext(ctx, query, chatID, subType)
+	_, err := r.q.ExecContext(ctx, query, chatID, subType, r.channel)
 	if err != nil {
 		return fmt.Errorf("failed to unsubscribe all: %w", err)
 	}
 
 	return nil
 }
+
+// BulkOutcome is what a bulk subscription change did for one symbol
+type BulkOutcome string
+
+const (
+	Subscribed        BulkOutcome = "subscribed"         // A new subscription was created
+	AlreadySubscribed BulkOutcome = "already_subscribed" // The subscription already existed
+	LimitReached      BulkOutcome = "limit_reached"      // Not subscribed because the chat is at its subscription limit
+	Unsubscribed      BulkOutcome = "unsubscribed"       // The subscription was removed
+	NotSubscribed     BulkOutcome = "not_subscribed"     // There was no subscription to remove
+)
+
+// SymbolResult is the outcome of a bulk subscription change for one symbol
+type SymbolResult struct {
+	Symbol  string
+	Outcome BulkOutcome
+}
+
+// SubscribeMany subscribes a chat to several symbols at once. Either every allowed
+// subscription is made or, on error, none is. Symbols beyond the chat's subscription
+// limit are reported as LimitReached rather than failing the whole request.
+func (r *SubscriptionRepository) SubscribeMany(ctx context.Context, chatID int64, subType SubscriptionType, symbols []string) ([]*SymbolResult, error) {
+	var results []*SymbolResult
+	err := inTransaction(ctx, r.q, func(tx *sql.Tx) error {
+		if err := r.lockChat(ctx, tx, chatID); err != nil {
+			return err
+		}
+
+		var err error
+		results, err = r.subscribeMany(ctx, tx, chatID, subType, uniqueSymbols(symbols))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	return results, nil
+}
+
+// ReplaceAll atomically replaces all of a chat's subscriptions of a type with
+// subscriptions to the given symbols, e.g. ["all"]
+func (r *SubscriptionRepository) ReplaceAll(ctx context.Context, chatID int64, subType SubscriptionType, symbols []string) ([]*SymbolResult, error) {
+	symbols = uniqueSymbols(symbols)
+
+	var results []*SymbolResult
+	err := inTransaction(ctx, r.q, func(tx *sql.Tx) error {
+		if err := r.lockChat(ctx, tx, chatID); err != nil {
+			return err
+		}
+
+		const query = `
+			DELETE FROM subscriptions
+			WHERE chat_id = $1 AND type = $2 AND symbol <> ALL($3) AND channel = $4
+		`
+		if _, err := tx.ExecContext(ctx, query, chatID, subType, pq.Array(symbols), r.channel); err != nil {
+			return fmt.Errorf("failed to remove replaced subscriptions: %w", err)
+		}
+
+		var err error
+		results, err = r.subscribeMany(ctx, tx, chatID, subType, symbols)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace subscriptions: %w", err)
+	}
+
+	return results, nil
+}
+
+// UnsubscribeMany removes a chat's subscriptions to several symbols in one statement
+func (r *SubscriptionRepository) UnsubscribeMany(ctx context.Context, chatID int64, subType SubscriptionType, symbols []string) ([]*SymbolResult, error) {
+	symbols = uniqueSymbols(symbols)
+
+	removed := make(map[string]bool)
+	err := inTransaction(ctx, r.q, func(tx *sql.Tx) error {
+		const query = `
+			DELETE FROM subscriptions
+			WHERE chat_id = $1 AND type = $2 AND symbol = ANY($3) AND channel = $4
+			RETURNING symbol
+		`
+
+		rows, err := tx.QueryContext(ctx, query, chatID, subType, pq.Array(symbols), r.channel)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var symbol string
+			if err := rows.Scan(&symbol); err != nil {
+				return err
+			}
+			removed[symbol] = true
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+
+	results := make([]*SymbolResult, len(symbols))
+	for i, symbol := range symbols {
+		results[i] = &SymbolResult{Symbol: symbol, Outcome: NotSubscribed}
+		if removed[symbol] {
+			results[i].Outcome = Unsubscribed
+		}
+	}
+
+	return results, nil
+}
+
+// subscribeMany inserts the subscriptions that fit within the chat's limit with a single
+// statement. It must run in a transaction holding the chat's lock.
+func (r *SubscriptionRepository) subscribeMany(ctx context.Context, tx *sql.Tx, chatID int64, subType SubscriptionType, symbols []string) ([]*SymbolResult, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT symbol, type FROM subscriptions WHERE chat_id = $1 AND channel = $2`, chatID, r.channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	total := 0
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var symbol string
+		var existingType SubscriptionType
+		if err := rows.Scan(&symbol, &existingType); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		total++
+		if existingType == subType {
+			existing[symbol] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscriptions: %w", err)
+	}
+
+	// Existing subscriptions are refreshed, new ones only made up to the limit
+	results := make([]*SymbolResult, len(symbols))
+	var insert []string
+	for i, symbol := range symbols {
+		results[i] = &SymbolResult{Symbol: symbol, Outcome: AlreadySubscribed}
+		if existing[symbol] {
+			insert = append(insert, symbol)
+			continue
+		}
+		if r.maxPerChat > 0 && total >= r.maxPerChat {
+			results[i].Outcome = LimitReached
+			continue
+		}
+		total++
+		results[i].Outcome = Subscribed
+		insert = append(insert, symbol)
+	}
+
+	if len(insert) == 0 {
+		return results, nil
+	}
+
+	const query = `
+		INSERT INTO subscriptions (chat_id, symbol, type, channel)
+		SELECT $1::bigint, symbol, $2::subscription_type, $4::text FROM unnest($3::text[]) AS symbol
+		ON CONFLICT (channel, chat_id, symbol, type) DO UPDATE
+		SET updated_at = NOW()
+	`
+	if _, err := tx.ExecContext(ctx, query, chatID, subType, pq.Array(insert), r.channel); err != nil {
+		return nil, fmt.Errorf("failed to insert subscriptions: %w", err)
+	}
+
+	return results, nil
+}
+
+// subscriptionLockSpace is the first key of the advisory locks taken on chats' subscriptions,
+// keeping them apart from other advisory locks. The second key is a hash of the channel and chat.
+const subscriptionLockSpace = 0x5375_6273 // "Subs"
+
+// lockChat serializes subscription changes for a chat of the repository's channel until the
+// transaction ends, so concurrent requests can't push it past its subscription limit
+func (r *SubscriptionRepository) lockChat(ctx context.Context, tx *sql.Tx, chatID int64) error {
+	const query = `SELECT pg_advisory_xact_lock($1::integer, hashtext($2::text || ':' || $3::bigint::text))`
+	if _, err := tx.ExecContext(ctx, query, subscriptionLockSpace, string(r.channel), chatID); err != nil {
+		return fmt.Errorf("failed to lock chat subscriptions: %w", err)
+	}
+	return nil
+}
+
+// uniqueSymbols removes duplicate symbols, keeping the first occurrence
+func uniqueSymbols(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	unique := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		if !seen[symbol] {
+			seen[symbol] = true
+			unique = append(unique, symbol)
+		}
+	}
+	return unique
+}
+
+// decodeFilter decodes a filter column, returning nil for NULL
+func decodeFilter(data []byte) (*filter.Filter, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	f := &filter.Filter{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
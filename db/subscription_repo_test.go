@@ -0,0 +1,310 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+)
+
+// voteType is a second subscription type, added to the test database's enum so that
+// lookups can be checked not to mix types up
+const voteType db.SubscriptionType = "test_vote"
+
+// match is a matched subscription's symbol, type and reason
+type match struct {
+	symbol  string
+	subType db.SubscriptionType
+	reason  db.MatchReason
+}
+
+// subscription is a subscription to create before a test case
+type subscription struct {
+	channel db.Channel
+	chatID  int64
+	symbol  string
+	subType db.SubscriptionType
+}
+
+// openSubscriptions opens the test database and returns a Telegram subscription repository on it
+func openSubscriptions(t *testing.T) (*db.Database, *db.SubscriptionRepository) {
+	database := pgtest.Open(t)
+	pgtest.Exec(t, database, `ALTER TYPE subscription_type ADD VALUE IF NOT EXISTS 'test_vote'`)
+	return database, db.NewSubscriptionRepository(database)
+}
+
+func TestResolveSubscribers(t *testing.T) {
+	ctx := context.Background()
+	_, repo := openSubscriptions(t)
+
+	fixtures := []subscription{
+		// Chat 1 holds an exact and an 'all' subscription, which used to return it twice
+		{db.TelegramChannel, 1, "$ZRA+0000", db.ProposalType},
+		{db.TelegramChannel, 1, "all", db.ProposalType},
+		// Chat 2 has a pattern for proposals and an exact subscription for votes only
+		{db.TelegramChannel, 2, "$ZRA+*", db.ProposalType},
+		{db.TelegramChannel, 2, "$ZRA+0000", voteType},
+		// Chat 3 only wants votes of everything
+		{db.TelegramChannel, 3, "all", voteType},
+		// Chat 4 has a prefix pattern and an unrelated symbol
+		{db.TelegramChannel, 4, "$Z*+0000", db.ProposalType},
+		{db.TelegramChannel, 4, "$ZIP+0001", db.ProposalType},
+		// Destination 5 on Discord subscribes to the same symbol as Telegram chat 1
+		{db.DiscordChannel, 5, "$ZRA+0000", db.ProposalType},
+	}
+	for _, f := range fixtures {
+		if _, err := repo.ForChannel(f.channel).Subscribe(ctx, f.chatID, f.subType, f.symbol); err != nil {
+			t.Fatalf("Subscribe(%+v): %v", f, err)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		channel db.Channel
+		symbol  string
+		subType db.SubscriptionType
+		want    map[int64][]match
+	}{
+		{
+			name: "ProposalExactPatternAndAll", channel: db.TelegramChannel, symbol: "$ZRA+0000", subType: db.ProposalType,
+			want: map[int64][]match{
+				1: {{"$ZRA+0000", db.ProposalType, db.MatchExact}, {"all", db.ProposalType, db.MatchAll}},
+				2: {{"$ZRA+*", db.ProposalType, db.MatchPattern}},
+				4: {{"$Z*+0000", db.ProposalType, db.MatchPattern}},
+			},
+		},
+		{
+			name: "VoteIgnoresProposalSubscriptions", channel: db.TelegramChannel, symbol: "$ZRA+0000", subType: voteType,
+			want: map[int64][]match{
+				2: {{"$ZRA+0000", voteType, db.MatchExact}},
+				3: {{"all", voteType, db.MatchAll}},
+			},
+		},
+		{
+			name: "ProposalOtherSeries", channel: db.TelegramChannel, symbol: "$ZIP+0001", subType: db.ProposalType,
+			want: map[int64][]match{
+				1: {{"all", db.ProposalType, db.MatchAll}},
+				4: {{"$ZIP+0001", db.ProposalType, db.MatchExact}},
+			},
+		},
+		{
+			name: "VoteWithoutExactSubscriptions", channel: db.TelegramChannel, symbol: "$ABC+0000", subType: voteType,
+			want: map[int64][]match{
+				3: {{"all", voteType, db.MatchAll}},
+			},
+		},
+		{
+			name: "OtherChannel", channel: db.DiscordChannel, symbol: "$ZRA+0000", subType: db.ProposalType,
+			want: map[int64][]match{
+				5: {{"$ZRA+0000", db.ProposalType, db.MatchExact}},
+			},
+		},
+		{
+			name: "OtherChannelWithoutMatches", channel: db.SlackChannel, symbol: "$ZRA+0000", subType: db.ProposalType,
+			want: map[int64][]match{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			subscribers, err := repo.ForChannel(c.channel).ResolveSubscribers(ctx, c.symbol, c.subType)
+			if err != nil {
+				t.Fatalf("ResolveSubscribers: %v", err)
+			}
+
+			got := make(map[int64][]match)
+			for i, subscriber := range subscribers {
+				if _, seen := got[subscriber.ChatID]; seen {
+					t.Errorf("chat %d is returned more than once", subscriber.ChatID)
+				}
+				if i > 0 && subscribers[i-1].ChatID > subscriber.ChatID {
+					t.Errorf("chat %d is returned after chat %d", subscriber.ChatID, subscribers[i-1].ChatID)
+				}
+				for _, m := range subscriber.Matches {
+					if m.Subscription.ChatID != subscriber.ChatID {
+						t.Errorf("chat %d has a match of chat %d", subscriber.ChatID, m.Subscription.ChatID)
+					}
+					got[subscriber.ChatID] = append(got[subscriber.ChatID], match{m.Subscription.Symbol, m.Subscription.Type, m.Reason})
+				}
+			}
+
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("ResolveSubscribers(%s, %s) = %+v, want %+v", c.symbol, c.subType, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeMany(t *testing.T) {
+	ctx := context.Background()
+	database, repo := openSubscriptions(t)
+
+	const chatID = 42
+
+	cases := []struct {
+		name       string
+		maxPerChat int
+		existing   []subscription
+		subType    db.SubscriptionType
+		symbols    []string
+		want       []db.BulkOutcome
+		wantStored []match // Every subscription of the chat afterwards, in any order
+	}{
+		{
+			name:    "NewSubscriptions",
+			subType: db.ProposalType, symbols: []string{"$ZRA+0000", "$ZIP+0000"},
+			want: []db.BulkOutcome{db.Subscribed, db.Subscribed},
+			wantStored: []match{
+				{"$ZRA+0000", db.ProposalType, ""},
+				{"$ZIP+0000", db.ProposalType, ""},
+			},
+		},
+		{
+			name:     "DuplicatesAndExisting",
+			existing: []subscription{{db.TelegramChannel, chatID, "$ZRA+0000", db.ProposalType}},
+			subType:  db.ProposalType, symbols: []string{"$ZRA+0000", "$ZIP+0000", "$ZIP+0000"},
+			want: []db.BulkOutcome{db.AlreadySubscribed, db.Subscribed},
+			wantStored: []match{
+				{"$ZRA+0000", db.ProposalType, ""},
+				{"$ZIP+0000", db.ProposalType, ""},
+			},
+		},
+		{
+			name:     "SameSymbolOtherType",
+			existing: []subscription{{db.TelegramChannel, chatID, "$ZRA+0000", voteType}},
+			subType:  db.ProposalType, symbols: []string{"$ZRA+0000"},
+			want: []db.BulkOutcome{db.Subscribed},
+			wantStored: []match{
+				{"$ZRA+0000", voteType, ""},
+				{"$ZRA+0000", db.ProposalType, ""},
+			},
+		},
+		{
+			name:       "LimitCountsEveryType",
+			maxPerChat: 2,
+			existing:   []subscription{{db.TelegramChannel, chatID, "$ZRA+0000", voteType}},
+			subType:    db.ProposalType, symbols: []string{"$ZRA+0000", "$ZIP+0000"},
+			want: []db.BulkOutcome{db.Subscribed, db.LimitReached},
+			wantStored: []match{
+				{"$ZRA+0000", voteType, ""},
+				{"$ZRA+0000", db.ProposalType, ""},
+			},
+		},
+		{
+			name:       "ExistingRefreshedAtLimit",
+			maxPerChat: 1,
+			existing:   []subscription{{db.TelegramChannel, chatID, "all", voteType}},
+			subType:    voteType, symbols: []string{"$ABC+0000", "all"},
+			want: []db.BulkOutcome{db.LimitReached, db.AlreadySubscribed},
+			wantStored: []match{
+				{"all", voteType, ""},
+			},
+		},
+		{
+			name:       "OtherChannelsDontCount",
+			maxPerChat: 1,
+			existing:   []subscription{{db.DiscordChannel, chatID, "$ZRA+0000", db.ProposalType}},
+			subType:    db.ProposalType, symbols: []string{"$ZIP+0000"},
+			want: []db.BulkOutcome{db.Subscribed},
+			wantStored: []match{
+				{"$ZIP+0000", db.ProposalType, ""},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := pgtest.Reset(ctx, database); err != nil {
+				t.Fatalf("Reset: %v", err)
+			}
+			for _, e := range c.existing {
+				if _, err := repo.ForChannel(e.channel).Subscribe(ctx, e.chatID, e.subType, e.symbol); err != nil {
+					t.Fatalf("Subscribe(%+v): %v", e, err)
+				}
+			}
+
+			limited := repo.ForChannel(db.TelegramChannel)
+			limited.SetMaxPerChat(c.maxPerChat)
+			results, err := limited.SubscribeMany(ctx, chatID, c.subType, c.symbols)
+			if err != nil {
+				t.Fatalf("SubscribeMany: %v", err)
+			}
+
+			var got []db.BulkOutcome
+			for _, result := range results {
+				got = append(got, result.Outcome)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("SubscribeMany outcomes = %v, want %v", got, c.want)
+			}
+
+			subs, err := repo.GetUserSubscriptions(ctx, chatID)
+			if err != nil {
+				t.Fatalf("GetUserSubscriptions: %v", err)
+			}
+			stored := make(map[match]bool)
+			for _, sub := range subs {
+				stored[match{sub.Symbol, sub.Type, ""}] = true
+			}
+			want := make(map[match]bool)
+			for _, m := range c.wantStored {
+				want[m] = true
+			}
+			if !reflect.DeepEqual(stored, want) {
+				t.Errorf("stored subscriptions = %v, want %v", stored, want)
+			}
+		})
+	}
+}
+
+func TestConcurrentSubscribesRespectTheLimit(t *testing.T) {
+	ctx := context.Background()
+	_, repo := openSubscriptions(t)
+
+	const chatID, limit, requests = 42, 3, 12
+	repo.SetMaxPerChat(limit)
+
+	// Single and bulk subscriptions race each other for the chat's last slots
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			symbol := fmt.Sprintf("$T%02d+0000", i)
+			if i%2 == 0 {
+				if _, err := repo.Subscribe(ctx, chatID, db.ProposalType, symbol); err != nil && !errors.Is(err, db.ErrSubscriptionLimit) {
+					errs <- fmt.Errorf("Subscribe(%s): %w", symbol, err)
+				}
+				return
+			}
+			if _, err := repo.SubscribeMany(ctx, chatID, db.ProposalType, []string{symbol}); err != nil {
+				errs <- fmt.Errorf("SubscribeMany(%s): %w", symbol, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	subs, err := repo.GetUserSubscriptions(ctx, chatID)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	if len(subs) != limit {
+		t.Errorf("chat has %d subscriptions after concurrent requests, want the limit of %d", len(subs), limit)
+	}
+
+	// Other channels have limits of their own
+	if _, err := repo.ForChannel(db.DiscordChannel).Subscribe(ctx, chatID, db.ProposalType, "$ZRA+0000"); err != nil {
+		t.Errorf("Subscribe on another channel: %v", err)
+	}
+}
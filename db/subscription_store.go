@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+
+	"github.com/ZeraVision/ZeraBot/filter"
+)
+
+// SubscriptionStore stores chat subscriptions. SubscriptionRepository implements it
+// on Postgres; memstore provides an in-memory implementation with the same semantics,
+// which storetest checks both against.
+type SubscriptionStore interface {
+	// Subscribe adds a subscription or refreshes an existing one. It returns
+	// ErrSubscriptionLimit if a new subscription would exceed MaxPerChat.
+	Subscribe(ctx context.Context, chatID int64, subType SubscriptionType, symbol string) (*Subscription, error)
+	// Unsubscribe removes a subscription, returning ErrSubscriptionNotFound if there is none
+	Unsubscribe(ctx context.Context, chatID int64, subType SubscriptionType, symbol string) error
+	// UnsubscribeAll removes every subscription of a type for a chat
+	UnsubscribeAll(ctx context.Context, chatID int64, subType SubscriptionType) error
+
+	// SubscribeMany atomically subscribes to several symbols, reporting an outcome per symbol
+	SubscribeMany(ctx context.Context, chatID int64, subType SubscriptionType, symbols []string) ([]*SymbolResult, error)
+	// UnsubscribeMany atomically unsubscribes from several symbols, reporting an outcome per symbol
+	UnsubscribeMany(ctx context.Context, chatID int64, subType SubscriptionType, symbols []string) ([]*SymbolResult, error)
+	// ReplaceAll atomically replaces every subscription of a type with the given symbols
+	ReplaceAll(ctx context.Context, chatID int64, subType SubscriptionType, symbols []string) ([]*SymbolResult, error)
+
+	// ResolveSubscribers returns each chat subscribed to a symbol once, with its matching subscriptions
+	ResolveSubscribers(ctx context.Context, symbol string, subType SubscriptionType) ([]*Subscriber, error)
+	// GetUserSubscriptions returns a chat's subscriptions, newest first
+	GetUserSubscriptions(ctx context.Context, chatID int64) ([]*Subscription, error)
+	// SetFilter replaces a subscription's filter, returning ErrSubscriptionNotFound if there is none
+	SetFilter(ctx context.Context, chatID int64, subType SubscriptionType, symbol string, f *filter.Filter) error
+
+	// MaxPerChat returns the maximum number of subscriptions per chat, 0 if unlimited
+	MaxPerChat() int
+}
+
+var _ SubscriptionStore = (*SubscriptionRepository)(nil)
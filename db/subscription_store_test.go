@@ -0,0 +1,25 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/storetest"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+)
+
+func TestSubscriptionStore(t *testing.T) {
+	database := pgtest.Open(t)
+
+	// Every case gets an empty database; the lock taken by Open is held throughout
+	storetest.Run(t, func(t *testing.T, maxPerChat int) db.SubscriptionStore {
+		if err := pgtest.Reset(context.Background(), database); err != nil {
+			t.Fatalf("failed to empty the test database: %v", err)
+		}
+
+		repo := db.NewSubscriptionRepository(database)
+		repo.SetMaxPerChat(maxPerChat)
+		return repo
+	})
+}
@@ -0,0 +1,398 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrWebhookNotFound is returned when a webhook doesn't exist or belongs to another chat
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Webhook is an outbound webhook receiving signed events
+type Webhook struct {
+	ID                  int64
+	ChatID              int64 // Chat that registered and manages the webhook
+	URL                 string
+	Secret              string   // Key the payloads are signed with
+	EventTypes          []string // Empty for every event type
+	Symbols             []string // Symbols or patterns, empty for every symbol
+	Enabled             bool
+	ConsecutiveFailures int
+	LastError           string
+	CreatedAt           string
+}
+
+// DeliveryStatus is the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"   // Waiting for its next attempt
+	DeliveryDelivered DeliveryStatus = "delivered" // Accepted by the receiver
+	DeliveryFailed    DeliveryStatus = "failed"    // Given up on
+)
+
+// WebhookDelivery is an event queued for delivery to a webhook
+type WebhookDelivery struct {
+	ID        int64
+	WebhookID int64
+	EventID   string
+	EventType string
+	Payload   []byte // The JSON body sent
+	Attempts  int
+}
+
+// WebhookRepository handles database operations for webhooks and their deliveries
+type WebhookRepository struct {
+	q Querier
+}
+
+func NewWebhookRepository(q Querier) *WebhookRepository {
+	return &WebhookRepository{q: q}
+}
+
+// Create registers a webhook and sets its ID
+func (r *WebhookRepository) Create(ctx context.Context, w *Webhook) error {
+	const query = `
+		INSERT INTO webhooks (chat_id, url, secret, event_types, symbols)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, enabled, created_at
+	`
+
+	err := r.q.QueryRowContext(
+		ctx,
+		query,
+		w.ChatID,
+		w.URL,
+		w.Secret,
+		pq.Array(nonNil(w.EventTypes)),
+		pq.Array(nonNil(w.Symbols)),
+	).Scan(&w.ID, &w.Enabled, &w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a webhook by ID, or ErrWebhookNotFound
+func (r *WebhookRepository) Get(ctx context.Context, id int64) (*Webhook, error) {
+	const query = `
+		SELECT id, chat_id, url, secret, event_types, symbols, enabled, consecutive_failures, last_error, created_at
+		FROM webhooks
+		WHERE id = $1
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks, err := scanWebhooks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, ErrWebhookNotFound
+	}
+	return webhooks[0], nil
+}
+
+// List returns the webhooks of a chat, or of every chat if chatID is 0, ordered by ID
+func (r *WebhookRepository) List(ctx context.Context, chatID int64) ([]*Webhook, error) {
+	const query = `
+		SELECT id, chat_id, url, secret, event_types, symbols, enabled, consecutive_failures, last_error, created_at
+		FROM webhooks
+		WHERE $1::bigint = 0 OR chat_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWebhooks(rows)
+}
+
+// ListEnabled returns every enabled webhook
+func (r *WebhookRepository) ListEnabled(ctx context.Context) ([]*Webhook, error) {
+	const query = `
+		SELECT id, chat_id, url, secret, event_types, symbols, enabled, consecutive_failures, last_error, created_at
+		FROM webhooks
+		WHERE enabled
+		ORDER BY id
+	`
+
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list enabled webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWebhooks(rows)
+}
+
+// CountByChat returns how many webhooks a chat has registered
+func (r *WebhookRepository) CountByChat(ctx context.Context, chatID int64) (int, error) {
+	var count int
+	if err := r.q.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhooks WHERE chat_id = $1`, chatID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	return count, nil
+}
+
+// Delete removes a webhook and its deliveries. A chatID of 0 deletes the webhook
+// of any chat, otherwise ErrWebhookNotFound is returned if the chat doesn't own it.
+func (r *WebhookRepository) Delete(ctx context.Context, chatID, id int64) error {
+	const query = `DELETE FROM webhooks WHERE id = $1 AND ($2::bigint = 0 OR chat_id = $2)`
+	return r.execOne(ctx, "delete webhook", query, id, chatID)
+}
+
+// SetEnabled enables or disables a webhook. Enabling resets its failure count.
+// A chatID of 0 changes the webhook of any chat.
+func (r *WebhookRepository) SetEnabled(ctx context.Context, chatID, id int64, enabled bool) error {
+	const query = `
+		UPDATE webhooks
+		SET enabled = $3,
+			consecutive_failures = CASE WHEN $3 THEN 0 ELSE consecutive_failures END
+		WHERE id = $1 AND ($2::bigint = 0 OR chat_id = $2)
+	`
+	return r.execOne(ctx, "update webhook", query, id, chatID, enabled)
+}
+
+// RecordSuccess resets a webhook's failure count after a successful delivery
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id int64) error {
+	const query = `
+		UPDATE webhooks SET consecutive_failures = 0, last_error = ''
+		WHERE id = $1 AND (consecutive_failures <> 0 OR last_error <> '')
+	`
+	if _, err := r.q.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to record webhook success: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed delivery attempt and disables the webhook once it has
+// failed disableAfter times in a row (0 never disables). It reports whether the
+// webhook was disabled, in which case its pending deliveries are failed too.
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id int64, message string, disableAfter int) (bool, error) {
+	var disabled bool
+	err := inTransaction(ctx, r.q, func(tx *sql.Tx) error {
+		const query = `
+			UPDATE webhooks
+			SET consecutive_failures = consecutive_failures + 1,
+				last_error = $2,
+				enabled = enabled AND NOT ($3 > 0 AND consecutive_failures + 1 >= $3)
+			WHERE id = $1
+			RETURNING NOT enabled
+		`
+		if err := tx.QueryRowContext(ctx, query, id, message, disableAfter).Scan(&disabled); err != nil {
+			return err
+		}
+		if !disabled {
+			return nil
+		}
+
+		const failPending = `
+			UPDATE webhook_deliveries SET status = 'failed', last_error = 'webhook disabled'
+			WHERE webhook_id = $1 AND status = 'pending'
+		`
+		_, err := tx.ExecContext(ctx, failPending, id)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook failure: %w", err)
+	}
+
+	return disabled, nil
+}
+
+// EnqueueDelivery queues an event for a webhook. An event already queued for the webhook is ignored.
+func (r *WebhookRepository) EnqueueDelivery(ctx context.Context, d *WebhookDelivery) error {
+	const query = `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`
+
+	if _, err := r.q.ExecContext(ctx, query, d.WebhookID, d.EventID, d.EventType, string(d.Payload)); err != nil {
+		return fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// DueDeliveries returns up to limit pending deliveries of enabled webhooks whose next attempt is due
+func (r *WebhookRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	const query = `
+		SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.attempts
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND w.enabled
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT $2
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		d := &WebhookDelivery{}
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// MarkDelivered records that a delivery was accepted
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id int64, now time.Time) error {
+	const query = `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, delivered_at = $2, last_error = ''
+		WHERE id = $1
+	`
+	if _, err := r.q.ExecContext(ctx, query, id, now); err != nil {
+		return fmt.Errorf("failed to mark webhook delivery delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkAttemptFailed records a failed delivery attempt. The delivery is retried at
+// nextAttempt, or given up on if nextAttempt is zero.
+func (r *WebhookRepository) MarkAttemptFailed(ctx context.Context, id int64, message string, nextAttempt time.Time) error {
+	const query = `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1,
+			last_error = $2,
+			status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE id = $1
+	`
+
+	var next sql.NullTime
+	if !nextAttempt.IsZero() {
+		next = sql.NullTime{Time: nextAttempt, Valid: true}
+	}
+
+	if _, err := r.q.ExecContext(ctx, query, id, message, next); err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// FailedDelivery is a webhook delivery that was given up on
+type FailedDelivery struct {
+	WebhookDelivery
+	ChatID    int64
+	URL       string
+	LastError string
+	CreatedAt time.Time
+}
+
+// FailedDeliveries returns up to limit deliveries that were given up on, newest first
+func (r *WebhookRepository) FailedDeliveries(ctx context.Context, limit int) ([]*FailedDelivery, error) {
+	const query = `
+		SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.attempts, w.chat_id, w.url, d.last_error, d.created_at
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'failed'
+		ORDER BY d.id DESC
+		LIMIT $1
+	`
+
+	rows, err := r.q.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*FailedDelivery
+	for rows.Next() {
+		d := &FailedDelivery{}
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &d.ChatID, &d.URL, &d.LastError, &d.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// execOne runs a statement that must affect exactly one webhook
+func (r *WebhookRepository) execOne(ctx context.Context, action, query string, args ...interface{}) error {
+	result, err := r.q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// scanWebhooks reads webhooks selected as id, chat_id, url, secret, event_types,
+// symbols, enabled, consecutive_failures, last_error, created_at
+func scanWebhooks(rows *sql.Rows) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	for rows.Next() {
+		w := &Webhook{}
+		err := rows.Scan(
+			&w.ID,
+			&w.ChatID,
+			&w.URL,
+			&w.Secret,
+			pq.Array(&w.EventTypes),
+			pq.Array(&w.Symbols),
+			&w.Enabled,
+			&w.ConsecutiveFailures,
+			&w.LastError,
+			&w.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// nonNil returns an empty slice for nil so it is stored as an empty array rather than NULL
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,132 @@
+package email
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+)
+
+// Handler serves the confirmation and unsubscribe links. Opening a link shows a
+// page with a button that posts the token back, so mail scanners that follow
+// links can't confirm or unsubscribe an address. Mail clients unsubscribe with
+// one click by posting to the unsubscribe link directly (RFC 8058).
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ConfirmPath, s.handleConfirm)
+	mux.HandleFunc(UnsubscribePath, s.handleUnsubscribe)
+	return mux
+}
+
+// handleConfirm asks to confirm an address on GET and confirms it on POST
+func (s *Service) handleConfirm(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	lang := i18n.DefaultLanguage
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writePage(w, http.StatusOK, &page{
+			Lang:    lang,
+			Title:   i18n.T(lang, "email.page.title"),
+			Message: i18n.T(lang, "email.page.confirm_prompt"),
+			Action:  ConfirmPath,
+			Token:   token,
+			Button:  i18n.T(lang, "email.page.confirm_button"),
+		})
+	case http.MethodPost:
+		now := time.Now()
+		e, err := s.repo.Confirm(r.Context(), token, now, now.Add(-s.cfg.ConfirmTTL))
+		if err != nil {
+			s.writeError(w, lang, err)
+			return
+		}
+
+		lang = s.chatLanguage(r.Context(), e.ChatID)
+		s.writePage(w, http.StatusOK, &page{
+			Lang:    lang,
+			Title:   i18n.T(lang, "email.page.title"),
+			Message: i18n.T(lang, "email.page.confirmed", e.Address),
+		})
+
+		if s.OnConfirmed != nil {
+			s.OnConfirmed(e)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleUnsubscribe asks to unsubscribe an address on GET and unsubscribes it on POST
+func (s *Service) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	lang := i18n.DefaultLanguage
+
+	switch r.Method {
+	case http.MethodGet:
+		e, err := s.repo.GetByUnsubscribeToken(r.Context(), token)
+		if err != nil {
+			s.writeError(w, lang, err)
+			return
+		}
+
+		lang = s.chatLanguage(r.Context(), e.ChatID)
+		s.writePage(w, http.StatusOK, &page{
+			Lang:    lang,
+			Title:   i18n.T(lang, "email.page.title"),
+			Message: i18n.T(lang, "email.page.unsubscribe_prompt", e.Address),
+			Action:  UnsubscribePath,
+			Token:   token,
+			Button:  i18n.T(lang, "email.page.unsubscribe_button"),
+		})
+	case http.MethodPost:
+		e, err := s.repo.DeleteByUnsubscribeToken(r.Context(), token)
+		if err != nil {
+			s.writeError(w, lang, err)
+			return
+		}
+
+		lang = s.chatLanguage(r.Context(), e.ChatID)
+		s.writePage(w, http.StatusOK, &page{
+			Lang:    lang,
+			Title:   i18n.T(lang, "email.page.title"),
+			Message: i18n.T(lang, "email.page.unsubscribed", e.Address),
+		})
+
+		if s.OnUnsubscribed != nil {
+			s.OnUnsubscribed(e)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeError shows the page for an invalid link, or logs unexpected errors
+func (s *Service) writeError(w http.ResponseWriter, lang string, err error) {
+	status := http.StatusNotFound
+	if !errors.Is(err, db.ErrEmailTokenInvalid) {
+		log.Printf("Error handling email link: %v", err)
+		status = http.StatusInternalServerError
+	}
+
+	s.writePage(w, status, &page{
+		Lang:    lang,
+		Title:   i18n.T(lang, "email.page.title"),
+		Message: i18n.T(lang, "email.page.invalid"),
+	})
+}
+
+// writePage renders a page
+func (s *Service) writePage(w http.ResponseWriter, status int, p *page) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := pageTemplate.Execute(w, p); err != nil {
+		log.Printf("Error rendering email page: %v", err)
+	}
+}
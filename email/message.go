@@ -0,0 +1,100 @@
+// Package email delivers proposal alerts and digests by email to addresses users
+// register through the bot and confirm through a link (double opt-in)
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// messageCount makes Message-IDs generated in the same nanosecond unique
+var messageCount atomic.Uint64
+
+// Message is an email with a plain text and an HTML version of its body
+type Message struct {
+	From    string // Sender, e.g. "ZeraBot <alerts@example.com>"
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // Additional headers, e.g. List-Unsubscribe
+}
+
+// Bytes encodes the message as multipart/alternative MIME
+func (m *Message) Bytes(now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := map[string]string{
+		"From":         m.From,
+		"To":           m.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date":         now.Format(time.RFC1123Z),
+		"Message-ID":   messageID(m.From, now),
+		"MIME-Version": "1.0",
+		"Content-Type": mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": body.Boundary()}),
+	}
+	for name, value := range m.Headers {
+		header[textproto.CanonicalMIMEHeaderKey(name)] = value
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&out, "%s: %s\r\n", name, header[name])
+	}
+	out.WriteString("\r\n")
+
+	// Clients show the last alternative they support, so HTML goes last
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create message part: %w", err)
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("failed to encode message part: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode message part: %w", err)
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish message: %w", err)
+	}
+
+	out.Write(buf.Bytes())
+	return out.Bytes(), nil
+}
+
+// messageID returns a unique Message-ID in the sender's domain
+func messageID(from string, now time.Time) string {
+	domain := "zerabot.local"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = d
+		}
+	}
+	return fmt.Sprintf("<%d.%d@%s>", now.UnixNano(), messageCount.Add(1), domain)
+}
@@ -0,0 +1,511 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+var (
+	// ErrInvalidAddress is returned for addresses that can't be parsed or carry a display name
+	ErrInvalidAddress = errors.New("invalid email address")
+	// ErrLimitReached is returned when a chat registered as many addresses as allowed
+	ErrLimitReached = errors.New("email address limit reached")
+	// ErrConfirmationPending is returned when an address was sent a confirmation link too recently to send another
+	ErrConfirmationPending = errors.New("confirmation recently sent")
+)
+
+// Paths the HTTP server serves the confirmation and unsubscribe links on
+const (
+	ConfirmPath     = "/email/confirm"
+	UnsubscribePath = "/email/unsubscribe"
+)
+
+// maxDigestItems limits how many proposals are listed in one digest
+const maxDigestItems = 50
+
+// sendTimeout bounds sending a single email
+const sendTimeout = 30 * time.Second
+
+// maxConcurrentSends is how many addresses are emailed at once
+const maxConcurrentSends = 4
+
+// Config configures email alerts
+type Config struct {
+	From           string        // Sender, e.g. "ZeraBot <alerts@example.com>"
+	BaseURL        string        // Public URL of the HTTP server, links point to it
+	MaxPerChat     int           // Addresses a chat may register, 0 for no limit
+	ConfirmTTL     time.Duration // How long a confirmation link is valid
+	ResendAfter    time.Duration // Wait before another confirmation link is sent to the same address
+	DigestInterval time.Duration // Time between daily digests
+	OnlyChatID     int64         // If set, only this chat's addresses get alerts (development)
+}
+
+// DefaultConfig returns the default limits and intervals
+func DefaultConfig() Config {
+	return Config{
+		MaxPerChat:     3,
+		ConfirmTTL:     48 * time.Hour,
+		ResendAfter:    10 * time.Minute,
+		DigestInterval: 24 * time.Hour,
+	}
+}
+
+// Service registers email addresses and emails them the alerts of their chat.
+// It is a notification channel.
+type Service struct {
+	cfg      Config
+	repo     *db.EmailRepository
+	subs     db.SubscriptionStore
+	settings *db.ChatSettingsRepository
+	sender   Sender
+	wake     chan struct{}
+
+	// OnConfirmed and OnUnsubscribed, if set, are called when an address is
+	// confirmed or unsubscribed through its link
+	OnConfirmed    func(*db.EmailRecipient)
+	OnUnsubscribed func(*db.EmailRecipient)
+}
+
+// NewService creates an email service. subs holds the Telegram subscriptions the
+// addresses mirror, settings their chats' languages and mute state.
+func NewService(cfg Config, repo *db.EmailRepository, subs db.SubscriptionStore, settings *db.ChatSettingsRepository, sender Sender) *Service {
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &Service{
+		cfg:      cfg,
+		repo:     repo,
+		subs:     subs,
+		settings: settings,
+		sender:   sender,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Repository returns the repository recipients are stored in
+func (s *Service) Repository() *db.EmailRepository {
+	return s.repo
+}
+
+// ConfirmTTL returns how long confirmation links are valid
+func (s *Service) ConfirmTTL() time.Duration {
+	return s.cfg.ConfirmTTL
+}
+
+// Name identifies email among notification channels
+func (s *Service) Name() string {
+	return "email"
+}
+
+// Register adds an address to a chat and emails it a confirmation link. Registering
+// an unconfirmed address again sends a new link, at most once every ResendAfter;
+// registering a confirmed one returns db.ErrEmailExists.
+func (s *Service) Register(ctx context.Context, chatID int64, address string) (*db.EmailRecipient, error) {
+	address, err := normalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByAddress(ctx, chatID, address)
+	switch {
+	case err == nil:
+		return s.resendConfirmation(ctx, existing)
+	case !errors.Is(err, db.ErrEmailNotFound):
+		return nil, err
+	}
+
+	if s.cfg.MaxPerChat > 0 {
+		count, err := s.repo.CountByChat(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= s.cfg.MaxPerChat {
+			return nil, ErrLimitReached
+		}
+	}
+
+	confirmToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	unsubscribeToken, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &db.EmailRecipient{
+		ChatID:           chatID,
+		Address:          address,
+		ConfirmToken:     confirmToken,
+		UnsubscribeToken: unsubscribeToken,
+	}
+	if err := s.repo.Create(ctx, e); err != nil {
+		return nil, err
+	}
+
+	// Don't keep addresses that never got their link
+	if err := s.sendConfirmation(ctx, e); err != nil {
+		if delErr := s.repo.Delete(ctx, chatID, e.ID); delErr != nil {
+			log.Printf("Failed to remove unconfirmable email recipient %d: %v", e.ID, delErr)
+		}
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// resendConfirmation sends a new confirmation link to an unconfirmed address
+func (s *Service) resendConfirmation(ctx context.Context, e *db.EmailRecipient) (*db.EmailRecipient, error) {
+	if e.Confirmed() {
+		return nil, db.ErrEmailExists
+	}
+
+	now := time.Now()
+	if now.Sub(e.ConfirmationSentAt) < s.cfg.ResendAfter {
+		return nil, ErrConfirmationPending
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RefreshConfirmation(ctx, e.ID, token, now); err != nil {
+		return nil, err
+	}
+	e.ConfirmToken, e.ConfirmationSentAt = token, now
+
+	if err := s.sendConfirmation(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// sendConfirmation emails the confirmation link of an address
+func (s *Service) sendConfirmation(ctx context.Context, e *db.EmailRecipient) error {
+	lang := s.chatLanguage(ctx, e.ChatID)
+	c := &content{
+		Lang:    lang,
+		Heading: i18n.T(lang, "email.confirm.heading"),
+		Intro:   i18n.T(lang, "email.confirm.intro"),
+		Action: &action{
+			Label: i18n.T(lang, "email.confirm.action"),
+			URL:   s.link(ConfirmPath, e.ConfirmToken),
+		},
+		Note: i18n.T(lang, "email.confirm.note", int(s.cfg.ConfirmTTL.Hours())),
+	}
+
+	// No unsubscribe link, the address isn't subscribed until it is confirmed
+	return s.send(ctx, e, i18n.T(lang, "email.confirm.subject"), c, false)
+}
+
+// NotifySubscribers queues an alert for the confirmed addresses of every chat that
+// would receive it in Telegram. Run emails it, on its own or in their next digest.
+func (s *Service) NotifySubscribers(alert *notify.ProposalAlert) error {
+	ctx := context.Background()
+	resolved, err := s.subs.ResolveSubscribers(ctx, alert.Symbol, db.ProposalType)
+	if err != nil {
+		return fmt.Errorf("failed to get subscribers: %w", err)
+	}
+
+	// A chat is notified if any of its matching subscriptions passes its filter
+	candidate := alert.FilterCandidate()
+	var chatIDs []int64
+	for _, subscriber := range resolved {
+		if s.cfg.OnlyChatID != 0 && subscriber.ChatID != s.cfg.OnlyChatID {
+			continue
+		}
+		for _, match := range subscriber.Matches {
+			if match.Subscription.Filter.Match(candidate) {
+				chatIDs = append(chatIDs, subscriber.ChatID)
+				break
+			}
+		}
+	}
+	if len(chatIDs) == 0 {
+		return nil
+	}
+
+	recipients, err := s.repo.ListConfirmed(ctx, chatIDs)
+	if err != nil {
+		return err
+	}
+
+	queued := false
+	for _, e := range recipients {
+		settings, err := s.settings.Get(ctx, e.ChatID)
+		if err != nil {
+			log.Printf("Failed to get settings for chat %d: %v", e.ChatID, err)
+			continue
+		}
+		if settings.Muted {
+			continue
+		}
+
+		pending := &db.PendingAlert{
+			ChatID:     e.ChatID,
+			Symbol:     alert.Symbol,
+			ProposalID: alert.ProposalID,
+			Title:      alert.Title,
+			Synopsis:   alert.Synopsis,
+		}
+		if err := s.repo.EnqueueAlert(ctx, e.ID, pending); err != nil {
+			log.Printf("Failed to queue email alert for recipient %d: %v", e.ID, err)
+			continue
+		}
+		queued = true
+	}
+
+	// Send right away rather than at the next tick
+	if queued {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// sendAlert emails a single proposal alert
+func (s *Service) sendAlert(ctx context.Context, e *db.EmailRecipient, lang string, alert *notify.ProposalAlert) error {
+	c := &content{
+		Lang:    lang,
+		Heading: i18n.T(lang, "email.alert.heading", alert.Symbol),
+		Items: []item{{
+			Symbol:   alert.Symbol,
+			Title:    util.Truncate(alert.Title, 200),
+			Synopsis: util.Truncate(alert.Synopsis, 2000),
+			URL:      alert.URL(),
+			Link:     i18n.T(lang, "email.alert.view"),
+		}},
+	}
+
+	subject := i18n.T(lang, "email.alert.subject", alert.Symbol, util.Truncate(alert.Title, 80))
+	return s.send(ctx, e, subject, c, true)
+}
+
+// Run emails queued alerts and due digests every interval, and as soon as alerts
+// are queued, until the context is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		s.SendDue(ctx, time.Now())
+	}
+}
+
+// SendDue emails the alerts queued for addresses getting an email per alert, and a
+// digest to every address with queued alerts whose last digest is at least
+// DigestInterval old. Several addresses are emailed at once.
+func (s *Service) SendDue(ctx context.Context, now time.Time) {
+	// Alerts that couldn't be sent on their own for a whole digest interval are given up on
+	if n, err := s.repo.DropStaleAlerts(ctx, now.Add(-s.cfg.DigestInterval)); err != nil {
+		log.Printf("Error dropping stale email alerts: %v", err)
+	} else if n > 0 {
+		log.Printf("Gave up on %d email alerts that couldn't be sent", n)
+	}
+
+	recipients, err := s.repo.DueRecipients(ctx, now.Add(-s.cfg.DigestInterval))
+	if err != nil {
+		log.Printf("Error getting due email recipients: %v", err)
+		return
+	}
+
+	slots := make(chan struct{}, maxConcurrentSends)
+	var wg sync.WaitGroup
+	for _, e := range recipients {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(e *db.EmailRecipient) {
+			defer func() { <-slots; wg.Done() }()
+
+			if e.DailyDigest {
+				if err := s.sendDigest(ctx, e, now); err != nil {
+					log.Printf("Error sending email digest to recipient %d: %v", e.ID, err)
+				}
+				return
+			}
+			if err := s.sendQueued(ctx, e); err != nil {
+				log.Printf("Error emailing alerts to recipient %d: %v", e.ID, err)
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+// sendQueued emails the alerts queued for an address one by one, removing each once
+// sent. Alerts that fail stay queued for the next run.
+func (s *Service) sendQueued(ctx context.Context, e *db.EmailRecipient) error {
+	alerts, err := s.repo.QueuedAlerts(ctx, e.ID)
+	if err != nil || len(alerts) == 0 {
+		return err
+	}
+
+	settings, err := s.settings.Get(ctx, e.ChatID)
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		// Alerts queued before the chat was muted are dropped
+		if !settings.Muted {
+			proposal := &notify.ProposalAlert{
+				Symbol:     alert.Symbol,
+				ProposalID: alert.ProposalID,
+				Title:      alert.Title,
+				Synopsis:   alert.Synopsis,
+			}
+			if err := s.sendAlert(ctx, e, language(settings), proposal); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.RemoveAlerts(ctx, e.ID, alert.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendDigest emails the alerts queued for an address as one summary and removes them from the queue
+func (s *Service) sendDigest(ctx context.Context, e *db.EmailRecipient, now time.Time) error {
+	alerts, err := s.repo.QueuedAlerts(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	settings, err := s.settings.Get(ctx, e.ChatID)
+	if err != nil {
+		return err
+	}
+
+	if !settings.Muted {
+		lang := language(settings)
+		c := &content{
+			Lang:    lang,
+			Heading: i18n.N(lang, "email.digest.heading", len(alerts), len(alerts)),
+		}
+		for i, alert := range alerts {
+			if i == maxDigestItems {
+				remaining := len(alerts) - maxDigestItems
+				c.More = i18n.N(lang, "digest.more", remaining, remaining)
+				break
+			}
+			c.Items = append(c.Items, item{
+				Symbol:   alert.Symbol,
+				Title:    util.Truncate(alert.Title, 200),
+				Synopsis: util.Truncate(alert.Synopsis, 300),
+				URL:      notify.ExplorerProposalURL + alert.ProposalID,
+				Link:     i18n.T(lang, "email.alert.view"),
+			})
+		}
+
+		subject := i18n.N(lang, "email.digest.subject", len(alerts), len(alerts))
+		if err := s.send(ctx, e, subject, c, true); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.MarkDigestSent(ctx, e.ID, alerts[len(alerts)-1].ID, now)
+}
+
+// send renders and sends an email to an address, with a one-click unsubscribe link if asked for
+func (s *Service) send(ctx context.Context, e *db.EmailRecipient, subject string, c *content, unsubscribe bool) error {
+	headers := map[string]string{"Auto-Submitted": "auto-generated"}
+	if unsubscribe {
+		unsubscribeURL := s.link(UnsubscribePath, e.UnsubscribeToken)
+		c.Footer = i18n.T(c.Lang, "email.footer")
+		c.UnsubscribeLabel = i18n.T(c.Lang, "email.unsubscribe")
+		c.UnsubscribeURL = unsubscribeURL
+
+		// RFC 8058 one-click unsubscribe, offered by mail clients next to the sender
+		headers["List-Unsubscribe"] = "<" + unsubscribeURL + ">"
+		headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	}
+
+	text, html, err := render(c)
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	err = s.sender.Send(ctx, &Message{
+		From:    s.cfg.From,
+		To:      e.Address,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// link returns the public URL of a confirmation or unsubscribe link
+func (s *Service) link(path, token string) string {
+	return s.cfg.BaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// chatLanguage returns the language of a chat, the default if its settings can't be read
+func (s *Service) chatLanguage(ctx context.Context, chatID int64) string {
+	settings, err := s.settings.Get(ctx, chatID)
+	if err != nil {
+		log.Printf("Failed to get settings for chat %d: %v", chatID, err)
+		return i18n.DefaultLanguage
+	}
+	return language(settings)
+}
+
+// language returns the language of a chat's settings
+func language(settings *db.ChatSettings) string {
+	if lang := i18n.Normalize(settings.Language); lang != "" {
+		return lang
+	}
+	return i18n.DefaultLanguage
+}
+
+// normalizeAddress checks that address is a bare email address with a domain name and lowercases it
+func normalizeAddress(address string) (string, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Name != "" || parsed.Address != address {
+		return "", ErrInvalidAddress
+	}
+	if _, domain, _ := strings.Cut(parsed.Address, "@"); !strings.Contains(domain, ".") {
+		return "", ErrInvalidAddress
+	}
+	return strings.ToLower(parsed.Address), nil
+}
+
+// newToken returns a random token for confirmation and unsubscribe links
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
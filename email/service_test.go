@@ -0,0 +1,326 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+	"github.com/ZeraVision/ZeraBot/internal/smtptest"
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+const (
+	testAddress = "user@example.com"
+	testBaseURL = "https://bot.example.com"
+)
+
+var testAlert = &notify.ProposalAlert{
+	Symbol:     "$ZRA+0000",
+	ProposalID: "abc123",
+	Title:      "Fund <the> treasury",
+	Synopsis:   "Move 1000 ZRA to the community treasury.",
+}
+
+// newSink starts a local SMTP sink and returns it with a sender delivering to it
+func newSink(t *testing.T) (*smtptest.Server, Sender) {
+	t.Helper()
+
+	sink, err := smtptest.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start SMTP sink: %v", err)
+	}
+	t.Cleanup(func() { sink.Close() })
+
+	return sink, NewSMTPSender(SMTPConfig{Host: sink.Host(), Port: sink.Port()})
+}
+
+// testConfig returns the configuration of test services
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.From = "ZeraBot <alerts@example.com>"
+	cfg.BaseURL = testBaseURL + "/"
+	return cfg
+}
+
+// newTestService opens the test database and returns a service on it sending to a local SMTP sink
+func newTestService(t *testing.T) (*Service, *smtptest.Server, *db.Database) {
+	t.Helper()
+
+	database := pgtest.Open(t)
+	sink, sender := newSink(t)
+	s := NewService(testConfig(), db.NewEmailRepository(database), db.NewSubscriptionRepository(database),
+		db.NewChatSettingsRepository(database), sender)
+	return s, sink, database
+}
+
+// onlyMessage returns the single message a sink received for an address
+func onlyMessage(t *testing.T, sink *smtptest.Server, address string) smtptest.Message {
+	t.Helper()
+
+	msgs := sink.MessagesTo(address)
+	if len(msgs) != 1 {
+		t.Fatalf("%s got %d messages, want 1", address, len(msgs))
+	}
+	return msgs[0]
+}
+
+func TestAlertMail(t *testing.T) {
+	sink, sender := newSink(t)
+	s := NewService(testConfig(), nil, nil, nil, sender)
+
+	e := &db.EmailRecipient{ChatID: 1, Address: testAddress, UnsubscribeToken: "unsub+token"}
+	if err := s.sendAlert(context.Background(), e, "en", testAlert); err != nil {
+		t.Fatalf("sendAlert: %v", err)
+	}
+
+	m := onlyMessage(t, sink, testAddress)
+	if want := i18n.T("en", "email.alert.subject", testAlert.Symbol, testAlert.Title); m.Subject != want {
+		t.Errorf("subject = %q, want %q", m.Subject, want)
+	}
+	if m.From != "alerts@example.com" {
+		t.Errorf("envelope sender = %q, want alerts@example.com", m.From)
+	}
+
+	unsubscribeURL := testBaseURL + UnsubscribePath + "?token=unsub%2Btoken"
+	headers := map[string]string{
+		"Auto-Submitted":        "auto-generated",
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	for name, want := range headers {
+		if got := m.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	for _, want := range []string{testAlert.Title, testAlert.Synopsis, testAlert.URL(), unsubscribeURL} {
+		if !strings.Contains(m.Text, want) {
+			t.Errorf("text part doesn't contain %q:\n%s", want, m.Text)
+		}
+	}
+	for _, want := range []string{"Fund &lt;the&gt; treasury", testAlert.Synopsis, testAlert.URL(), unsubscribeURL} {
+		if !strings.Contains(m.HTML, want) {
+			t.Errorf("HTML part doesn't contain %q:\n%s", want, m.HTML)
+		}
+	}
+}
+
+func TestAlertMailRejected(t *testing.T) {
+	sink, sender := newSink(t)
+	s := NewService(testConfig(), nil, nil, nil, sender)
+	sink.RejectNext("550 mailbox unavailable")
+
+	e := &db.EmailRecipient{ChatID: 1, Address: testAddress, UnsubscribeToken: "token"}
+	if err := s.sendAlert(context.Background(), e, "en", testAlert); err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("sendAlert = %v, want the rejection", err)
+	}
+	if len(sink.Messages()) != 0 {
+		t.Errorf("sink kept %d rejected messages", len(sink.Messages()))
+	}
+}
+
+func TestConfirmationMail(t *testing.T) {
+	ctx := context.Background()
+	s, sink, _ := newTestService(t)
+
+	e, err := s.Register(ctx, 1, "User@Example.com")
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if e.Address != testAddress {
+		t.Errorf("registered %q, want the address lowercased", e.Address)
+	}
+
+	m := onlyMessage(t, sink, testAddress)
+	if want := i18n.T("en", "email.confirm.subject"); m.Subject != want {
+		t.Errorf("subject = %q, want %q", m.Subject, want)
+	}
+	confirmURL := testBaseURL + ConfirmPath + "?token=" + url.QueryEscape(e.ConfirmToken)
+	if !strings.Contains(m.Text, confirmURL) || !strings.Contains(m.HTML, confirmURL) {
+		t.Errorf("confirmation mail doesn't link to %s:\n%s", confirmURL, m.Text)
+	}
+	// The address isn't subscribed until it is confirmed, so there is nothing to unsubscribe from
+	if got := m.Header.Get("List-Unsubscribe"); got != "" {
+		t.Errorf("List-Unsubscribe = %q, want none", got)
+	}
+
+	// Another link isn't sent right away
+	if _, err := s.Register(ctx, 1, testAddress); !errors.Is(err, ErrConfirmationPending) {
+		t.Errorf("Register again = %v, want ErrConfirmationPending", err)
+	}
+
+	// An address whose confirmation mail is rejected isn't kept
+	sink.RejectNext("550 mailbox unavailable")
+	if _, err := s.Register(ctx, 1, "bounce@example.com"); err == nil {
+		t.Error("Register with a rejected confirmation mail succeeded")
+	}
+	if _, err := s.Repository().GetByAddress(ctx, 1, "bounce@example.com"); !errors.Is(err, db.ErrEmailNotFound) {
+		t.Errorf("GetByAddress of the rejected address = %v, want ErrEmailNotFound", err)
+	}
+}
+
+// openLink requests an email link and returns the status and page
+func openLink(t *testing.T, method, rawURL, token string) (int, string) {
+	t.Helper()
+
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader(url.Values{"token": {token}}.Encode())
+	} else {
+		rawURL += "?token=" + url.QueryEscape(token)
+	}
+
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestConfirmAndUnsubscribeLinks(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestService(t)
+
+	var confirmed, unsubscribed []*db.EmailRecipient
+	s.OnConfirmed = func(e *db.EmailRecipient) { confirmed = append(confirmed, e) }
+	s.OnUnsubscribed = func(e *db.EmailRecipient) { unsubscribed = append(unsubscribed, e) }
+
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	e, err := s.Register(ctx, 1, testAddress)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// Opening the link only shows a form posting the token back
+	status, body := openLink(t, http.MethodGet, server.URL+ConfirmPath, e.ConfirmToken)
+	if status != http.StatusOK || !strings.Contains(body, `method="post"`) || !strings.Contains(body, e.ConfirmToken) {
+		t.Errorf("GET confirm = %d %s, want a form with the token", status, body)
+	}
+	if stored, err := s.Repository().Get(ctx, e.ID); err != nil || stored.Confirmed() {
+		t.Fatalf("after GET confirm the address is confirmed %v (%v), want unconfirmed", stored != nil && stored.Confirmed(), err)
+	}
+
+	status, body = openLink(t, http.MethodPost, server.URL+ConfirmPath, e.ConfirmToken)
+	if status != http.StatusOK || !strings.Contains(body, i18n.T("en", "email.page.confirmed", testAddress)) {
+		t.Errorf("POST confirm = %d %s, want the address confirmed", status, body)
+	}
+	if stored, err := s.Repository().Get(ctx, e.ID); err != nil || !stored.Confirmed() {
+		t.Fatalf("after POST confirm the address isn't confirmed (%v)", err)
+	}
+	if len(confirmed) != 1 || confirmed[0].ID != e.ID {
+		t.Errorf("OnConfirmed calls = %+v, want one for recipient %d", confirmed, e.ID)
+	}
+
+	if status, _ := openLink(t, http.MethodPost, server.URL+ConfirmPath, "wrong"); status != http.StatusNotFound {
+		t.Errorf("POST confirm with an unknown token = %d, want 404", status)
+	}
+
+	// The same goes for unsubscribing
+	status, body = openLink(t, http.MethodGet, server.URL+UnsubscribePath, e.UnsubscribeToken)
+	if status != http.StatusOK || !strings.Contains(body, i18n.T("en", "email.page.unsubscribe_prompt", testAddress)) {
+		t.Errorf("GET unsubscribe = %d %s, want the prompt", status, body)
+	}
+	if _, err := s.Repository().Get(ctx, e.ID); err != nil {
+		t.Fatalf("after GET unsubscribe the address is gone: %v", err)
+	}
+
+	status, body = openLink(t, http.MethodPost, server.URL+UnsubscribePath, e.UnsubscribeToken)
+	if status != http.StatusOK || !strings.Contains(body, i18n.T("en", "email.page.unsubscribed", testAddress)) {
+		t.Errorf("POST unsubscribe = %d %s, want the address unsubscribed", status, body)
+	}
+	if _, err := s.Repository().Get(ctx, e.ID); !errors.Is(err, db.ErrEmailNotFound) {
+		t.Errorf("after POST unsubscribe Get = %v, want ErrEmailNotFound", err)
+	}
+	if len(unsubscribed) != 1 || unsubscribed[0].ID != e.ID {
+		t.Errorf("OnUnsubscribed calls = %+v, want one for recipient %d", unsubscribed, e.ID)
+	}
+
+	if status, _ := openLink(t, http.MethodGet, server.URL+UnsubscribePath, e.UnsubscribeToken); status != http.StatusNotFound {
+		t.Errorf("GET unsubscribe after unsubscribing = %d, want 404", status)
+	}
+	if status, _ := openLink(t, http.MethodPut, server.URL+ConfirmPath, e.ConfirmToken); status != http.StatusMethodNotAllowed {
+		t.Errorf("PUT confirm = %d, want 405", status)
+	}
+}
+
+func TestNotifySubscribersEmailsConfirmedAddresses(t *testing.T) {
+	ctx := context.Background()
+	s, sink, database := newTestService(t)
+	subs := db.NewSubscriptionRepository(database)
+
+	// Chat 1 subscribed to the symbol with a confirmed and an unconfirmed address,
+	// chat 2 only to another symbol
+	recipients := map[string]int64{testAddress: 1, "pending@example.com": 1, "other@example.com": 2}
+	for address, chatID := range recipients {
+		e, err := s.Register(ctx, chatID, address)
+		if err != nil {
+			t.Fatalf("Register(%s): %v", address, err)
+		}
+		if address != "pending@example.com" {
+			if _, err := s.Repository().Confirm(ctx, e.ConfirmToken, time.Now(), time.Now().Add(-time.Hour)); err != nil {
+				t.Fatalf("Confirm(%s): %v", address, err)
+			}
+		}
+	}
+	if _, err := subs.Subscribe(ctx, 1, db.ProposalType, "$ZRA+*"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err := subs.Subscribe(ctx, 2, db.ProposalType, "$ZIP+0000"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	sink.Reset()
+
+	if err := s.NotifySubscribers(testAlert); err != nil {
+		t.Fatalf("NotifySubscribers: %v", err)
+	}
+	// Alerts are only queued, and sent by the next run
+	if n := len(sink.Messages()); n != 0 {
+		t.Fatalf("%d messages sent by NotifySubscribers, want none", n)
+	}
+
+	// A rejected alert stays queued for the next run
+	sink.RejectNext("451 try again later")
+	s.SendDue(ctx, time.Now())
+	if n := len(sink.Messages()); n != 0 {
+		t.Fatalf("%d messages after a rejection, want none", n)
+	}
+	s.SendDue(ctx, time.Now())
+
+	m := onlyMessage(t, sink, testAddress)
+	if want := i18n.T("en", "email.alert.subject", testAlert.Symbol, testAlert.Title); m.Subject != want {
+		t.Errorf("subject = %q, want %q", m.Subject, want)
+	}
+	for _, address := range []string{"pending@example.com", "other@example.com"} {
+		if n := len(sink.MessagesTo(address)); n != 0 {
+			t.Errorf("%s got %d messages, want none", address, n)
+		}
+	}
+
+	// Sent alerts are removed from the queue
+	s.SendDue(ctx, time.Now())
+	if n := len(sink.MessagesTo(testAddress)); n != 1 {
+		t.Errorf("%s got %d messages after another run, want 1", testAddress, n)
+	}
+}
@@ -0,0 +1,116 @@
+package email
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// content is what every email is rendered from: a heading, an optional introduction,
+// proposals, a call to action and a footer with the unsubscribe link
+type content struct {
+	Lang             string
+	Heading          string
+	Intro            string
+	Items            []item
+	More             string // Shown after the items if some were left out
+	Action           *action
+	Note             string
+	Footer           string
+	UnsubscribeLabel string
+	UnsubscribeURL   string
+}
+
+// item is a proposal listed in an email
+type item struct {
+	Symbol   string
+	Title    string
+	Synopsis string
+	URL      string
+	Link     string // Label of the URL
+}
+
+// action is a link rendered as a button
+type action struct {
+	Label string
+	URL   string
+}
+
+var textTemplate = template.Must(template.New("text").Parse(`{{.Heading}}
+{{if .Intro}}
+{{.Intro}}
+{{end}}{{range .Items}}
+{{.Symbol}}: {{.Title}}
+{{if .Synopsis}}{{.Synopsis}}
+{{end}}{{.Link}}: {{.URL}}
+{{end}}{{if .More}}
+{{.More}}
+{{end}}{{with .Action}}
+{{.Label}}: {{.URL}}
+{{end}}{{if .Note}}
+{{.Note}}
+{{end}}{{if .UnsubscribeURL}}
+--
+{{.Footer}}
+{{.UnsubscribeLabel}}: {{.UnsubscribeURL}}
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"></head>
+<body style="margin:0;padding:24px;background:#f4f5f7;font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;color:#1f2328">
+<div style="max-width:560px;margin:0 auto;background:#ffffff;border-radius:8px;padding:24px">
+<h2 style="margin:0 0 16px;font-size:20px">{{.Heading}}</h2>
+{{if .Intro}}<p style="line-height:1.5">{{.Intro}}</p>{{end}}
+{{range .Items}}<div style="border-top:1px solid #e5e7eb;padding:12px 0">
+<div style="font-weight:600"><code>{{.Symbol}}</code> {{.Title}}</div>
+{{if .Synopsis}}<p style="margin:8px 0;line-height:1.5;color:#444c56">{{.Synopsis}}</p>{{end}}
+<a href="{{.URL}}" style="color:#0969da">{{.Link}}</a>
+</div>
+{{end}}{{if .More}}<p style="color:#57606a">{{.More}}</p>{{end}}
+{{with .Action}}<p style="margin:24px 0"><a href="{{.URL}}" style="background:#0969da;color:#ffffff;padding:10px 18px;border-radius:6px;text-decoration:none;font-weight:600">{{.Label}}</a></p>{{end}}
+{{if .Note}}<p style="color:#57606a;font-size:13px">{{.Note}}</p>{{end}}
+</div>
+{{if .UnsubscribeURL}}<p style="max-width:560px;margin:16px auto 0;color:#57606a;font-size:12px;text-align:center">{{.Footer}} <a href="{{.UnsubscribeURL}}" style="color:#57606a">{{.UnsubscribeLabel}}</a></p>{{end}}
+</body>
+</html>
+`))
+
+// render renders content as the plain text and HTML bodies of an email
+func render(c *content) (text, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	if err := textTemplate.Execute(&textBuf, c); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplate.Execute(&htmlBuf, c); err != nil {
+		return "", "", err
+	}
+	return textBuf.String(), htmlBuf.String(), nil
+}
+
+// page is a page served for confirmation and unsubscribe links, optionally with
+// a form posting the token back
+type page struct {
+	Lang    string
+	Title   string
+	Message string
+	Action  string // Form action, no form if empty
+	Token   string
+	Button  string
+}
+
+var pageTemplate = htmltemplate.Must(htmltemplate.New("page").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Title}}</title></head>
+<body style="margin:0;padding:48px 24px;background:#f4f5f7;font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;color:#1f2328">
+<div style="max-width:480px;margin:0 auto;background:#ffffff;border-radius:8px;padding:24px;text-align:center">
+<h2 style="margin:0 0 16px;font-size:20px">{{.Title}}</h2>
+<p style="line-height:1.5">{{.Message}}</p>
+{{if .Action}}<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit" style="background:#0969da;color:#ffffff;border:0;padding:10px 18px;border-radius:6px;font-weight:600;font-size:15px;cursor:pointer">{{.Button}}</button>
+</form>{{end}}
+</div>
+</body>
+</html>
+`))
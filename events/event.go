@@ -0,0 +1,99 @@
+// Package events keeps a log of live events, new proposals and processed blocks, and
+// streams them to API clients, which can resume from the log after a disconnect
+package events
+
+import (
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+// Event types
+const (
+	EventProposalCreated = "proposal.created" // A governance proposal was accepted on chain
+	EventBlockProcessed  = "block.processed"  // The bot processed a block broadcast by the network
+)
+
+// EventTypes lists the event types clients can filter on
+var EventTypes = []string{EventProposalCreated, EventBlockProcessed}
+
+// IsEventType reports whether t is a known event type
+func IsEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Message is an event as sent to clients
+type Message struct {
+	ID        int64       `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// NewMessage converts a logged event to the message sent to clients
+func NewMessage(e *db.Event) *Message {
+	return &Message{ID: e.ID, Type: e.Type, CreatedAt: e.CreatedAt.UTC(), Data: e.Data}
+}
+
+// ProposalData is the data of proposal.created events
+type ProposalData struct {
+	Symbol     string   `json:"symbol"`
+	ProposalID string   `json:"proposal_id"`
+	Title      string   `json:"title"`
+	Synopsis   string   `json:"synopsis"`
+	Types      []string `json:"types"`
+	Proposer   string   `json:"proposer"`
+	URL        string   `json:"url"`
+}
+
+// newProposalData builds the data of a proposal.created event from an alert
+func newProposalData(alert *notify.ProposalAlert) ProposalData {
+	types := alert.Types
+	if types == nil {
+		types = []string{}
+	}
+
+	return ProposalData{
+		Symbol:     alert.Symbol,
+		ProposalID: alert.ProposalID,
+		Title:      alert.Title,
+		Synopsis:   alert.Synopsis,
+		Types:      types,
+		Proposer:   alert.Proposer,
+		URL:        alert.URL(),
+	}
+}
+
+// BlockData is the data of block.processed events
+type BlockData struct {
+	Height uint64 `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// Filter selects the events a client receives
+type Filter struct {
+	Types  []string // Event types, every type if empty
+	Symbol string   // Only events about this contract or no contract, every event if empty
+}
+
+// Matches reports whether an event passes the filter
+func (f Filter) Matches(e *db.Event) bool {
+	if f.Symbol != "" && e.Symbol != "" && e.Symbol != f.Symbol {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
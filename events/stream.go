@@ -0,0 +1,203 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/metrics"
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+var (
+	// ErrTooManyClients is returned when subscribing while MaxClients are connected
+	ErrTooManyClients = errors.New("too many event stream clients")
+	// ErrClosed is returned when subscribing after the stream stopped
+	ErrClosed = errors.New("event stream closed")
+)
+
+// Config controls the event log and its clients
+type Config struct {
+	MaxClients int           // Clients streaming at once, 0 for no limit
+	BufferSize int           // Events buffered per client before a slow client is disconnected
+	Retention  time.Duration // How long events are kept for clients resuming a stream
+}
+
+// DefaultConfig returns the default stream settings
+func DefaultConfig() Config {
+	return Config{
+		MaxClients: 1000,
+		BufferSize: 64,
+		Retention:  7 * 24 * time.Hour,
+	}
+}
+
+// Stream logs events and broadcasts them to subscribed clients. It is a
+// notification channel, logging a proposal.created event for every alert.
+type Stream struct {
+	cfg  Config
+	repo db.EventStore
+
+	publishMu sync.Mutex // Serializes publishing so clients get events in ID order
+
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+	closed        bool
+}
+
+// Subscription receives the events published after it was created
+type Subscription struct {
+	C <-chan *db.Event // Closed when the client is too slow or the stream stops
+
+	stream *Stream
+	events chan *db.Event
+	filter Filter
+}
+
+// NewStream creates a stream logging events in repo
+func NewStream(cfg Config, repo db.EventStore) *Stream {
+	return &Stream{
+		cfg:           cfg,
+		repo:          repo,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Name identifies the event stream among notification channels
+func (s *Stream) Name() string {
+	return "events"
+}
+
+// NotifySubscribers logs and broadcasts a proposal.created event
+func (s *Stream) NotifySubscribers(alert *notify.ProposalAlert) error {
+	_, err := s.Publish(context.Background(), EventProposalCreated, alert.Symbol, newProposalData(alert))
+	return err
+}
+
+// PublishBlock logs and broadcasts a block.processed event
+func (s *Stream) PublishBlock(height uint64, hash string) {
+	if _, err := s.Publish(context.Background(), EventBlockProcessed, "", BlockData{Height: height, Hash: hash}); err != nil {
+		log.Printf("Failed to publish block #%d event: %v", height, err)
+	}
+}
+
+// Publish appends an event about a contract, or none if symbol is empty, to the
+// log and sends it to every matching subscription
+func (s *Stream) Publish(ctx context.Context, eventType, symbol string, data interface{}) (*db.Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+
+	e := &db.Event{Type: eventType, Symbol: symbol, Data: payload}
+	if err := s.repo.Append(ctx, e); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscriptions {
+		if !sub.filter.Matches(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			// The client reconnects and catches up from the log
+			log.Printf("Disconnecting slow event stream client")
+			s.remove(sub)
+		}
+	}
+
+	return e, nil
+}
+
+// Since returns up to limit logged events after an ID that pass a filter, oldest first
+func (s *Stream) Since(ctx context.Context, filter Filter, afterID int64, limit int) ([]*db.Event, error) {
+	return s.repo.List(ctx, db.EventQuery{AfterID: afterID, Types: filter.Types, Symbol: filter.Symbol, Limit: limit})
+}
+
+// Subscribe starts receiving published events that pass a filter
+func (s *Stream) Subscribe(filter Filter) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.cfg.MaxClients > 0 && len(s.subscriptions) >= s.cfg.MaxClients {
+		return nil, ErrTooManyClients
+	}
+
+	events := make(chan *db.Event, s.cfg.BufferSize)
+	sub := &Subscription{C: events, stream: s, events: events, filter: filter}
+	s.subscriptions[sub] = struct{}{}
+	metrics.EventStreamClients.Add(1)
+	return sub, nil
+}
+
+// Close stops the subscription
+func (sub *Subscription) Close() {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+	sub.stream.remove(sub)
+}
+
+// remove closes a subscription if it is still open. The caller holds s.mu.
+func (s *Stream) remove(sub *Subscription) {
+	if _, ok := s.subscriptions[sub]; !ok {
+		return
+	}
+	delete(s.subscriptions, sub)
+	close(sub.events)
+	metrics.EventStreamClients.Add(-1)
+}
+
+// Run deletes events older than the retention every interval until the context is
+// cancelled, then closes every subscription so streaming requests end
+func (s *Stream) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Prune(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			s.close()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes events older than the retention
+func (s *Stream) Prune(ctx context.Context, now time.Time) {
+	n, err := s.repo.DeleteBefore(ctx, now.Add(-s.cfg.Retention))
+	if err != nil {
+		log.Printf("Error pruning event log: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Pruned %d events from the event log", n)
+	}
+}
+
+// close closes every subscription and refuses new ones
+func (s *Stream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for sub := range s.subscriptions {
+		s.remove(sub)
+	}
+}
@@ -0,0 +1,186 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/memstore"
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+// receive returns the next event of a subscription, failing if none arrives
+func receive(t *testing.T, sub *Subscription) *db.Event {
+	t.Helper()
+	select {
+	case e, ok := <-sub.C:
+		if !ok {
+			t.Fatal("subscription closed, want an event")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+// expectClosed checks that a subscription was closed, after any buffered events
+func expectClosed(t *testing.T, sub *Subscription) {
+	t.Helper()
+	for {
+		select {
+		case _, ok := <-sub.C:
+			if !ok {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("subscription still open, want it closed")
+		}
+	}
+}
+
+func TestPublishFilters(t *testing.T) {
+	s := NewStream(Config{BufferSize: 8}, memstore.NewEventStore())
+
+	everything, _ := s.Subscribe(Filter{})
+	zra, _ := s.Subscribe(Filter{Symbol: "$ZRA+0000"})
+	blocks, _ := s.Subscribe(Filter{Types: []string{EventBlockProcessed}})
+
+	if err := s.NotifySubscribers(&notify.ProposalAlert{Symbol: "$ETH+0000", ProposalID: "a1"}); err != nil {
+		t.Fatalf("NotifySubscribers: %v", err)
+	}
+	if err := s.NotifySubscribers(&notify.ProposalAlert{Symbol: "$ZRA+0000", ProposalID: "b2"}); err != nil {
+		t.Fatalf("NotifySubscribers: %v", err)
+	}
+	s.PublishBlock(12, "beef")
+
+	for i, want := range []string{EventProposalCreated, EventProposalCreated, EventBlockProcessed} {
+		if e := receive(t, everything); e.ID != int64(i+1) || e.Type != want {
+			t.Errorf("unfiltered event %d = #%d %s, want #%d %s", i+1, e.ID, e.Type, i+1, want)
+		}
+	}
+
+	// Block events are about no contract, so they pass symbol filters
+	if e := receive(t, zra); e.Symbol != "$ZRA+0000" {
+		t.Errorf("$ZRA+0000 subscription got an event about %q first", e.Symbol)
+	}
+	if e := receive(t, zra); e.Type != EventBlockProcessed {
+		t.Errorf("$ZRA+0000 subscription got %s, want the block event", e.Type)
+	}
+	if e := receive(t, blocks); e.Type != EventBlockProcessed || string(e.Data) != `{"height":12,"hash":"beef"}` {
+		t.Errorf("block subscription got %s %s, want the block event", e.Type, e.Data)
+	}
+
+	for name, sub := range map[string]*Subscription{"unfiltered": everything, "$ZRA+0000": zra, "block": blocks} {
+		select {
+		case e := <-sub.C:
+			t.Errorf("%s subscription got unexpected event #%d %s", name, e.ID, e.Type)
+		default:
+		}
+	}
+}
+
+func TestSince(t *testing.T) {
+	s := NewStream(DefaultConfig(), memstore.NewEventStore())
+	ctx := context.Background()
+	for _, symbol := range []string{"$ZRA+0000", "$ETH+0000", "", "$ZRA+0000"} {
+		if _, err := s.Publish(ctx, EventProposalCreated, symbol, nil); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	missed, err := s.Since(ctx, Filter{Symbol: "$ZRA+0000"}, 1, 10)
+	if err != nil {
+		t.Fatalf("Since: %v", err)
+	}
+	var ids []int64
+	for _, e := range missed {
+		ids = append(ids, e.ID)
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 4 {
+		t.Errorf("events after #1 for $ZRA+0000 = %v, want [3 4]", ids)
+	}
+	if missed, _ := s.Since(ctx, Filter{}, 0, 2); len(missed) != 2 {
+		t.Errorf("Since with limit 2 returned %d events", len(missed))
+	}
+}
+
+func TestSlowClientDisconnected(t *testing.T) {
+	s := NewStream(Config{BufferSize: 2}, memstore.NewEventStore())
+	slow, _ := s.Subscribe(Filter{})
+	fast, _ := s.Subscribe(Filter{})
+
+	for i := 0; i < 3; i++ {
+		s.PublishBlock(uint64(i), "")
+		receive(t, fast)
+	}
+
+	// The slow client got what fit in its buffer, then was disconnected to catch up from the log
+	for want := int64(1); want <= 2; want++ {
+		if e := receive(t, slow); e.ID != want {
+			t.Errorf("slow client got #%d, want #%d", e.ID, want)
+		}
+	}
+	expectClosed(t, slow)
+
+	s.PublishBlock(3, "")
+	if e := receive(t, fast); e.ID != 4 {
+		t.Errorf("fast client got #%d, want #4", e.ID)
+	}
+	slow.Close() // Closing a disconnected subscription again is harmless
+}
+
+func TestMaxClients(t *testing.T) {
+	s := NewStream(Config{MaxClients: 2, BufferSize: 1}, memstore.NewEventStore())
+
+	first, err := s.Subscribe(Filter{})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err := s.Subscribe(Filter{}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err := s.Subscribe(Filter{}); !errors.Is(err, ErrTooManyClients) {
+		t.Fatalf("third Subscribe = %v, want ErrTooManyClients", err)
+	}
+
+	first.Close()
+	if _, err := s.Subscribe(Filter{}); err != nil {
+		t.Errorf("Subscribe after a client left: %v", err)
+	}
+}
+
+func TestRunClosesStream(t *testing.T) {
+	store := memstore.NewEventStore()
+	s := NewStream(Config{BufferSize: 1, Retention: time.Hour}, store)
+	sub, _ := s.Subscribe(Filter{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Run(ctx, time.Hour)
+
+	expectClosed(t, sub)
+	if _, err := s.Subscribe(Filter{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe after Run = %v, want ErrClosed", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	store := memstore.NewEventStore()
+	s := NewStream(Config{Retention: time.Hour}, store)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, 30 * time.Minute} {
+		store.SetClock(func() time.Time { return now.Add(-age) })
+		s.PublishBlock(0, "")
+	}
+
+	s.Prune(ctx, now)
+	kept, _ := s.Since(ctx, Filter{}, 0, 10)
+	if len(kept) != 1 || kept[0].ID != 3 {
+		t.Errorf("events kept = %d, want only #3 within the retention", len(kept))
+	}
+}
@@ -0,0 +1,331 @@
+// Package filter parses and evaluates the keyword, pattern, type and proposer filters
+// that narrow which proposals a subscription is notified about
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxRegexLength bounds user-supplied patterns
+const maxRegexLength = 200
+
+// Filter narrows which proposals a subscription is notified about.
+// Empty fields place no restriction.
+type Filter struct {
+	Include      []string `json:"include,omitempty"`       // Keywords, at least one of these or IncludeRegex must match
+	Exclude      []string `json:"exclude,omitempty"`       // Keywords that reject a proposal
+	IncludeRegex []string `json:"include_regex,omitempty"` // Patterns, at least one of these or Include must match
+	ExcludeRegex []string `json:"exclude_regex,omitempty"` // Patterns that reject a proposal
+	Types        []string `json:"types,omitempty"`         // Proposal types, at least one must apply
+	Proposers    []string `json:"proposers,omitempty"`     // Proposer public keys (hex), one must match
+
+	// IncludeRegex and ExcludeRegex compiled by Parse or UnmarshalJSON
+	includeRe []*regexp.Regexp
+	excludeRe []*regexp.Regexp
+}
+
+// Candidate holds the proposal fields a filter is evaluated against
+type Candidate struct {
+	Title    string
+	Synopsis string
+	Types    []string
+	Proposer string
+}
+
+// Proposal types that can be filtered on
+const (
+	TypeYesNo      = "yesno"      // Proposal voted on with yes or no
+	TypeOptions    = "options"    // Proposal voted on with multiple options
+	TypeExecutable = "executable" // Proposal that executes transactions when passed
+)
+
+var validTypes = map[string]bool{
+	TypeYesNo:      true,
+	TypeOptions:    true,
+	TypeExecutable: true,
+}
+
+// IsEmpty reports whether the filter places no restriction
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.Include) == 0 && len(f.Exclude) == 0 &&
+		len(f.IncludeRegex) == 0 && len(f.ExcludeRegex) == 0 &&
+		len(f.Types) == 0 && len(f.Proposers) == 0)
+}
+
+// Match reports whether a proposal passes the filter. A nil filter matches everything.
+func (f *Filter) Match(c Candidate) bool {
+	if f.IsEmpty() {
+		return true
+	}
+
+	text := strings.ToLower(c.Title + "\n" + c.Synopsis)
+	includeRe, excludeRe := f.regexps()
+
+	for _, keyword := range f.Exclude {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	for _, re := range excludeRe {
+		if matchRegex(re, c.Title, c.Synopsis) {
+			return false
+		}
+	}
+
+	if len(f.Include) > 0 || len(f.IncludeRegex) > 0 {
+		included := false
+		for _, keyword := range f.Include {
+			if strings.Contains(text, strings.ToLower(keyword)) {
+				included = true
+				break
+			}
+		}
+		for _, re := range includeRe {
+			if included {
+				break
+			}
+			included = matchRegex(re, c.Title, c.Synopsis)
+		}
+		if !included {
+			return false
+		}
+	}
+
+	if len(f.Types) > 0 && !overlaps(f.Types, c.Types) {
+		return false
+	}
+
+	if len(f.Proposers) > 0 && !containsFold(f.Proposers, c.Proposer) {
+		return false
+	}
+
+	return true
+}
+
+// UnmarshalJSON decodes a stored filter and compiles its patterns
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	type fields Filter // Without this method, so decoding doesn't recurse
+	var decoded fields
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*f = Filter(decoded)
+	f.includeRe = compileAll(f.IncludeRegex)
+	f.excludeRe = compileAll(f.ExcludeRegex)
+	return nil
+}
+
+// regexps returns the compiled include and exclude patterns. Filters built without
+// Parse or UnmarshalJSON have them compiled here, without changing the filter, so
+// filters can be matched concurrently.
+func (f *Filter) regexps() (include, exclude []*regexp.Regexp) {
+	include, exclude = f.includeRe, f.excludeRe
+	if len(include) != len(f.IncludeRegex) {
+		include = compileAll(f.IncludeRegex)
+	}
+	if len(exclude) != len(f.ExcludeRegex) {
+		exclude = compileAll(f.ExcludeRegex)
+	}
+	return include, exclude
+}
+
+// String formats the filter in the syntax accepted by Parse
+func (f *Filter) String() string {
+	if f.IsEmpty() {
+		return ""
+	}
+
+	var terms []string
+	for _, keyword := range f.Include {
+		terms = append(terms, "+"+quote(keyword))
+	}
+	for _, keyword := range f.Exclude {
+		terms = append(terms, "-"+quote(keyword))
+	}
+	for _, pattern := range f.IncludeRegex {
+		terms = append(terms, "+"+quote("/"+pattern+"/"))
+	}
+	for _, pattern := range f.ExcludeRegex {
+		terms = append(terms, "-"+quote("/"+pattern+"/"))
+	}
+	for _, t := range f.Types {
+		terms = append(terms, "type:"+t)
+	}
+	for _, p := range f.Proposers {
+		terms = append(terms, "proposer:"+p)
+	}
+	return strings.Join(terms, " ")
+}
+
+// Parse builds a filter from space separated terms:
+//
+//	+word or word     include proposals mentioning word ("quoted phrases" allowed)
+//	-word             exclude proposals mentioning word (+-word includes "-word")
+//	+/regex/, /regex/ include proposals whose title or synopsis match regex
+//	-/regex/          exclude proposals whose title or synopsis match regex
+//	type:T            only proposals of type T (yesno, options, executable)
+//	proposer:KEY      only proposals submitted by the public key KEY (hex)
+func Parse(input string) (*Filter, error) {
+	terms, err := split(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(terms) == 0 {
+		return nil, errors.New("no filter terms given")
+	}
+
+	f := &Filter{}
+	for _, term := range terms {
+		switch {
+		case strings.HasPrefix(strings.ToLower(term), "type:"):
+			t := strings.ToLower(term[len("type:"):])
+			if !validTypes[t] {
+				return nil, fmt.Errorf("unknown proposal type %q (use yesno, options, or executable)", t)
+			}
+			f.Types = append(f.Types, t)
+		case strings.HasPrefix(strings.ToLower(term), "proposer:"):
+			p := strings.ToLower(term[len("proposer:"):])
+			if p == "" {
+				return nil, errors.New("proposer must not be empty")
+			}
+			f.Proposers = append(f.Proposers, p)
+		default:
+			// Only the first sign is an operator, so +-word includes "-word"
+			exclude := strings.HasPrefix(term, "-")
+			if exclude || strings.HasPrefix(term, "+") {
+				term = term[1:]
+			}
+			if term == "" {
+				return nil, errors.New("empty keyword")
+			}
+
+			if len(term) >= 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/") {
+				pattern := term[1 : len(term)-1]
+				re, err := compileRegex(pattern)
+				if err != nil {
+					return nil, err
+				}
+				if exclude {
+					f.ExcludeRegex = append(f.ExcludeRegex, pattern)
+					f.excludeRe = append(f.excludeRe, re)
+				} else {
+					f.IncludeRegex = append(f.IncludeRegex, pattern)
+					f.includeRe = append(f.includeRe, re)
+				}
+				continue
+			}
+
+			if exclude {
+				f.Exclude = append(f.Exclude, term)
+			} else {
+				f.Include = append(f.Include, term)
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// split splits input on whitespace, keeping double-quoted phrases together
+func split(input string) ([]string, error) {
+	var terms []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n'):
+			if current.Len() > 0 {
+				terms = append(terms, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuotes {
+		return nil, errors.New("unterminated quote")
+	}
+	if current.Len() > 0 {
+		terms = append(terms, current.String())
+	}
+
+	return terms, nil
+}
+
+// compileRegex validates and compiles a user-supplied pattern, matched case-insensitively
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("empty regular expression")
+	}
+	if len(pattern) > maxRegexLength {
+		return nil, fmt.Errorf("regular expression longer than %d characters", maxRegexLength)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// compileAll compiles patterns that were validated when the filter was parsed.
+// An invalid one compiles to a pattern that never matches.
+func compileAll(patterns []string) []*regexp.Regexp {
+	if len(patterns) == 0 {
+		return nil
+	}
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := compileRegex(pattern)
+		if err != nil {
+			re = neverMatches
+		}
+		compiled[i] = re
+	}
+	return compiled
+}
+
+// neverMatches stands in for patterns that don't compile
+var neverMatches = regexp.MustCompile(`[^\s\S]`)
+
+// matchRegex reports whether a pattern matches any of the given texts
+func matchRegex(re *regexp.Regexp, texts ...string) bool {
+	for _, text := range texts {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(a, b []string) bool {
+	for _, x := range a {
+		if containsFold(b, x) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func quote(s string) string {
+	if strings.ContainsAny(s, " \t\n") {
+		return `"` + s + `"`
+	}
+	return s
+}
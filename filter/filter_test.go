@@ -0,0 +1,204 @@
+package filter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// fields returns a copy of a filter without its compiled patterns, for comparison
+func fields(f *Filter) Filter {
+	c := *f
+	c.includeRe, c.excludeRe = nil, nil
+	return c
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		input string
+		want  *Filter
+	}{
+		{"fee", &Filter{Include: []string{"fee"}}},
+		{"+fee -burn", &Filter{Include: []string{"fee"}, Exclude: []string{"burn"}}},
+		{`"fee cut" -"treasury spend"`, &Filter{Include: []string{"fee cut"}, Exclude: []string{"treasury spend"}}},
+		{`+"fee cut"`, &Filter{Include: []string{"fee cut"}}},
+		{"+-x", &Filter{Include: []string{"-x"}}},
+		{"--x", &Filter{Exclude: []string{"-x"}}},
+		{"-+x", &Filter{Exclude: []string{"+x"}}},
+		{"/fee.*cut/ -/spam|scam/", &Filter{IncludeRegex: []string{"fee.*cut"}, ExcludeRegex: []string{"spam|scam"}}},
+		{`"/fee cut/"`, &Filter{IncludeRegex: []string{"fee cut"}}},
+		{`-"/fee cut/"`, &Filter{ExcludeRegex: []string{"fee cut"}}},
+		{"type:YesNo type:executable", &Filter{Types: []string{"yesno", "executable"}}},
+		{"proposer:ABCDEF", &Filter{Proposers: []string{"abcdef"}}},
+		{"+type:yesno", &Filter{Include: []string{"type:yesno"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			got, err := Parse(c.input)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", c.input, err)
+			}
+			if !reflect.DeepEqual(fields(got), fields(c.want)) {
+				t.Errorf("Parse(%q) = %+v, want %+v", c.input, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"   ",
+		"+",
+		"-",
+		`"unterminated`,
+		"type:binary",
+		"proposer:",
+		"/(/",
+		"//",
+		"/" + string(make([]byte, maxRegexLength+1)) + "/",
+	}
+
+	for _, input := range cases {
+		if f, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", input, f)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	proposal := Candidate{
+		Title:    "Cut network fees",
+		Synopsis: "Lower the base fee by 10%",
+		Types:    []string{TypeYesNo, TypeExecutable},
+		Proposer: "ABCDEF",
+	}
+
+	cases := []struct {
+		filter string
+		want   bool
+	}{
+		{"fee", true},
+		{"FEES", true},
+		{"treasury", false},
+		{"treasury fee", true}, // Any include term is enough
+		{"fee -base", false},
+		{"-treasury", true},
+		{`"network fees"`, true},
+		{`"fees network"`, false},
+		{"/^cut/", true},
+		{"/^lower/", true}, // Patterns match the title or the synopsis on their own
+		{"/fees.*lower/", false},
+		{"-/10%$/", false},
+		{`treasury "/base fee/"`, true},
+		{"type:yesno", true},
+		{"type:options", false},
+		{"type:options type:executable", true},
+		{"proposer:abcdef", true},
+		{"proposer:123456", false},
+		{"fee type:options", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.filter, func(t *testing.T) {
+			f, err := Parse(c.filter)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", c.filter, err)
+			}
+			if got := f.Match(proposal); got != c.want {
+				t.Errorf("%q matched = %v, want %v", c.filter, got, c.want)
+			}
+		})
+	}
+}
+
+func TestEmptyFilterMatchesEverything(t *testing.T) {
+	var f *Filter
+	if !f.IsEmpty() || !f.Match(Candidate{Title: "anything"}) {
+		t.Error("a nil filter should be empty and match everything")
+	}
+	if !(&Filter{}).Match(Candidate{}) {
+		t.Error("an empty filter should match everything")
+	}
+	if s := f.String(); s != "" {
+		t.Errorf("nil filter String() = %q, want empty", s)
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	cases := []struct {
+		filter *Filter
+		want   string
+	}{
+		{&Filter{Include: []string{"fee"}}, "+fee"},
+		{&Filter{Include: []string{"fee cut"}, Exclude: []string{"burn"}}, `+"fee cut" -burn`},
+		{&Filter{Include: []string{"-x"}, Exclude: []string{"+y", "-z"}}, "+-x -+y --z"},
+		{&Filter{IncludeRegex: []string{"fee cut"}, ExcludeRegex: []string{"spam|scam"}}, `+"/fee cut/" -/spam|scam/`},
+		{&Filter{Types: []string{"yesno"}, Proposers: []string{"abcdef"}}, "type:yesno proposer:abcdef"},
+		{&Filter{Include: []string{"type:yesno"}}, "+type:yesno"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			s := c.filter.String()
+			if s != c.want {
+				t.Errorf("String() = %q, want %q", s, c.want)
+			}
+
+			parsed, err := Parse(s)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", s, err)
+			}
+			if !reflect.DeepEqual(fields(parsed), fields(c.filter)) {
+				t.Errorf("Parse(%q) = %+v, want %+v", s, parsed, c.filter)
+			}
+		})
+	}
+}
+
+func TestPatternsCompiledOnce(t *testing.T) {
+	parsed, err := Parse("/^cut/ -/spam|scam/")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(parsed.includeRe) != 1 || len(parsed.excludeRe) != 1 {
+		t.Fatalf("Parse compiled %d include and %d exclude patterns, want 1 each", len(parsed.includeRe), len(parsed.excludeRe))
+	}
+
+	// Stored filters are compiled when they are decoded
+	data, err := json.Marshal(parsed)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	decoded := &Filter{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(fields(decoded), fields(parsed)) {
+		t.Errorf("decoded %+v, want %+v", decoded, parsed)
+	}
+	if len(decoded.includeRe) != 1 || len(decoded.excludeRe) != 1 {
+		t.Errorf("Unmarshal compiled %d include and %d exclude patterns, want 1 each", len(decoded.includeRe), len(decoded.excludeRe))
+	}
+
+	proposal := Candidate{Title: "Cut fees"}
+	literal := &Filter{IncludeRegex: []string{"^cut"}, ExcludeRegex: []string{"spam|scam"}}
+	invalid := &Filter{IncludeRegex: []string{"("}}
+	for name, c := range map[string]struct {
+		filter *Filter
+		want   bool
+	}{
+		"Parsed":  {parsed, true},
+		"Decoded": {decoded, true},
+		"Literal": {literal, true}, // Compiled when matched
+		"Invalid": {invalid, false},
+	} {
+		if got := c.filter.Match(proposal); got != c.want {
+			t.Errorf("%s filter matched = %v, want %v", name, got, c.want)
+		}
+	}
+	if literal.includeRe != nil {
+		t.Error("Match changed a literal filter")
+	}
+}
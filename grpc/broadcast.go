@@ -2,16 +2,20 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net"
-	"os"
 	"time"
 
 	"golang.org/x/time/rate"
 
+	"github.com/ZeraVision/ZeraBot/contract"
+	"github.com/ZeraVision/ZeraBot/db"
 	"github.com/ZeraVision/ZeraBot/proposal"
 	zera_protobuf "github.com/ZeraVision/go-zera-network/grpc/protobuf"
+	"github.com/ZeraVision/zera-go-sdk/transcode"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -21,37 +25,108 @@ const (
 	DB_TOTAL_TIMEOUT                  = 1 * time.Minute
 )
 
-// Create a global rate limiter
-var limiter = rate.NewLimiter(rate.Every(3*time.Second), 1) // 1 request per 3 seconds with a burst of 1
+// IngestConfig controls which block broadcasts are accepted
+type IngestConfig struct {
+	TrustedDomain string // Domain whose addresses may broadcast blocks
+	SecretAuth    string // Block hash accepted from any sender, disabled if empty
+	TrustAll      bool   // Accept broadcasts from any sender (development)
+}
+
+// Ingest receives blocks broadcast by the network and processes them
+type Ingest struct {
+	cfg       IngestConfig
+	limiter   *rate.Limiter
+	contracts *contract.Processor
+	proposals *proposal.Processor
+	blocks    *db.BlockRepository
+
+	// OnProcessed is called with the height and hex encoded hash of every processed block, e.g. to publish it
+	OnProcessed func(height uint64, hash string)
+}
+
+// NewIngest creates an ingest service passing blocks to the contract and proposal processors
+// and keeping the blocks they act on in blocks, so they can be replayed
+func NewIngest(cfg IngestConfig, contracts *contract.Processor, proposals *proposal.Processor, blocks *db.BlockRepository) *Ingest {
+	return &Ingest{
+		cfg:       cfg,
+		limiter:   rate.NewLimiter(rate.Every(3*time.Second), 1), // 1 request per 3 seconds with a burst of 1
+		contracts: contracts,
+		proposals: proposals,
+		blocks:    blocks,
+	}
+}
 
-func Broadcast(ctx context.Context, block *zera_protobuf.Block) (*emptypb.Empty, error) {
+func (i *Ingest) Broadcast(ctx context.Context, block *zera_protobuf.Block) (*emptypb.Empty, error) {
 
 	log.Printf("Block #%d received", block.BlockHeader.BlockHeight)
 
 	// Rate limit the broadcasts
-	if !limiter.Allow() {
+	if !i.limiter.Allow() {
 		log.Println("Broadcast rate limit exceeded (1 per 3 seconds), rejecting broadcast")
 		return &emptypb.Empty{}, nil
 	}
 
-	if !isSenderFromDomain(ctx) && string(block.BlockHeader.Hash) != os.Getenv("SECRET_AUTH") {
+	if !i.isSenderFromDomain(ctx) && (i.cfg.SecretAuth == "" || string(block.BlockHeader.Hash) != i.cfg.SecretAuth) {
 		return &emptypb.Empty{}, nil
 	}
 
 	log.Printf("Block #%d processing", block.BlockHeader.BlockHeight)
 
-	go func(block *zera_protobuf.Block) {
-		proposal.ProcessProposals(block)
-	}(block)
+	go i.receive(block)
 
 	return &emptypb.Empty{}, nil // awk
 
 }
 
+// receive keeps a block with contracts or proposals and processes it
+func (i *Ingest) receive(block *zera_protobuf.Block) {
+	txns := block.GetTransactions()
+	if len(txns.GetContractTxns()) > 0 || len(txns.GetGovernanceProposals()) > 0 {
+		if data, err := proto.Marshal(block); err != nil {
+			log.Printf("Error encoding block #%d: %v", block.BlockHeader.BlockHeight, err)
+		} else if err := i.blocks.Save(context.Background(), block.BlockHeader.BlockHeight, transcode.HexEncode(block.BlockHeader.Hash), data); err != nil {
+			log.Printf("Error keeping block #%d: %v", block.BlockHeader.BlockHeight, err)
+		}
+	}
+
+	i.ProcessBlock(block)
+}
+
+// ReplayHeight processes a kept block again, e.g. after alerts for it failed.
+// It returns db.ErrBlockNotFound if the block at the height wasn't kept.
+func (i *Ingest) ReplayHeight(ctx context.Context, height uint64) error {
+	data, err := i.blocks.Get(ctx, height)
+	if err != nil {
+		return err
+	}
+
+	block := &zera_protobuf.Block{}
+	if err := proto.Unmarshal(data, block); err != nil {
+		return fmt.Errorf("failed to decode block #%d: %w", height, err)
+	}
+
+	log.Printf("Block #%d replaying", height)
+	i.ProcessBlock(block)
+	return nil
+}
+
+// ProcessBlock records the contracts of a block and notifies subscribers of its proposals
+func (i *Ingest) ProcessBlock(block *zera_protobuf.Block) {
+	if err := i.contracts.ProcessContracts(block); err != nil {
+		log.Printf("Error processing contracts for block #%d: %v", block.BlockHeader.BlockHeight, err)
+	}
+	if err := i.proposals.ProcessProposals(block); err != nil {
+		log.Printf("Error processing proposals for block #%d: %v", block.BlockHeader.BlockHeight, err)
+	}
+	if i.OnProcessed != nil {
+		i.OnProcessed(block.BlockHeader.BlockHeight, transcode.HexEncode(block.BlockHeader.Hash))
+	}
+}
+
 // isSenderFromDomain checks if the sender's IP matches a trusted source
-func isSenderFromDomain(ctx context.Context) bool {
+func (i *Ingest) isSenderFromDomain(ctx context.Context) bool {
 
-	if os.Getenv("ENVIRONMENT") == "development" {
+	if i.cfg.TrustAll {
 		return true
 	}
 
@@ -69,7 +144,7 @@ func isSenderFromDomain(ctx context.Context) bool {
 	}
 
 	// Compare the sender's IP against a trusted domain
-	expectedDomain := os.Getenv("GRPC_ADDRESS")
+	expectedDomain := i.cfg.TrustedDomain
 	ips, err := net.LookupIP(expectedDomain)
 	if err != nil {
 		log.Printf("Failed to resolve domain %s: %v", expectedDomain, err)
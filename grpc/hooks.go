@@ -4,9 +4,10 @@ import (
 	grpc_network_listener "github.com/ZeraVision/go-zera-network/grpc/listener"
 )
 
-func InitialHookups() {
+// InitialHookups starts the validator service and routes block broadcasts to the ingest service
+func (i *Ingest) InitialHookups() {
 	validatorServer := grpc_network_listener.NewValidatorService()
-	validatorServer.HandleBroadcast = Broadcast
+	validatorServer.HandleBroadcast = i.Broadcast
 
 	validatorServer.StartService()
 
@@ -0,0 +1,140 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// DefaultLanguage is used when a chat has no preference and as the fallback
+// for keys missing from a locale file
+const DefaultLanguage = "en"
+
+// message is a single catalog entry. Plain strings are stored under "other";
+// plural-aware entries carry one form per plural category.
+type message map[string]string
+
+// catalog maps language -> key -> message
+var catalog = map[string]map[string]message{}
+
+func init() {
+	if err := load(localesFS); err != nil {
+		panic(fmt.Sprintf("i18n: %v", err))
+	}
+}
+
+// load parses every locale file in the given filesystem into the catalog
+func load(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return fmt.Errorf("failed to list locales: %w", err)
+	}
+
+	for _, name := range files {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("failed to read locale %s: %w", name, err)
+		}
+
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("failed to parse locale %s: %w", name, err)
+		}
+
+		lang := strings.TrimSuffix(strings.TrimPrefix(name, "locales/"), ".json")
+		messages := make(map[string]message, len(raw))
+		for key, value := range raw {
+			var plain string
+			if err := json.Unmarshal(value, &plain); err == nil {
+				messages[key] = message{"other": plain}
+				continue
+			}
+
+			var forms message
+			if err := json.Unmarshal(value, &forms); err != nil {
+				return fmt.Errorf("invalid value for %q in locale %s: %w", key, name, err)
+			}
+			if _, ok := forms["other"]; !ok {
+				return fmt.Errorf("plural entry %q in locale %s is missing the \"other\" form", key, name)
+			}
+			messages[key] = forms
+		}
+		catalog[lang] = messages
+	}
+
+	if _, ok := catalog[DefaultLanguage]; !ok {
+		return fmt.Errorf("default locale %q not found", DefaultLanguage)
+	}
+
+	return nil
+}
+
+// Supported returns the languages that have a locale file, sorted
+func Supported() []string {
+	langs := make([]string, 0, len(catalog))
+	for lang := range catalog {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Normalize maps an IETF language tag (e.g., "es-MX", "zh-hans") to a supported
+// language, returning an empty string if there is no matching locale
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return ""
+	}
+
+	if _, ok := catalog[tag]; ok {
+		return tag
+	}
+
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if _, ok := catalog[base]; ok {
+		return base
+	}
+
+	return ""
+}
+
+// T returns the message for key in the given language, formatted with args
+func T(lang, key string, args ...any) string {
+	return format(lookup(lang, key, "other"), args)
+}
+
+// N returns the plural form of key appropriate for n in the given language,
+// formatted with args. The count is not implicitly passed to the format string.
+func N(lang, key string, n int, args ...any) string {
+	return format(lookup(lang, key, pluralCategory(lang, n)), args)
+}
+
+// lookup finds the message form for key, falling back to the "other" form and
+// then to the default language. The key itself is returned if nothing matches.
+func lookup(lang, key, category string) string {
+	for _, l := range []string{lang, DefaultLanguage} {
+		msg, ok := catalog[l][key]
+		if !ok {
+			continue
+		}
+		if form, ok := msg[category]; ok {
+			return form
+		}
+		return msg["other"]
+	}
+	return key
+}
+
+func format(s string, args []any) string {
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
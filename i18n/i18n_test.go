@@ -0,0 +1,175 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// useLocales replaces the catalog with locale files given as JSON for the rest of the test
+func useLocales(t *testing.T, locales map[string]string) {
+	t.Helper()
+	saved := catalog
+	t.Cleanup(func() { catalog = saved })
+
+	fsys := fstest.MapFS{}
+	for lang, data := range locales {
+		fsys["locales/"+lang+".json"] = &fstest.MapFile{Data: []byte(data)}
+	}
+	catalog = map[string]map[string]message{}
+	if err := load(fsys); err != nil {
+		t.Fatalf("failed to load locales: %v", err)
+	}
+}
+
+func TestPluralCategory(t *testing.T) {
+	cases := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 0, "other"},
+		{"en", 1, "one"},
+		{"en", 2, "other"},
+		{"en", 21, "other"},
+		{"es", 0, "other"},
+		{"es", 1, "one"},
+		{"es", 5, "other"},
+		{"zh", 0, "other"},
+		{"zh", 1, "other"},
+		{"zh", 2, "other"},
+		{"fr", 1, "one"}, // No rule, so English's is used
+		{"fr", 3, "other"},
+	}
+	for _, c := range cases {
+		if got := pluralCategory(c.lang, c.n); got != c.want {
+			t.Errorf("pluralCategory(%q, %d) = %q, want %q", c.lang, c.n, got, c.want)
+		}
+	}
+}
+
+func TestN(t *testing.T) {
+	cases := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 1, "…and 1 more proposal"},
+		{"en", 3, "…and 3 more proposals"},
+		{"es", 1, "…y 1 propuesta más"},
+		{"es", 3, "…y 3 propuestas más"},
+		{"zh", 1, "…以及另外 1 个提案"}, // zh has a plain string, used for every count
+		{"zh", 3, "…以及另外 3 个提案"},
+		{"fr", 1, "…and 1 more proposal"}, // No locale, so English
+	}
+	for _, c := range cases {
+		if got := N(c.lang, "digest.more", c.n, c.n); got != c.want {
+			t.Errorf("N(%q, digest.more, %d) = %q, want %q", c.lang, c.n, got, c.want)
+		}
+	}
+}
+
+func TestLookupFallback(t *testing.T) {
+	useLocales(t, map[string]string{
+		"en": `{
+			"items": {"one": "%d item", "other": "%d items"},
+			"hello": "Hello",
+			"only.en": "Only in English"
+		}`,
+		"xx": `{
+			"items": "%d things",
+			"hello": "Xello"
+		}`,
+	})
+
+	cases := []struct {
+		name string
+		got  string
+		want string
+	}{
+		// xx uses the English rule, so 1 is "one", but a plain string only has "other"
+		{"PlainStringForOne", N("xx", "items", 1, 1), "1 things"},
+		{"PlainStringForOther", N("xx", "items", 2, 2), "2 things"},
+		{"PluralForm", N("en", "items", 1, 1), "1 item"},
+		{"TOfPluralEntry", T("en", "items", 2), "2 items"},
+		{"Translated", T("xx", "hello"), "Xello"},
+		{"MissingKeyUsesDefault", T("xx", "only.en"), "Only in English"},
+		{"MissingPluralUsesDefault", N("yy", "items", 1, 1), "1 item"},
+		{"UnknownLanguageUsesDefault", T("yy", "hello"), "Hello"},
+		{"UnknownKey", T("xx", "nope"), "nope"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := map[string]map[string]string{
+		"PluralWithoutOther": {"en": `{"items": {"one": "%d item"}}`},
+		"InvalidValue":       {"en": `{"items": 3}`},
+		"InvalidJSON":        {"en": `{"items": `},
+		"NoDefaultLocale":    {"es": `{"hello": "Hola"}`},
+	}
+	for name, locales := range cases {
+		t.Run(name, func(t *testing.T) {
+			saved := catalog
+			defer func() { catalog = saved }()
+			catalog = map[string]map[string]message{}
+
+			fsys := fstest.MapFS{}
+			for lang, data := range locales {
+				fsys["locales/"+lang+".json"] = &fstest.MapFile{Data: []byte(data)}
+			}
+			if err := load(fsys); err == nil {
+				t.Error("load succeeded, want an error")
+			}
+		})
+	}
+}
+
+// verbRe matches fmt verbs, including explicit argument indexes such as %[2]s
+var verbRe = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+
+// verbs returns the fmt verbs of a message. Indexed verbs may be reordered by a
+// translation, so they are sorted.
+func verbs(s string) string {
+	found := verbRe.FindAllString(s, -1)
+	if strings.Contains(s, "%[") {
+		sort.Strings(found)
+	}
+	return strings.Join(found, " ")
+}
+
+func TestLocalesMatchDefault(t *testing.T) {
+	if got := Supported(); strings.Join(got, ",") != "en,es,zh" {
+		t.Errorf("Supported() = %v, want en, es and zh", got)
+	}
+
+	base := catalog[DefaultLanguage]
+	for _, lang := range Supported() {
+		messages := catalog[lang]
+		for key, msg := range base {
+			translated, ok := messages[key]
+			if !ok {
+				t.Errorf("%s is missing %q", lang, key)
+				continue
+			}
+			// Every form must take the same arguments, since the caller doesn't know which is used
+			want := verbs(msg["other"])
+			for category, form := range translated {
+				if got := verbs(form); got != want {
+					t.Errorf("%s %q (%s) has verbs %q, want %q like %s", lang, key, category, got, want, DefaultLanguage)
+				}
+			}
+		}
+		for key := range messages {
+			if _, ok := base[key]; !ok {
+				t.Errorf("%s has %q, which %s doesn't", lang, key, DefaultLanguage)
+			}
+		}
+	}
+}
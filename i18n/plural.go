@@ -0,0 +1,25 @@
+package i18n
+
+// pluralRules maps a language to a function selecting the CLDR plural category
+// for a count. Languages without an entry use the English rule.
+var pluralRules = map[string]func(n int) string{
+	"en": oneOther,
+	"es": oneOther,
+	"zh": func(int) string { return "other" },
+}
+
+// oneOther is the rule for languages that only distinguish singular from plural
+func oneOther(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// pluralCategory returns the plural category of n in the given language
+func pluralCategory(lang string, n int) string {
+	if rule, ok := pluralRules[lang]; ok {
+		return rule(n)
+	}
+	return oneOther(n)
+}
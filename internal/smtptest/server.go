@@ -0,0 +1,313 @@
+// Package smtptest provides a local SMTP sink for tests. It accepts every message,
+// records it with its decoded text and HTML parts, and can be told to reject the next one:
+//
+//	sink, err := smtptest.NewServer("127.0.0.1:0")
+//	...
+//	defer sink.Close()
+//	sender := email.NewSMTPSender(email.SMTPConfig{Host: sink.Host(), Port: sink.Port()})
+//	...
+//	msgs := sink.MessagesTo("user@example.com")
+package smtptest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is a message received by the sink
+type Message struct {
+	From    string   // Envelope sender
+	To      []string // Envelope recipients
+	Header  mail.Header
+	Subject string // Decoded subject
+	Text    string // Decoded text/plain part
+	HTML    string // Decoded text/html part
+	Raw     []byte
+}
+
+// Server is an SMTP server that records messages instead of delivering them
+type Server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	messages []Message
+	rejects  []string
+	onMsg    func(Message)
+}
+
+// NewServer starts a sink listening on addr, e.g. "127.0.0.1:0" for a random port.
+// Call Close when done.
+func NewServer(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s := &Server{listener: listener, conns: make(map[net.Conn]bool)}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Close stops the server, closing open sessions
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Host returns the host the server listens on
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr())
+	return host
+}
+
+// Port returns the port the server listens on
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr())
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// OnMessage sets a function called with every message received
+func (s *Server) OnMessage(fn func(Message)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onMsg = fn
+}
+
+// RejectNext makes the sink reject the next message with the given SMTP reply,
+// e.g. "550 mailbox unavailable"
+func (s *Server) RejectNext(reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejects = append(s.rejects, reply)
+}
+
+// Messages returns the messages received so far
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// MessagesTo returns the messages received for a recipient
+func (s *Server) MessagesTo(address string) []Message {
+	var msgs []Message
+	for _, m := range s.Messages() {
+		for _, to := range m.To {
+			if strings.EqualFold(to, address) {
+				msgs = append(msgs, m)
+				break
+			}
+		}
+	}
+	return msgs
+}
+
+// Reset forgets the messages received and pending rejections
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+	s.rejects = nil
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle runs one SMTP session. Only the commands net/smtp uses without
+// authentication or TLS are supported.
+func (s *Server) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	reply("220 smtptest ready")
+
+	var from string
+	var to []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-smtptest")
+			reply("250 8BITMIME")
+		case "HELO":
+			reply("250 smtptest")
+		case "MAIL":
+			from = trimPath(arg, "FROM:")
+			to = nil
+			reply("250 OK")
+		case "RCPT":
+			to = append(to, trimPath(arg, "TO:"))
+			reply("250 OK")
+		case "DATA":
+			if from == "" || len(to) == 0 {
+				reply("503 need MAIL and RCPT first")
+				continue
+			}
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			reply(s.receive(from, to, data))
+			from, to = "", nil
+		case "RSET":
+			from, to = "", nil
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// receive records a message, or rejects it if a rejection is queued, and returns the reply
+func (s *Server) receive(from string, to []string, data []byte) string {
+	s.mu.Lock()
+	if len(s.rejects) > 0 {
+		reply := s.rejects[0]
+		s.rejects = s.rejects[1:]
+		s.mu.Unlock()
+		return reply
+	}
+	s.mu.Unlock()
+
+	msg := Message{From: from, To: to, Raw: data}
+	if parsed, err := mail.ReadMessage(bytes.NewReader(data)); err == nil {
+		msg.Header = parsed.Header
+		msg.Subject, _ = new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+		msg.Text, msg.HTML = decodeBody(parsed.Header.Get("Content-Type"), parsed.Header.Get("Content-Transfer-Encoding"), parsed.Body)
+	} else {
+		log.Printf("smtptest: failed to parse message: %v", err)
+	}
+
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	onMsg := s.onMsg
+	s.mu.Unlock()
+
+	if onMsg != nil {
+		onMsg(msg)
+	}
+	return "250 OK"
+}
+
+// readData reads a DATA section up to the terminating dot, undoing dot stuffing
+func readData(r *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return buf.Bytes(), nil
+		}
+		buf.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
+
+// trimPath extracts the address from a MAIL FROM or RCPT TO argument
+func trimPath(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	arg, _, _ = strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(arg, "<>")
+}
+
+// decodeBody returns the text and HTML of a single part or multipart body
+func decodeBody(contentType, encoding string, body io.Reader) (text, html string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err != nil {
+				return text, html
+			}
+			t, h := decodeBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if t != "" {
+				text = t
+			}
+			if h != "" {
+				html = h
+			}
+		}
+	}
+
+	if strings.EqualFold(encoding, "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	content, _ := io.ReadAll(body)
+
+	switch mediaType {
+	case "text/html":
+		return "", string(content)
+	default:
+		return string(content), ""
+	}
+}
@@ -0,0 +1,302 @@
+// Package telegramtest provides a fake Telegram Bot API server for end-to-end tests.
+// It records the messages the bot sends and can be told to fail or rate limit calls:
+//
+//	api := telegramtest.NewServer()
+//	defer api.Close()
+//	botAPI, err := api.NewBotAPI()
+//	...
+//	api.RateLimitNext("sendMessage", 3)
+//	bot.WebhookHandler(httptest.NewRecorder(), telegramtest.NewUpdateRequest(telegramtest.CommandUpdate(1, 1, "/help")))
+//	msgs := api.MessagesTo(1)
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Token is the bot token clients of the fake server use
+const Token = "123456:TEST-TOKEN"
+
+// BotUser is the bot's own account as returned by getMe
+var BotUser = tgbotapi.User{ID: 123456, IsBot: true, FirstName: "ZeraBot", UserName: "ZeraTestBot"}
+
+// Request is a recorded Bot API call
+type Request struct {
+	Method string
+	Params url.Values
+}
+
+// Message is a message sent or edited by the bot
+type Message struct {
+	ChatID                int64
+	MessageID             int
+	Text                  string
+	ParseMode             string
+	DisableNotification   bool
+	DisableWebPagePreview bool
+	ReplyMarkup           string // Raw JSON of the keyboard, if any
+	Edited                bool   // Whether the message came from editMessageText
+}
+
+// CallbackAnswer is a recorded answerCallbackQuery call
+type CallbackAnswer struct {
+	CallbackQueryID string
+	Text            string
+}
+
+// failure is an error response queued for a method
+type failure struct {
+	code        int
+	description string
+	retryAfter  int
+}
+
+// Server is a fake Telegram Bot API
+type Server struct {
+	server *httptest.Server
+
+	mu              sync.Mutex
+	requests        []Request
+	messages        []Message
+	callbackAnswers []CallbackAnswer
+	inlineAnswers   []Request
+	failures        map[string][]failure
+	members         map[[2]int64]string
+	webhookURL      string
+	nextMessageID   int
+}
+
+// NewServer starts a fake Bot API server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		failures:      make(map[string][]failure),
+		members:       make(map[[2]int64]string),
+		nextMessageID: 1,
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Endpoint returns the API endpoint format to pass to tgbotapi.NewBotAPIWithAPIEndpoint
+func (s *Server) Endpoint() string {
+	return s.server.URL + "/bot%s/%s"
+}
+
+// NewBotAPI returns a Bot API client talking to the fake server
+func (s *Server) NewBotAPI() (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithAPIEndpoint(Token, s.Endpoint())
+}
+
+// FailNext makes the next call of method fail with a Telegram error, e.g.
+// FailNext("sendMessage", 400, "Bad Request: can't parse entities")
+func (s *Server) FailNext(method string, code int, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], failure{code: code, description: description})
+}
+
+// RateLimitNext makes the next call of method fail with 429 Too Many Requests,
+// asking the client to retry after the given number of seconds
+func (s *Server) RateLimitNext(method string, retryAfter int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], failure{
+		code:        http.StatusTooManyRequests,
+		description: fmt.Sprintf("Too Many Requests: retry after %d", retryAfter),
+		retryAfter:  retryAfter,
+	})
+}
+
+// SetChatMember sets a user's status in a chat as returned by getChatMember,
+// e.g. "creator", "administrator" or "member" (the default)
+func (s *Server) SetChatMember(chatID, userID int64, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.members[[2]int64{chatID, userID}] = status
+}
+
+// Requests returns every call made to the server
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Messages returns every message sent or edited successfully
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// MessagesTo returns the messages sent or edited in a chat
+func (s *Server) MessagesTo(chatID int64) []Message {
+	var messages []Message
+	for _, msg := range s.Messages() {
+		if msg.ChatID == chatID {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// CallbackAnswers returns every answerCallbackQuery call
+func (s *Server) CallbackAnswers() []CallbackAnswer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]CallbackAnswer(nil), s.callbackAnswers...)
+}
+
+// InlineAnswers returns every answerInlineQuery call
+func (s *Server) InlineAnswers() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.inlineAnswers...)
+}
+
+// WebhookURL returns the URL last set with setWebhook
+func (s *Server) WebhookURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.webhookURL
+}
+
+// Reset forgets recorded calls and queued failures
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.messages = nil
+	s.callbackAnswers = nil
+	s.inlineAnswers = nil
+	s.failures = make(map[string][]failure)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	// Paths look like /bot<token>/<method>
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != Token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", 0)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error(), 0)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request := Request{Method: method, Params: r.PostForm}
+	s.requests = append(s.requests, request)
+
+	if queued := s.failures[method]; len(queued) > 0 {
+		s.failures[method] = queued[1:]
+		writeError(w, queued[0].code, queued[0].description, queued[0].retryAfter)
+		return
+	}
+
+	switch method {
+	case "getMe":
+		writeResult(w, BotUser)
+	case "sendMessage", "editMessageText":
+		writeResult(w, s.recordMessage(method, r.PostForm))
+	case "getChatMember":
+		chatID, _ := strconv.ParseInt(r.PostForm.Get("chat_id"), 10, 64)
+		userID, _ := strconv.ParseInt(r.PostForm.Get("user_id"), 10, 64)
+		status := s.members[[2]int64{chatID, userID}]
+		if status == "" {
+			status = "member"
+		}
+		writeResult(w, tgbotapi.ChatMember{User: &tgbotapi.User{ID: userID}, Status: status})
+	case "setWebhook":
+		s.webhookURL = r.PostForm.Get("url")
+		writeResult(w, true)
+	case "deleteWebhook":
+		s.webhookURL = ""
+		writeResult(w, true)
+	case "getWebhookInfo":
+		writeResult(w, tgbotapi.WebhookInfo{URL: s.webhookURL})
+	case "answerCallbackQuery":
+		s.callbackAnswers = append(s.callbackAnswers, CallbackAnswer{
+			CallbackQueryID: r.PostForm.Get("callback_query_id"),
+			Text:            r.PostForm.Get("text"),
+		})
+		writeResult(w, true)
+	case "answerInlineQuery":
+		s.inlineAnswers = append(s.inlineAnswers, request)
+		writeResult(w, true)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found: method not found", 0)
+	}
+}
+
+// recordMessage records a sent or edited message and returns it as Telegram would.
+// Callers must hold s.mu.
+func (s *Server) recordMessage(method string, params url.Values) tgbotapi.Message {
+	chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+
+	msg := Message{
+		ChatID:                chatID,
+		Text:                  params.Get("text"),
+		ParseMode:             params.Get("parse_mode"),
+		DisableNotification:   params.Get("disable_notification") == "true",
+		DisableWebPagePreview: params.Get("disable_web_page_preview") == "true",
+		ReplyMarkup:           params.Get("reply_markup"),
+		Edited:                method == "editMessageText",
+	}
+
+	if msg.Edited {
+		msg.MessageID, _ = strconv.Atoi(params.Get("message_id"))
+	} else {
+		msg.MessageID = s.nextMessageID
+		s.nextMessageID++
+	}
+	s.messages = append(s.messages, msg)
+
+	return tgbotapi.Message{
+		MessageID: msg.MessageID,
+		From:      &BotUser,
+		Chat:      &tgbotapi.Chat{ID: chatID},
+		Date:      int(time.Now().Unix()),
+		Text:      msg.Text,
+	}
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), 0)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: data})
+}
+
+func writeError(w http.ResponseWriter, code int, description string, retryAfter int) {
+	response := tgbotapi.APIResponse{Ok: false, ErrorCode: code, Description: description}
+	if retryAfter > 0 {
+		response.Parameters = &tgbotapi.ResponseParameters{RetryAfter: retryAfter}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
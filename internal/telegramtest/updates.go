@@ -0,0 +1,78 @@
+package telegramtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// lastUpdateID numbers updates built by this package, also from parallel tests
+var lastUpdateID atomic.Int64
+
+// CommandUpdate builds an update with a command message such as "/proposalSubscribe $ZRA+0000".
+// Chats with the same ID as the user are private chats, others are groups.
+func CommandUpdate(chatID, userID int64, text string) tgbotapi.Update {
+	command, _, _ := strings.Cut(text, " ")
+
+	chatType := "group"
+	if chatID == userID {
+		chatType = "private"
+	}
+
+	return newUpdate(tgbotapi.Update{
+		Message: &tgbotapi.Message{
+			MessageID: 1,
+			From:      &tgbotapi.User{ID: userID, FirstName: "Test", UserName: "tester", LanguageCode: "en"},
+			Chat:      &tgbotapi.Chat{ID: chatID, Type: chatType},
+			Date:      int(time.Now().Unix()),
+			Text:      text,
+			Entities:  []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+		},
+	})
+}
+
+// CallbackUpdate builds an update for a press of an inline keyboard button on a bot message
+func CallbackUpdate(chatID, userID int64, messageID int, data string) tgbotapi.Update {
+	return newUpdate(tgbotapi.Update{
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:   "callback-" + data,
+			From: &tgbotapi.User{ID: userID, FirstName: "Test", LanguageCode: "en"},
+			Message: &tgbotapi.Message{
+				MessageID: messageID,
+				From:      &BotUser,
+				Chat:      &tgbotapi.Chat{ID: chatID},
+			},
+			Data: data,
+		},
+	})
+}
+
+// InlineUpdate builds an update for an inline query such as "@ZeraTestBot zra"
+func InlineUpdate(userID int64, query string) tgbotapi.Update {
+	return newUpdate(tgbotapi.Update{
+		InlineQuery: &tgbotapi.InlineQuery{
+			ID:    "inline-" + query,
+			From:  &tgbotapi.User{ID: userID, FirstName: "Test", LanguageCode: "en"},
+			Query: query,
+		},
+	})
+}
+
+// NewUpdateRequest builds the webhook request Telegram would send for an update
+func NewUpdateRequest(update tgbotapi.Update) *http.Request {
+	body, _ := json.Marshal(update)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func newUpdate(update tgbotapi.Update) tgbotapi.Update {
+	update.UpdateID = int(lastUpdateID.Add(1))
+	return update
+}
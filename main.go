@@ -3,96 +3,38 @@ package main
 import (
 	"context"
 	"log"
-	"net/http"
-	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
+	"github.com/ZeraVision/ZeraBot/app"
 	"github.com/ZeraVision/ZeraBot/config"
-	"github.com/ZeraVision/ZeraBot/db"
-	"github.com/ZeraVision/ZeraBot/db/migrations"
-	"github.com/ZeraVision/ZeraBot/grpc"
-	"github.com/ZeraVision/ZeraBot/server"
-	"github.com/ZeraVision/ZeraBot/telegram"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
-var (
-	bot      *telegram.Bot
-	cfg      *config.Config
-	srv      *server.Server
-	database *db.Database
-)
-
 func init() {
 	godotenv.Load(".env")
 }
 
 func main() {
 	// Load configuration
-	var err error
-	cfg, err = config.Load()
+	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Initialize database
-	database, err = db.NewDatabase(cfg.DatabaseURL)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer database.Close()
-
-	// Run database migrations
-	if err := migrations.RunMigrations(context.Background(), database.DB()); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
-	}
-
-	// Initialize bot with database
-	bot, err = telegram.NewBot(cfg.BotToken, cfg.Env == "development", database)
-	if err != nil {
-		log.Fatalf("Failed to initialize bot: %v", err)
-	}
-
-	// Set up server
-	webhookPath := "/" + os.Getenv("WEBHOOK_SECRET")
-	srv, err = server.New(bot, cfg.Domain, webhookPath, cfg.Env == "production")
+	// Connect to the database and Telegram and wire everything together
+	a, err := app.Open(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
-	}
-
-	// Set up webhook // TODO this service seems to die after a short time (not get new webhooks at entry)
-	if err := bot.SetupWebhook(srv.WebhookURL()); err != nil {
-		log.Fatalf("Failed to set up webhook: %v", err)
-	}
-
-	// Start server in a goroutine
-	go func() {
-		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
-
-	go func() {
-		grpc.InitialHookups()
-	}()
-
-	// Send a startup notification to the test channel
-	if bot != nil {
-		bot.SendMessage(-4897181115, "🤖 *Bot started successfully!*")
+		log.Fatalf("Failed to start: %v", err)
 	}
+	defer a.Close()
 
-	// Wait for interrupt signal
-	stopChan := make(chan os.Signal, 1)
-	signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM)
-	<-stopChan
+	// Run until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Shutdown server
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Printf("Error during server shutdown: %v", err)
+	if err := a.Run(ctx); err != nil {
+		log.Printf("Error while running: %v", err)
 	}
 }
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+)
+
+// Counters published through expvar at /debug/vars
+var (
+	Commands              = expvar.NewMap("commands_total")                // Commands handled, by command
+	RateLimited           = expvar.NewMap("rate_limited_total")            // Commands rejected by a rate limit, by "chat" or "user"
+	BannedCommands        = expvar.NewInt("banned_commands_total")         // Commands ignored from banned users
+	Bans                  = expvar.NewInt("bans_total")                    // Temporary bans issued
+	ActiveBans            = expvar.NewInt("bans_active")                   // Bans currently in effect
+	SubscriptionLimitHits = expvar.NewInt("subscription_limit_hits_total") // Subscriptions rejected by the per-chat limit
+	EventStreamClients    = expvar.NewInt("event_stream_clients")          // Clients streaming live events
+)
+
+// Handler serves all published metrics to requests bearing the token
+func Handler(token string) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		expvar.Handler().ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,32 @@
+// Package notify defines the alerts delivered to subscribers, independent of where they are delivered
+package notify
+
+import "github.com/ZeraVision/ZeraBot/filter"
+
+// ProposalAlert describes a new governance proposal to be announced to subscribers
+type ProposalAlert struct {
+	Symbol     string
+	ProposalID string
+	Title      string
+	Synopsis   string
+	Types      []string // Proposal types as defined by the filter package
+	Proposer   string   // Hex encoded public key of the proposer
+}
+
+// FilterCandidate returns the fields subscription filters are evaluated against
+func (a *ProposalAlert) FilterCandidate() filter.Candidate {
+	return filter.Candidate{
+		Title:    a.Title,
+		Synopsis: a.Synopsis,
+		Types:    a.Types,
+		Proposer: a.Proposer,
+	}
+}
+
+// ExplorerProposalURL is the base URL of proposals on the block explorer
+const ExplorerProposalURL = "https://explorer.zera.vision/proposal/"
+
+// URL returns the link to the proposal on the block explorer
+func (a *ProposalAlert) URL() string {
+	return ExplorerProposalURL + a.ProposalID
+}
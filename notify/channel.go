@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Channel delivers proposal alerts to its subscribers, e.g. Telegram chats or Discord webhooks
+type Channel interface {
+	// Name identifies the channel in logs and errors
+	Name() string
+	// NotifySubscribers sends an alert to every subscriber of its symbol
+	NotifySubscribers(alert *ProposalAlert) error
+}
+
+// Fanout publishes alerts to several channels
+type Fanout struct {
+	channels []Channel
+}
+
+// NewFanout creates a notifier publishing to the given channels in order
+func NewFanout(channels ...Channel) *Fanout {
+	return &Fanout{channels: channels}
+}
+
+// Add adds a channel
+func (f *Fanout) Add(channel Channel) {
+	f.channels = append(f.channels, channel)
+}
+
+// NotifySubscribers sends an alert through every channel. A failing channel
+// doesn't stop the others; their errors are returned together.
+func (f *Fanout) NotifySubscribers(alert *ProposalAlert) error {
+	var errs []error
+	for _, channel := range f.channels {
+		if err := channel.NotifySubscribers(alert); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
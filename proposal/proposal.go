@@ -1,23 +1,35 @@
 package proposal
 
 import (
-	"fmt"
+	"context"
 	"log"
 
-	"github.com/ZeraVision/ZeraBot/telegram"
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/filter"
+	"github.com/ZeraVision/ZeraBot/notify"
 	"github.com/ZeraVision/ZeraBot/txnstatus"
-	"github.com/ZeraVision/ZeraBot/util"
 	zera_protobuf "github.com/ZeraVision/go-zera-network/grpc/protobuf"
 	"github.com/ZeraVision/zera-go-sdk/transcode"
 )
 
-// ProcessProposals processes new governance proposals and notifies subscribers
-func ProcessProposals(block *zera_protobuf.Block) error {
-	bot := telegram.GetBot()
-	if bot == nil {
-		return fmt.Errorf("telegram bot not initialized")
-	}
+// Notifier delivers proposal alerts to subscribers
+type Notifier interface {
+	NotifySubscribers(alert *notify.ProposalAlert) error
+}
+
+// Processor records new governance proposals and notifies subscribers
+type Processor struct {
+	notifier  Notifier
+	proposals *db.ProposalRepository
+}
 
+// NewProcessor creates a processor recording proposals in a repository and announcing them through a notifier
+func NewProcessor(notifier Notifier, proposals *db.ProposalRepository) *Processor {
+	return &Processor{notifier: notifier, proposals: proposals}
+}
+
+// ProcessProposals processes new governance proposals and notifies subscribers
+func (p *Processor) ProcessProposals(block *zera_protobuf.Block) error {
 	for _, proposal := range block.Transactions.GovernanceProposals {
 		status, err := txnstatus.GetStatus(proposal.Base.Hash, block.Transactions.TxnFeesAndStatus)
 		if err != nil {
@@ -30,14 +42,12 @@ func ProcessProposals(block *zera_protobuf.Block) error {
 			continue
 		}
 
-		// Extract the contract ID (symbol) from the proposal
-		symbol := proposal.ContractId
-
-		// Format the proposal message
-		message := formatProposalMessage(proposal)
+		if err := p.proposals.Save(context.Background(), newProposalRecord(proposal, block)); err != nil {
+			log.Printf("Failed to record proposal %s: %v", proposal.Base.Hash, err)
+		}
 
 		// Notify subscribers
-		if err := bot.NotifySubscribers(symbol, message); err != nil {
+		if err := p.notifier.NotifySubscribers(newProposalAlert(proposal)); err != nil {
 			log.Printf("Failed to notify subscribers for proposal %s: %v", proposal.Base.Hash, err)
 		}
 	}
@@ -45,26 +55,41 @@ func ProcessProposals(block *zera_protobuf.Block) error {
 	return nil
 }
 
-// formatProposalMessage formats a proposal into a user-friendly message
-func formatProposalMessage(proposal *zera_protobuf.GovernanceProposal) string {
-
-	proposalID := transcode.HexEncode(proposal.Base.Hash)
-
-	return fmt.Sprintf(`🗳️ *New Proposal* 🗳️
-
-*Symbol:* %s
+// newProposalAlert extracts the details subscribers are notified about from a proposal
+func newProposalAlert(proposal *zera_protobuf.GovernanceProposal) *notify.ProposalAlert {
+	return &notify.ProposalAlert{
+		Symbol:     proposal.ContractId, // The contract ID is the subscribed symbol
+		ProposalID: transcode.HexEncode(proposal.Base.Hash),
+		Title:      proposal.Title,
+		Synopsis:   proposal.Synopsis,
+		Types:      proposalTypes(proposal),
+		Proposer:   transcode.HexEncode(proposal.GetBase().GetPublicKey().GetSingle()),
+	}
+}
 
-*Title:* %s
+// newProposalRecord builds the stored record of a proposal
+func newProposalRecord(proposal *zera_protobuf.GovernanceProposal, block *zera_protobuf.Block) *db.Proposal {
+	return &db.Proposal{
+		ProposalID:  transcode.HexEncode(proposal.Base.Hash),
+		ContractID:  proposal.ContractId,
+		Title:       proposal.Title,
+		Synopsis:    proposal.Synopsis,
+		Proposer:    transcode.HexEncode(proposal.GetBase().GetPublicKey().GetSingle()),
+		Types:       proposalTypes(proposal),
+		BlockHeight: block.GetBlockHeader().GetBlockHeight(),
+	}
+}
 
-*Synopsis:* %s
+// proposalTypes classifies a proposal for subscription filters
+func proposalTypes(proposal *zera_protobuf.GovernanceProposal) []string {
+	types := []string{filter.TypeYesNo}
+	if len(proposal.GetOptions()) > 0 {
+		types = []string{filter.TypeOptions}
+	}
 
-*Proposal ID:* %s
+	if len(proposal.GetGovernanceTxn()) > 0 {
+		types = append(types, filter.TypeExecutable)
+	}
 
-[View on Explorer](https://explorer.zera.vision/proposal/%s)`,
-		proposal.ContractId,
-		util.EscapeMarkdown(util.Truncate(proposal.Title, 200)),
-		util.EscapeMarkdown(util.Truncate(proposal.Synopsis, 500)),
-		proposalID,
-		proposalID,
-	)
+	return types
 }
@@ -0,0 +1,926 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: proto/zerabot/v1/query.proto
+
+package zerabotv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Channel is where a subscription's alerts are delivered
+type Channel int32
+
+const (
+	Channel_CHANNEL_UNSPECIFIED Channel = 0 // Telegram
+	Channel_CHANNEL_TELEGRAM    Channel = 1 // chat_id is a Telegram chat
+	Channel_CHANNEL_DISCORD     Channel = 2 // chat_id is the ID of a Discord destination
+	Channel_CHANNEL_SLACK       Channel = 3 // chat_id is the ID of a Slack destination
+	Channel_CHANNEL_MATRIX      Channel = 4 // chat_id is the ID of a Matrix destination
+)
+
+// Enum value maps for Channel.
+var (
+	Channel_name = map[int32]string{
+		0: "CHANNEL_UNSPECIFIED",
+		1: "CHANNEL_TELEGRAM",
+		2: "CHANNEL_DISCORD",
+		3: "CHANNEL_SLACK",
+		4: "CHANNEL_MATRIX",
+	}
+	Channel_value = map[string]int32{
+		"CHANNEL_UNSPECIFIED": 0,
+		"CHANNEL_TELEGRAM":    1,
+		"CHANNEL_DISCORD":     2,
+		"CHANNEL_SLACK":       3,
+		"CHANNEL_MATRIX":      4,
+	}
+)
+
+func (x Channel) Enum() *Channel {
+	p := new(Channel)
+	*p = x
+	return p
+}
+
+func (x Channel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Channel) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_zerabot_v1_query_proto_enumTypes[0].Descriptor()
+}
+
+func (Channel) Type() protoreflect.EnumType {
+	return &file_proto_zerabot_v1_query_proto_enumTypes[0]
+}
+
+func (x Channel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Channel.Descriptor instead.
+func (Channel) EnumDescriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{0}
+}
+
+// Proposal is a governance proposal seen on chain
+type Proposal struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`         // Hex encoded transaction hash
+	Symbol        string                 `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"` // Contract the proposal is for, e.g. $ZRA+0000
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Synopsis      string                 `protobuf:"bytes,4,opt,name=synopsis,proto3" json:"synopsis,omitempty"`
+	Types         []string               `protobuf:"bytes,5,rep,name=types,proto3" json:"types,omitempty"`       // yesno or options, plus executable
+	Proposer      string                 `protobuf:"bytes,6,opt,name=proposer,proto3" json:"proposer,omitempty"` // Hex encoded public key
+	BlockHeight   uint64                 `protobuf:"varint,7,opt,name=block_height,json=blockHeight,proto3" json:"block_height,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // When the bot saw the proposal
+	ExplorerUrl   string                 `protobuf:"bytes,9,opt,name=explorer_url,json=explorerUrl,proto3" json:"explorer_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Proposal) Reset() {
+	*x = Proposal{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Proposal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Proposal) ProtoMessage() {}
+
+func (x *Proposal) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Proposal.ProtoReflect.Descriptor instead.
+func (*Proposal) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Proposal) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Proposal) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Proposal) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Proposal) GetSynopsis() string {
+	if x != nil {
+		return x.Synopsis
+	}
+	return ""
+}
+
+func (x *Proposal) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *Proposal) GetProposer() string {
+	if x != nil {
+		return x.Proposer
+	}
+	return ""
+}
+
+func (x *Proposal) GetBlockHeight() uint64 {
+	if x != nil {
+		return x.BlockHeight
+	}
+	return 0
+}
+
+func (x *Proposal) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Proposal) GetExplorerUrl() string {
+	if x != nil {
+		return x.ExplorerUrl
+	}
+	return ""
+}
+
+type ListProposalsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`                        // Only proposals of this contract, every contract if empty
+	PageSize      int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`   // 1 to 100, 25 if 0
+	PageToken     string                 `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"` // next_page_token of the previous page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProposalsRequest) Reset() {
+	*x = ListProposalsRequest{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProposalsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProposalsRequest) ProtoMessage() {}
+
+func (x *ListProposalsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProposalsRequest.ProtoReflect.Descriptor instead.
+func (*ListProposalsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListProposalsRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *ListProposalsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListProposalsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListProposalsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Proposals     []*Proposal            `protobuf:"bytes,1,rep,name=proposals,proto3" json:"proposals,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Empty on the last page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProposalsResponse) Reset() {
+	*x = ListProposalsResponse{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProposalsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProposalsResponse) ProtoMessage() {}
+
+func (x *ListProposalsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProposalsResponse.ProtoReflect.Descriptor instead.
+func (*ListProposalsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListProposalsResponse) GetProposals() []*Proposal {
+	if x != nil {
+		return x.Proposals
+	}
+	return nil
+}
+
+func (x *ListProposalsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type GetProposalRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProposalRequest) Reset() {
+	*x = GetProposalRequest{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProposalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProposalRequest) ProtoMessage() {}
+
+func (x *GetProposalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProposalRequest.ProtoReflect.Descriptor instead.
+func (*GetProposalRequest) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetProposalRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type StreamProposalsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbol        string                 `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`                                    // Only proposals of this contract, every contract if empty
+	AfterEventId  int64                  `protobuf:"varint,2,opt,name=after_event_id,json=afterEventId,proto3" json:"after_event_id,omitempty"` // Replay proposals after this event ID first, none if 0
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamProposalsRequest) Reset() {
+	*x = StreamProposalsRequest{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamProposalsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamProposalsRequest) ProtoMessage() {}
+
+func (x *StreamProposalsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamProposalsRequest.ProtoReflect.Descriptor instead.
+func (*StreamProposalsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamProposalsRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *StreamProposalsRequest) GetAfterEventId() int64 {
+	if x != nil {
+		return x.AfterEventId
+	}
+	return 0
+}
+
+type ProposalEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       int64                  `protobuf:"varint,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"` // Pass as after_event_id to resume after this event
+	Proposal      *Proposal              `protobuf:"bytes,2,opt,name=proposal,proto3" json:"proposal,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProposalEvent) Reset() {
+	*x = ProposalEvent{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProposalEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProposalEvent) ProtoMessage() {}
+
+func (x *ProposalEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProposalEvent.ProtoReflect.Descriptor instead.
+func (*ProposalEvent) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ProposalEvent) GetEventId() int64 {
+	if x != nil {
+		return x.EventId
+	}
+	return 0
+}
+
+func (x *ProposalEvent) GetProposal() *Proposal {
+	if x != nil {
+		return x.Proposal
+	}
+	return nil
+}
+
+type Subscription struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Channel       Channel                `protobuf:"varint,2,opt,name=channel,proto3,enum=zerabot.v1.Channel" json:"channel,omitempty"`
+	ChatId        int64                  `protobuf:"varint,3,opt,name=chat_id,json=chatId,proto3" json:"chat_id,omitempty"`
+	Symbol        string                 `protobuf:"bytes,4,opt,name=symbol,proto3" json:"symbol,omitempty"`      // A symbol, a pattern such as $ZRA+* or "all"
+	Filtered      bool                   `protobuf:"varint,5,opt,name=filtered,proto3" json:"filtered,omitempty"` // Whether a filter narrows the proposals it matches
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Subscription) Reset() {
+	*x = Subscription{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subscription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subscription) ProtoMessage() {}
+
+func (x *Subscription) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subscription.ProtoReflect.Descriptor instead.
+func (*Subscription) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Subscription) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Subscription) GetChannel() Channel {
+	if x != nil {
+		return x.Channel
+	}
+	return Channel_CHANNEL_UNSPECIFIED
+}
+
+func (x *Subscription) GetChatId() int64 {
+	if x != nil {
+		return x.ChatId
+	}
+	return 0
+}
+
+func (x *Subscription) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *Subscription) GetFiltered() bool {
+	if x != nil {
+		return x.Filtered
+	}
+	return false
+}
+
+type ListSubscriptionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Channel       Channel                `protobuf:"varint,1,opt,name=channel,proto3,enum=zerabot.v1.Channel" json:"channel,omitempty"`
+	ChatId        int64                  `protobuf:"varint,2,opt,name=chat_id,json=chatId,proto3" json:"chat_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubscriptionsRequest) Reset() {
+	*x = ListSubscriptionsRequest{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubscriptionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubscriptionsRequest) ProtoMessage() {}
+
+func (x *ListSubscriptionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubscriptionsRequest.ProtoReflect.Descriptor instead.
+func (*ListSubscriptionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListSubscriptionsRequest) GetChannel() Channel {
+	if x != nil {
+		return x.Channel
+	}
+	return Channel_CHANNEL_UNSPECIFIED
+}
+
+func (x *ListSubscriptionsRequest) GetChatId() int64 {
+	if x != nil {
+		return x.ChatId
+	}
+	return 0
+}
+
+type ListSubscriptionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subscriptions []*Subscription        `protobuf:"bytes,1,rep,name=subscriptions,proto3" json:"subscriptions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubscriptionsResponse) Reset() {
+	*x = ListSubscriptionsResponse{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubscriptionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubscriptionsResponse) ProtoMessage() {}
+
+func (x *ListSubscriptionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubscriptionsResponse.ProtoReflect.Descriptor instead.
+func (*ListSubscriptionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListSubscriptionsResponse) GetSubscriptions() []*Subscription {
+	if x != nil {
+		return x.Subscriptions
+	}
+	return nil
+}
+
+type SubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Channel       Channel                `protobuf:"varint,1,opt,name=channel,proto3,enum=zerabot.v1.Channel" json:"channel,omitempty"`
+	ChatId        int64                  `protobuf:"varint,2,opt,name=chat_id,json=chatId,proto3" json:"chat_id,omitempty"`
+	Symbol        string                 `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SubscribeRequest) GetChannel() Channel {
+	if x != nil {
+		return x.Channel
+	}
+	return Channel_CHANNEL_UNSPECIFIED
+}
+
+func (x *SubscribeRequest) GetChatId() int64 {
+	if x != nil {
+		return x.ChatId
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+type UnsubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Channel       Channel                `protobuf:"varint,1,opt,name=channel,proto3,enum=zerabot.v1.Channel" json:"channel,omitempty"`
+	ChatId        int64                  `protobuf:"varint,2,opt,name=chat_id,json=chatId,proto3" json:"chat_id,omitempty"`
+	Symbol        string                 `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsubscribeRequest) Reset() {
+	*x = UnsubscribeRequest{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribeRequest) ProtoMessage() {}
+
+func (x *UnsubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribeRequest.ProtoReflect.Descriptor instead.
+func (*UnsubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UnsubscribeRequest) GetChannel() Channel {
+	if x != nil {
+		return x.Channel
+	}
+	return Channel_CHANNEL_UNSPECIFIED
+}
+
+func (x *UnsubscribeRequest) GetChatId() int64 {
+	if x != nil {
+		return x.ChatId
+	}
+	return 0
+}
+
+func (x *UnsubscribeRequest) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+type UnsubscribeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnsubscribeResponse) Reset() {
+	*x = UnsubscribeResponse{}
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnsubscribeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnsubscribeResponse) ProtoMessage() {}
+
+func (x *UnsubscribeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_zerabot_v1_query_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnsubscribeResponse.ProtoReflect.Descriptor instead.
+func (*UnsubscribeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_zerabot_v1_query_proto_rawDescGZIP(), []int{11}
+}
+
+var File_proto_zerabot_v1_query_proto protoreflect.FileDescriptor
+
+const file_proto_zerabot_v1_query_proto_rawDesc = "" +
+	"\n" +
+	"\x1cproto/zerabot/v1/query.proto\x12\n" +
+	"zerabot.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x97\x02\n" +
+	"\bProposal\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06symbol\x18\x02 \x01(\tR\x06symbol\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x1a\n" +
+	"\bsynopsis\x18\x04 \x01(\tR\bsynopsis\x12\x14\n" +
+	"\x05types\x18\x05 \x03(\tR\x05types\x12\x1a\n" +
+	"\bproposer\x18\x06 \x01(\tR\bproposer\x12!\n" +
+	"\fblock_height\x18\a \x01(\x04R\vblockHeight\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12!\n" +
+	"\fexplorer_url\x18\t \x01(\tR\vexplorerUrl\"j\n" +
+	"\x14ListProposalsRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"s\n" +
+	"\x15ListProposalsResponse\x122\n" +
+	"\tproposals\x18\x01 \x03(\v2\x14.zerabot.v1.ProposalR\tproposals\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"$\n" +
+	"\x12GetProposalRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"V\n" +
+	"\x16StreamProposalsRequest\x12\x16\n" +
+	"\x06symbol\x18\x01 \x01(\tR\x06symbol\x12$\n" +
+	"\x0eafter_event_id\x18\x02 \x01(\x03R\fafterEventId\"\\\n" +
+	"\rProposalEvent\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\x03R\aeventId\x120\n" +
+	"\bproposal\x18\x02 \x01(\v2\x14.zerabot.v1.ProposalR\bproposal\"\x9a\x01\n" +
+	"\fSubscription\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12-\n" +
+	"\achannel\x18\x02 \x01(\x0e2\x13.zerabot.v1.ChannelR\achannel\x12\x17\n" +
+	"\achat_id\x18\x03 \x01(\x03R\x06chatId\x12\x16\n" +
+	"\x06symbol\x18\x04 \x01(\tR\x06symbol\x12\x1a\n" +
+	"\bfiltered\x18\x05 \x01(\bR\bfiltered\"b\n" +
+	"\x18ListSubscriptionsRequest\x12-\n" +
+	"\achannel\x18\x01 \x01(\x0e2\x13.zerabot.v1.ChannelR\achannel\x12\x17\n" +
+	"\achat_id\x18\x02 \x01(\x03R\x06chatId\"[\n" +
+	"\x19ListSubscriptionsResponse\x12>\n" +
+	"\rsubscriptions\x18\x01 \x03(\v2\x18.zerabot.v1.SubscriptionR\rsubscriptions\"r\n" +
+	"\x10SubscribeRequest\x12-\n" +
+	"\achannel\x18\x01 \x01(\x0e2\x13.zerabot.v1.ChannelR\achannel\x12\x17\n" +
+	"\achat_id\x18\x02 \x01(\x03R\x06chatId\x12\x16\n" +
+	"\x06symbol\x18\x03 \x01(\tR\x06symbol\"t\n" +
+	"\x12UnsubscribeRequest\x12-\n" +
+	"\achannel\x18\x01 \x01(\x0e2\x13.zerabot.v1.ChannelR\achannel\x12\x17\n" +
+	"\achat_id\x18\x02 \x01(\x03R\x06chatId\x12\x16\n" +
+	"\x06symbol\x18\x03 \x01(\tR\x06symbol\"\x15\n" +
+	"\x13UnsubscribeResponse*t\n" +
+	"\aChannel\x12\x17\n" +
+	"\x13CHANNEL_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10CHANNEL_TELEGRAM\x10\x01\x12\x13\n" +
+	"\x0fCHANNEL_DISCORD\x10\x02\x12\x11\n" +
+	"\rCHANNEL_SLACK\x10\x03\x12\x12\n" +
+	"\x0eCHANNEL_MATRIX\x10\x042\xf4\x03\n" +
+	"\fQueryService\x12T\n" +
+	"\rListProposals\x12 .zerabot.v1.ListProposalsRequest\x1a!.zerabot.v1.ListProposalsResponse\x12C\n" +
+	"\vGetProposal\x12\x1e.zerabot.v1.GetProposalRequest\x1a\x14.zerabot.v1.Proposal\x12R\n" +
+	"\x0fStreamProposals\x12\".zerabot.v1.StreamProposalsRequest\x1a\x19.zerabot.v1.ProposalEvent0\x01\x12`\n" +
+	"\x11ListSubscriptions\x12$.zerabot.v1.ListSubscriptionsRequest\x1a%.zerabot.v1.ListSubscriptionsResponse\x12C\n" +
+	"\tSubscribe\x12\x1c.zerabot.v1.SubscribeRequest\x1a\x18.zerabot.v1.Subscription\x12N\n" +
+	"\vUnsubscribe\x12\x1e.zerabot.v1.UnsubscribeRequest\x1a\x1f.zerabot.v1.UnsubscribeResponseB:Z8github.com/ZeraVision/ZeraBot/proto/zerabot/v1;zerabotv1b\x06proto3"
+
+var (
+	file_proto_zerabot_v1_query_proto_rawDescOnce sync.Once
+	file_proto_zerabot_v1_query_proto_rawDescData []byte
+)
+
+func file_proto_zerabot_v1_query_proto_rawDescGZIP() []byte {
+	file_proto_zerabot_v1_query_proto_rawDescOnce.Do(func() {
+		file_proto_zerabot_v1_query_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_zerabot_v1_query_proto_rawDesc), len(file_proto_zerabot_v1_query_proto_rawDesc)))
+	})
+	return file_proto_zerabot_v1_query_proto_rawDescData
+}
+
+var file_proto_zerabot_v1_query_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_zerabot_v1_query_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_proto_zerabot_v1_query_proto_goTypes = []any{
+	(Channel)(0),                      // 0: zerabot.v1.Channel
+	(*Proposal)(nil),                  // 1: zerabot.v1.Proposal
+	(*ListProposalsRequest)(nil),      // 2: zerabot.v1.ListProposalsRequest
+	(*ListProposalsResponse)(nil),     // 3: zerabot.v1.ListProposalsResponse
+	(*GetProposalRequest)(nil),        // 4: zerabot.v1.GetProposalRequest
+	(*StreamProposalsRequest)(nil),    // 5: zerabot.v1.StreamProposalsRequest
+	(*ProposalEvent)(nil),             // 6: zerabot.v1.ProposalEvent
+	(*Subscription)(nil),              // 7: zerabot.v1.Subscription
+	(*ListSubscriptionsRequest)(nil),  // 8: zerabot.v1.ListSubscriptionsRequest
+	(*ListSubscriptionsResponse)(nil), // 9: zerabot.v1.ListSubscriptionsResponse
+	(*SubscribeRequest)(nil),          // 10: zerabot.v1.SubscribeRequest
+	(*UnsubscribeRequest)(nil),        // 11: zerabot.v1.UnsubscribeRequest
+	(*UnsubscribeResponse)(nil),       // 12: zerabot.v1.UnsubscribeResponse
+	(*timestamppb.Timestamp)(nil),     // 13: google.protobuf.Timestamp
+}
+var file_proto_zerabot_v1_query_proto_depIdxs = []int32{
+	13, // 0: zerabot.v1.Proposal.created_at:type_name -> google.protobuf.Timestamp
+	1,  // 1: zerabot.v1.ListProposalsResponse.proposals:type_name -> zerabot.v1.Proposal
+	1,  // 2: zerabot.v1.ProposalEvent.proposal:type_name -> zerabot.v1.Proposal
+	0,  // 3: zerabot.v1.Subscription.channel:type_name -> zerabot.v1.Channel
+	0,  // 4: zerabot.v1.ListSubscriptionsRequest.channel:type_name -> zerabot.v1.Channel
+	7,  // 5: zerabot.v1.ListSubscriptionsResponse.subscriptions:type_name -> zerabot.v1.Subscription
+	0,  // 6: zerabot.v1.SubscribeRequest.channel:type_name -> zerabot.v1.Channel
+	0,  // 7: zerabot.v1.UnsubscribeRequest.channel:type_name -> zerabot.v1.Channel
+	2,  // 8: zerabot.v1.QueryService.ListProposals:input_type -> zerabot.v1.ListProposalsRequest
+	4,  // 9: zerabot.v1.QueryService.GetProposal:input_type -> zerabot.v1.GetProposalRequest
+	5,  // 10: zerabot.v1.QueryService.StreamProposals:input_type -> zerabot.v1.StreamProposalsRequest
+	8,  // 11: zerabot.v1.QueryService.ListSubscriptions:input_type -> zerabot.v1.ListSubscriptionsRequest
+	10, // 12: zerabot.v1.QueryService.Subscribe:input_type -> zerabot.v1.SubscribeRequest
+	11, // 13: zerabot.v1.QueryService.Unsubscribe:input_type -> zerabot.v1.UnsubscribeRequest
+	3,  // 14: zerabot.v1.QueryService.ListProposals:output_type -> zerabot.v1.ListProposalsResponse
+	1,  // 15: zerabot.v1.QueryService.GetProposal:output_type -> zerabot.v1.Proposal
+	6,  // 16: zerabot.v1.QueryService.StreamProposals:output_type -> zerabot.v1.ProposalEvent
+	9,  // 17: zerabot.v1.QueryService.ListSubscriptions:output_type -> zerabot.v1.ListSubscriptionsResponse
+	7,  // 18: zerabot.v1.QueryService.Subscribe:output_type -> zerabot.v1.Subscription
+	12, // 19: zerabot.v1.QueryService.Unsubscribe:output_type -> zerabot.v1.UnsubscribeResponse
+	14, // [14:20] is the sub-list for method output_type
+	8,  // [8:14] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_proto_zerabot_v1_query_proto_init() }
+func file_proto_zerabot_v1_query_proto_init() {
+	if File_proto_zerabot_v1_query_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_zerabot_v1_query_proto_rawDesc), len(file_proto_zerabot_v1_query_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_zerabot_v1_query_proto_goTypes,
+		DependencyIndexes: file_proto_zerabot_v1_query_proto_depIdxs,
+		EnumInfos:         file_proto_zerabot_v1_query_proto_enumTypes,
+		MessageInfos:      file_proto_zerabot_v1_query_proto_msgTypes,
+	}.Build()
+	File_proto_zerabot_v1_query_proto = out.File
+	file_proto_zerabot_v1_query_proto_goTypes = nil
+	file_proto_zerabot_v1_query_proto_depIdxs = nil
+}
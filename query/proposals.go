@@ -0,0 +1,230 @@
+package query
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/events"
+	"github.com/ZeraVision/ZeraBot/notify"
+	zerabotv1 "github.com/ZeraVision/ZeraBot/proto/zerabot/v1"
+	"github.com/ZeraVision/ZeraBot/symbol"
+)
+
+// Page sizes
+const (
+	defaultPageSize = 25
+	maxPageSize     = 100
+)
+
+// replayBatch is how many logged events are read at a time when a stream resumes
+const replayBatch = 500
+
+// ListProposals lists proposals, newest first
+func (s *Server) ListProposals(ctx context.Context, req *zerabotv1.ListProposalsRequest) (*zerabotv1.ListProposalsResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize < 0 || pageSize > maxPageSize:
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 1 and %d", maxPageSize)
+	}
+
+	contractID, err := contractFilter(req.GetSymbol())
+	if err != nil {
+		return nil, err
+	}
+
+	page := db.ProposalPage{ContractID: contractID, Limit: pageSize + 1}
+	if req.GetPageToken() != "" {
+		if page.Before, page.BeforeID, err = parsePageToken(req.GetPageToken()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "page_token must be a next_page_token returned by ListProposals")
+		}
+	}
+
+	proposals, err := s.proposals.List(ctx, page)
+	if err != nil {
+		log.Printf("gRPC error listing proposals: %v", err)
+		return nil, status.Error(codes.Internal, "failed to list proposals")
+	}
+
+	// One more than the page size was fetched to tell whether there is a next page
+	resp := &zerabotv1.ListProposalsResponse{}
+	if len(proposals) > pageSize {
+		proposals = proposals[:pageSize]
+		last := proposals[pageSize-1]
+		resp.NextPageToken = pageToken(last.CreatedAt, last.ProposalID)
+	}
+	for _, p := range proposals {
+		resp.Proposals = append(resp.Proposals, newProposal(p))
+	}
+
+	return resp, nil
+}
+
+// GetProposal returns one proposal
+func (s *Server) GetProposal(ctx context.Context, req *zerabotv1.GetProposalRequest) (*zerabotv1.Proposal, error) {
+	p, err := s.proposals.Get(ctx, req.GetId())
+	if errors.Is(err, db.ErrProposalNotFound) {
+		return nil, status.Error(codes.NotFound, "no such proposal has been seen on chain")
+	}
+	if err != nil {
+		log.Printf("gRPC error getting proposal: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get proposal")
+	}
+
+	return newProposal(p), nil
+}
+
+// StreamProposals streams new proposals from the event log, first replaying the
+// ones after after_event_id if it is set
+func (s *Server) StreamProposals(req *zerabotv1.StreamProposalsRequest, stream grpc.ServerStreamingServer[zerabotv1.ProposalEvent]) error {
+	if req.GetAfterEventId() < 0 {
+		return status.Error(codes.InvalidArgument, "after_event_id must not be negative")
+	}
+	contractID, err := contractFilter(req.GetSymbol())
+	if err != nil {
+		return err
+	}
+	filter := events.Filter{Types: []string{events.EventProposalCreated}, Symbol: contractID}
+
+	// Subscribe before reading the log so no event falls between the two
+	ctx := stream.Context()
+	sub, err := s.events.Subscribe(filter)
+	if errors.Is(err, events.ErrTooManyClients) {
+		return status.Error(codes.ResourceExhausted, "too many streams, retry later")
+	}
+	if errors.Is(err, events.ErrClosed) {
+		return status.Error(codes.Unavailable, "the service is shutting down")
+	}
+	if err != nil {
+		log.Printf("gRPC error subscribing to events: %v", err)
+		return status.Error(codes.Internal, "failed to subscribe to proposals")
+	}
+	defer sub.Close()
+
+	sent := req.GetAfterEventId()
+	for replay := sent > 0; replay; {
+		missed, err := s.events.Since(ctx, filter, sent, replayBatch)
+		if err != nil {
+			log.Printf("gRPC error replaying events: %v", err)
+			return status.Error(codes.Internal, "failed to replay proposals")
+		}
+		for _, e := range missed {
+			if err := stream.Send(s.newProposalEvent(ctx, e)); err != nil {
+				return err
+			}
+			sent = e.ID
+		}
+		replay = len(missed) == replayBatch
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case e, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "stream ended, resume with after_event_id")
+			}
+			// Already sent from the log
+			if e.ID <= sent {
+				continue
+			}
+			if err := stream.Send(s.newProposalEvent(ctx, e)); err != nil {
+				return err
+			}
+			sent = e.ID
+		}
+	}
+}
+
+// newProposalEvent converts a logged proposal.created event. The proposal is read
+// from the database, which has its block height, or else built from the event.
+func (s *Server) newProposalEvent(ctx context.Context, e *db.Event) *zerabotv1.ProposalEvent {
+	var data events.ProposalData
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		log.Printf("Failed to decode event %d: %v", e.ID, err)
+	}
+
+	if p, err := s.proposals.Get(ctx, data.ProposalID); err == nil {
+		return &zerabotv1.ProposalEvent{EventId: e.ID, Proposal: newProposal(p)}
+	}
+
+	return &zerabotv1.ProposalEvent{
+		EventId: e.ID,
+		Proposal: &zerabotv1.Proposal{
+			Id:          data.ProposalID,
+			Symbol:      data.Symbol,
+			Title:       data.Title,
+			Synopsis:    data.Synopsis,
+			Types:       data.Types,
+			Proposer:    data.Proposer,
+			CreatedAt:   timestamppb.New(e.CreatedAt),
+			ExplorerUrl: data.URL,
+		},
+	}
+}
+
+// contractFilter normalizes and validates an optional symbol filter
+func contractFilter(input string) (string, error) {
+	if input == "" {
+		return "", nil
+	}
+
+	contractID := symbol.Normalize(input)
+	if !symbol.IsValid(contractID) {
+		return "", status.Error(codes.InvalidArgument, "symbols look like $ZRA+0000")
+	}
+	return contractID, nil
+}
+
+// newProposal converts a stored proposal
+func newProposal(p *db.Proposal) *zerabotv1.Proposal {
+	return &zerabotv1.Proposal{
+		Id:          p.ProposalID,
+		Symbol:      p.ContractID,
+		Title:       p.Title,
+		Synopsis:    p.Synopsis,
+		Types:       p.Types,
+		Proposer:    p.Proposer,
+		BlockHeight: p.BlockHeight,
+		CreatedAt:   timestamppb.New(p.CreatedAt),
+		ExplorerUrl: notify.ExplorerProposalURL + p.ProposalID,
+	}
+}
+
+// pageToken encodes the position after a proposal as an opaque page token
+func pageToken(createdAt time.Time, proposalID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + proposalID))
+}
+
+// parsePageToken decodes a token from pageToken
+func parsePageToken(token string) (time.Time, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	nanos, proposalID, ok := strings.Cut(string(data), ":")
+	if !ok {
+		return time.Time{}, "", errors.New("malformed page token")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	return time.Unix(0, n), proposalID, nil
+}
@@ -0,0 +1,83 @@
+// Package query serves the proposals the bot has seen and manages alert subscriptions
+// over gRPC (proto/zerabot/v1/query.proto), so other backends can use the bot as
+// their source of governance events
+package query
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/events"
+	zerabotv1 "github.com/ZeraVision/ZeraBot/proto/zerabot/v1"
+)
+
+// Config configures the query service
+type Config struct {
+	Address      string   // Address to listen on, e.g. ":50061"
+	Tokens       []string // Bearer tokens accepted in the authorization metadata
+	ManageTokens []string // Tokens that may also change subscriptions, of any chat or destination
+}
+
+// Server serves the query service
+type Server struct {
+	zerabotv1.UnimplementedQueryServiceServer
+
+	cfg           Config
+	proposals     *db.ProposalRepository
+	subscriptions func(db.Channel) db.SubscriptionStore
+	destinations  db.DestinationStore
+	events        *events.Stream
+	grpcServer    *grpc.Server
+}
+
+// NewServer creates the query service. Subscriptions are managed on the channel
+// the subscription repository is for and the channels of destinations.
+func NewServer(cfg Config, proposals *db.ProposalRepository, subscriptions *db.SubscriptionRepository, destinations db.DestinationStore, stream *events.Stream) *Server {
+	tokens := append(append([]string(nil), cfg.Tokens...), cfg.ManageTokens...)
+	s := &Server{
+		cfg:       cfg,
+		proposals: proposals,
+		subscriptions: func(channel db.Channel) db.SubscriptionStore {
+			return subscriptions.ForChannel(channel)
+		},
+		destinations: destinations,
+		events:       stream,
+		grpcServer: grpc.NewServer(
+			grpc.UnaryInterceptor(unaryAuth(tokens)),
+			grpc.StreamInterceptor(streamAuth(tokens)),
+		),
+	}
+	zerabotv1.RegisterQueryServiceServer(s.grpcServer, s)
+	return s
+}
+
+// Start listens on the configured address and serves until Stop is called
+func (s *Server) Start() error {
+	lis, err := net.Listen("tcp", s.cfg.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.cfg.Address, err)
+	}
+
+	log.Printf("Starting gRPC query service on %s", lis.Addr())
+	return s.grpcServer.Serve(lis)
+}
+
+// Stop waits for calls in progress to finish, or ends them when ctx is done
+func (s *Server) Stop(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	zerabotv1 "github.com/ZeraVision/ZeraBot/proto/zerabot/v1"
+	"github.com/ZeraVision/ZeraBot/symbol"
+)
+
+// channels maps the channels of the API to those subscriptions are stored for
+var channels = map[zerabotv1.Channel]db.Channel{
+	zerabotv1.Channel_CHANNEL_UNSPECIFIED: db.TelegramChannel,
+	zerabotv1.Channel_CHANNEL_TELEGRAM:    db.TelegramChannel,
+	zerabotv1.Channel_CHANNEL_DISCORD:     db.DiscordChannel,
+	zerabotv1.Channel_CHANNEL_SLACK:       db.SlackChannel,
+	zerabotv1.Channel_CHANNEL_MATRIX:      db.MatrixChannel,
+}
+
+// ListSubscriptions lists the subscriptions of a chat or destination
+func (s *Server) ListSubscriptions(ctx context.Context, req *zerabotv1.ListSubscriptionsRequest) (*zerabotv1.ListSubscriptionsResponse, error) {
+	repo, err := s.subscriptionsFor(ctx, req.GetChannel(), req.GetChatId())
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := repo.GetUserSubscriptions(ctx, req.GetChatId())
+	if err != nil {
+		log.Printf("gRPC error listing subscriptions: %v", err)
+		return nil, status.Error(codes.Internal, "failed to list subscriptions")
+	}
+
+	resp := &zerabotv1.ListSubscriptionsResponse{}
+	for _, sub := range subs {
+		resp.Subscriptions = append(resp.Subscriptions, newSubscription(req.GetChannel(), sub))
+	}
+	return resp, nil
+}
+
+// Subscribe subscribes a chat or destination to a symbol, a pattern or "all". Only
+// manage tokens may change subscriptions.
+func (s *Server) Subscribe(ctx context.Context, req *zerabotv1.SubscribeRequest) (*zerabotv1.Subscription, error) {
+	if !authorized(ctx, s.cfg.ManageTokens) {
+		return nil, errCannotManage
+	}
+	sym, err := subscriptionSymbol(req.GetSymbol())
+	if err != nil {
+		return nil, err
+	}
+	repo, err := s.subscriptionsFor(ctx, req.GetChannel(), req.GetChatId())
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := repo.Subscribe(ctx, req.GetChatId(), db.ProposalType, sym)
+	if errors.Is(err, db.ErrSubscriptionLimit) {
+		return nil, status.Errorf(codes.ResourceExhausted, "the chat is at its limit of %d subscriptions", repo.MaxPerChat())
+	}
+	if err != nil {
+		log.Printf("gRPC error subscribing: %v", err)
+		return nil, status.Error(codes.Internal, "failed to subscribe")
+	}
+
+	return newSubscription(req.GetChannel(), sub), nil
+}
+
+// Unsubscribe removes a subscription. Only manage tokens may change subscriptions.
+func (s *Server) Unsubscribe(ctx context.Context, req *zerabotv1.UnsubscribeRequest) (*zerabotv1.UnsubscribeResponse, error) {
+	if !authorized(ctx, s.cfg.ManageTokens) {
+		return nil, errCannotManage
+	}
+	sym, err := subscriptionSymbol(req.GetSymbol())
+	if err != nil {
+		return nil, err
+	}
+	repo, err := s.subscriptionsFor(ctx, req.GetChannel(), req.GetChatId())
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.Unsubscribe(ctx, req.GetChatId(), db.ProposalType, sym)
+	if errors.Is(err, db.ErrSubscriptionNotFound) {
+		return nil, status.Error(codes.NotFound, "no such subscription")
+	}
+	if err != nil {
+		log.Printf("gRPC error unsubscribing: %v", err)
+		return nil, status.Error(codes.Internal, "failed to unsubscribe")
+	}
+
+	return &zerabotv1.UnsubscribeResponse{}, nil
+}
+
+// subscriptionsFor returns the subscriptions of a channel after checking the chat ID.
+// Outside Telegram it must be the ID of a destination on that channel.
+func (s *Server) subscriptionsFor(ctx context.Context, channel zerabotv1.Channel, chatID int64) (db.SubscriptionStore, error) {
+	dbChannel, ok := channels[channel]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "unknown channel")
+	}
+	if chatID == 0 {
+		return nil, status.Error(codes.InvalidArgument, "chat_id is required")
+	}
+
+	if dbChannel != db.TelegramChannel {
+		d, err := s.destinations.Get(ctx, chatID)
+		if errors.Is(err, db.ErrDestinationNotFound) || (err == nil && d.Channel != dbChannel) {
+			return nil, status.Errorf(codes.NotFound, "no %s destination %d", dbChannel, chatID)
+		}
+		if err != nil {
+			log.Printf("gRPC error getting destination: %v", err)
+			return nil, status.Error(codes.Internal, "failed to get destination")
+		}
+	}
+
+	return s.subscriptions(dbChannel), nil
+}
+
+// subscriptionSymbol normalizes and validates the symbol of a subscription
+func subscriptionSymbol(input string) (string, error) {
+	if strings.EqualFold(strings.TrimSpace(input), "all") {
+		return "all", nil
+	}
+
+	sym := symbol.Normalize(input)
+	if !symbol.IsValid(sym) && !symbol.IsPattern(sym) {
+		return "", status.Error(codes.InvalidArgument, "symbols look like $ZRA+0000, patterns like $ZRA+* or 'all'")
+	}
+	return sym, nil
+}
+
+// newSubscription converts a stored subscription
+func newSubscription(channel zerabotv1.Channel, sub *db.Subscription) *zerabotv1.Subscription {
+	if channel == zerabotv1.Channel_CHANNEL_UNSPECIFIED {
+		channel = zerabotv1.Channel_CHANNEL_TELEGRAM
+	}
+
+	return &zerabotv1.Subscription{
+		Id:       sub.ID,
+		Channel:  channel,
+		ChatId:   sub.ChatID,
+		Symbol:   sub.Symbol,
+		Filtered: sub.Filter != nil,
+	}
+}
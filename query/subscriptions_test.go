@@ -0,0 +1,201 @@
+package query
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/memstore"
+	zerabotv1 "github.com/ZeraVision/ZeraBot/proto/zerabot/v1"
+)
+
+const (
+	readToken   = "r34d"
+	manageToken = "m4n4ge"
+)
+
+// destinations are destinations by ID
+type destinations map[int64]*db.Destination
+
+func (d destinations) Get(ctx context.Context, id int64) (*db.Destination, error) {
+	if dest, ok := d[id]; ok {
+		return dest, nil
+	}
+	return nil, db.ErrDestinationNotFound
+}
+
+// testService is a client of a query service served over an in-memory connection
+type testService struct {
+	zerabotv1.QueryServiceClient
+	stores map[db.Channel]*memstore.SubscriptionStore
+}
+
+// newTestService serves a query service with subscriptions in memory, Discord
+// destination 7 and Slack destination 8
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+
+	stores := make(map[db.Channel]*memstore.SubscriptionStore)
+	for _, channel := range channels {
+		if stores[channel] == nil {
+			stores[channel] = memstore.NewSubscriptionStore()
+		}
+	}
+
+	s := NewServer(Config{Tokens: []string{readToken}, ManageTokens: []string{manageToken}}, nil, nil, destinations{
+		7: {ID: 7, Channel: db.DiscordChannel, Name: "announcements"},
+		8: {ID: 8, Channel: db.SlackChannel, Name: "governance"},
+	}, nil)
+	s.subscriptions = func(channel db.Channel) db.SubscriptionStore {
+		return stores[channel]
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	go s.grpcServer.Serve(lis)
+	t.Cleanup(s.grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to connect to the query service: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &testService{QueryServiceClient: zerabotv1.NewQueryServiceClient(conn), stores: stores}
+}
+
+// as returns a context sending a bearer token, or no token if it is empty
+func as(token string) context.Context {
+	if token == "" {
+		return context.Background()
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// expectCode checks that a call failed with a status code
+func expectCode(t *testing.T, call string, err error, code codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != code {
+		t.Errorf("%s: code %s, want %s (%v)", call, got, code, err)
+	}
+}
+
+func TestSubscriptionAuth(t *testing.T) {
+	svc := newTestService(t)
+	list := &zerabotv1.ListSubscriptionsRequest{ChatId: 1}
+	sub := &zerabotv1.SubscribeRequest{ChatId: 1, Symbol: "$ZRA+0000"}
+	unsub := &zerabotv1.UnsubscribeRequest{ChatId: 1, Symbol: "$ZRA+0000"}
+
+	for _, token := range []string{"", "wrong", "Bearer " + readToken} {
+		_, err := svc.ListSubscriptions(as(token), list)
+		expectCode(t, "ListSubscriptions with token "+token, err, codes.Unauthenticated)
+		_, err = svc.Subscribe(as(token), sub)
+		expectCode(t, "Subscribe with token "+token, err, codes.Unauthenticated)
+	}
+
+	// Read tokens can list subscriptions but not change them
+	if _, err := svc.ListSubscriptions(as(readToken), list); err != nil {
+		t.Errorf("ListSubscriptions with a read token: %v", err)
+	}
+	_, err := svc.Subscribe(as(readToken), sub)
+	expectCode(t, "Subscribe with a read token", err, codes.PermissionDenied)
+	_, err = svc.Unsubscribe(as(readToken), unsub)
+	expectCode(t, "Unsubscribe with a read token", err, codes.PermissionDenied)
+
+	if _, err := svc.Subscribe(as(manageToken), sub); err != nil {
+		t.Fatalf("Subscribe with a manage token: %v", err)
+	}
+	resp, err := svc.ListSubscriptions(as(readToken), list)
+	if err != nil || len(resp.GetSubscriptions()) != 1 {
+		t.Fatalf("ListSubscriptions after subscribing = %v, %v, want the subscription", resp, err)
+	}
+	if _, err := svc.Unsubscribe(as(manageToken), unsub); err != nil {
+		t.Errorf("Unsubscribe with a manage token: %v", err)
+	}
+}
+
+func TestSubscriptionErrors(t *testing.T) {
+	svc := newTestService(t)
+	ctx := as(manageToken)
+
+	subscribes := []struct {
+		name string
+		req  *zerabotv1.SubscribeRequest
+		code codes.Code
+	}{
+		{"InvalidSymbol", &zerabotv1.SubscribeRequest{ChatId: 1, Symbol: "$ZRA+12"}, codes.InvalidArgument},
+		{"MissingChat", &zerabotv1.SubscribeRequest{Symbol: "$ZRA+0000"}, codes.InvalidArgument},
+		{"UnknownChannel", &zerabotv1.SubscribeRequest{ChatId: 1, Channel: 99, Symbol: "$ZRA+0000"}, codes.InvalidArgument},
+		{"UnknownDestination", &zerabotv1.SubscribeRequest{ChatId: 9, Channel: zerabotv1.Channel_CHANNEL_DISCORD, Symbol: "$ZRA+0000"}, codes.NotFound},
+		{"DestinationOnAnotherChannel", &zerabotv1.SubscribeRequest{ChatId: 8, Channel: zerabotv1.Channel_CHANNEL_DISCORD, Symbol: "$ZRA+0000"}, codes.NotFound},
+	}
+	for _, tt := range subscribes {
+		_, err := svc.Subscribe(ctx, tt.req)
+		expectCode(t, tt.name, err, tt.code)
+	}
+
+	_, err := svc.Unsubscribe(ctx, &zerabotv1.UnsubscribeRequest{ChatId: 1, Symbol: "$ZRA+0000"})
+	expectCode(t, "Unsubscribe without a subscription", err, codes.NotFound)
+	_, err = svc.ListSubscriptions(ctx, &zerabotv1.ListSubscriptionsRequest{ChatId: 7, Channel: zerabotv1.Channel_CHANNEL_SLACK})
+	expectCode(t, "ListSubscriptions of a destination on another channel", err, codes.NotFound)
+
+	// Nothing was stored by the rejected calls
+	for channel, store := range svc.stores {
+		for _, chatID := range []int64{1, 7, 8, 9} {
+			if subs, _ := store.GetUserSubscriptions(context.Background(), chatID); len(subs) != 0 {
+				t.Errorf("%s chat %d has subscriptions %v, want none", channel, chatID, subs)
+			}
+		}
+	}
+}
+
+func TestDestinationSubscriptions(t *testing.T) {
+	svc := newTestService(t)
+	ctx := as(manageToken)
+
+	sub, err := svc.Subscribe(ctx, &zerabotv1.SubscribeRequest{ChatId: 7, Channel: zerabotv1.Channel_CHANNEL_DISCORD, Symbol: "$zra+*"})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if sub.GetChannel() != zerabotv1.Channel_CHANNEL_DISCORD || sub.GetChatId() != 7 || sub.GetSymbol() != "$ZRA+*" {
+		t.Errorf("subscription = %v, want Discord destination 7 subscribed to $ZRA+*", sub)
+	}
+
+	// The subscription is stored for Discord only
+	if subs, _ := svc.stores[db.DiscordChannel].GetUserSubscriptions(context.Background(), 7); len(subs) != 1 {
+		t.Errorf("Discord destination 7 has %d subscriptions, want 1", len(subs))
+	}
+	resp, err := svc.ListSubscriptions(ctx, &zerabotv1.ListSubscriptionsRequest{ChatId: 7})
+	if err != nil || len(resp.GetSubscriptions()) != 0 {
+		t.Errorf("Telegram chat 7's subscriptions = %v, %v, want none", resp, err)
+	}
+}
+
+func TestSubscriptionLimit(t *testing.T) {
+	svc := newTestService(t)
+	ctx := as(manageToken)
+	svc.stores[db.TelegramChannel].SetMaxPerChat(1)
+
+	if _, err := svc.Subscribe(ctx, &zerabotv1.SubscribeRequest{ChatId: 1, Symbol: "$ZRA+0000"}); err != nil {
+		t.Fatalf("first Subscribe: %v", err)
+	}
+	_, err := svc.Subscribe(ctx, &zerabotv1.SubscribeRequest{ChatId: 1, Symbol: "all"})
+	expectCode(t, "Subscribe over the limit", err, codes.ResourceExhausted)
+
+	// Subscribing again to the same symbol only refreshes it
+	if _, err := svc.Subscribe(ctx, &zerabotv1.SubscribeRequest{ChatId: 1, Symbol: "$ZRA+0000"}); err != nil {
+		t.Errorf("Subscribe to the same symbol at the limit: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ZeraVision/ZeraBot/db"
+)
+
+// maxSuggestionDistance is the largest edit distance at which a known contract
+// is still offered as a "did you mean" suggestion
+const maxSuggestionDistance = 2
+
+// Registry caches the instrument contracts known to exist on chain so symbols
+// can be validated without a database round trip
+type Registry struct {
+	repo db.ContractStore
+
+	mu        sync.RWMutex
+	contracts map[string]*db.Contract
+}
+
+func NewRegistry(repo db.ContractStore) *Registry {
+	return &Registry{
+		repo:      repo,
+		contracts: make(map[string]*db.Contract),
+	}
+}
+
+// Load fills the cache with every contract stored in the database
+func (r *Registry) Load(ctx context.Context) error {
+	contracts, err := r.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, contract := range contracts {
+		r.contracts[contract.ContractID] = contract
+	}
+
+	return nil
+}
+
+// Add records contracts observed on chain, storing the ones not cached yet
+func (r *Registry) Add(ctx context.Context, contracts ...*db.Contract) error {
+	for _, contract := range contracts {
+		if contract.ContractID == "" {
+			continue
+		}
+
+		r.mu.RLock()
+		known, ok := r.contracts[contract.ContractID]
+		r.mu.RUnlock()
+		if ok && (contract.Symbol == "" || contract.Symbol == known.Symbol) &&
+			(contract.Name == "" || contract.Name == known.Name) {
+			continue
+		}
+
+		if err := r.repo.Save(ctx, contract); err != nil {
+			return fmt.Errorf("failed to add contract %s: %w", contract.ContractID, err)
+		}
+
+		r.mu.Lock()
+		if !ok {
+			r.contracts[contract.ContractID] = contract
+		} else {
+			updated := *known
+			if contract.Symbol != "" {
+				updated.Symbol = contract.Symbol
+			}
+			if contract.Name != "" {
+				updated.Name = contract.Name
+			}
+			r.contracts[contract.ContractID] = &updated
+		}
+		r.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Exists reports whether a contract ID is known
+func (r *Registry) Exists(contractID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.contracts[contractID]
+	return ok
+}
+
+// Get returns a known contract, or nil if it is unknown
+func (r *Registry) Get(contractID string) *db.Contract {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.contracts[contractID]
+}
+
+// Suggest returns up to limit known contract IDs closest to an unknown one,
+// nearest first
+func (r *Registry) Suggest(contractID string, limit int) []string {
+	type candidate struct {
+		id       string
+		distance int
+	}
+
+	target := strings.ToUpper(contractID)
+
+	r.mu.RLock()
+	var candidates []candidate
+	for id := range r.contracts {
+		if d := distance(target, strings.ToUpper(id)); d <= maxSuggestionDistance {
+			candidates = append(candidates, candidate{id: id, distance: d})
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].id < candidates[j].id
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < limit; i++ {
+		suggestions = append(suggestions, candidates[i].id)
+	}
+	return suggestions
+}
+
+// distance returns the Levenshtein edit distance between two strings
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+)
+
+// memContracts is a db.ContractStore in memory
+type memContracts map[string]*db.Contract
+
+func (m memContracts) Save(ctx context.Context, contract *db.Contract) error {
+	m[contract.ContractID] = contract
+	return nil
+}
+
+func (m memContracts) List(ctx context.Context) ([]*db.Contract, error) {
+	var contracts []*db.Contract
+	for _, contract := range m {
+		contracts = append(contracts, contract)
+	}
+	return contracts, nil
+}
+
+func TestAddAndLoad(t *testing.T) {
+	ctx := context.Background()
+	store := memContracts{}
+	reg := NewRegistry(store)
+
+	if err := reg.Add(ctx, &db.Contract{ContractID: "$ZRA+0000", Symbol: "ZRA"}, &db.Contract{}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := reg.Add(ctx, &db.Contract{ContractID: "$ZRA+0000", Name: "Zera"}, &db.Contract{ContractID: "$ETH+0000", Symbol: "ETH"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	for _, id := range []string{"$ZRA+0000", "$ETH+0000"} {
+		if !reg.Exists(id) || store[id] == nil {
+			t.Errorf("%s isn't known and stored", id)
+		}
+	}
+	if len(store) != 2 {
+		t.Errorf("%d contracts stored, want 2 without the one lacking an ID", len(store))
+	}
+	if got := reg.Get("$ZRA+0000"); got.Symbol != "ZRA" || got.Name != "Zera" {
+		t.Errorf("contract seen again = %+v, want the symbol kept and the name added", got)
+	}
+
+	// A restarted registry loads the stored contracts
+	restarted := NewRegistry(store)
+	if err := restarted.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := restarted.ByTicker("ETH"); len(got) != 1 || got[0].ContractID != "$ETH+0000" {
+		t.Errorf("ByTicker after Load = %+v, want $ETH+0000", got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	reg := NewRegistry(memContracts{})
+	if err := reg.Add(context.Background(),
+		&db.Contract{ContractID: "$ZRA+0000"},
+		&db.Contract{ContractID: "$ZRA+0001"},
+		&db.Contract{ContractID: "$BTC+0000"},
+	); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if got, want := reg.Suggest("$ZRB+0000", 3), []string{"$ZRA+0000", "$ZRA+0001"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Suggest = %v, want %v", got, want)
+	}
+	if got := reg.Suggest("$ABCD+9999", 3); len(got) != 0 {
+		t.Errorf("Suggest = %v, want no suggestions", got)
+	}
+}
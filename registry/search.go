@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+)
+
+// Search match ranks, best first
+const (
+	rankExact = iota
+	rankTickerPrefix
+	rankPrefix
+	rankContains
+	rankNoMatch
+)
+
+// Search returns up to limit known contracts whose ID, symbol or name contain the
+// query, case-insensitively, best matches first. An empty query returns contracts
+// in ID order.
+func (r *Registry) Search(query string, limit int) []*db.Contract {
+	type result struct {
+		contract *db.Contract
+		rank     int
+	}
+
+	query = strings.ToUpper(strings.TrimSpace(query))
+
+	r.mu.RLock()
+	var results []result
+	for _, contract := range r.contracts {
+		if rank := searchRank(contract, query); rank != rankNoMatch {
+			results = append(results, result{contract: contract, rank: rank})
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].rank != results[j].rank {
+			return results[i].rank < results[j].rank
+		}
+		return results[i].contract.ContractID < results[j].contract.ContractID
+	})
+
+	var contracts []*db.Contract
+	for i := 0; i < len(results) && i < limit; i++ {
+		contracts = append(contracts, results[i].contract)
+	}
+	return contracts
+}
+
+// ByTicker returns the known contracts with a ticker, e.g. every ZRA series, in ID order
+func (r *Registry) ByTicker(ticker string) []*db.Contract {
+	ticker = strings.ToUpper(strings.TrimPrefix(ticker, "$"))
+
+	r.mu.RLock()
+	var contracts []*db.Contract
+	for id, contract := range r.contracts {
+		if strings.ToUpper(Ticker(id)) == ticker {
+			contracts = append(contracts, contract)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].ContractID < contracts[j].ContractID
+	})
+	return contracts
+}
+
+// Ticker returns the ticker of a contract ID, e.g. ZRA for $ZRA+0000
+func Ticker(contractID string) string {
+	ticker, _, _ := strings.Cut(strings.TrimPrefix(contractID, "$"), "+")
+	return ticker
+}
+
+// searchRank ranks how well a contract matches an upper-cased query
+func searchRank(contract *db.Contract, query string) int {
+	id := strings.ToUpper(contract.ContractID)
+	ticker := strings.ToUpper(Ticker(contract.ContractID))
+	bare := strings.TrimPrefix(query, "$")
+
+	switch {
+	case query == "":
+		return rankContains
+	case id == query || id == "$"+query || ticker == bare:
+		return rankExact
+	case strings.HasPrefix(ticker, bare):
+		return rankTickerPrefix
+	case strings.HasPrefix(strings.ToUpper(contract.Symbol), bare) ||
+		strings.HasPrefix(strings.ToUpper(contract.Name), query):
+		return rankPrefix
+	case strings.Contains(id, query) || strings.Contains(strings.ToUpper(contract.Name), query):
+		return rankContains
+	default:
+		return rankNoMatch
+	}
+}
@@ -0,0 +1,138 @@
+// Package replay feeds recorded blocks through the same pipeline as block broadcasts
+// and captures the messages the bot sends in response, using a fake Telegram server.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/abuse"
+	"github.com/ZeraVision/ZeraBot/contract"
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/grpc"
+	"github.com/ZeraVision/ZeraBot/internal/telegramtest"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/proposal"
+	"github.com/ZeraVision/ZeraBot/registry"
+	"github.com/ZeraVision/ZeraBot/telegram"
+	zera_protobuf "github.com/ZeraVision/go-zera-network/grpc/protobuf"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// LoadBlock reads a block fixture. Files ending in .json are decoded as protojson,
+// anything else as binary protobuf.
+func LoadBlock(path string) (*zera_protobuf.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block fixture: %w", err)
+	}
+
+	block := &zera_protobuf.Block{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = protojson.Unmarshal(data, block)
+	} else {
+		err = proto.Unmarshal(data, block)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode block fixture %s: %w", path, err)
+	}
+
+	return block, nil
+}
+
+// Harness runs blocks through the contract and proposal processors with a bot that
+// talks to a fake Telegram server. Only Telegram is notified: outbound webhooks, email
+// and other channels are left out, so a replay queues no deliveries.
+type Harness struct {
+	API    *telegramtest.Server
+	Bot    *telegram.Bot
+	Ingest *grpc.Ingest
+}
+
+// NewHarness starts a fake Telegram server and builds a bot and the block pipeline
+// around it and the database, without rate limits or a subscription limit. Call Close when done.
+func NewHarness(database *db.Database) (*Harness, error) {
+	api := telegramtest.NewServer()
+
+	botAPI, err := api.NewBotAPI()
+	if err != nil {
+		api.Close()
+		return nil, fmt.Errorf("failed to connect to fake Telegram server: %w", err)
+	}
+
+	reg := registry.NewRegistry(db.NewContractRepository(database))
+	if err := reg.Load(context.Background()); err != nil {
+		api.Close()
+		return nil, fmt.Errorf("failed to load contract registry: %w", err)
+	}
+
+	proposals := db.NewProposalRepository(database)
+	bot := telegram.NewBot(botAPI, telegram.Services{
+		Database:      database,
+		Subscriptions: db.NewSubscriptionRepository(database),
+		Settings:      db.NewChatSettingsRepository(database),
+		PendingAlerts: db.NewPendingAlertRepository(database),
+		Proposals:     proposals,
+		Registry:      reg,
+		Guard:         abuse.NewGuard(abuse.Config{}, db.NewBanRepository(database)),
+	}, telegram.Options{})
+
+	ingest := grpc.NewIngest(grpc.IngestConfig{}, contract.NewProcessor(reg),
+		proposal.NewProcessor(notify.NewFanout(bot), proposals), db.NewBlockRepository(database))
+
+	return &Harness{API: api, Bot: bot, Ingest: ingest}, nil
+}
+
+// Close shuts the fake Telegram server down. The database is left open.
+func (h *Harness) Close() {
+	h.API.Close()
+}
+
+// Replay processes a block and returns the messages the bot sent while doing so
+func (h *Harness) Replay(block *zera_protobuf.Block) []telegramtest.Message {
+	before := len(h.API.Messages())
+	h.Ingest.ProcessBlock(block)
+	return h.API.Messages()[before:]
+}
+
+// ReplayFile loads a block fixture and replays it
+func (h *Harness) ReplayFile(path string) ([]telegramtest.Message, error) {
+	block, err := LoadBlock(path)
+	if err != nil {
+		return nil, err
+	}
+	return h.Replay(block), nil
+}
+
+// ExpectMessage checks that one of the messages went to a chat and contains all of the given texts
+func ExpectMessage(messages []telegramtest.Message, chatID int64, contains ...string) error {
+	for _, msg := range messages {
+		if msg.ChatID == chatID && containsAll(msg.Text, contains) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no message to chat %d containing %q among %d sent", chatID, contains, len(messages))
+}
+
+// ExpectNoMessage checks that none of the messages went to a chat
+func ExpectNoMessage(messages []telegramtest.Message, chatID int64) error {
+	for _, msg := range messages {
+		if msg.ChatID == chatID {
+			return fmt.Errorf("unexpected message to chat %d: %q", chatID, msg.Text)
+		}
+	}
+	return nil
+}
+
+func containsAll(text string, substrings []string) bool {
+	for _, s := range substrings {
+		if !strings.Contains(text, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package replay_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/replay"
+)
+
+// fixtureChatID is the chat subscribed to every proposal while fixtures are replayed
+const fixtureChatID = -100123
+
+// fixtureAlerts lists, for each fixture in testdata, the texts the alert sent for it
+// must contain, or nil if nothing may be sent
+var fixtureAlerts = map[string][]string{
+	"proposal_ok.json": {
+		"$FIX+0000",
+		"Fund the fixture treasury",
+		"Move 1000 FIX to the community treasury.",
+		notify.ExplorerProposalURL + strings.Repeat("a1", 32),
+	},
+	"proposal_failed.json": nil,
+}
+
+// fixtures returns the block fixtures in testdata, failing if one has no expectation
+func fixtures(t *testing.T) []string {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+
+	for _, path := range paths {
+		if _, ok := fixtureAlerts[filepath.Base(path)]; !ok {
+			t.Errorf("fixture %s has no expected alert", path)
+		}
+	}
+	return paths
+}
+
+func TestLoadBlock(t *testing.T) {
+	for _, path := range fixtures(t) {
+		block, err := replay.LoadBlock(path)
+		if err != nil {
+			t.Errorf("LoadBlock: %v", err)
+			continue
+		}
+		if block.GetBlockHeader().GetBlockHeight() == 0 || len(block.GetTransactions().GetGovernanceProposals()) == 0 {
+			t.Errorf("%s decoded to a block without a height or proposals", path)
+		}
+	}
+
+	if _, err := replay.LoadBlock(filepath.Join("testdata", "missing.json")); err == nil {
+		t.Error("LoadBlock of a missing file succeeded")
+	}
+}
+
+func TestReplayFixtures(t *testing.T) {
+	for _, path := range fixtures(t) {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			database, harness := newHarness(t)
+			if _, err := db.NewSubscriptionRepository(database).Subscribe(context.Background(), fixtureChatID, db.ProposalType, "all"); err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+
+			messages, err := harness.ReplayFile(path)
+			if err != nil {
+				t.Fatalf("ReplayFile: %v", err)
+			}
+
+			want := fixtureAlerts[filepath.Base(path)]
+			if want == nil {
+				if err := replay.ExpectNoMessage(messages, fixtureChatID); err != nil {
+					t.Error(err)
+				}
+				return
+			}
+
+			if len(messages) != 1 {
+				t.Errorf("%d messages sent, want 1", len(messages))
+			}
+			if err := replay.ExpectMessage(messages, fixtureChatID, want...); err != nil {
+				t.Error(err)
+			}
+
+			// Replaying a block again announces its proposals again, e.g. after an outage
+			again, err := harness.ReplayFile(path)
+			if err != nil {
+				t.Fatalf("ReplayFile: %v", err)
+			}
+			if err := replay.ExpectMessage(again, fixtureChatID, want...); err != nil {
+				t.Errorf("second replay: %v", err)
+			}
+		})
+	}
+}
+
+// newHarness opens the test database and builds a harness on it
+func newHarness(t *testing.T) (*db.Database, *replay.Harness) {
+	database := pgtest.Open(t)
+
+	harness, err := replay.NewHarness(database)
+	if err != nil {
+		t.Fatalf("NewHarness: %v", err)
+	}
+	t.Cleanup(harness.Close)
+
+	return database, harness
+}
+
+func TestHarnessNotifiesOnlyTelegram(t *testing.T) {
+	ctx := context.Background()
+	database, harness := newHarness(t)
+
+	const chatID = fixtureChatID
+	if _, err := db.NewSubscriptionRepository(database).Subscribe(ctx, chatID, db.ProposalType, "all"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	// A webhook for the same chat must not get deliveries queued by a replay
+	webhooks := db.NewWebhookRepository(database)
+	if err := webhooks.Create(ctx, &db.Webhook{ChatID: chatID, URL: "https://example.com/hook", Secret: "secret"}); err != nil {
+		t.Fatalf("Create webhook: %v", err)
+	}
+
+	messages, err := harness.ReplayFile("testdata/proposal_ok.json")
+	if err != nil {
+		t.Fatalf("ReplayFile: %v", err)
+	}
+	if err := replay.ExpectMessage(messages, chatID, "Fund the fixture treasury"); err != nil {
+		t.Error(err)
+	}
+
+	var deliveries int
+	if err := database.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_deliveries`).Scan(&deliveries); err != nil {
+		t.Fatalf("failed to count webhook deliveries: %v", err)
+	}
+	if deliveries != 0 {
+		t.Errorf("replay queued %d webhook deliveries, want none", deliveries)
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"strings"
 
+	"github.com/ZeraVision/ZeraBot/metrics"
 	"github.com/ZeraVision/ZeraBot/telegram"
 )
 
@@ -16,26 +17,51 @@ type Server struct {
 	webhookURL string
 }
 
+// Config configures the HTTP server
+type Config struct {
+	Domain       string
+	WebhookPath  string // Path Telegram posts updates to
+	Production   bool
+	NgrokURL     string // Public URL used for the webhook in development, if set
+	MetricsToken string // Bearer token for /debug/vars, metrics are not served if empty
+
+	Handlers map[string]http.Handler // Additional handlers by path pattern, e.g. email links
+}
+
 // New creates a new server instance
-func New(bot *telegram.Bot, domain, webhookPath string, isProduction bool) (*Server, error) {
+func New(bot *telegram.Bot, cfg Config) (*Server, error) {
+	// ServeMux panics on an empty pattern
+	if !strings.HasPrefix(cfg.WebhookPath, "/") {
+		return nil, fmt.Errorf("invalid webhook path %q, it must start with /", cfg.WebhookPath)
+	}
+
 	s := &Server{
 		bot: bot,
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc(webhookPath, s.webhookHandler)
+	mux.HandleFunc(cfg.WebhookPath, s.webhookHandler)
+
+	// Metrics are only served when a token protects them
+	if cfg.MetricsToken != "" {
+		mux.Handle("/debug/vars", metrics.Handler(cfg.MetricsToken))
+	}
+
+	for pattern, handler := range cfg.Handlers {
+		mux.Handle(pattern, handler)
+	}
 
 	server := &http.Server{
 		Handler: mux,
 	}
 
 	server.Addr = ":8080"
-	if isProduction {
-		s.webhookURL = fmt.Sprintf("https://%s%s", domain, webhookPath)
+	if cfg.Production {
+		s.webhookURL = fmt.Sprintf("https://%s%s", cfg.Domain, cfg.WebhookPath)
 		log.Printf("Running in production mode on %s", server.Addr)
 	} else {
-		if ngrokURL := os.Getenv("NGROK_URL"); ngrokURL != "" {
-			s.webhookURL = fmt.Sprintf("%s%s", ngrokURL, webhookPath)
+		if cfg.NgrokURL != "" {
+			s.webhookURL = fmt.Sprintf("%s%s", cfg.NgrokURL, cfg.WebhookPath)
 		}
 		log.Printf("Running in development mode on http://localhost%s", server.Addr)
 	}
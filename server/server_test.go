@@ -0,0 +1,19 @@
+package server
+
+import "testing"
+
+func TestNewRejectsInvalidWebhookPath(t *testing.T) {
+	for _, path := range []string{"", "webhook"} {
+		if _, err := New(nil, Config{WebhookPath: path}); err == nil {
+			t.Errorf("New with webhook path %q succeeded, want an error", path)
+		}
+	}
+
+	s, err := New(nil, Config{WebhookPath: "/secret", Production: true, Domain: "bot.example.com"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if got, want := s.WebhookURL(), "https://bot.example.com/secret"; got != want {
+		t.Errorf("WebhookURL = %q, want %q", got, want)
+	}
+}
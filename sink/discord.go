@@ -0,0 +1,100 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+// discordColor is the embed accent color (Zera blue)
+const discordColor = 0x2F6FED
+
+// DiscordHosts are the hosts Discord webhook URLs may point at by default
+var DiscordHosts = []string{"discord.com", "discordapp.com"}
+
+// Discord sends alerts to Discord incoming webhooks as embeds. Destinations need a
+// "url" setting with the webhook URL.
+type Discord struct {
+	client *http.Client
+
+	// Hosts are the hosts webhook URLs may point at, and their subdomains, e.g. a local
+	// stand-in of Discord in tests. They default to DiscordHosts.
+	Hosts []string
+}
+
+// NewDiscord creates a Discord sender. A nil client uses http.DefaultClient.
+func NewDiscord(client *http.Client) *Discord {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Discord{client: client, Hosts: DiscordHosts}
+}
+
+type discordMessage struct {
+	Username string         `json:"username,omitempty"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	URL         string              `json:"url"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// Validate checks that the destination has a Discord webhook URL on one of the allowed hosts
+func (s *Discord) Validate(settings map[string]string) error {
+	u, err := url.Parse(settings["url"])
+	if err != nil || u.Scheme != "https" || !strings.HasPrefix(u.Path, "/api/webhooks/") || !s.allowedHost(u.Host) {
+		return errors.New("url must be a Discord webhook URL (https://discord.com/api/webhooks/...)")
+	}
+	return nil
+}
+
+// allowedHost reports whether host is one of the allowed hosts or a subdomain of one
+func (s *Discord) allowedHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range s.Hosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send posts the alert as an embed with the title, synopsis and explorer link
+func (s *Discord) Send(ctx context.Context, d *db.Destination, alert *notify.ProposalAlert) error {
+	title := alert.Title
+	if title == "" {
+		title = "New proposal for " + alert.Symbol
+	}
+
+	message := discordMessage{
+		Username: "ZeraBot",
+		Embeds: []discordEmbed{{
+			Title:       util.Truncate(title, 256),
+			Description: util.Truncate(alert.Synopsis, 500),
+			URL:         alert.URL(),
+			Color:       discordColor,
+			Fields: []discordEmbedField{
+				{Name: "Contract", Value: alert.Symbol, Inline: true},
+				{Name: "Proposal", Value: "`" + alert.ProposalID + "`", Inline: true},
+			},
+		}},
+	}
+
+	return postJSON(ctx, s.client, http.MethodPost, d.Settings["url"], message, nil)
+}
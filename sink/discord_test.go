@@ -0,0 +1,199 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+// testAlert is the alert sent in sink tests
+var testAlert = &notify.ProposalAlert{
+	Symbol:     "$ZRA+0000",
+	ProposalID: "abc123",
+	Title:      "Fund the treasury",
+	Synopsis:   "Move 1000 ZRA to the community treasury.",
+}
+
+// request is a request received by a stand-in
+type request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// standIn is a local stand-in for a chat service, answering with the statuses it is
+// given in turn and 200 once they run out
+type standIn struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []request
+}
+
+// newStandIn starts a stand-in serving https, whose certificate the sender's client must trust
+func newStandIn(t *testing.T, statuses ...int) *standIn {
+	s := &standIn{statuses: statuses}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, request{Method: r.Method, Path: r.URL.EscapedPath(), Header: r.Header, Body: body})
+
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+		if status >= 300 {
+			w.Write([]byte(`{"message": "stand-in failure"}`))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// received returns the requests received so far
+func (s *standIn) received() []request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]request(nil), s.requests...)
+}
+
+// host returns the stand-in's host and port
+func (s *standIn) host() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+func TestDiscordValidate(t *testing.T) {
+	standIn := newStandIn(t)
+
+	cases := []struct {
+		name  string
+		url   string
+		hosts []string
+		valid bool
+	}{
+		{"Discord", "https://discord.com/api/webhooks/1/token", nil, true},
+		{"Subdomain", "https://ptb.discord.com/api/webhooks/1/token", nil, true},
+		{"OldDomain", "https://discordapp.com/api/webhooks/1/token", nil, true},
+		{"NotHTTPS", "http://discord.com/api/webhooks/1/token", nil, false},
+		{"NotAWebhook", "https://discord.com/channels/1", nil, false},
+		{"OtherHost", "https://example.com/api/webhooks/1/token", nil, false},
+		{"LookalikeHost", "https://discord.com.example.com/api/webhooks/1/token", nil, false},
+		{"Missing", "", nil, false},
+		{"StandInNotAllowed", standIn.URL + "/api/webhooks/1/token", nil, false},
+		{"StandInAllowed", standIn.URL + "/api/webhooks/1/token", []string{standIn.host()}, true},
+		{"DiscordNotAllowed", "https://discord.com/api/webhooks/1/token", []string{standIn.host()}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			discord := NewDiscord(nil)
+			if c.hosts != nil {
+				discord.Hosts = c.hosts
+			}
+
+			err := discord.Validate(map[string]string{"url": c.url})
+			if (err == nil) != c.valid {
+				t.Errorf("Validate(%q) = %v, want valid %v", c.url, err, c.valid)
+			}
+		})
+	}
+}
+
+func TestDiscordSend(t *testing.T) {
+	standIn := newStandIn(t)
+	discord := NewDiscord(standIn.Client())
+	discord.Hosts = []string{standIn.host()}
+
+	settings := map[string]string{"url": standIn.URL + "/api/webhooks/1/token"}
+	if err := discord.Validate(settings); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	untitled := *testAlert
+	untitled.Title = ""
+	untitled.Synopsis = strings.Repeat("a", 600)
+
+	d := &db.Destination{ID: 1, Channel: db.DiscordChannel, Name: "community", Settings: settings}
+	for _, alert := range []*notify.ProposalAlert{testAlert, &untitled} {
+		if err := discord.Send(context.Background(), d, alert); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	requests := standIn.received()
+	if len(requests) != 2 {
+		t.Fatalf("stand-in got %d requests, want 2", len(requests))
+	}
+
+	want := []discordMessage{
+		{
+			Username: "ZeraBot",
+			Embeds: []discordEmbed{{
+				Title:       "Fund the treasury",
+				Description: "Move 1000 ZRA to the community treasury.",
+				URL:         testAlert.URL(),
+				Color:       discordColor,
+				Fields: []discordEmbedField{
+					{Name: "Contract", Value: "$ZRA+0000", Inline: true},
+					{Name: "Proposal", Value: "`abc123`", Inline: true},
+				},
+			}},
+		},
+		{
+			Username: "ZeraBot",
+			Embeds: []discordEmbed{{
+				Title:       "New proposal for $ZRA+0000",
+				Description: strings.Repeat("a", 497) + "...",
+				URL:         testAlert.URL(),
+				Color:       discordColor,
+				Fields: []discordEmbedField{
+					{Name: "Contract", Value: "$ZRA+0000", Inline: true},
+					{Name: "Proposal", Value: "`abc123`", Inline: true},
+				},
+			}},
+		},
+	}
+
+	for i, r := range requests {
+		if r.Method != http.MethodPost || r.Path != "/api/webhooks/1/token" {
+			t.Errorf("request %d is %s %s, want POST to the webhook URL", i+1, r.Method, r.Path)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("request %d Content-Type = %q, want application/json", i+1, got)
+		}
+
+		var got discordMessage
+		if err := json.Unmarshal(r.Body, &got); err != nil {
+			t.Fatalf("request %d body %s: %v", i+1, r.Body, err)
+		}
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want[i])
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("request %d body = %s, want %s", i+1, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestDiscordSendError(t *testing.T) {
+	standIn := newStandIn(t, http.StatusNotFound)
+	discord := NewDiscord(standIn.Client())
+
+	d := &db.Destination{Channel: db.DiscordChannel, Settings: map[string]string{"url": standIn.URL + "/api/webhooks/1/token"}}
+	err := discord.Send(context.Background(), d, testAlert)
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "stand-in failure") {
+		t.Errorf("Send = %v, want an error with the status and the response", err)
+	}
+}
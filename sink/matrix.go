@@ -0,0 +1,88 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+// Matrix sends alerts to Matrix rooms as m.room.message events with an HTML body.
+// Destinations need "homeserver" (e.g. https://matrix.org), "room" (a room ID such
+// as !abc:matrix.org) and "token" (the access token of a user in the room) settings.
+type Matrix struct {
+	client *http.Client
+}
+
+// NewMatrix creates a Matrix sender. A nil client uses http.DefaultClient.
+func NewMatrix(client *http.Client) *Matrix {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Matrix{client: client}
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// Validate checks that the destination has a homeserver URL, room ID and access token
+func (s *Matrix) Validate(settings map[string]string) error {
+	u, err := url.Parse(settings["homeserver"])
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("homeserver must be the URL of a Matrix homeserver, e.g. https://matrix.org")
+	}
+	if room := settings["room"]; !strings.HasPrefix(room, "!") || !strings.Contains(room, ":") {
+		return errors.New("room must be a Matrix room ID such as !abc:matrix.org")
+	}
+	if settings["token"] == "" {
+		return errors.New("token must be the access token of a user in the room")
+	}
+	return nil
+}
+
+// Send posts the alert to the room. The transaction ID is derived from the proposal
+// so a retried send doesn't post the alert twice.
+func (s *Matrix) Send(ctx context.Context, d *db.Destination, alert *notify.ProposalAlert) error {
+	title := alert.Title
+	if title == "" {
+		title = "New proposal for " + alert.Symbol
+	}
+	synopsis := util.Truncate(alert.Synopsis, 500)
+
+	body := fmt.Sprintf("New proposal for %s: %s\n\n%s\n\nProposal ID: %s\n%s",
+		alert.Symbol, title, synopsis, alert.ProposalID, alert.URL())
+	formatted := fmt.Sprintf("<p><strong>New proposal for %s</strong></p><p><a href=\"%s\">%s</a></p><p>%s</p><p>Proposal ID: <code>%s</code></p>",
+		html.EscapeString(alert.Symbol),
+		html.EscapeString(alert.URL()),
+		html.EscapeString(title),
+		html.EscapeString(synopsis),
+		html.EscapeString(alert.ProposalID),
+	)
+
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimSuffix(d.Settings["homeserver"], "/"),
+		url.PathEscape(d.Settings["room"]),
+		url.PathEscape("zerabot."+alert.ProposalID),
+	)
+
+	message := matrixMessage{
+		MsgType:       "m.text",
+		Body:          body,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted,
+	}
+	header := http.Header{"Authorization": {"Bearer " + d.Settings["token"]}}
+
+	return postJSON(ctx, s.client, http.MethodPut, endpoint, message, header)
+}
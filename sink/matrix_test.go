@@ -0,0 +1,106 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+)
+
+func TestMatrixSend(t *testing.T) {
+	backoff := retryBackoff
+	retryBackoff = 0
+	t.Cleanup(func() { retryBackoff = backoff })
+
+	// The first attempt fails, and the retry must reuse its transaction ID
+	standIn := newStandIn(t, http.StatusBadGateway)
+	matrix := NewMatrix(standIn.Client())
+
+	settings := map[string]string{"homeserver": standIn.URL + "/", "room": "!abc:matrix.org", "token": "secret"}
+	if err := matrix.Validate(settings); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	alert := *testAlert
+	alert.Title = "Fund <b>the</b> treasury"
+
+	d := &db.Destination{Channel: db.MatrixChannel, Settings: settings}
+	if err := matrix.Send(context.Background(), d, &alert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	requests := standIn.received()
+	if len(requests) != 2 {
+		t.Fatalf("stand-in got %d requests, want a failed attempt and a retry", len(requests))
+	}
+
+	const path = "/_matrix/client/v3/rooms/%21abc:matrix.org/send/m.room.message/zerabot.abc123"
+	for i, r := range requests {
+		if r.Method != http.MethodPut || r.Path != path {
+			t.Errorf("request %d is %s %s, want PUT %s", i+1, r.Method, r.Path, path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("request %d Authorization = %q, want the access token", i+1, got)
+		}
+	}
+
+	var got matrixMessage
+	if err := json.Unmarshal(requests[1].Body, &got); err != nil {
+		t.Fatalf("body %s: %v", requests[1].Body, err)
+	}
+	if got.MsgType != "m.text" || got.Format != "org.matrix.custom.html" {
+		t.Errorf("msgtype %q and format %q, want m.text with HTML", got.MsgType, got.Format)
+	}
+
+	wantBody := "New proposal for $ZRA+0000: Fund <b>the</b> treasury\n\nMove 1000 ZRA to the community treasury.\n\nProposal ID: abc123\n" + alert.URL()
+	if got.Body != wantBody {
+		t.Errorf("body = %q, want %q", got.Body, wantBody)
+	}
+	for _, want := range []string{
+		"<strong>New proposal for $ZRA+0000</strong>",
+		`<a href="` + alert.URL() + `">Fund &lt;b&gt;the&lt;/b&gt; treasury</a>`,
+		"<code>abc123</code>",
+	} {
+		if !strings.Contains(got.FormattedBody, want) {
+			t.Errorf("formatted body %q doesn't contain %q", got.FormattedBody, want)
+		}
+	}
+}
+
+func TestMatrixValidate(t *testing.T) {
+	valid := map[string]string{"homeserver": "https://matrix.org", "room": "!abc:matrix.org", "token": "secret"}
+
+	cases := []struct {
+		name    string
+		setting string
+		value   string
+		valid   bool
+	}{
+		{"Valid", "", "", true},
+		{"PlainHTTPHomeserver", "homeserver", "http://localhost:8008", true},
+		{"NoHomeserver", "homeserver", "", false},
+		{"RelativeHomeserver", "homeserver", "matrix.org", false},
+		{"RoomAlias", "room", "#zera:matrix.org", false},
+		{"RoomWithoutServer", "room", "!abc", false},
+		{"NoToken", "token", "", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settings := make(map[string]string)
+			for k, v := range valid {
+				settings[k] = v
+			}
+			if c.setting != "" {
+				settings[c.setting] = c.value
+			}
+
+			if err := NewMatrix(nil).Validate(settings); (err == nil) != c.valid {
+				t.Errorf("Validate = %v, want valid %v", err, c.valid)
+			}
+		})
+	}
+}
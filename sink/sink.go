@@ -0,0 +1,219 @@
+// Package sink delivers proposal alerts to destinations outside Telegram, such as
+// Discord webhooks, that operators register and subscribe to symbols
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+// sendTimeout bounds delivery to a single destination, retries included
+const sendTimeout = 10 * time.Second
+
+// maxSendAttempts is how many times a request is sent before its failure is reported
+const maxSendAttempts = 3
+
+// retryBackoff is the wait before the first retry, doubled for each further one
+var retryBackoff = time.Second
+
+// queueSize is how many alerts a channel holds while they wait to be sent
+const queueSize = 256
+
+// maxConcurrentSends is how many destinations an alert is sent to at once
+const maxConcurrentSends = 8
+
+// errQueueFull is returned when an alert can't be queued because sending is behind
+var errQueueFull = errors.New("alert queue is full")
+
+// Sender delivers an alert to one destination of its channel
+type Sender interface {
+	// Validate checks the settings of a destination before it is registered
+	Validate(settings map[string]string) error
+	// Send delivers an alert to a destination
+	Send(ctx context.Context, d *db.Destination, alert *notify.ProposalAlert) error
+}
+
+// Channel is a notification channel delivering alerts to the destinations
+// subscribed to a symbol through a sender
+type Channel struct {
+	channel      db.Channel
+	subs         db.SubscriptionStore
+	destinations db.DestinationStore
+	sender       Sender
+	queue        chan *notify.ProposalAlert
+}
+
+// NewChannel creates a channel. subs must hold the channel's subscriptions, e.g.
+// a SubscriptionRepository from ForChannel.
+func NewChannel(channel db.Channel, subs db.SubscriptionStore, destinations db.DestinationStore, sender Sender) *Channel {
+	return &Channel{
+		channel:      channel,
+		subs:         subs,
+		destinations: destinations,
+		sender:       sender,
+		queue:        make(chan *notify.ProposalAlert, queueSize),
+	}
+}
+
+// Name identifies the channel among notification channels
+func (c *Channel) Name() string {
+	return string(c.channel)
+}
+
+// NotifySubscribers queues an alert for Run to send, so slow destinations don't hold
+// up block processing. It fails if the queue is full.
+func (c *Channel) NotifySubscribers(alert *notify.ProposalAlert) error {
+	select {
+	case c.queue <- alert:
+		return nil
+	default:
+		return fmt.Errorf("%w, alert %s dropped", errQueueFull, alert.ProposalID)
+	}
+}
+
+// Run sends queued alerts until the context is cancelled
+func (c *Channel) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alert := <-c.queue:
+			c.send(ctx, alert)
+		}
+	}
+}
+
+// send sends an alert to every destination with a matching subscription whose filter
+// it passes, several at a time. Failed deliveries are logged and don't stop the others.
+func (c *Channel) send(ctx context.Context, alert *notify.ProposalAlert) {
+	resolved, err := c.subs.ResolveSubscribers(ctx, alert.Symbol, db.ProposalType)
+	if err != nil {
+		log.Printf("Failed to get %s subscribers of %s: %v", c.channel, alert.Symbol, err)
+		return
+	}
+
+	candidate := alert.FilterCandidate()
+	slots := make(chan struct{}, maxConcurrentSends)
+	var wg sync.WaitGroup
+	for _, subscriber := range resolved {
+		for _, match := range subscriber.Matches {
+			if match.Subscription.Filter.Match(candidate) {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func(destinationID int64) {
+					defer func() { <-slots; wg.Done() }()
+					c.deliver(ctx, destinationID, alert)
+				}(subscriber.ChatID)
+				break
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// deliver sends an alert to one destination
+func (c *Channel) deliver(ctx context.Context, destinationID int64, alert *notify.ProposalAlert) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	d, err := c.destinations.Get(ctx, destinationID)
+	if err != nil {
+		log.Printf("Failed to get %s destination %d: %v", c.channel, destinationID, err)
+		return
+	}
+
+	if err := c.sender.Send(ctx, d, alert); err != nil {
+		log.Printf("Failed to send alert to %s destination %s: %v", c.channel, d.Name, err)
+	}
+}
+
+// postJSON posts a JSON body and fails on responses other than 2xx. Network errors, 429
+// and 5xx responses are retried up to maxSendAttempts times while ctx allows.
+func postJSON(ctx context.Context, client *http.Client, method, url string, body interface{}, header http.Header) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		wait, err := send(ctx, client, method, url, data, header)
+		if err == nil {
+			return nil
+		}
+		if wait < 0 || attempt == maxSendAttempts {
+			return err
+		}
+
+		if wait == 0 {
+			wait = retryBackoff << (attempt - 1)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt of a request. On failure it returns how long to wait before
+// retrying: 0 for the default backoff, or -1 if the request must not be retried.
+func send(ctx context.Context, client *http.Client, method, url string, data []byte, header http.Header) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return -1, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return 0, nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(message))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, err
+	case resp.StatusCode >= 500:
+		return 0, err
+	default:
+		return -1, err
+	}
+}
+
+// Senders returns the senders of every channel outside Telegram
+func Senders(client *http.Client) map[db.Channel]Sender {
+	return map[db.Channel]Sender{
+		db.DiscordChannel: NewDiscord(client),
+		db.SlackChannel:   NewSlack(client),
+		db.MatrixChannel:  NewMatrix(client),
+	}
+}
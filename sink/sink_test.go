@@ -0,0 +1,167 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/memstore"
+	"github.com/ZeraVision/ZeraBot/filter"
+)
+
+// destinations is a db.DestinationStore in memory
+type destinations map[int64]*db.Destination
+
+func (m destinations) Get(ctx context.Context, id int64) (*db.Destination, error) {
+	d, ok := m[id]
+	if !ok {
+		return nil, db.ErrDestinationNotFound
+	}
+	return d, nil
+}
+
+func TestChannelNotifiesSubscribedDestinations(t *testing.T) {
+	ctx := context.Background()
+	standIn := newStandIn(t)
+	// Destination 2's requests are rejected without stopping the others
+	rejecting := newStandIn(t, http.StatusBadRequest)
+	discord := NewDiscord(standIn.Client())
+
+	const (
+		exact    = 1 // Subscribed to the alert's symbol
+		failing  = 2 // Subscribed through a pattern, its delivery is rejected
+		missing  = 3 // Subscribed, but the destination was removed
+		all      = 4 // Subscribed to everything
+		filtered = 5 // Subscribed with a filter the alert fails
+		other    = 6 // Subscribed to another symbol
+	)
+	subs := memstore.NewSubscriptionStore()
+	dests := destinations{}
+	for id, symbol := range map[int64]string{exact: "$ZRA+0000", failing: "$ZRA+*", missing: "$ZRA+0000", all: "all", filtered: "$ZRA+0000", other: "$ZIP+0000"} {
+		if _, err := subs.Subscribe(ctx, id, db.ProposalType, symbol); err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		if id != missing {
+			server := standIn
+			if id == failing {
+				server = rejecting
+			}
+			url := fmt.Sprintf("%s/api/webhooks/%d/token", server.URL, id)
+			dests[id] = &db.Destination{ID: id, Channel: db.DiscordChannel, Settings: map[string]string{"url": url}}
+		}
+	}
+	f, err := filter.Parse("burn")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := subs.SetFilter(ctx, filtered, db.ProposalType, "$ZRA+0000", f); err != nil {
+		t.Fatalf("SetFilter: %v", err)
+	}
+
+	channel := NewChannel(db.DiscordChannel, subs, dests, discord)
+	channel.send(ctx, testAlert)
+
+	var paths []string
+	for _, r := range append(standIn.received(), rejecting.received()...) {
+		paths = append(paths, r.Path)
+	}
+	sort.Strings(paths) // Destinations are sent to concurrently
+	want := []string{"/api/webhooks/1/token", "/api/webhooks/2/token", "/api/webhooks/4/token"}
+	if fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Errorf("requests to %v, want %v", paths, want)
+	}
+}
+
+func TestChannelQueuesAlerts(t *testing.T) {
+	ctx := context.Background()
+	standIn := newStandIn(t)
+	subs := memstore.NewSubscriptionStore()
+	if _, err := subs.Subscribe(ctx, 1, db.ProposalType, "all"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	dests := destinations{1: {ID: 1, Channel: db.DiscordChannel, Settings: map[string]string{"url": standIn.URL + "/api/webhooks/1/token"}}}
+	channel := NewChannel(db.DiscordChannel, subs, dests, NewDiscord(standIn.Client()))
+
+	// Queued without sending until Run picks the alerts up
+	for i := 0; i < queueSize; i++ {
+		if err := channel.NotifySubscribers(testAlert); err != nil {
+			t.Fatalf("NotifySubscribers %d: %v", i+1, err)
+		}
+	}
+	if err := channel.NotifySubscribers(testAlert); !errors.Is(err, errQueueFull) {
+		t.Fatalf("NotifySubscribers with a full queue = %v, want errQueueFull", err)
+	}
+	if n := len(standIn.received()); n != 0 {
+		t.Fatalf("%d requests before Run, want none", n)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		channel.Run(runCtx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(standIn.received()) < queueSize {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests, want %d once Run sent the queue", len(standIn.received()), queueSize)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPostJSONRetries(t *testing.T) {
+	backoff := retryBackoff
+	t.Cleanup(func() { retryBackoff = backoff })
+
+	cases := []struct {
+		name         string
+		statuses     []int
+		backoff      time.Duration
+		wantErr      bool
+		wantRequests int
+	}{
+		{"Delivered", nil, time.Millisecond, false, 1},
+		{"RetriedAfterServerError", []int{http.StatusServiceUnavailable}, time.Millisecond, false, 2},
+		{"RetriedWhenRateLimited", []int{http.StatusTooManyRequests, http.StatusBadGateway}, time.Millisecond, false, 3},
+		{"GivenUpAfterLastAttempt", []int{500, 500, 500, 200}, time.Millisecond, true, maxSendAttempts},
+		{"RejectedIsNotRetried", []int{http.StatusBadRequest}, time.Millisecond, true, 1},
+		{"UnauthorizedIsNotRetried", []int{http.StatusUnauthorized}, time.Millisecond, true, 1},
+		{"NoTimeLeftToRetry", []int{http.StatusServiceUnavailable}, time.Hour, true, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			standIn := newStandIn(t, c.statuses...)
+			retryBackoff = c.backoff
+
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+
+			err := postJSON(ctx, standIn.Client(), http.MethodPost, standIn.URL, map[string]string{"text": "hi"}, nil)
+			if (err != nil) != c.wantErr {
+				t.Errorf("postJSON = %v, want error %v", err, c.wantErr)
+			}
+
+			requests := standIn.received()
+			if len(requests) != c.wantRequests {
+				t.Fatalf("stand-in got %d requests, want %d", len(requests), c.wantRequests)
+			}
+			for i, r := range requests {
+				if string(r.Body) != `{"text":"hi"}` {
+					t.Errorf("request %d body = %s, want the same body on every attempt", i+1, r.Body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+// Slack sends alerts to Slack incoming webhooks formatted with Block Kit.
+// Destinations need a "url" setting with the webhook URL.
+type Slack struct {
+	client *http.Client
+}
+
+// NewSlack creates a Slack sender. A nil client uses http.DefaultClient.
+func NewSlack(client *http.Client) *Slack {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Slack{client: client}
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // Fallback for notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string        `json:"type"`
+	Text     *slackText    `json:"text,omitempty"`
+	Elements []interface{} `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackButton struct {
+	Type string    `json:"type"`
+	Text slackText `json:"text"`
+	URL  string    `json:"url"`
+}
+
+// Validate checks that the destination has an https webhook URL
+func (s *Slack) Validate(settings map[string]string) error {
+	u, err := url.Parse(settings["url"])
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("url must be a Slack incoming webhook URL (https://hooks.slack.com/services/...)")
+	}
+	return nil
+}
+
+// Send posts the alert as a header with the title, the synopsis, the contract and
+// proposal ID, and a button linking to the explorer
+func (s *Slack) Send(ctx context.Context, d *db.Destination, alert *notify.ProposalAlert) error {
+	title := alert.Title
+	if title == "" {
+		title = "New proposal for " + alert.Symbol
+	}
+
+	blocks := []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: util.Truncate(title, 150)}},
+	}
+	if alert.Synopsis != "" {
+		blocks = append(blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: escapeSlack(util.Truncate(alert.Synopsis, 500))},
+		})
+	}
+	blocks = append(blocks,
+		slackBlock{
+			Type: "context",
+			Elements: []interface{}{
+				slackText{Type: "mrkdwn", Text: "*Contract:* " + escapeSlack(alert.Symbol)},
+				slackText{Type: "mrkdwn", Text: "*Proposal:* `" + alert.ProposalID + "`"},
+			},
+		},
+		slackBlock{
+			Type: "actions",
+			Elements: []interface{}{
+				slackButton{Type: "button", Text: slackText{Type: "plain_text", Text: "View on explorer"}, URL: alert.URL()},
+			},
+		},
+	)
+
+	message := slackMessage{
+		Text:   escapeSlack("New proposal for " + alert.Symbol + ": " + title),
+		Blocks: blocks,
+	}
+
+	return postJSON(ctx, s.client, http.MethodPost, d.Settings["url"], message, nil)
+}
+
+// escapeSlack escapes the characters Slack treats as control characters in mrkdwn text
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,98 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+)
+
+func TestSlackSend(t *testing.T) {
+	standIn := newStandIn(t)
+	slack := NewSlack(standIn.Client())
+
+	settings := map[string]string{"url": standIn.URL + "/services/T000/B000/XXX"}
+	if err := slack.Validate(settings); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	alert := *testAlert
+	alert.Title = "Raise <limits> & fees"
+	alert.Synopsis = "Fees go from 1 to 2 if votes > 50%."
+
+	d := &db.Destination{Channel: db.SlackChannel, Settings: settings}
+	if err := slack.Send(context.Background(), d, &alert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	requests := standIn.received()
+	if len(requests) != 1 || requests[0].Method != http.MethodPost || requests[0].Path != "/services/T000/B000/XXX" {
+		t.Fatalf("requests = %+v, want one POST to the webhook URL", requests)
+	}
+
+	// Plain text is sent as is, mrkdwn is escaped
+	want := `{"text":"New proposal for $ZRA+0000: Raise &lt;limits&gt; &amp; fees","blocks":[` +
+		`{"type":"header","text":{"type":"plain_text","text":"Raise <limits> & fees"}},` +
+		`{"type":"section","text":{"type":"mrkdwn","text":"Fees go from 1 to 2 if votes &gt; 50%."}},` +
+		`{"type":"context","elements":[{"type":"mrkdwn","text":"*Contract:* $ZRA+0000"},{"type":"mrkdwn","text":"*Proposal:* ` + "`abc123`" + `"}]},` +
+		`{"type":"actions","elements":[{"type":"button","text":{"type":"plain_text","text":"View on explorer"},"url":"` + alert.URL() + `"}]}]}`
+
+	var got, wantMessage interface{}
+	if err := json.Unmarshal(requests[0].Body, &got); err != nil {
+		t.Fatalf("body %s: %v", requests[0].Body, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantMessage); err != nil {
+		t.Fatalf("want: %v", err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(wantMessage)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("body = %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestSlackSendWithoutSynopsis(t *testing.T) {
+	standIn := newStandIn(t)
+	slack := NewSlack(standIn.Client())
+
+	alert := *testAlert
+	alert.Synopsis = ""
+
+	d := &db.Destination{Channel: db.SlackChannel, Settings: map[string]string{"url": standIn.URL}}
+	if err := slack.Send(context.Background(), d, &alert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var got slackMessage
+	if err := json.Unmarshal(standIn.received()[0].Body, &got); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	var types []string
+	for _, block := range got.Blocks {
+		types = append(types, block.Type)
+	}
+	if strings.Join(types, ",") != "header,context,actions" {
+		t.Errorf("blocks %v, want no section without a synopsis", types)
+	}
+}
+
+func TestSlackValidate(t *testing.T) {
+	cases := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://hooks.slack.com/services/T000/B000/XXX", true},
+		{"http://hooks.slack.com/services/T000/B000/XXX", false},
+		{"hooks.slack.com/services/T000/B000/XXX", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		if err := NewSlack(nil).Validate(map[string]string{"url": c.url}); (err == nil) != c.valid {
+			t.Errorf("Validate(%q) = %v, want valid %v", c.url, err, c.valid)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package symbol
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Wildcard matches any ticker or series in a symbol pattern
+const Wildcard = "*"
+
+// IsValid checks if a symbol follows the format $LETTERS+4DIGITS
+func IsValid(symbol string) bool {
+	ticker, series, ok := split(symbol)
+	return ok && isTicker(ticker) && isSeries(series)
+}
+
+// IsPattern checks if a symbol is a valid pattern: $TICKER+NNNN where the
+// ticker is "*", letters ending in "*" (a prefix), or letters, and the series is
+// "*" or four digits. At least one wildcard is required and "$*+*" is rejected
+// in favour of subscribing to 'all'.
+func IsPattern(symbol string) bool {
+	ticker, series, ok := split(symbol)
+	if !ok || !strings.Contains(symbol, Wildcard) || (ticker == Wildcard && series == Wildcard) {
+		return false
+	}
+
+	tickerOK := isTicker(ticker) || ticker == Wildcard ||
+		(strings.HasSuffix(ticker, Wildcard) && isTicker(strings.TrimSuffix(ticker, Wildcard)))
+	seriesOK := isSeries(series) || series == Wildcard
+
+	return tickerOK && seriesOK
+}
+
+// IsTicker checks if a symbol is a bare ticker without a series, such as $ZRA
+func IsTicker(symbol string) bool {
+	ticker, ok := strings.CutPrefix(symbol, "$")
+	return ok && isTicker(ticker)
+}
+
+// Normalize converts user input such as "zra", "$zra", or "zra+0000" to the canonical
+// form "$ZRA" or "$ZRA+0000": a leading $, an uppercase ticker, and no whitespace
+func Normalize(input string) string {
+	ticker, series, hasSeries := strings.Cut(strings.TrimPrefix(strings.TrimSpace(input), "$"), "+")
+
+	symbol := "$" + strings.ToUpper(strings.TrimSpace(ticker))
+	if hasSeries {
+		symbol += "+" + strings.TrimSpace(series)
+	}
+	return symbol
+}
+
+// Match reports whether a symbol matches a pattern. Non-pattern input only matches itself.
+func Match(pattern, symbol string) bool {
+	if !IsPattern(pattern) {
+		return pattern == symbol
+	}
+
+	patternTicker, patternSeries, _ := split(pattern)
+	ticker, series, ok := split(symbol)
+	if !ok {
+		return false
+	}
+
+	if patternSeries != Wildcard && patternSeries != series {
+		return false
+	}
+
+	if prefix, isPrefix := strings.CutSuffix(patternTicker, Wildcard); isPrefix {
+		return strings.HasPrefix(ticker, prefix)
+	}
+	return patternTicker == ticker
+}
+
+// split splits "$TICKER+SERIES" into its parts
+func split(symbol string) (ticker, series string, ok bool) {
+	rest, ok := strings.CutPrefix(symbol, "$")
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, "+")
+}
+
+func isTicker(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if !unicode.IsLetter(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSeries(s string) bool {
+	if len(s) != 4 {
+		return false
+	}
+	for _, c := range s {
+		if !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
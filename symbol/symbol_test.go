@@ -0,0 +1,148 @@
+package symbol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsValid(t *testing.T) {
+	cases := map[string]bool{
+		"$ZRA+0000":  true,
+		"$zra+0000":  true, // Case is left to Normalize
+		"$A+9999":    true,
+		"ZRA+0000":   false,
+		"$ZRA":       false,
+		"$ZRA+000":   false,
+		"$ZRA+00000": false,
+		"$ZRA+00a0":  false,
+		"$Z1A+0000":  false,
+		"$+0000":     false,
+		"$ZRA+*":     false,
+		"$ZR%+0000":  false,
+		"$ZR_+0000":  false,
+		"$ZRA+00_0":  false,
+		"$ZRA+000%":  false,
+		" $ZRA+0000": false,
+		"":           false,
+	}
+	for s, want := range cases {
+		if got := IsValid(s); got != want {
+			t.Errorf("IsValid(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	cases := map[string]bool{
+		"$ZRA+*":    true,
+		"$*+0000":   true,
+		"$ZR*+0000": true,
+		"$ZR*+*":    true,
+		"$*+*":      false, // Subscribe to 'all' instead
+		"$ZRA+0000": false, // No wildcard
+		"$Z*A+0000": false, // Wildcards only end a ticker
+		"$**+0000":  false,
+		"$ZRA+00*":  false,
+		"$ZRA*":     false,
+		"ZRA+*":     false,
+		"$*":        false,
+		"$%+0000":   false, // LIKE metacharacters would change the SQL match
+		"$ZR%+*":    false,
+		"$ZR_+*":    false,
+		"$_*+0000":  false,
+		"$ZRA+%":    false,
+		"$ZRA+_":    false,
+		"$ZRA+00_0": false,
+	}
+	for s, want := range cases {
+		if got := IsPattern(s); got != want {
+			t.Errorf("IsPattern(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"zra":           "$ZRA",
+		"$zra":          "$ZRA",
+		"  Zra  ":       "$ZRA",
+		"zra+0000":      "$ZRA+0000",
+		"$zra+0000":     "$ZRA+0000",
+		" $zra + 0000 ": "$ZRA+0000",
+		"zr*+*":         "$ZR*+*",
+		"$ZRA+0000":     "$ZRA+0000",
+	}
+	for input, want := range cases {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// matchCases are patterns and symbols with whether they match
+var matchCases = []struct {
+	pattern, symbol string
+	want            bool
+}{
+	{"$ZRA+*", "$ZRA+0000", true},
+	{"$ZRA+*", "$ZRA+1234", true},
+	{"$ZRA+*", "$ZRAB+0000", false},
+	{"$ZRA+*", "$ZR+0000", false},
+	{"$ZRA+*", "$zra+0000", false}, // Matching is case-sensitive, like LIKE
+	{"$*+0000", "$ZRA+0000", true},
+	{"$*+0000", "$A+0000", true},
+	{"$*+0000", "$ZRA+0001", false},
+	{"$ZR*+0000", "$ZR+0000", true}, // The prefix may be the whole ticker
+	{"$ZR*+0000", "$ZRA+0000", true},
+	{"$ZR*+0000", "$ZRABC+0000", true},
+	{"$ZR*+0000", "$ZA+0000", false},
+	{"$ZR*+0000", "$ZRA+0001", false},
+	{"$ZR*+*", "$ZRX+9999", true},
+	{"$ZR*+*", "$XZR+9999", false},
+	{"$ZRA+0000", "$ZRA+0000", true}, // Not a pattern, only matches itself
+	{"$ZRA+0000", "$ZRA+0001", false},
+	{"$*+*", "$ZRA+0000", false}, // Rejected as a pattern, so only matches itself
+}
+
+func TestMatch(t *testing.T) {
+	for _, c := range matchCases {
+		if got := Match(c.pattern, c.symbol); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.symbol, got, c.want)
+		}
+	}
+}
+
+// TestMatchAgreesWithSQL checks Match against the LIKE that ResolveSubscribers
+// evaluates in Postgres for stored patterns: symbol LIKE replace(pattern, '*', '%')
+func TestMatchAgreesWithSQL(t *testing.T) {
+	for _, c := range matchCases {
+		if !IsPattern(c.pattern) {
+			continue // Stored subscriptions without a * are compared with =
+		}
+		sql := like(c.symbol, strings.ReplaceAll(c.pattern, Wildcard, "%"))
+		if got := Match(c.pattern, c.symbol); got != sql {
+			t.Errorf("Match(%q, %q) = %v, but LIKE gives %v", c.pattern, c.symbol, got, sql)
+		}
+	}
+}
+
+// like evaluates SQL LIKE: % matches any run of characters, _ any single character
+// and everything else itself, case-sensitively
+func like(s, pattern string) bool {
+	if pattern == "" {
+		return s == ""
+	}
+	switch pattern[0] {
+	case '%':
+		for i := 0; i <= len(s); i++ {
+			if like(s[i:], pattern[1:]) {
+				return true
+			}
+		}
+		return false
+	case '_':
+		return s != "" && like(s[1:], pattern[1:])
+	default:
+		return s != "" && s[0] == pattern[0] && like(s[1:], pattern[1:])
+	}
+}
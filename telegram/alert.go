@@ -0,0 +1,35 @@
+package telegram
+
+import (
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+// formatProposalAlert formats a proposal alert into a user-friendly message in the given language and format
+func formatProposalAlert(lang string, format db.MessageFormat, alert *notify.ProposalAlert) string {
+	key := "proposal.new"
+	if format == db.CompactFormat {
+		key = "proposal.new_compact"
+	}
+
+	return i18n.T(lang, key,
+		alert.Symbol,
+		util.EscapeMarkdown(util.Truncate(alert.Title, 200)),
+		util.EscapeMarkdown(util.Truncate(alert.Synopsis, 500)),
+		alert.ProposalID,
+		alert.URL(),
+	)
+}
+
+// newPendingAlert converts a proposal alert into an alert queued for a chat
+func newPendingAlert(chatID int64, alert *notify.ProposalAlert) *db.PendingAlert {
+	return &db.PendingAlert{
+		ChatID:     chatID,
+		Symbol:     alert.Symbol,
+		ProposalID: alert.ProposalID,
+		Title:      alert.Title,
+		Synopsis:   alert.Synopsis,
+	}
+}
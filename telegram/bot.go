@@ -5,23 +5,20 @@ import (
 	"fmt"
 	"log"
 	"strings"
-	"unicode"
+	"sync/atomic"
 
+	"github.com/ZeraVision/ZeraBot/abuse"
 	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/email"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/metrics"
+	"github.com/ZeraVision/ZeraBot/registry"
+	"github.com/ZeraVision/ZeraBot/symbol"
 	"github.com/ZeraVision/ZeraBot/util"
+	"github.com/ZeraVision/ZeraBot/webhook"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-var bot *Bot
-
-func GetBot() *Bot {
-	return bot
-}
-
-func SetBot(b *Bot) {
-	bot = b
-}
-
 // NewSetWebhook creates a new webhook configuration
 func NewSetWebhook(url string) tgbotapi.WebhookConfig {
 	wh, _ := tgbotapi.NewWebhook(url)
@@ -51,46 +48,108 @@ func (b *Bot) SetupWebhook(webhookURL string) error {
 }
 
 type Bot struct {
-	API      *tgbotapi.BotAPI
-	database *db.Database
-	subRepo  *db.SubscriptionRepository
+	API          *tgbotapi.BotAPI
+	database     *db.Database
+	subRepo      db.SubscriptionStore
+	settingsRepo *db.ChatSettingsRepository
+	pendingRepo  *db.PendingAlertRepository
+	proposalRepo *db.ProposalRepository
+	registry     *registry.Registry
+	guard        *abuse.Guard
+	webhooks     *webhook.Service
+	emails       *email.Service
+	onlyChatID   int64
+	maintenance  atomic.Pointer[maintenanceMode]
+}
+
+// Services are the stores and helpers a bot depends on
+type Services struct {
+	Database      *db.Database // Used to run digest deliveries in a transaction
+	Subscriptions db.SubscriptionStore
+	Settings      *db.ChatSettingsRepository
+	PendingAlerts *db.PendingAlertRepository
+	Proposals     *db.ProposalRepository
+	Registry      *registry.Registry
+	Guard         *abuse.Guard
+	Webhooks      *webhook.Service // Optional, /webhook is unavailable without it
+	Email         *email.Service   // Optional, /email is unavailable without it
+}
+
+// Options adjusts the bot's behaviour
+type Options struct {
+	OnlyChatID int64 // If set, proposal alerts are only sent to this chat (development)
 }
 
-// NewBot creates a new Telegram bot instance with the provided token and database
-func NewBot(token string, debug bool, database *db.Database) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+// NewBotAPI creates a Telegram Bot API client. apiEndpoint is a Bot API endpoint
+// format such as tgbotapi.APIEndpoint, empty for the default.
+func NewBotAPI(token, apiEndpoint string, debug bool) (*tgbotapi.BotAPI, error) {
+	if apiEndpoint == "" {
+		apiEndpoint = tgbotapi.APIEndpoint
+	}
+
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, apiEndpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
 	api.Debug = debug
+	return api, nil
+}
 
-	bot := &Bot{
-		API:      api,
-		database: database,
-		subRepo:  db.NewSubscriptionRepository(database),
+// NewBot creates a new Telegram bot instance using a Bot API client, e.g. one
+// pointed at a telegramtest server, and the services it depends on
+func NewBot(api *tgbotapi.BotAPI, services Services, opts Options) *Bot {
+	return &Bot{
+		API:          api,
+		database:     services.Database,
+		subRepo:      services.Subscriptions,
+		settingsRepo: services.Settings,
+		pendingRepo:  services.PendingAlerts,
+		proposalRepo: services.Proposals,
+		registry:     services.Registry,
+		guard:        services.Guard,
+		webhooks:     services.Webhooks,
+		emails:       services.Email,
+		onlyChatID:   opts.OnlyChatID,
 	}
+}
+
+// SetSubscriptionStore replaces where subscriptions are stored, e.g. with an in-memory store
+func (b *Bot) SetSubscriptionStore(store db.SubscriptionStore) {
+	b.subRepo = store
+}
+
+// Guard returns the rate limiter and ban list
+func (b *Bot) Guard() *abuse.Guard {
+	return b.guard
+}
+
+// Registry returns the registry of known contracts
+func (b *Bot) Registry() *registry.Registry {
+	return b.registry
+}
 
-	SetBot(bot)
-	return bot, nil
+// Proposals returns the repository of recorded proposals
+func (b *Bot) Proposals() *db.ProposalRepository {
+	return b.proposalRepo
 }
 
 // SendToChatID sends a message to a specific chat ID
-func SendToChatID(chatID int64, message string) error {
-	if bot == nil {
-		return fmt.Errorf("bot not initialized")
-	}
+func (b *Bot) SendToChatID(chatID int64, message string) error {
+	return b.sendWithFallback(tgbotapi.NewMessage(chatID, message))
+}
 
-	// First try with Markdown parsing
-	msg := tgbotapi.NewMessage(chatID, message)
+// sendWithFallback sends a message with Markdown parsing, retrying without
+// parsing if Telegram rejects the formatting
+func (b *Bot) sendWithFallback(msg tgbotapi.MessageConfig) error {
 	msg.ParseMode = "Markdown"
 
-	_, err := bot.API.Send(msg)
+	_, err := b.API.Send(msg)
 	if err != nil {
 		// If Markdown parsing fails, try without parsing
-		log.Printf("Markdown parsing failed for chat %d, retrying without parsing: %v", chatID, err)
+		log.Printf("Markdown parsing failed for chat %d, retrying without parsing: %v", msg.ChatID, err)
 		msg.ParseMode = ""
-		_, err = bot.API.Send(msg)
+		_, err = b.API.Send(msg)
 	}
 
 	return err
@@ -118,50 +177,34 @@ func (b *Bot) isGroupAdmin(chatID int64, userID int64) (bool, error) {
 	return member.IsAdministrator() || member.IsCreator(), nil
 }
 
-// isValidSymbolFormat checks if a symbol follows the format $LETTERS+4DIGITS
-func isValidSymbolFormat(symbol string) bool {
-	if len(symbol) < 6 { // Minimum length: $A+0000 (6 chars)
-		return false
-	}
-
-	// Check for leading $
-	if symbol[0] != '$' {
-		return false
-	}
+// isValidSubscriptionSymbol checks if a symbol can be subscribed to: an exact
+// symbol such as $ZRA+0000 or a pattern such as $ZRA+*
+func isValidSubscriptionSymbol(s string) bool {
+	return symbol.IsValid(s) || symbol.IsPattern(s)
+}
 
-	// Find the + separator
-	plusIndex := strings.Index(symbol, "+")
-	if plusIndex == -1 || plusIndex == 1 { // Must have letters before +
-		return false
-	}
+// maxSymbolSuggestions limits how many "did you mean" suggestions are offered
+const maxSymbolSuggestions = 3
 
-	// Check letters part (between $ and +)
-	letters := symbol[1:plusIndex]
-	if len(letters) == 0 {
-		return false
-	}
-	for _, c := range letters {
-		if !unicode.IsLetter(c) {
-			return false
-		}
+// unseenWarning warns about an exact symbol the registry hasn't seen, with "did you mean"
+// suggestions. The registry only knows the contracts seen on chain since the bot was
+// deployed, so an unseen contract may still exist and is subscribed to anyway. Patterns
+// and 'all' also cover contracts that don't exist yet, so they are not checked.
+func (b *Bot) unseenWarning(lang string, s string) string {
+	if !symbol.IsValid(s) || b.registry.Exists(s) {
+		return ""
 	}
 
-	// Check numbers part (after +)
-	numbers := symbol[plusIndex+1:]
-	if len(numbers) != 4 {
-		return false
-	}
-	for _, c := range numbers {
-		if !unicode.IsDigit(c) {
-			return false
-		}
+	suggestions := b.registry.Suggest(s, maxSymbolSuggestions)
+	if len(suggestions) == 0 {
+		return i18n.T(lang, "symbol.unseen", util.EscapeMarkdown(s))
 	}
 
-	return true
+	return i18n.T(lang, "symbol.unseen_did_you_mean", util.EscapeMarkdown(s), util.EscapeMarkdown(strings.Join(suggestions, ", ")))
 }
 
-// processSymbols processes a comma-separated string of symbols, removes any whitespace,
-// and ensures the symbol part is uppercase (e.g., $zra+0000 becomes $ZRA+0000)
+// processSymbols splits a comma-separated string of symbols and normalizes each one
+// (e.g., "zra, $zip+0000" becomes $ZRA and $ZIP+0000). The "all" keyword is kept as is.
 func processSymbols(input string) []string {
 	symbols := strings.Split(input, ",")
 	var result []string
@@ -171,15 +214,8 @@ func processSymbols(input string) []string {
 			continue
 		}
 
-		// Convert to uppercase if it's not the "all" keyword
 		if strings.ToLower(s) != "all" {
-			// Split into parts before and after +
-			parts := strings.SplitN(s, "+", 2)
-			if len(parts) == 2 {
-				// Convert the symbol part (after $) to uppercase, keep the numbers as is
-				symbolPart := strings.TrimPrefix(parts[0], "$")
-				s = "$" + strings.ToUpper(symbolPart) + "+" + parts[1]
-			}
+			s = symbol.Normalize(s)
 		}
 		result = append(result, s)
 	}
@@ -187,18 +223,18 @@ func processSymbols(input string) []string {
 }
 
 // handleSubscribe handles the /subscribe command
-func (b *Bot) handleSubscribe(chatID int64, args string) error {
+func (b *Bot) handleSubscribe(chatID int64, lang string, args string) error {
 	symbolsInput := strings.TrimSpace(args)
 	if symbolsInput == "" {
-		return SendToChatID(chatID, "Please provide a symbol to subscribe to (e.g., /proposalSubscribe $ZRA+0000 or /proposalSubscribe $ZRA+0000,$ZIP+0000)")
+		return b.SendToChatID(chatID, i18n.T(lang, "subscribe.usage"))
 	}
 
 	symbols := processSymbols(symbolsInput)
 
-	// Validate symbol format
+	// Validate symbol format, bare tickers are resolved below
 	for _, s := range symbols {
-		if strings.ToLower(s) != "all" && !isValidSymbolFormat(s) {
-			return SendToChatID(chatID, "❌ Invalid symbol format. Please use format $SYMBOL+NNNN (e.g., $ZRA+0000) or 'all'")
+		if strings.ToLower(s) != "all" && !isValidSubscriptionSymbol(s) && !symbol.IsTicker(s) {
+			return b.SendToChatID(chatID, i18n.T(lang, "symbol.invalid_format"))
 		}
 	}
 
@@ -214,56 +250,95 @@ func (b *Bot) handleSubscribe(chatID int64, args string) error {
 	}
 
 	if hasAll {
-		// Unsubscribe from all existing subscriptions first
-		err := b.subRepo.UnsubscribeAll(context.Background(), chatID, db.ProposalType)
+		// Replace all existing subscriptions with 'all' in one transaction
+		results, err := b.subRepo.ReplaceAll(context.Background(), chatID, db.ProposalType, []string{"all"})
 		if err != nil {
-			return fmt.Errorf("failed to clear existing subscriptions: %w", err)
+			return fmt.Errorf("failed to subscribe to all: %w", err)
+		}
+		if results[0].Outcome == db.LimitReached {
+			metrics.SubscriptionLimitHits.Add(1)
+			return b.SendToChatID(chatID, i18n.T(lang, "subscribe.limit_reached", "all", b.subRepo.MaxPerChat()))
 		}
 
-		// Subscribe to "all" (special handling might be needed here)
-		_, err = b.subRepo.Subscribe(context.Background(), chatID, db.ProposalType, "all")
-		if err != nil {
-			return fmt.Errorf("failed to subscribe to all: %w", err)
+		return b.SendToChatID(chatID, i18n.T(lang, "subscribe.all_success"))
+	}
+
+	// Resolve tickers and warn about symbols the registry hasn't seen, once they are subscribed
+	var toSubscribe []string
+	warnings := make(map[string]string)
+	for _, s := range symbols {
+		if symbol.IsTicker(s) {
+			resolved, msg := b.resolveTicker(chatID, lang, s)
+			if resolved == "" {
+				if msg != "" {
+					resultMsgs = append(resultMsgs, msg)
+				}
+				continue
+			}
+			if msg != "" {
+				warnings[resolved] = msg
+			}
+			s = resolved
 		}
 
-		return SendToChatID(chatID, "✅ Subscribed to all proposals.")
+		if msg := b.unseenWarning(lang, s); msg != "" {
+			warnings[s] = msg
+		}
+		toSubscribe = append(toSubscribe, s)
 	}
 
-	// Process individual symbols
-	var successCount int
-	for _, symbol := range symbols {
-		_, err := b.subRepo.Subscribe(context.Background(), chatID, db.ProposalType, symbol)
+	// Subscribe to all symbols at once
+	var subscribed, warned []string
+	if len(toSubscribe) > 0 {
+		results, err := b.subRepo.SubscribeMany(context.Background(), chatID, db.ProposalType, toSubscribe)
 		if err != nil {
-			resultMsgs = append(resultMsgs, fmt.Sprintf("❌ Failed to subscribe to %s: %v", util.EscapeMarkdown(symbol), err))
-		} else {
-			successCount++
+			return err
+		}
+
+		for _, result := range results {
+			if result.Outcome == db.LimitReached {
+				metrics.SubscriptionLimitHits.Add(1)
+				resultMsgs = append(resultMsgs, i18n.T(lang, "subscribe.limit_reached", util.EscapeMarkdown(result.Symbol), b.subRepo.MaxPerChat()))
+				continue
+			}
+			subscribed = append(subscribed, result.Symbol)
+			if msg, ok := warnings[result.Symbol]; ok {
+				warned = append(warned, msg)
+				delete(warnings, result.Symbol)
+			}
 		}
 	}
+	resultMsgs = append(resultMsgs, warned...)
 
-	if successCount > 0 {
-		msg := fmt.Sprintf("✅ Successfully subscribed to %s", util.EscapeMarkdown(symbols[0]))
+	if successCount := len(subscribed); successCount > 0 {
+		msg := i18n.T(lang, "subscribe.success_one", util.EscapeMarkdown(subscribed[0]))
 		if successCount > 1 {
-			msg = fmt.Sprintf("✅ Successfully subscribed to %d symbols", successCount)
+			msg = i18n.N(lang, "subscribe.success_many", successCount, successCount)
 		}
 		resultMsgs = append([]string{msg}, resultMsgs...)
 	}
 
-	return SendToChatID(chatID, strings.Join(resultMsgs, "\n"))
+	// Nothing left to report if every symbol was an ambiguous ticker with a prompt sent
+	if len(resultMsgs) == 0 {
+		return nil
+	}
+
+	return b.SendToChatID(chatID, strings.Join(resultMsgs, "\n"))
 }
 
 // handleUnsubscribe handles the /unsubscribe command
-func (b *Bot) handleUnsubscribe(chatID int64, args string) error {
+func (b *Bot) handleUnsubscribe(chatID int64, lang string, args string) error {
 	symbolsInput := strings.TrimSpace(args)
 	if symbolsInput == "" {
-		return SendToChatID(chatID, "Please provide a symbol to unsubscribe from (e.g., /proposalUnsubscribe $ZRA+0000 or /proposalUnsubscribe $ZRA+0000,$ZIP+0000)")
+		return b.SendToChatID(chatID, i18n.T(lang, "unsubscribe.usage"))
 	}
 
 	symbols := processSymbols(symbolsInput)
 
-	// Validate symbol format
+	// Validate symbol format, bare tickers are resolved below
 	for _, s := range symbols {
-		if strings.ToLower(s) != "all" && !isValidSymbolFormat(s) {
-			return SendToChatID(chatID, "❌ Invalid symbol format. Please use format $SYMBOL+NNNN (e.g., $ZRA+0000) or 'all'")
+		if strings.ToLower(s) != "all" && !isValidSubscriptionSymbol(s) && !symbol.IsTicker(s) {
+			return b.SendToChatID(chatID, i18n.T(lang, "symbol.invalid_format"))
 		}
 	}
 
@@ -283,54 +358,84 @@ func (b *Bot) handleUnsubscribe(chatID int64, args string) error {
 		if err != nil {
 			return fmt.Errorf("failed to unsubscribe from all: %w", err)
 		}
-		return SendToChatID(chatID, "✅ Unsubscribed from all proposals")
+		return b.SendToChatID(chatID, i18n.T(lang, "unsubscribe.all_success"))
 	}
 
-	// Process individual symbols
-	var successCount int
-	for _, symbol := range symbols {
-		err := b.subRepo.Unsubscribe(context.Background(), chatID, db.ProposalType, symbol)
+	// A bare ticker unsubscribes from every subscribed series of it
+	var targets []string
+	for _, s := range symbols {
+		if !symbol.IsTicker(s) {
+			targets = append(targets, s)
+			continue
+		}
+
+		matched, err := b.subscribedWithTicker(chatID, s)
 		if err != nil {
-			resultMsgs = append(resultMsgs, fmt.Sprintf("❌ Failed to unsubscribe from %s: %v", util.EscapeMarkdown(symbol), err))
-		} else {
-			successCount++
+			return err
+		}
+		if len(matched) == 0 {
+			matched = []string{s}
 		}
+		targets = append(targets, matched...)
 	}
 
-	if successCount > 0 {
-		msg := fmt.Sprintf("✅ Successfully unsubscribed from %s", util.EscapeMarkdown(symbols[0]))
+	// Unsubscribe from all symbols at once
+	results, err := b.subRepo.UnsubscribeMany(context.Background(), chatID, db.ProposalType, targets)
+	if err != nil {
+		return err
+	}
+
+	var unsubscribed []string
+	for _, result := range results {
+		if result.Outcome == db.NotSubscribed {
+			resultMsgs = append(resultMsgs, i18n.T(lang, "unsubscribe.not_subscribed", util.EscapeMarkdown(result.Symbol)))
+			continue
+		}
+		unsubscribed = append(unsubscribed, result.Symbol)
+	}
+
+	if successCount := len(unsubscribed); successCount > 0 {
+		msg := i18n.T(lang, "unsubscribe.success_one", util.EscapeMarkdown(unsubscribed[0]))
 		if successCount > 1 {
-			msg = fmt.Sprintf("✅ Successfully unsubscribed from %d symbols", successCount)
+			msg = i18n.N(lang, "unsubscribe.success_many", successCount, successCount)
 		}
 		resultMsgs = append([]string{msg}, resultMsgs...)
 	}
 
 	if len(resultMsgs) == 0 {
-		return SendToChatID(chatID, "No valid symbols provided to unsubscribe from.")
+		return b.SendToChatID(chatID, i18n.T(lang, "unsubscribe.none"))
 	}
 
-	return SendToChatID(chatID, strings.Join(resultMsgs, "\n"))
+	return b.SendToChatID(chatID, strings.Join(resultMsgs, "\n"))
 }
 
 // handleMySubscriptions handles the /mysubscriptions command
-func (b *Bot) handleMySubscriptions(chatID int64) error {
+func (b *Bot) handleMySubscriptions(chatID int64, lang string) error {
 	subs, err := b.subRepo.GetUserSubscriptions(context.Background(), chatID)
 	if err != nil {
 		return fmt.Errorf("failed to get subscriptions: %w", err)
 	}
 
 	if len(subs) == 0 {
-		return SendToChatID(chatID, "You are not subscribed to any proposals yet.\nUse /subscribe [symbol] to subscribe.")
+		return b.SendToChatID(chatID, i18n.T(lang, "subscriptions.empty"))
 	}
 
 	var subList []string
 	for _, sub := range subs {
-		subList = append(subList, fmt.Sprintf("• %s (%s)", util.EscapeMarkdown(sub.Symbol), util.EscapeMarkdown(string(sub.Type))))
+		if sub.Filter.IsEmpty() {
+			subList = append(subList, i18n.T(lang, "subscriptions.item", util.EscapeMarkdown(sub.Symbol), util.EscapeMarkdown(string(sub.Type))))
+			continue
+		}
+		subList = append(subList, i18n.T(lang, "subscriptions.item_filtered",
+			util.EscapeMarkdown(sub.Symbol),
+			util.EscapeMarkdown(string(sub.Type)),
+			util.EscapeMarkdown(sub.Filter.String()),
+		))
 	}
 
-	message := fmt.Sprintf("📋 Your subscriptions (%d):\n%s",
-		len(subs),
+	message := fmt.Sprintf("%s\n%s",
+		i18n.N(lang, "subscriptions.header", len(subs), len(subs)),
 		strings.Join(subList, "\n"))
 
-	return SendToChatID(chatID, message)
+	return b.SendToChatID(chatID, message)
 }
@@ -0,0 +1,61 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/registry"
+)
+
+// memContracts is a db.ContractStore in memory
+type memContracts map[string]*db.Contract
+
+func (m memContracts) Save(ctx context.Context, contract *db.Contract) error {
+	m[contract.ContractID] = contract
+	return nil
+}
+
+func (m memContracts) List(ctx context.Context) ([]*db.Contract, error) {
+	var contracts []*db.Contract
+	for _, contract := range m {
+		contracts = append(contracts, contract)
+	}
+	return contracts, nil
+}
+
+// newRegistry returns a registry knowing the given contracts
+func newRegistry(t *testing.T, contracts ...*db.Contract) *registry.Registry {
+	t.Helper()
+
+	reg := registry.NewRegistry(memContracts{})
+	if err := reg.Add(context.Background(), contracts...); err != nil {
+		t.Fatalf("failed to fill the registry: %v", err)
+	}
+	return reg
+}
+
+func TestUnseenWarning(t *testing.T) {
+	b := &Bot{registry: newRegistry(t, &db.Contract{ContractID: "$ZRA+0000", Symbol: "ZRA"})}
+
+	cases := []struct {
+		name   string
+		symbol string
+		want   string
+	}{
+		{"Known", "$ZRA+0000", ""},
+		{"Pattern", "$ABC+*", ""},
+		{"All", "all", ""},
+		{"Unseen", "$ETH+0000", i18n.T("en", "symbol.unseen", "$ETH+0000")},
+		{"UnseenWithSuggestion", "$ZRB+0000", i18n.T("en", "symbol.unseen_did_you_mean", "$ZRB+0000", "$ZRA+0000")},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := b.unseenWarning("en", c.symbol); got != c.want {
+				t.Errorf("unseenWarning(%q) = %q, want %q", c.symbol, got, c.want)
+			}
+		})
+	}
+}
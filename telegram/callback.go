@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/abuse"
+	"github.com/ZeraVision/ZeraBot/i18n"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleCallback routes inline keyboard presses by the prefix of their callback data
+func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
+	// Callbacks from inline-mode messages carry no chat
+	if query.Message == nil {
+		b.answerCallback(query.ID, "")
+		return
+	}
+
+	decision := b.guard.Check(context.Background(), query.Message.Chat.ID, query.From.ID, time.Now())
+	if decision.Verdict != abuse.Allowed {
+		b.answerCallback(query.ID, "")
+		return
+	}
+
+	if b.inMaintenance() {
+		lang := chatLanguage(b.chatSettings(query.Message.Chat.ID))
+		b.answerCallback(query.ID, i18n.T(lang, "maintenance.callback"))
+		return
+	}
+
+	prefix, data, _ := strings.Cut(query.Data, ":")
+	switch prefix {
+	case settingsCallbackPrefix:
+		b.handleSettingsCallback(query, data)
+	case subscribeCallbackPrefix:
+		b.handleSubscribeCallback(query, data)
+	default:
+		log.Printf("Unknown callback data %q from chat %d", query.Data, query.Message.Chat.ID)
+		b.answerCallback(query.ID, "")
+	}
+}
+
+// answerCallback acknowledges a callback query, optionally showing a short notification
+func (b *Bot) answerCallback(queryID, text string) {
+	if _, err := b.API.Request(tgbotapi.NewCallback(queryID, text)); err != nil {
+		log.Printf("Error answering callback query: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package telegram
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/util"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxDigestItems limits how many proposals are listed in one digest to stay
+// well below Telegram's message size limit
+const maxDigestItems = 25
+
+// RunDigestScheduler sends due digests every interval until the context is cancelled
+func (b *Bot) RunDigestScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			b.sendDueDigests(ctx, now)
+		}
+	}
+}
+
+// sendDueDigests sends a digest to every chat with queued alerts whose digest period has ended
+func (b *Bot) sendDueDigests(ctx context.Context, now time.Time) {
+	chatIDs, err := b.pendingRepo.GetChatsWithPending(ctx)
+	if err != nil {
+		log.Printf("Error getting chats with pending alerts: %v", err)
+		return
+	}
+
+	for _, chatID := range chatIDs {
+		settings, err := b.settingsRepo.Get(ctx, chatID)
+		if err != nil {
+			log.Printf("Error getting settings for chat %d: %v", chatID, err)
+			continue
+		}
+
+		// Start the first period when the chat receives its first queued alert
+		if settings.DeliveryMode != db.InstantDelivery && settings.LastDigestAt.IsZero() {
+			if err := b.settingsRepo.MarkDigestSent(ctx, chatID, now); err != nil {
+				log.Printf("Error starting digest period for chat %d: %v", chatID, err)
+			}
+			continue
+		}
+
+		// Wait until quiet hours or a snooze end before delivering held alerts
+		if holdsAlerts(settings, now) {
+			continue
+		}
+
+		// Instant chats only have alerts queued while quiet, so those are sent right away
+		if settings.DeliveryMode != db.InstantDelivery && !digestDue(settings, now) {
+			continue
+		}
+
+		if err := b.sendDigest(ctx, settings, now); err != nil {
+			log.Printf("Error sending digest to chat %d: %v", chatID, err)
+		}
+	}
+}
+
+// sendDigest sends all queued alerts for a chat as one summary and removes them from the queue
+func (b *Bot) sendDigest(ctx context.Context, settings *db.ChatSettings, now time.Time) error {
+	alerts, err := b.pendingRepo.List(ctx, settings.ChatID)
+	if err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	if !settings.Muted {
+		msg := tgbotapi.NewMessage(settings.ChatID, formatDigest(chatLanguage(settings), settings.DeliveryMode, alerts))
+		msg.DisableWebPagePreview = true
+		msg.DisableNotification = isQuiet(settings, now)
+		if err := b.sendWithFallback(msg); err != nil {
+			return fmt.Errorf("failed to send digest: %w", err)
+		}
+	}
+
+	// Clear the queue and start the next period in one transaction so they can't get out of step
+	return b.database.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := db.NewPendingAlertRepository(tx).DeleteUpTo(ctx, settings.ChatID, alerts[len(alerts)-1].ID); err != nil {
+			return err
+		}
+		return db.NewChatSettingsRepository(tx).MarkDigestSent(ctx, settings.ChatID, now)
+	})
+}
+
+// digestDue reports whether a digest period boundary in the chat's timezone
+// has passed since the last digest was sent
+func digestDue(settings *db.ChatSettings, now time.Time) bool {
+	loc := chatLocation(settings)
+	local := now.In(loc)
+
+	var boundary time.Time
+	switch settings.DeliveryMode {
+	case db.HourlyDigest:
+		boundary = time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, loc)
+	case db.DailyDigest:
+		boundary = time.Date(local.Year(), local.Month(), local.Day(), settings.DigestHour, 0, 0, 0, loc)
+		if local.Before(boundary) {
+			boundary = boundary.AddDate(0, 0, -1)
+		}
+	default:
+		return true
+	}
+
+	return settings.LastDigestAt.Before(boundary)
+}
+
+// formatDigest formats queued alerts into a single summary message
+func formatDigest(lang string, mode db.DeliveryMode, alerts []*db.PendingAlert) string {
+	header := "digest.header." + string(mode)
+	if mode == db.InstantDelivery {
+		header = "digest.header.held"
+	}
+
+	lines := []string{i18n.N(lang, header, len(alerts), len(alerts)), ""}
+	for i, alert := range alerts {
+		if i == maxDigestItems {
+			remaining := len(alerts) - maxDigestItems
+			lines = append(lines, i18n.N(lang, "digest.more", remaining, remaining))
+			break
+		}
+		lines = append(lines, i18n.T(lang, "digest.item",
+			alert.Symbol,
+			util.EscapeMarkdown(util.Truncate(alert.Title, 100)),
+			notify.ExplorerProposalURL+alert.ProposalID,
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
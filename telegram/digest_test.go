@@ -0,0 +1,174 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+	"github.com/ZeraVision/ZeraBot/internal/telegramtest"
+)
+
+func TestDigestDue(t *testing.T) {
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		name     string
+		mode     db.DeliveryMode
+		timezone string
+		hour     int // DigestHour
+		last     time.Time
+		now      time.Time
+		want     bool
+	}{
+		{"Instant", db.InstantDelivery, "UTC", 9, utc(1, 15, 12, 0), utc(1, 15, 12, 0), true},
+
+		{"HourlyNeverSent", db.HourlyDigest, "UTC", 9, time.Time{}, utc(1, 15, 12, 0), true},
+		{"HourlySameHour", db.HourlyDigest, "UTC", 9, utc(1, 15, 11, 0), utc(1, 15, 11, 59), false},
+		{"HourlyNextHour", db.HourlyDigest, "UTC", 9, utc(1, 15, 10, 59), utc(1, 15, 11, 0), true},
+		{"HourlySentOnTheHour", db.HourlyDigest, "UTC", 9, utc(1, 15, 11, 0), utc(1, 15, 12, 0), true},
+		// Kolkata is UTC+5:30, so its hours start at half past in UTC
+		{"HourlyKolkataSameHour", db.HourlyDigest, "Asia/Kolkata", 9, utc(1, 15, 10, 30), utc(1, 15, 11, 29), false},
+		{"HourlyKolkataNextHour", db.HourlyDigest, "Asia/Kolkata", 9, utc(1, 15, 10, 29), utc(1, 15, 10, 30), true},
+		{"HourlyKolkataUTCHour", db.HourlyDigest, "Asia/Kolkata", 9, utc(1, 15, 10, 45), utc(1, 15, 11, 15), false},
+
+		{"DailyNeverSent", db.DailyDigest, "UTC", 9, time.Time{}, utc(1, 15, 8, 0), true},
+		{"DailyBeforeHour", db.DailyDigest, "UTC", 9, utc(1, 14, 9, 0), utc(1, 15, 8, 59), false},
+		{"DailyAtHour", db.DailyDigest, "UTC", 9, utc(1, 14, 9, 0), utc(1, 15, 9, 0), true},
+		{"DailySentToday", db.DailyDigest, "UTC", 9, utc(1, 15, 9, 0), utc(1, 15, 23, 59), false},
+		{"DailyMissedYesterday", db.DailyDigest, "UTC", 9, utc(1, 14, 8, 0), utc(1, 15, 8, 0), true},
+		{"DailyMidnight", db.DailyDigest, "UTC", 0, utc(1, 14, 0, 0), utc(1, 15, 0, 0), true},
+		// 09:00 in New York is 14:00 UTC in winter
+		{"DailyNewYorkBeforeHour", db.DailyDigest, "America/New_York", 9, utc(1, 14, 14, 0), utc(1, 15, 13, 59), false},
+		{"DailyNewYorkAtHour", db.DailyDigest, "America/New_York", 9, utc(1, 14, 14, 0), utc(1, 15, 14, 0), true},
+		// and 13:00 UTC after the clocks go forward on 8 March
+		{"DailyNewYorkAfterDST", db.DailyDigest, "America/New_York", 9, utc(3, 7, 14, 0), utc(3, 8, 13, 0), true},
+		// 09:00 in Tokyo is midnight UTC, so the local day starts before the UTC one
+		{"DailyTokyoBeforeHour", db.DailyDigest, "Asia/Tokyo", 9, utc(1, 14, 0, 0), utc(1, 14, 23, 59), false},
+		{"DailyTokyoAtHour", db.DailyDigest, "Asia/Tokyo", 9, utc(1, 14, 0, 0), utc(1, 15, 0, 0), true},
+		{"DailyUnknownTimezoneIsUTC", db.DailyDigest, "Mars/Olympus_Mons", 9, utc(1, 14, 9, 0), utc(1, 15, 9, 0), true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settings := db.DefaultChatSettings(1)
+			settings.DeliveryMode = c.mode
+			settings.Timezone = c.timezone
+			settings.DigestHour = c.hour
+			settings.LastDigestAt = c.last
+
+			if got := digestDue(settings, c.now); got != c.want {
+				t.Errorf("digestDue at %s, last sent %s = %v, want %v",
+					c.now.Format(time.RFC3339), c.last.Format(time.RFC3339), got, c.want)
+			}
+		})
+	}
+}
+
+func TestSendDueDigests(t *testing.T) {
+	database := pgtest.Open(t)
+	ctx := context.Background()
+
+	api := telegramtest.NewServer()
+	t.Cleanup(api.Close)
+	botAPI, err := api.NewBotAPI()
+	if err != nil {
+		t.Fatalf("failed to connect to fake Telegram server: %v", err)
+	}
+	settingsRepo := db.NewChatSettingsRepository(database)
+	pendingRepo := db.NewPendingAlertRepository(database)
+	bot := NewBot(botAPI, Services{Database: database, Settings: settingsRepo, PendingAlerts: pendingRepo}, Options{})
+
+	now := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	const (
+		starting = 1 // Daily digests, first alert queued
+		hourly   = 2 // Hourly digests, last sent two hours ago
+		notDue   = 3 // Daily digests at 09:00, already sent today
+		held     = 4 // Instant, with alerts held in quiet hours
+		snoozed  = 5 // Hourly digests that are due, but snoozed
+	)
+	setup := map[int64]func(s *db.ChatSettings){
+		starting: func(s *db.ChatSettings) { s.DeliveryMode = db.DailyDigest },
+		hourly:   func(s *db.ChatSettings) { s.DeliveryMode = db.HourlyDigest },
+		notDue:   func(s *db.ChatSettings) { s.DeliveryMode = db.DailyDigest },
+		held:     func(s *db.ChatSettings) { s.QuietStart, s.QuietEnd = 11*60, 13*60 },
+		snoozed: func(s *db.ChatSettings) {
+			s.DeliveryMode = db.HourlyDigest
+			s.SnoozedUntil = now.Add(time.Hour)
+		},
+	}
+	lastSent := map[int64]time.Time{
+		hourly:  now.Add(-2 * time.Hour),
+		notDue:  now.Add(-2 * time.Hour),
+		snoozed: now.Add(-2 * time.Hour),
+	}
+	for chatID, change := range setup {
+		settings := db.DefaultChatSettings(chatID)
+		change(settings)
+		if err := settingsRepo.Save(ctx, settings); err != nil {
+			t.Fatalf("failed to save settings: %v", err)
+		}
+		if sentAt, ok := lastSent[chatID]; ok {
+			if err := settingsRepo.MarkDigestSent(ctx, chatID, sentAt); err != nil {
+				t.Fatalf("failed to mark digest sent: %v", err)
+			}
+		}
+		alert := &db.PendingAlert{ChatID: chatID, Symbol: "$ZRA+0000", ProposalID: "p1", Title: "Raise the fee"}
+		if err := pendingRepo.Enqueue(ctx, alert); err != nil {
+			t.Fatalf("failed to enqueue alert: %v", err)
+		}
+	}
+
+	bot.sendDueDigests(ctx, now)
+
+	// expect checks whether a chat got a digest, and how many alerts it still has queued
+	expect := func(chatID int64, header string, queued int) {
+		t.Helper()
+		messages := api.MessagesTo(chatID)
+		switch {
+		case header == "" && len(messages) != 0:
+			t.Errorf("chat %d got %d messages, want none: %+v", chatID, len(messages), messages)
+		case header != "" && (len(messages) != 1 || !strings.Contains(messages[0].Text, header)):
+			t.Errorf("chat %d got %+v, want one digest containing %q", chatID, messages, header)
+		}
+		alerts, err := pendingRepo.List(ctx, chatID)
+		if err != nil {
+			t.Fatalf("failed to list alerts: %v", err)
+		}
+		if len(alerts) != queued {
+			t.Errorf("chat %d has %d alerts queued, want %d", chatID, len(alerts), queued)
+		}
+	}
+	expect(starting, "", 1)
+	expect(hourly, "Hourly proposal digest", 0)
+	expect(notDue, "", 1)
+	expect(held, "", 1)
+	expect(snoozed, "", 1)
+
+	// The first queued alert starts the period instead of being sent straight away
+	settings, err := settingsRepo.Get(ctx, starting)
+	if err != nil {
+		t.Fatalf("failed to get settings: %v", err)
+	}
+	if !settings.LastDigestAt.Equal(now) {
+		t.Errorf("digest period of chat %d started at %s, want %s", starting, settings.LastDigestAt, now)
+	}
+	settings, err = settingsRepo.Get(ctx, hourly)
+	if err != nil {
+		t.Fatalf("failed to get settings: %v", err)
+	}
+	if !settings.LastDigestAt.Equal(now) {
+		t.Errorf("digest of chat %d marked sent at %s, want %s", hourly, settings.LastDigestAt, now)
+	}
+
+	// Once quiet hours and the snooze end, held alerts go out, and the next day's digest is due
+	api.Reset()
+	bot.sendDueDigests(ctx, time.Date(2026, 1, 16, 9, 0, 0, 0, time.UTC))
+	expect(starting, "Daily proposal digest", 0)
+	expect(notDue, "Daily proposal digest", 0)
+	expect(held, "Proposals you missed", 0)
+	expect(snoozed, "Hourly proposal digest", 0)
+}
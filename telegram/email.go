@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/email"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+// handleEmail handles the /email command, which emails the chat's alerts to confirmed
+// addresses: "/email [list]", "/email add ADDRESS", "/email remove|daily|instant ID".
+// Addresses are personal, so it only works in private chats.
+func (b *Bot) handleEmail(chatID, userID int64, lang string, args string) error {
+	if b.emails == nil {
+		return b.SendToChatID(chatID, i18n.T(lang, "command.unknown"))
+	}
+	if chatID != userID {
+		return b.SendToChatID(chatID, i18n.T(lang, "email.private_only"))
+	}
+
+	fields := strings.Fields(args)
+	action := "list"
+	if len(fields) > 0 {
+		action = strings.ToLower(fields[0])
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "list":
+		return b.listEmails(ctx, chatID, lang)
+	case "add":
+		if len(fields) != 2 {
+			return b.SendToChatID(chatID, i18n.T(lang, "email.usage"))
+		}
+		return b.addEmail(ctx, chatID, lang, fields[1])
+	case "remove", "daily", "instant":
+		if len(fields) != 2 {
+			return b.SendToChatID(chatID, i18n.T(lang, "email.usage"))
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil {
+			return b.SendToChatID(chatID, i18n.T(lang, "email.usage"))
+		}
+		return b.changeEmail(ctx, chatID, lang, action, id)
+	default:
+		return b.SendToChatID(chatID, i18n.T(lang, "email.usage"))
+	}
+}
+
+// addEmail registers an address and sends it a confirmation link
+func (b *Bot) addEmail(ctx context.Context, chatID int64, lang string, address string) error {
+	e, err := b.emails.Register(ctx, chatID, address)
+	switch {
+	case errors.Is(err, email.ErrInvalidAddress):
+		return b.SendToChatID(chatID, i18n.T(lang, "email.invalid_address"))
+	case errors.Is(err, email.ErrLimitReached):
+		return b.SendToChatID(chatID, i18n.T(lang, "email.limit_reached"))
+	case errors.Is(err, email.ErrConfirmationPending):
+		return b.SendToChatID(chatID, i18n.T(lang, "email.confirmation_pending", util.EscapeMarkdown(strings.ToLower(address))))
+	case errors.Is(err, db.ErrEmailExists):
+		return b.SendToChatID(chatID, i18n.T(lang, "email.exists", util.EscapeMarkdown(strings.ToLower(address))))
+	case err != nil:
+		return fmt.Errorf("failed to register email: %w", err)
+	}
+
+	hours := int(b.emails.ConfirmTTL().Hours())
+	return b.SendToChatID(chatID, i18n.T(lang, "email.confirmation_sent", util.EscapeMarkdown(e.Address), hours))
+}
+
+// listEmails lists a chat's addresses with their status
+func (b *Bot) listEmails(ctx context.Context, chatID int64, lang string) error {
+	recipients, err := b.emails.Repository().List(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to list email recipients: %w", err)
+	}
+	if len(recipients) == 0 {
+		return b.SendToChatID(chatID, i18n.T(lang, "email.none"))
+	}
+
+	lines := []string{i18n.T(lang, "email.list_header")}
+	for _, e := range recipients {
+		status := i18n.T(lang, "email.status_pending")
+		if e.Confirmed() {
+			status = i18n.T(lang, "email.status_confirmed")
+		}
+		mode := i18n.T(lang, "email.mode_instant")
+		if e.DailyDigest {
+			mode = i18n.T(lang, "email.mode_daily")
+		}
+		lines = append(lines, i18n.T(lang, "email.item", e.ID, util.EscapeMarkdown(e.Address), status, mode))
+	}
+	lines = append(lines, i18n.T(lang, "email.list_footer"))
+	return b.SendToChatID(chatID, strings.Join(lines, "\n\n"))
+}
+
+// changeEmail removes one of a chat's addresses or changes how it gets alerts
+func (b *Bot) changeEmail(ctx context.Context, chatID int64, lang string, action string, id int64) error {
+	repo := b.emails.Repository()
+
+	var err error
+	switch action {
+	case "remove":
+		err = repo.Delete(ctx, chatID, id)
+	case "daily":
+		err = repo.SetDailyDigest(ctx, chatID, id, true)
+	case "instant":
+		err = repo.SetDailyDigest(ctx, chatID, id, false)
+	}
+	if errors.Is(err, db.ErrEmailNotFound) {
+		return b.SendToChatID(chatID, i18n.T(lang, "email.not_found", id))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s email recipient: %w", action, err)
+	}
+
+	key := "email." + action
+	if action == "remove" {
+		key = "email.removed"
+	}
+	return b.SendToChatID(chatID, i18n.T(lang, key, id))
+}
+
+// NotifyEmailConfirmed tells a chat that one of its addresses was confirmed
+func (b *Bot) NotifyEmailConfirmed(e *db.EmailRecipient) {
+	lang := chatLanguage(b.chatSettings(e.ChatID))
+	b.SendMessage(e.ChatID, i18n.T(lang, "email.confirmed", util.EscapeMarkdown(e.Address)))
+}
+
+// NotifyEmailUnsubscribed tells a chat that one of its addresses unsubscribed through its link
+func (b *Bot) NotifyEmailUnsubscribed(e *db.EmailRecipient) {
+	lang := chatLanguage(b.chatSettings(e.ChatID))
+	b.SendMessage(e.ChatID, i18n.T(lang, "email.unsubscribed", util.EscapeMarkdown(e.Address)))
+}
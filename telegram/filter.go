@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/filter"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+// handleFilter handles the /proposalFilter command:
+// "/proposalFilter [symbol]" shows, "/proposalFilter [symbol] clear" removes,
+// and "/proposalFilter [symbol] [terms]" replaces the filter of a subscription
+func (b *Bot) handleFilter(chatID int64, lang string, args string) error {
+	symbolInput, terms, _ := strings.Cut(strings.TrimSpace(args), " ")
+	terms = strings.TrimSpace(terms)
+	if symbolInput == "" {
+		return b.SendToChatID(chatID, i18n.T(lang, "filter.usage"))
+	}
+
+	symbols := processSymbols(symbolInput)
+	if len(symbols) != 1 {
+		return b.SendToChatID(chatID, i18n.T(lang, "filter.usage"))
+	}
+	symbol := symbols[0]
+	if strings.ToLower(symbol) == "all" {
+		symbol = "all"
+	} else if !isValidSubscriptionSymbol(symbol) {
+		return b.SendToChatID(chatID, i18n.T(lang, "symbol.invalid_format"))
+	}
+
+	sub, err := b.findSubscription(chatID, symbol)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return b.SendToChatID(chatID, i18n.T(lang, "filter.not_subscribed", util.EscapeMarkdown(symbol)))
+	}
+
+	switch strings.ToLower(terms) {
+	case "":
+		if sub.Filter.IsEmpty() {
+			return b.SendToChatID(chatID, i18n.T(lang, "filter.none", util.EscapeMarkdown(symbol)))
+		}
+		return b.SendToChatID(chatID, i18n.T(lang, "filter.current", util.EscapeMarkdown(symbol), util.EscapeMarkdown(sub.Filter.String())))
+	case "clear", "off":
+		if err := b.subRepo.SetFilter(context.Background(), chatID, db.ProposalType, symbol, nil); err != nil {
+			return fmt.Errorf("failed to clear filter: %w", err)
+		}
+		return b.SendToChatID(chatID, i18n.T(lang, "filter.cleared", util.EscapeMarkdown(symbol)))
+	}
+
+	f, err := filter.Parse(terms)
+	if err != nil {
+		return b.SendToChatID(chatID, i18n.T(lang, "filter.invalid", util.EscapeMarkdown(err.Error())))
+	}
+
+	if err := b.subRepo.SetFilter(context.Background(), chatID, db.ProposalType, symbol, f); err != nil {
+		return fmt.Errorf("failed to set filter: %w", err)
+	}
+
+	return b.SendToChatID(chatID, i18n.T(lang, "filter.set", util.EscapeMarkdown(symbol), util.EscapeMarkdown(f.String())))
+}
+
+// findSubscription returns the chat's proposal subscription to a symbol, or nil if there is none
+func (b *Bot) findSubscription(chatID int64, symbol string) (*db.Subscription, error) {
+	subs, err := b.subRepo.GetUserSubscriptions(context.Background(), chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+
+	for _, sub := range subs {
+		if sub.Type == db.ProposalType && sub.Symbol == symbol {
+			return sub, nil
+		}
+	}
+
+	return nil, nil
+}
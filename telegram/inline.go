@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxInlineResults limits how many contracts are offered per inline query (Telegram allows 50)
+	maxInlineResults = 20
+	// inlineCacheSeconds is how long Telegram may cache inline results
+	inlineCacheSeconds = 60
+	// recentProposalsWindow is the period recent proposal counts are shown for
+	recentProposalsWindow = 30 * 24 * time.Hour
+)
+
+// handleInlineQuery answers "@bot query" with matching known contracts. Picking one
+// sends a ready-to-use /proposalSubscribe command to the current chat.
+func (b *Bot) handleInlineQuery(query *tgbotapi.InlineQuery) {
+	// Inline queries arrive with every keystroke, so they have a limit of their own
+	if !b.guard.AllowInline(query.From.ID, time.Now()) {
+		return
+	}
+
+	lang := i18n.Normalize(b.chatSettings(query.From.ID).Language)
+	if lang == "" {
+		lang = i18n.Normalize(query.From.LanguageCode)
+	}
+	if lang == "" {
+		lang = i18n.DefaultLanguage
+	}
+
+	contracts := b.registry.Search(query.Query, maxInlineResults)
+
+	ids := make([]string, len(contracts))
+	for i, contract := range contracts {
+		ids[i] = contract.ContractID
+	}
+
+	counts, err := b.proposalRepo.CountSince(context.Background(), ids, time.Now().Add(-recentProposalsWindow))
+	if err != nil {
+		// Still offer the contracts, just without counts
+		log.Printf("Error counting recent proposals for inline query: %v", err)
+	}
+
+	results := make([]interface{}, 0, len(contracts))
+	for _, contract := range contracts {
+		results = append(results, inlineContractResult(lang, contract, counts[contract.ContractID]))
+	}
+
+	// Descriptions are in the user's language, so Telegram must not share cached results between users
+	inline := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     inlineCacheSeconds,
+		IsPersonal:    true,
+	}
+	if _, err := b.API.Request(inline); err != nil {
+		log.Printf("Error answering inline query: %v", err)
+	}
+}
+
+// inlineContractResult builds the inline result offering a subscription to a contract
+func inlineContractResult(lang string, contract *db.Contract, recentProposals int) tgbotapi.InlineQueryResultArticle {
+	title := contract.ContractID
+	if contract.Name != "" {
+		title = fmt.Sprintf("%s · %s", contract.ContractID, contract.Name)
+	}
+
+	result := tgbotapi.NewInlineQueryResultArticle(contract.ContractID, title, "/proposalSubscribe "+contract.ContractID)
+	result.Description = i18n.N(lang, "inline.description", recentProposals, recentProposals)
+	return result
+}
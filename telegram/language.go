@@ -0,0 +1,64 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/util"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatLanguage returns the language chosen in a chat's settings, or the default language
+func chatLanguage(settings *db.ChatSettings) string {
+	if lang := i18n.Normalize(settings.Language); lang != "" {
+		return lang
+	}
+	return i18n.DefaultLanguage
+}
+
+// messageLanguage returns the language to reply in for an incoming message.
+// A stored chat setting wins; private chats otherwise follow the user's Telegram client language.
+func messageLanguage(message *tgbotapi.Message, settings *db.ChatSettings) string {
+	if lang := i18n.Normalize(settings.Language); lang != "" {
+		return lang
+	}
+
+	if message.Chat.IsPrivate() && message.From != nil {
+		if lang := i18n.Normalize(message.From.LanguageCode); lang != "" {
+			return lang
+		}
+	}
+
+	return i18n.DefaultLanguage
+}
+
+// handleLanguage handles the /language command
+func (b *Bot) handleLanguage(chatID int64, lang string, args string) error {
+	requested := strings.TrimSpace(args)
+	if requested == "" {
+		return b.SendToChatID(chatID, i18n.T(lang, "language.current", i18n.T(lang, "language.name"), availableLanguages()))
+	}
+
+	newLang := i18n.Normalize(requested)
+	if newLang == "" {
+		return b.SendToChatID(chatID, i18n.T(lang, "language.unsupported", util.EscapeMarkdown(requested), availableLanguages()))
+	}
+
+	if err := b.settingsRepo.SetLanguage(context.Background(), chatID, newLang); err != nil {
+		return fmt.Errorf("failed to set language: %w", err)
+	}
+
+	return b.SendToChatID(chatID, i18n.T(newLang, "language.changed", i18n.T(newLang, "language.name")))
+}
+
+// availableLanguages lists the supported languages as "code (name)" pairs
+func availableLanguages() string {
+	var langs []string
+	for _, l := range i18n.Supported() {
+		langs = append(langs, fmt.Sprintf("%s (%s)", l, i18n.T(l, "language.name")))
+	}
+	return strings.Join(langs, ", ")
+}
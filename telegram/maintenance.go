@@ -0,0 +1,37 @@
+package telegram
+
+import (
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+// maintenanceMode is set while operators work on the bot
+type maintenanceMode struct {
+	enabled bool
+	message string // Shown along with the notice if set
+}
+
+// SetMaintenance turns maintenance mode on or off. While it is on, commands and
+// buttons are answered with a notice, including message if set. Alerts are still sent.
+func (b *Bot) SetMaintenance(enabled bool, message string) {
+	b.maintenance.Store(&maintenanceMode{enabled: enabled, message: message})
+}
+
+// maintenanceNotice returns the notice commands are answered with, or "" if the bot
+// isn't in maintenance mode
+func (b *Bot) maintenanceNotice(lang string) string {
+	m := b.maintenance.Load()
+	if m == nil || !m.enabled {
+		return ""
+	}
+	if m.message == "" {
+		return i18n.T(lang, "maintenance.active")
+	}
+	return i18n.T(lang, "maintenance.active_message", util.EscapeMarkdown(m.message))
+}
+
+// inMaintenance reports whether the bot is in maintenance mode
+func (b *Bot) inMaintenance() bool {
+	m := b.maintenance.Load()
+	return m != nil && m.enabled
+}
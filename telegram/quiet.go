@@ -0,0 +1,200 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/util"
+)
+
+// maxSnooze is the longest a chat can snooze alerts for
+const maxSnooze = 7 * 24 * time.Hour
+
+// isQuiet reports whether a chat is snoozed or inside its quiet hours
+func isQuiet(settings *db.ChatSettings, now time.Time) bool {
+	if now.Before(settings.SnoozedUntil) {
+		return true
+	}
+
+	if settings.QuietStart == settings.QuietEnd {
+		return false
+	}
+
+	local := now.In(chatLocation(settings))
+	minute := local.Hour()*60 + local.Minute()
+	if settings.QuietStart < settings.QuietEnd {
+		return minute >= settings.QuietStart && minute < settings.QuietEnd
+	}
+	// The window wraps around midnight, e.g. 23:00-07:00
+	return minute >= settings.QuietStart || minute < settings.QuietEnd
+}
+
+// holdsAlerts reports whether alerts for a chat should be queued instead of sent right now
+func holdsAlerts(settings *db.ChatSettings, now time.Time) bool {
+	return settings.QuietMode == db.HoldAlerts && isQuiet(settings, now)
+}
+
+// chatLocation returns the chat's timezone, falling back to UTC
+func chatLocation(settings *db.ChatSettings) *time.Location {
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// handleQuiet handles the /quiet command
+func (b *Bot) handleQuiet(chatID int64, lang string, settings *db.ChatSettings, args string) error {
+	value := strings.TrimSpace(args)
+	if value == "" {
+		return b.SendToChatID(chatID, formatQuietStatus(lang, settings)+"\n\n"+i18n.T(lang, "quiet.usage"))
+	}
+
+	name := settingQuietHours
+	if mode := db.QuietMode(strings.ToLower(value)); mode == db.HoldAlerts || mode == db.SilentAlerts {
+		name = settingQuietMode
+	}
+
+	if err := b.updateSetting(settings, name, value); err != nil {
+		if errors.Is(err, errInvalidSettingValue) {
+			return b.SendToChatID(chatID, i18n.T(lang, "quiet.usage"))
+		}
+		return err
+	}
+
+	return b.SendToChatID(chatID, formatQuietStatus(lang, settings))
+}
+
+// handleSnooze handles the /snooze command
+func (b *Bot) handleSnooze(chatID int64, lang string, settings *db.ChatSettings, args string) error {
+	value := strings.TrimSpace(args)
+	if value == "" {
+		return b.SendToChatID(chatID, formatSnoozeStatus(lang, settings, time.Now())+"\n\n"+i18n.T(lang, "snooze.usage"))
+	}
+
+	if err := b.updateSetting(settings, settingSnooze, value); err != nil {
+		if errors.Is(err, errInvalidSettingValue) {
+			return b.SendToChatID(chatID, i18n.T(lang, "snooze.usage"))
+		}
+		return err
+	}
+
+	return b.SendToChatID(chatID, formatSnoozeStatus(lang, settings, time.Now()))
+}
+
+// updateSetting applies and saves a single setting change
+func (b *Bot) updateSetting(settings *db.ChatSettings, name, value string) error {
+	if err := applySetting(settings, name, value); err != nil {
+		return err
+	}
+
+	if err := b.settingsRepo.Save(context.Background(), settings); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	return nil
+}
+
+// parseQuietHours parses a local time range such as "23:00-07:00"
+func parseQuietHours(value string) (start, end int, err error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected a range like 23:00-07:00, got %q", value)
+	}
+
+	if start, err = parseClock(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseClock(to); err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, errors.New("quiet hours must not start and end at the same time")
+	}
+
+	return start, end, nil
+}
+
+// parseClock parses "HH:MM" or "HH" into minutes after midnight
+func parseClock(value string) (int, error) {
+	hours, minutes, hasMinutes := strings.Cut(strings.TrimSpace(value), ":")
+
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid hour in %q", value)
+	}
+
+	m := 0
+	if hasMinutes {
+		m, err = strconv.Atoi(minutes)
+		if err != nil || m < 0 || m > 59 {
+			return 0, fmt.Errorf("invalid minutes in %q", value)
+		}
+	}
+
+	return h*60 + m, nil
+}
+
+// formatClock formats minutes after midnight as "HH:MM"
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseSnooze parses a snooze duration such as "30m", "4h", or "2d"
+func parseSnooze(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+
+	if d <= 0 || d > maxSnooze {
+		return 0, fmt.Errorf("snooze must be between 1m and %s", maxSnooze)
+	}
+
+	return d, nil
+}
+
+// formatQuietStatus describes a chat's quiet hours
+func formatQuietStatus(lang string, settings *db.ChatSettings) string {
+	if settings.QuietStart == settings.QuietEnd {
+		return i18n.T(lang, "quiet.off")
+	}
+
+	return i18n.T(lang, "quiet.on",
+		formatClock(settings.QuietStart),
+		formatClock(settings.QuietEnd),
+		util.EscapeMarkdown(settings.Timezone),
+		i18n.T(lang, "quiet.mode."+string(settings.QuietMode)),
+	)
+}
+
+// formatSnoozeStatus describes whether a chat is snoozed
+func formatSnoozeStatus(lang string, settings *db.ChatSettings, now time.Time) string {
+	if !now.Before(settings.SnoozedUntil) {
+		return i18n.T(lang, "snooze.off")
+	}
+
+	until := settings.SnoozedUntil.In(chatLocation(settings))
+	return i18n.T(lang, "snooze.until",
+		until.Format("2006-01-02 15:04"),
+		util.EscapeMarkdown(settings.Timezone),
+		i18n.T(lang, "quiet.mode."+string(settings.QuietMode)),
+	)
+}
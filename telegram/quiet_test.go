@@ -0,0 +1,160 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+)
+
+func TestIsQuiet(t *testing.T) {
+	// at returns a UTC time on a winter day, when New York is at UTC-5
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 1, 15, hour, minute, 0, 0, time.UTC)
+	}
+	summer := func(hour, minute int) time.Time {
+		return time.Date(2026, 7, 15, hour, minute, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		name       string
+		timezone   string
+		start, end int
+		snoozed    time.Time
+		now        time.Time
+		want       bool
+	}{
+		{"OffAtMidnight", "UTC", 0, 0, time.Time{}, at(0, 0), false},
+		{"OffWhenStartEqualsEnd", "UTC", 600, 600, time.Time{}, at(10, 0), false},
+
+		{"SameDayBefore", "UTC", 9 * 60, 17 * 60, time.Time{}, at(8, 59), false},
+		{"SameDayStart", "UTC", 9 * 60, 17 * 60, time.Time{}, at(9, 0), true},
+		{"SameDayInside", "UTC", 9 * 60, 17 * 60, time.Time{}, at(16, 59), true},
+		{"SameDayEnd", "UTC", 9 * 60, 17 * 60, time.Time{}, at(17, 0), false},
+
+		{"WrapBeforeStart", "UTC", 23 * 60, 7 * 60, time.Time{}, at(22, 59), false},
+		{"WrapStart", "UTC", 23 * 60, 7 * 60, time.Time{}, at(23, 0), true},
+		{"WrapMidnight", "UTC", 23 * 60, 7 * 60, time.Time{}, at(0, 0), true},
+		{"WrapBeforeEnd", "UTC", 23 * 60, 7 * 60, time.Time{}, at(6, 59), true},
+		{"WrapEnd", "UTC", 23 * 60, 7 * 60, time.Time{}, at(7, 0), false},
+		{"WrapMidday", "UTC", 23 * 60, 7 * 60, time.Time{}, at(12, 0), false},
+
+		// 23:00-07:00 in New York is 04:00-12:00 UTC in winter and 03:00-11:00 UTC in summer
+		{"NewYorkBeforeStart", "America/New_York", 23 * 60, 7 * 60, time.Time{}, at(3, 59), false},
+		{"NewYorkStart", "America/New_York", 23 * 60, 7 * 60, time.Time{}, at(4, 0), true},
+		{"NewYorkBeforeEnd", "America/New_York", 23 * 60, 7 * 60, time.Time{}, at(11, 59), true},
+		{"NewYorkEnd", "America/New_York", 23 * 60, 7 * 60, time.Time{}, at(12, 0), false},
+		{"NewYorkSummerStart", "America/New_York", 23 * 60, 7 * 60, time.Time{}, summer(3, 0), true},
+		{"NewYorkSummerEnd", "America/New_York", 23 * 60, 7 * 60, time.Time{}, summer(11, 0), false},
+
+		// 22:00-06:00 in Kolkata is 16:30-00:30 UTC, so the window wraps at a different midnight
+		{"KolkataBeforeStart", "Asia/Kolkata", 22 * 60, 6 * 60, time.Time{}, at(16, 29), false},
+		{"KolkataStart", "Asia/Kolkata", 22 * 60, 6 * 60, time.Time{}, at(16, 30), true},
+		{"KolkataUTCMidnight", "Asia/Kolkata", 22 * 60, 6 * 60, time.Time{}, at(0, 0), true},
+		{"KolkataBeforeEnd", "Asia/Kolkata", 22 * 60, 6 * 60, time.Time{}, at(0, 29), true},
+		{"KolkataEnd", "Asia/Kolkata", 22 * 60, 6 * 60, time.Time{}, at(0, 30), false},
+
+		{"UnknownTimezoneIsUTC", "Mars/Olympus_Mons", 23 * 60, 7 * 60, time.Time{}, at(23, 30), true},
+
+		{"Snoozed", "UTC", 0, 0, at(12, 0), at(11, 59), true},
+		{"SnoozeEnded", "UTC", 0, 0, at(12, 0), at(12, 0), false},
+		{"SnoozedOutsideQuietHours", "UTC", 23 * 60, 7 * 60, at(12, 0), at(10, 0), true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			settings := db.DefaultChatSettings(1)
+			settings.Timezone = c.timezone
+			settings.QuietStart, settings.QuietEnd = c.start, c.end
+			settings.SnoozedUntil = c.snoozed
+
+			if got := isQuiet(settings, c.now); got != c.want {
+				t.Errorf("isQuiet at %s = %v, want %v", c.now.Format(time.RFC3339), got, c.want)
+			}
+		})
+	}
+}
+
+func TestHoldsAlerts(t *testing.T) {
+	now := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	settings := db.DefaultChatSettings(1)
+	settings.QuietStart, settings.QuietEnd = 23*60, 7*60
+
+	if !holdsAlerts(settings, now) {
+		t.Error("alerts aren't held in quiet hours")
+	}
+	settings.QuietMode = db.SilentAlerts
+	if holdsAlerts(settings, now) {
+		t.Error("silent quiet hours hold alerts")
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	cases := []struct {
+		value      string
+		start, end int
+		ok         bool
+	}{
+		{"23:00-07:00", 23 * 60, 7 * 60, true},
+		{"9-17", 9 * 60, 17 * 60, true},
+		{" 22:30 - 06:15 ", 22*60 + 30, 6*60 + 15, true},
+		{"00:00-23:59", 0, 23*60 + 59, true},
+		{"07:00-07:00", 0, 0, false}, // Start == end would turn quiet hours off
+		{"7-07:00", 0, 0, false},
+		{"24:00-07:00", 0, 0, false},
+		{"23:60-07:00", 0, 0, false},
+		{"23:00", 0, 0, false},
+		{"off", 0, 0, false},
+	}
+	for _, c := range cases {
+		start, end, err := parseQuietHours(c.value)
+		if !c.ok {
+			if err == nil {
+				t.Errorf("parseQuietHours(%q) = %d-%d, want an error", c.value, start, end)
+			}
+			continue
+		}
+		if err != nil || start != c.start || end != c.end {
+			t.Errorf("parseQuietHours(%q) = %d-%d, %v, want %d-%d", c.value, start, end, err, c.start, c.end)
+		}
+	}
+}
+
+func TestParseSnooze(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"30m", 30 * time.Minute, true},
+		{"4h", 4 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"2d", 48 * time.Hour, true},
+		{" 1D ", 24 * time.Hour, true},
+		{"7d", maxSnooze, true},
+		{"168h", maxSnooze, true},
+		{"10080m", maxSnooze, true},
+		{"8d", 0, false}, // Longer than the 7d cap
+		{"169h", 0, false},
+		{"10081m", 0, false},
+		{"7d1h", 0, false},
+		{"0m", 0, false},
+		{"0d", 0, false},
+		{"-1h", 0, false},
+		{"1.5d", 0, false},
+		{"d", 0, false},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+	for _, c := range cases {
+		got, err := parseSnooze(c.value)
+		if !c.ok {
+			if err == nil {
+				t.Errorf("parseSnooze(%q) = %s, want an error", c.value, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("parseSnooze(%q) = %s, %v, want %s", c.value, got, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/metrics"
+	"github.com/ZeraVision/ZeraBot/registry"
+	"github.com/ZeraVision/ZeraBot/symbol"
+	"github.com/ZeraVision/ZeraBot/util"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// subscribeCallbackPrefix prefixes callback data of buttons that subscribe to a contract
+const subscribeCallbackPrefix = "sub"
+
+// resolveTicker resolves a bare ticker such as $ZRA to the symbol to subscribe to, along
+// with a message to pass on if there is one. If several contracts share the ticker, the
+// chat is asked to pick one with a keyboard and "" is returned. If none has been seen on
+// chain, the pattern of every series of the ticker is returned with a warning to pass on
+// once it is subscribed.
+func (b *Bot) resolveTicker(chatID int64, lang string, ticker string) (string, string) {
+	contracts := b.registry.ByTicker(ticker)
+	switch len(contracts) {
+	case 0:
+		pattern := tickerPattern(ticker)
+		return pattern, i18n.T(lang, "symbol.unseen_ticker", util.EscapeMarkdown(ticker), util.EscapeMarkdown(pattern))
+	case 1:
+		return contracts[0].ContractID, ""
+	}
+
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "symbol.choose_contract", util.EscapeMarkdown(ticker)))
+	msg.ReplyMarkup = contractKeyboard(contracts)
+	if err := b.sendWithFallback(msg); err != nil {
+		log.Printf("Error sending contract choice to chat %d: %v", chatID, err)
+		return "", i18n.T(lang, "subscribe.failed_symbol", util.EscapeMarkdown(ticker), err)
+	}
+
+	return "", ""
+}
+
+// tickerPattern returns the pattern matching every series of a ticker, e.g. $ZRA+* for $ZRA
+func tickerPattern(ticker string) string {
+	return ticker + "+" + symbol.Wildcard
+}
+
+// contractKeyboard builds a keyboard with one subscribe button per contract
+func contractKeyboard(contracts []*db.Contract) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, contract := range contracts {
+		label := contract.ContractID
+		if contract.Name != "" {
+			label = fmt.Sprintf("%s · %s", contract.ContractID, contract.Name)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, subscribeCallbackPrefix+":"+contract.ContractID),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleSubscribeCallback subscribes the chat to the contract picked from a contract keyboard
+func (b *Bot) handleSubscribeCallback(query *tgbotapi.CallbackQuery, contractID string) {
+	chatID := query.Message.Chat.ID
+	settings := b.chatSettings(chatID)
+	lang := chatLanguage(settings)
+
+	if settings.AdminOnly {
+		isAdmin, err := b.isGroupAdmin(chatID, query.From.ID)
+		if err != nil {
+			log.Printf("Error checking admin status: %v", err)
+			b.answerCallback(query.ID, i18n.T(lang, "admin.verify_failed"))
+			return
+		}
+		if !isAdmin {
+			b.answerCallback(query.ID, i18n.T(lang, "admin.required"))
+			return
+		}
+	}
+
+	if !symbol.IsValid(contractID) {
+		log.Printf("Invalid contract %q in subscribe callback from chat %d", contractID, chatID)
+		b.answerCallback(query.ID, i18n.T(lang, "symbol.invalid_format"))
+		return
+	}
+
+	_, err := b.subRepo.Subscribe(context.Background(), chatID, db.ProposalType, contractID)
+	if errors.Is(err, db.ErrSubscriptionLimit) {
+		metrics.SubscriptionLimitHits.Add(1)
+		b.answerCallback(query.ID, i18n.T(lang, "subscribe.limit_reached", contractID, b.subRepo.MaxPerChat()))
+		return
+	}
+	if err != nil {
+		log.Printf("Error subscribing chat %d to %s: %v", chatID, contractID, err)
+		b.answerCallback(query.ID, i18n.T(lang, "subscribe.error"))
+		return
+	}
+
+	// Replacing the text also removes the keyboard
+	edit := tgbotapi.NewEditMessageText(chatID, query.Message.MessageID, i18n.T(lang, "subscribe.success_one", util.EscapeMarkdown(contractID)))
+	edit.ParseMode = "Markdown"
+	if _, err := b.API.Send(edit); err != nil {
+		log.Printf("Error updating contract choice in chat %d: %v", chatID, err)
+	}
+
+	b.answerCallback(query.ID, "")
+}
+
+// subscribedWithTicker returns the chat's proposal subscriptions to any series of a ticker,
+// exact ones and the pattern of every series
+func (b *Bot) subscribedWithTicker(chatID int64, ticker string) ([]string, error) {
+	subs, err := b.subRepo.GetUserSubscriptions(context.Background(), chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+
+	var symbols []string
+	for _, sub := range subs {
+		if sub.Type != db.ProposalType {
+			continue
+		}
+		if (symbol.IsValid(sub.Symbol) && "$"+registry.Ticker(sub.Symbol) == ticker) || sub.Symbol == tickerPattern(ticker) {
+			symbols = append(symbols, sub.Symbol)
+		}
+	}
+
+	return symbols, nil
+}
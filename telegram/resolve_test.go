@@ -0,0 +1,120 @@
+package telegram
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/db/memstore"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/internal/telegramtest"
+)
+
+// tickerContracts are $ZRA with one series and $ZIP with two
+var tickerContracts = []*db.Contract{
+	{ContractID: "$ZRA+0000", Symbol: "ZRA"},
+	{ContractID: "$ZIP+0000", Symbol: "ZIP"},
+	{ContractID: "$ZIP+0001", Symbol: "ZIP"},
+}
+
+// newTickerBot returns a bot with subscriptions in memory, talking to a fake Telegram server
+func newTickerBot(t *testing.T) (*Bot, *telegramtest.Server) {
+	t.Helper()
+
+	api := telegramtest.NewServer()
+	t.Cleanup(api.Close)
+	botAPI, err := api.NewBotAPI()
+	if err != nil {
+		t.Fatalf("failed to connect to fake Telegram server: %v", err)
+	}
+
+	return &Bot{
+		API:      botAPI,
+		subRepo:  memstore.NewSubscriptionStore(),
+		registry: newRegistry(t, tickerContracts...),
+	}, api
+}
+
+// subscribedSymbols returns the symbols a chat is subscribed to, sorted
+func subscribedSymbols(t *testing.T, b *Bot, chatID int64) []string {
+	t.Helper()
+
+	subs, err := b.subRepo.GetUserSubscriptions(context.Background(), chatID)
+	if err != nil {
+		t.Fatalf("GetUserSubscriptions: %v", err)
+	}
+	var symbols []string
+	for _, sub := range subs {
+		symbols = append(symbols, sub.Symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func TestSubscribeBareTickers(t *testing.T) {
+	b, api := newTickerBot(t)
+	const chat = 1
+
+	if err := b.handleSubscribe(chat, "en", "zra,ETH,$zip"); err != nil {
+		t.Fatalf("handleSubscribe: %v", err)
+	}
+
+	messages := api.MessagesTo(chat)
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want the $ZIP contract choice and the result: %+v", len(messages), messages)
+	}
+
+	// $ZIP has two series, so the chat is asked which one it means
+	choice := messages[0]
+	if choice.Text != i18n.T("en", "symbol.choose_contract", "$ZIP") {
+		t.Errorf("choice text = %q, want the contract choice for $ZIP", choice.Text)
+	}
+	for _, contractID := range []string{"$ZIP+0000", "$ZIP+0001"} {
+		if !strings.Contains(choice.ReplyMarkup, `"callback_data":"`+subscribeCallbackPrefix+":"+contractID+`"`) {
+			t.Errorf("choice keyboard = %q, want a button for %s", choice.ReplyMarkup, contractID)
+		}
+	}
+
+	// $ZRA resolves to its only series, and $ETH isn't known yet so every series of it is followed
+	want := i18n.N("en", "subscribe.success_many", 2, 2) + "\n" +
+		i18n.T("en", "symbol.unseen_ticker", "$ETH", `$ETH+\*`)
+	if messages[1].Text != want {
+		t.Errorf("result = %q, want %q", messages[1].Text, want)
+	}
+
+	if got := subscribedSymbols(t, b, chat); strings.Join(got, ",") != "$ETH+*,$ZRA+0000" {
+		t.Errorf("subscriptions = %v, want $ETH+* and $ZRA+0000", got)
+	}
+
+	// A bare ticker unsubscribes from the pattern as well as exact series
+	if err := b.handleUnsubscribe(chat, "en", "eth,zra"); err != nil {
+		t.Fatalf("handleUnsubscribe: %v", err)
+	}
+	if got := subscribedSymbols(t, b, chat); len(got) != 0 {
+		t.Errorf("subscriptions after unsubscribing = %v, want none", got)
+	}
+}
+
+func TestUnseenWarningsOnlyForSubscribedSymbols(t *testing.T) {
+	b, api := newTickerBot(t)
+	b.subRepo.(*memstore.SubscriptionStore).SetMaxPerChat(1)
+	const chat = 2
+
+	if err := b.handleSubscribe(chat, "en", "$ETH+0000,$ZRB+0000,btc"); err != nil {
+		t.Fatalf("handleSubscribe: %v", err)
+	}
+
+	// Only $ETH+0000 fits in the limit, so only it is warned about
+	messages := api.MessagesTo(chat)
+	want := strings.Join([]string{
+		i18n.T("en", "subscribe.success_one", "$ETH+0000"),
+		i18n.T("en", "subscribe.limit_reached", "$ZRB+0000", 1),
+		i18n.T("en", "subscribe.limit_reached", `$BTC+\*`, 1),
+		i18n.T("en", "symbol.unseen", "$ETH+0000"),
+	}, "\n")
+	if len(messages) != 1 || messages[0].Text != want {
+		t.Fatalf("messages = %+v, want only %q", messages, want)
+	}
+}
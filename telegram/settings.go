@@ -0,0 +1,314 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/util"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const settingsCallbackPrefix = "settings"
+
+// Setting names accepted by /settings and the settings keyboard
+const (
+	settingLanguage     = "language"
+	settingTimezone     = "timezone"
+	settingMute         = "mute"
+	settingFormat       = "format"
+	settingLinkPreviews = "previews"
+	settingAdminOnly    = "adminonly"
+	settingDelivery     = "delivery"
+	settingDigestHour   = "digesthour"
+	settingQuietHours   = "quiet"
+	settingQuietMode    = "quietmode"
+	settingSnooze       = "snooze"
+)
+
+// Settings keyboard menus
+const (
+	settingsMenuMain     = "main"
+	settingsMenuLanguage = "language"
+	settingsMenuTimezone = "timezone"
+)
+
+// commonTimezones are offered as buttons; any IANA zone can be set with /settings timezone
+var commonTimezones = []string{
+	"UTC",
+	"Europe/London",
+	"Europe/Madrid",
+	"America/New_York",
+	"America/Mexico_City",
+	"America/Sao_Paulo",
+	"Asia/Shanghai",
+	"Asia/Singapore",
+}
+
+var (
+	errUnknownSetting      = errors.New("unknown setting")
+	errInvalidSettingValue = errors.New("invalid setting value")
+)
+
+// chatSettings returns the stored settings for a chat, falling back to the defaults on error
+func (b *Bot) chatSettings(chatID int64) *db.ChatSettings {
+	settings, err := b.settingsRepo.Get(context.Background(), chatID)
+	if err != nil {
+		log.Printf("Error getting settings for chat %d: %v", chatID, err)
+		return db.DefaultChatSettings(chatID)
+	}
+	return settings
+}
+
+// handleSettings handles the /settings command. Without arguments it shows the
+// settings keyboard; "/settings [name] [value]" changes a single setting.
+func (b *Bot) handleSettings(chatID int64, lang string, settings *db.ChatSettings, args string) error {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		msg := tgbotapi.NewMessage(chatID, formatSettings(lang, settings))
+		msg.ParseMode = "Markdown"
+		msg.ReplyMarkup = settingsKeyboard(lang, settings, settingsMenuMain)
+		_, err := b.API.Send(msg)
+		return err
+	}
+
+	if len(fields) != 2 {
+		return b.SendToChatID(chatID, i18n.T(lang, "settings.usage"))
+	}
+
+	if err := b.updateSetting(settings, fields[0], fields[1]); err != nil {
+		if errors.Is(err, errUnknownSetting) || errors.Is(err, errInvalidSettingValue) {
+			return b.SendToChatID(chatID, i18n.T(lang, "settings.invalid", util.EscapeMarkdown(fields[0]), util.EscapeMarkdown(fields[1])))
+		}
+		return err
+	}
+
+	// Reply in the (possibly new) chat language
+	return b.SendToChatID(chatID, formatSettings(chatLanguage(settings), settings))
+}
+
+// handleSettingsCallback handles presses on the settings keyboard
+func (b *Bot) handleSettingsCallback(query *tgbotapi.CallbackQuery, data string) {
+	chatID := query.Message.Chat.ID
+	settings := b.chatSettings(chatID)
+	lang := chatLanguage(settings)
+
+	isAdmin, err := b.isGroupAdmin(chatID, query.From.ID)
+	if err != nil {
+		log.Printf("Error checking admin status: %v", err)
+		b.answerCallback(query.ID, i18n.T(lang, "admin.verify_failed"))
+		return
+	}
+	if !isAdmin {
+		b.answerCallback(query.ID, i18n.T(lang, "admin.required"))
+		return
+	}
+
+	// Data is either "menu:<menu>" or "<setting>:<value>"
+	name, value, _ := strings.Cut(data, ":")
+	menu := settingsMenuMain
+	if name == "menu" {
+		menu = value
+	} else {
+		if err := b.updateSetting(settings, name, value); err != nil {
+			log.Printf("Error applying settings callback %q in chat %d: %v", query.Data, chatID, err)
+			b.answerCallback(query.ID, i18n.T(lang, "settings.error"))
+			return
+		}
+		lang = chatLanguage(settings)
+	}
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(
+		chatID,
+		query.Message.MessageID,
+		formatSettings(lang, settings),
+		settingsKeyboard(lang, settings, menu),
+	)
+	edit.ParseMode = "Markdown"
+	if _, err := b.API.Send(edit); err != nil {
+		log.Printf("Error updating settings message in chat %d: %v", chatID, err)
+	}
+
+	b.answerCallback(query.ID, "")
+}
+
+// applySetting validates and applies a single setting change
+func applySetting(settings *db.ChatSettings, name, value string) error {
+	switch strings.ToLower(name) {
+	case settingLanguage:
+		lang := i18n.Normalize(value)
+		if lang == "" {
+			return fmt.Errorf("%w: unsupported language %q", errInvalidSettingValue, value)
+		}
+		settings.Language = lang
+	case settingTimezone:
+		loc, err := time.LoadLocation(value)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidSettingValue, err)
+		}
+		settings.Timezone = loc.String()
+	case settingMute:
+		return applyToggle(&settings.Muted, value)
+	case settingLinkPreviews:
+		return applyToggle(&settings.LinkPreviews, value)
+	case settingAdminOnly:
+		return applyToggle(&settings.AdminOnly, value)
+	case settingDelivery:
+		switch db.DeliveryMode(strings.ToLower(value)) {
+		case db.InstantDelivery, db.HourlyDigest, db.DailyDigest:
+			settings.DeliveryMode = db.DeliveryMode(strings.ToLower(value))
+		default:
+			return fmt.Errorf("%w: unknown delivery mode %q", errInvalidSettingValue, value)
+		}
+	case settingDigestHour:
+		hour, err := strconv.Atoi(strings.TrimSuffix(value, ":00"))
+		if err != nil || hour < 0 || hour > 23 {
+			return fmt.Errorf("%w: digest hour must be between 0 and 23", errInvalidSettingValue)
+		}
+		settings.DigestHour = hour
+	case settingQuietHours:
+		if strings.ToLower(value) == "off" {
+			settings.QuietStart, settings.QuietEnd = 0, 0
+			return nil
+		}
+		start, end, err := parseQuietHours(value)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidSettingValue, err)
+		}
+		settings.QuietStart, settings.QuietEnd = start, end
+	case settingQuietMode:
+		switch db.QuietMode(strings.ToLower(value)) {
+		case db.HoldAlerts, db.SilentAlerts:
+			settings.QuietMode = db.QuietMode(strings.ToLower(value))
+		default:
+			return fmt.Errorf("%w: unknown quiet mode %q", errInvalidSettingValue, value)
+		}
+	case settingSnooze:
+		if strings.ToLower(value) == "off" {
+			settings.SnoozedUntil = time.Time{}
+			return nil
+		}
+		d, err := parseSnooze(value)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidSettingValue, err)
+		}
+		settings.SnoozedUntil = time.Now().Add(d)
+	case settingFormat:
+		switch db.MessageFormat(strings.ToLower(value)) {
+		case db.FullFormat:
+			settings.MessageFormat = db.FullFormat
+		case db.CompactFormat:
+			settings.MessageFormat = db.CompactFormat
+		default:
+			return fmt.Errorf("%w: unknown format %q", errInvalidSettingValue, value)
+		}
+	default:
+		return fmt.Errorf("%w: %q", errUnknownSetting, name)
+	}
+	return nil
+}
+
+// applyToggle sets a boolean setting from "on", "off", or "toggle"
+func applyToggle(setting *bool, value string) error {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes":
+		*setting = true
+	case "off", "false", "no":
+		*setting = false
+	case "toggle":
+		*setting = !*setting
+	default:
+		return fmt.Errorf("%w: expected on or off, got %q", errInvalidSettingValue, value)
+	}
+	return nil
+}
+
+// formatSettings renders the current settings of a chat
+func formatSettings(lang string, settings *db.ChatSettings) string {
+	return i18n.T(lang, "settings.summary",
+		i18n.T(chatLanguage(settings), "language.name"),
+		util.EscapeMarkdown(settings.Timezone),
+		onOff(lang, settings.Muted),
+		i18n.T(lang, "settings.format."+string(settings.MessageFormat)),
+		onOff(lang, settings.LinkPreviews),
+		onOff(lang, settings.AdminOnly),
+		i18n.T(lang, "settings.delivery."+string(settings.DeliveryMode)),
+		settings.DigestHour,
+		formatQuietStatus(lang, settings),
+		formatSnoozeStatus(lang, settings, time.Now()),
+	)
+}
+
+// settingsKeyboard builds the inline keyboard for the given settings menu
+func settingsKeyboard(lang string, settings *db.ChatSettings, menu string) tgbotapi.InlineKeyboardMarkup {
+	switch menu {
+	case settingsMenuLanguage:
+		var row []tgbotapi.InlineKeyboardButton
+		for _, l := range i18n.Supported() {
+			row = append(row, settingsButton(i18n.T(l, "language.name"), settingLanguage, l))
+		}
+		return tgbotapi.NewInlineKeyboardMarkup(row, backRow(lang))
+	case settingsMenuTimezone:
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for i := 0; i < len(commonTimezones); i += 2 {
+			row := []tgbotapi.InlineKeyboardButton{settingsButton(commonTimezones[i], settingTimezone, commonTimezones[i])}
+			if i+1 < len(commonTimezones) {
+				row = append(row, settingsButton(commonTimezones[i+1], settingTimezone, commonTimezones[i+1]))
+			}
+			rows = append(rows, row)
+		}
+		return tgbotapi.NewInlineKeyboardMarkup(append(rows, backRow(lang))...)
+	}
+
+	nextFormat := db.CompactFormat
+	if settings.MessageFormat == db.CompactFormat {
+		nextFormat = db.FullFormat
+	}
+
+	nextDelivery := db.HourlyDigest
+	switch settings.DeliveryMode {
+	case db.HourlyDigest:
+		nextDelivery = db.DailyDigest
+	case db.DailyDigest:
+		nextDelivery = db.InstantDelivery
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			settingsButton(i18n.T(lang, "settings.button.language"), "menu", settingsMenuLanguage),
+			settingsButton(i18n.T(lang, "settings.button.timezone"), "menu", settingsMenuTimezone),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			settingsButton(i18n.T(lang, "settings.button.mute", onOff(lang, settings.Muted)), settingMute, "toggle"),
+			settingsButton(i18n.T(lang, "settings.button.previews", onOff(lang, settings.LinkPreviews)), settingLinkPreviews, "toggle"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			settingsButton(i18n.T(lang, "settings.button.format", i18n.T(lang, "settings.format."+string(settings.MessageFormat))), settingFormat, string(nextFormat)),
+			settingsButton(i18n.T(lang, "settings.button.adminonly", onOff(lang, settings.AdminOnly)), settingAdminOnly, "toggle"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			settingsButton(i18n.T(lang, "settings.button.delivery", i18n.T(lang, "settings.delivery."+string(settings.DeliveryMode))), settingDelivery, string(nextDelivery)),
+		),
+	)
+}
+
+func settingsButton(text, name, value string) tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardButtonData(text, settingsCallbackPrefix+":"+name+":"+value)
+}
+
+func backRow(lang string) []tgbotapi.InlineKeyboardButton {
+	return tgbotapi.NewInlineKeyboardRow(settingsButton(i18n.T(lang, "settings.button.back"), "menu", settingsMenuMain))
+}
+
+func onOff(lang string, on bool) string {
+	if on {
+		return i18n.T(lang, "settings.on")
+	}
+	return i18n.T(lang, "settings.off")
+}
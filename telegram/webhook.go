@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
+	"github.com/ZeraVision/ZeraBot/abuse"
 	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/metrics"
+	"github.com/ZeraVision/ZeraBot/notify"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -29,6 +33,21 @@ func (b *Bot) WebhookHandler(w http.ResponseWriter, r *http.Request) {
 			b.handleCommand(update.Message)
 		}
 	}
+
+	if update.CallbackQuery != nil {
+		b.handleCallback(update.CallbackQuery)
+	}
+
+	if update.InlineQuery != nil {
+		b.handleInlineQuery(update.InlineQuery)
+	}
+}
+
+// knownCommands lists the commands counted by name in metrics, others are counted as "unknown"
+var knownCommands = map[string]bool{
+	"start": true, "help": true, "proposalsubscribe": true, "proposalunsubscribe": true,
+	"proposalfilter": true, "mysubscriptions": true, "language": true, "settings": true,
+	"quiet": true, "snooze": true, "webhook": true, "email": true,
 }
 
 func (b *Bot) handleCommand(message *tgbotapi.Message) {
@@ -37,50 +56,136 @@ func (b *Bot) handleCommand(message *tgbotapi.Message) {
 	command := message.Command()
 	args := message.CommandArguments()
 
-	// Check if the command requires admin privileges
-	isRestrictedCommand := strings.ToLower(command) == "proposalsubscribe" ||
-		strings.ToLower(command) == "proposalunsubscribe"
+	// Reject floods before they cause any database work
+	if !b.allowCommand(chatID, message.From) {
+		return
+	}
+
+	name := strings.ToLower(command)
+	if !knownCommands[name] {
+		name = "unknown"
+	}
+	metrics.Commands.Add(name, 1)
+
+	settings := b.chatSettings(chatID)
+	lang := messageLanguage(message, settings)
+
+	if notice := b.maintenanceNotice(lang); notice != "" {
+		b.SendMessage(chatID, notice)
+		return
+	}
+
+	// Check if the command requires admin privileges. Subscription commands are
+	// restricted unless the chat turned off admin-only mode; changing settings always is.
+	isSubscriptionCommand := strings.ToLower(command) == "proposalsubscribe" ||
+		strings.ToLower(command) == "proposalunsubscribe" ||
+		strings.ToLower(command) == "proposalfilter"
+	isSettingsCommand := strings.ToLower(command) == "language" ||
+		strings.ToLower(command) == "settings" ||
+		strings.ToLower(command) == "quiet" ||
+		strings.ToLower(command) == "snooze"
+	isSettingsChange := isSettingsCommand && strings.TrimSpace(args) != ""
+	isRestrictedCommand := (isSubscriptionCommand && settings.AdminOnly) || isSettingsChange
 
 	if isRestrictedCommand {
 		isAdmin, err := b.isGroupAdmin(chatID, userID)
 		if err != nil {
 			log.Printf("Error checking admin status: %v", err)
-			b.SendMessage(chatID, "❌ Failed to verify admin status. Please try again later.")
+			b.SendMessage(chatID, i18n.T(lang, "admin.verify_failed"))
 			return
 		}
 		if !isAdmin {
-			b.SendMessage(chatID, "❌ This command is only available to group administrators.")
+			b.SendMessage(chatID, i18n.T(lang, "admin.required"))
 			return
 		}
 	}
 
 	switch strings.ToLower(command) {
 	case "start":
-		b.sendHelpMessage(chatID)
+		b.sendHelpMessage(chatID, lang)
 	case "help":
 		// Only respond to /help if it's addressed to this bot (private chat or /help@botname in groups)
 		if !b.isCommandAddressedToBot(message) {
 			return // Ignore the command if not addressed to this bot
 		}
-		b.sendHelpMessage(chatID)
+		b.sendHelpMessage(chatID, lang)
 	case "proposalsubscribe":
-		if err := b.handleSubscribe(chatID, args); err != nil {
+		if err := b.handleSubscribe(chatID, lang, args); err != nil {
 			log.Printf("Error handling subscribe command: %v", err)
-			b.SendMessage(chatID, "❌ Failed to subscribe. Please try again later.")
+			b.SendMessage(chatID, i18n.T(lang, "subscribe.error"))
 		}
 	case "proposalunsubscribe":
-		if err := b.handleUnsubscribe(chatID, args); err != nil {
+		if err := b.handleUnsubscribe(chatID, lang, args); err != nil {
 			log.Printf("Error handling unsubscribe command: %v", err)
-			b.SendMessage(chatID, "❌ Failed to unsubscribe. Please try again later.")
+			b.SendMessage(chatID, i18n.T(lang, "unsubscribe.error"))
+		}
+	case "proposalfilter":
+		if err := b.handleFilter(chatID, lang, args); err != nil {
+			log.Printf("Error handling filter command: %v", err)
+			b.SendMessage(chatID, i18n.T(lang, "filter.error"))
 		}
 	case "mysubscriptions":
-		if err := b.handleMySubscriptions(chatID); err != nil {
+		if err := b.handleMySubscriptions(chatID, lang); err != nil {
 			log.Printf("Error handling my subscriptions command: %v", err)
-			b.SendMessage(chatID, "❌ Failed to list subscriptions. Please try again later.")
+			b.SendMessage(chatID, i18n.T(lang, "subscriptions.error"))
+		}
+	case "language":
+		if err := b.handleLanguage(chatID, lang, args); err != nil {
+			log.Printf("Error handling language command: %v", err)
+			b.SendMessage(chatID, i18n.T(lang, "language.error"))
+		}
+	case "settings":
+		if err := b.handleSettings(chatID, lang, settings, args); err != nil {
+			log.Printf("Error handling settings command: %v", err)
+			b.SendMessage(chatID, i18n.T(lang, "settings.error"))
+		}
+	case "quiet":
+		if err := b.handleQuiet(chatID, lang, settings, args); err != nil {
+			log.Printf("Error handling quiet command: %v", err)
+			b.SendMessage(chatID, i18n.T(lang, "settings.error"))
+		}
+	case "snooze":
+		if err := b.handleSnooze(chatID, lang, settings, args); err != nil {
+			log.Printf("Error handling snooze command: %v", err)
+			b.SendMessage(chatID, i18n.T(lang, "settings.error"))
+		}
+	case "webhook":
+		if err := b.handleWebhook(chatID, userID, lang, args); err != nil {
+			log.Printf("Error handling webhook command: %v", err)
+			b.SendMessage(chatID, i18n.T(lang, "webhook.error"))
+		}
+	case "email":
+		if err := b.handleEmail(chatID, userID, lang, args); err != nil {
+			log.Printf("Error handling email command: %v", err)
+			b.SendMessage(chatID, i18n.T(lang, "email.error"))
 		}
 	default:
-		b.SendMessage(chatID, "❌ Unknown command. Use /help to see available commands.")
+		b.SendMessage(chatID, i18n.T(lang, "command.unknown"))
+	}
+}
+
+// allowCommand checks a user's command against the rate limits and ban list,
+// telling the user once when they are limited or banned
+func (b *Bot) allowCommand(chatID int64, user *tgbotapi.User) bool {
+	decision := b.guard.Check(context.Background(), chatID, user.ID, time.Now())
+	if decision.Verdict == abuse.Allowed {
+		return true
 	}
+
+	if decision.Notify {
+		lang := i18n.Normalize(user.LanguageCode)
+		if lang == "" {
+			lang = i18n.DefaultLanguage
+		}
+
+		key := "abuse.rate_limited"
+		if decision.Verdict == abuse.Banned {
+			key = "abuse.banned"
+		}
+		b.SendMessage(chatID, i18n.T(lang, key))
+	}
+
+	return false
 }
 
 // isCommandAddressedToBot checks if a command was explicitly addressed to this bot
@@ -110,66 +215,95 @@ func (b *Bot) isCommandAddressedToBot(message *tgbotapi.Message) bool {
 }
 
 // sendHelpMessage sends the help message to the specified chat
-func (b *Bot) sendHelpMessage(chatID int64) {
-	helpText := `🤖 *Zera Bot Help* 🤖
-
-*Available commands:*
-/start - Start the bot
-/help - Show this help message
-/proposalSubscribe [symbols] - Subscribe to proposal updates
-/proposalUnsubscribe [symbols] - Unsubscribe from proposal updates
-/mySubscriptions - List all your current subscriptions
-
-*Examples:*
-- Subscribe to multiple tokens: /proposalSubscribe ZRA,ETH,BTC
-- Unsubscribe from all: /proposalUnsubscribe all
-- Unsubscribe from specific tokens: /proposalUnsubscribe ETH,BTC
-- Check your subscriptions: /mySubscriptions
-
-*Note:* Use 'all' to manage all subscriptions at once.`
-
-	b.SendMessage(chatID, helpText)
+func (b *Bot) sendHelpMessage(chatID int64, lang string) {
+	b.SendMessage(chatID, i18n.T(lang, "help.text"))
 }
 
 // SendMessage sends a message to the specified chat with Markdown parsing
 func (b *Bot) SendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-	if _, err := b.API.Send(msg); err != nil {
-		log.Printf("Markdown parsing failed for chat %d, retrying without parsing: %v", chatID, err)
-		// If Markdown parsing fails, try without parsing
-		msg.ParseMode = ""
-		if _, err := b.API.Send(msg); err != nil {
-			log.Printf("Error sending message: %v", err)
-		}
+	if err := b.sendWithFallback(tgbotapi.NewMessage(chatID, text)); err != nil {
+		log.Printf("Error sending message: %v", err)
 	}
 }
 
-// NotifySubscribers sends a notification to all subscribers of a specific symbol
-func (b *Bot) NotifySubscribers(symbol string, message string) error {
-	subscribers, err := b.subRepo.GetSubscribers(context.Background(), symbol, db.ProposalType)
+// Name identifies Telegram among notification channels
+func (b *Bot) Name() string {
+	return string(db.TelegramChannel)
+}
+
+// NotifySubscribers sends a proposal alert to all subscribers of its symbol,
+// rendered in each chat's language
+func (b *Bot) NotifySubscribers(alert *notify.ProposalAlert) error {
+	resolved, err := b.subRepo.ResolveSubscribers(context.Background(), alert.Symbol, db.ProposalType)
 	if err != nil {
 		return fmt.Errorf("failed to get subscribers: %w", err)
 	}
 
-	// Only keep ZERABot test chat if in dev mode
-	if os.Getenv("DEV") == "TRUE" {
-		// Filter subscribers to only keep -4897181115 if it exists
+	// A chat is notified if any of its matching subscriptions passes its filter
+	candidate := alert.FilterCandidate()
+	var subscribers []int64
+	for _, subscriber := range resolved {
+		for _, match := range subscriber.Matches {
+			if match.Subscription.Filter.Match(candidate) {
+				subscribers = append(subscribers, subscriber.ChatID)
+				break
+			}
+		}
+	}
+
+	// Only keep the test chat if in dev mode
+	if b.onlyChatID != 0 {
 		var filteredSubscribers []int64
 		for _, subID := range subscribers {
-			if subID == -4897181115 {
+			if subID == b.onlyChatID {
 				filteredSubscribers = append(filteredSubscribers, subID)
 			}
 		}
 		subscribers = filteredSubscribers
 	}
 
+	// Render each language and format combination at most once
+	rendered := make(map[string]string)
+	now := time.Now()
 	for _, chatID := range subscribers {
+		settings := b.chatSettings(chatID)
+		if settings.Muted {
+			continue
+		}
 
-		if err := SendToChatID(chatID, message); err != nil {
+		// Digest chats, and chats holding alerts while quiet, get the alert in their next summary
+		if settings.DeliveryMode != db.InstantDelivery || holdsAlerts(settings, now) {
+			if err := b.pendingRepo.Enqueue(context.Background(), newPendingAlert(chatID, alert)); err != nil {
+				log.Printf("Failed to queue notification for chat %d: %v", chatID, err)
+			}
+			continue
+		}
+
+		lang := chatLanguage(settings)
+		key := lang + ":" + string(settings.MessageFormat)
+		message, ok := rendered[key]
+		if !ok {
+			message = formatProposalAlert(lang, settings.MessageFormat, alert)
+			rendered[key] = message
+		}
+
+		msg := tgbotapi.NewMessage(chatID, message)
+		msg.DisableWebPagePreview = !settings.LinkPreviews
+		msg.DisableNotification = isQuiet(settings, now)
+		if err := b.sendWithFallback(msg); err != nil {
 			log.Printf("Failed to send notification to chat %d: %v", chatID, err)
 		}
 	}
 
 	return nil
 }
+
+// SendProposal sends a proposal alert to one chat in its language and format, whatever
+// its subscriptions and mute or delivery settings, e.g. to resend an alert it missed
+func (b *Bot) SendProposal(chatID int64, alert *notify.ProposalAlert) error {
+	settings := b.chatSettings(chatID)
+
+	msg := tgbotapi.NewMessage(chatID, formatProposalAlert(chatLanguage(settings), settings.MessageFormat, alert))
+	msg.DisableWebPagePreview = !settings.LinkPreviews
+	return b.sendWithFallback(msg)
+}
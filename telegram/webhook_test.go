@@ -0,0 +1,515 @@
+package telegram_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/abuse"
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/email"
+	"github.com/ZeraVision/ZeraBot/filter"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/internal/pgtest"
+	"github.com/ZeraVision/ZeraBot/internal/smtptest"
+	"github.com/ZeraVision/ZeraBot/internal/telegramtest"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/registry"
+	"github.com/ZeraVision/ZeraBot/telegram"
+	"github.com/ZeraVision/ZeraBot/webhook"
+)
+
+// testBot is a bot talking to a fake Telegram server, with its stores on the test database
+// and emails sent to a local SMTP sink
+type testBot struct {
+	*telegram.Bot
+	api      *telegramtest.Server
+	mail     *smtptest.Server
+	database *db.Database
+}
+
+// knownContracts are in the registry of every test bot
+var knownContracts = []*db.Contract{
+	{ContractID: "$ZRA+0000", Symbol: "ZRA", Name: "Zera"},
+	{ContractID: "$ZIP+0001", Symbol: "ZIP", Name: "Zip"},
+}
+
+// newTestBot opens the test database and builds a bot on it and a fake Telegram server
+func newTestBot(t *testing.T) *testBot {
+	t.Helper()
+	database := pgtest.Open(t)
+
+	api := telegramtest.NewServer()
+	t.Cleanup(api.Close)
+	botAPI, err := api.NewBotAPI()
+	if err != nil {
+		t.Fatalf("failed to connect to fake Telegram server: %v", err)
+	}
+
+	reg := registry.NewRegistry(db.NewContractRepository(database))
+	if err := reg.Add(context.Background(), knownContracts...); err != nil {
+		t.Fatalf("failed to add contracts: %v", err)
+	}
+
+	mail, err := smtptest.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start SMTP sink: %v", err)
+	}
+	t.Cleanup(func() { mail.Close() })
+
+	subs := db.NewSubscriptionRepository(database)
+	settings := db.NewChatSettingsRepository(database)
+	emailCfg := email.DefaultConfig()
+	emailCfg.From = "ZeraBot <alerts@example.com>"
+	emailCfg.BaseURL = "https://bot.example.com"
+	sender := email.NewSMTPSender(email.SMTPConfig{Host: mail.Host(), Port: mail.Port()})
+
+	bot := telegram.NewBot(botAPI, telegram.Services{
+		Database:      database,
+		Subscriptions: subs,
+		Settings:      settings,
+		PendingAlerts: db.NewPendingAlertRepository(database),
+		Proposals:     db.NewProposalRepository(database),
+		Registry:      reg,
+		Guard:         abuse.NewGuard(abuse.Config{}, db.NewBanRepository(database)),
+		Webhooks:      webhook.NewService(webhook.DefaultConfig(), db.NewWebhookRepository(database)),
+		Email:         email.NewService(emailCfg, db.NewEmailRepository(database), subs, settings, sender),
+	}, telegram.Options{})
+
+	return &testBot{Bot: bot, api: api, mail: mail, database: database}
+}
+
+// command sends a command through the webhook handler and returns the bot's replies in the chat
+func (b *testBot) command(chatID, userID int64, text string) []telegramtest.Message {
+	before := len(b.api.MessagesTo(chatID))
+	b.WebhookHandler(httptest.NewRecorder(), telegramtest.NewUpdateRequest(telegramtest.CommandUpdate(chatID, userID, text)))
+	return b.api.MessagesTo(chatID)[before:]
+}
+
+// expectReply sends a command and checks that the bot answered with exactly one message
+// containing every given text
+func (b *testBot) expectReply(t *testing.T, chatID, userID int64, text string, contains ...string) telegramtest.Message {
+	t.Helper()
+
+	replies := b.command(chatID, userID, text)
+	if len(replies) != 1 {
+		t.Fatalf("%s: got %d replies, want 1: %+v", text, len(replies), replies)
+	}
+	for _, s := range contains {
+		if !strings.Contains(replies[0].Text, s) {
+			t.Errorf("%s: reply %q doesn't contain %q", text, replies[0].Text, s)
+		}
+	}
+	return replies[0]
+}
+
+// settings returns a chat's stored settings
+func (b *testBot) settings(t *testing.T, chatID int64) *db.ChatSettings {
+	t.Helper()
+
+	settings, err := db.NewChatSettingsRepository(b.database).Get(context.Background(), chatID)
+	if err != nil {
+		t.Fatalf("failed to get settings of chat %d: %v", chatID, err)
+	}
+	return settings
+}
+
+func TestSubscriptionCommands(t *testing.T) {
+	bot := newTestBot(t)
+	const chat = 1 // A private chat, where the user is always an admin
+
+	bot.expectReply(t, chat, chat, "/proposalSubscribe $zra+0000", i18n.T("en", "subscribe.success_one", "$ZRA+0000"))
+	bot.expectReply(t, chat, chat, "/proposalSubscribe $ZRA+0000,$ZIP+0001", i18n.N("en", "subscribe.success_many", 2, 2))
+	bot.expectReply(t, chat, chat, "/proposalSubscribe $ZRA+12", i18n.T("en", "symbol.invalid_format"))
+	bot.expectReply(t, chat, chat, "/proposalSubscribe", i18n.T("en", "subscribe.usage"))
+
+	bot.expectReply(t, chat, chat, "/mySubscriptions",
+		i18n.N("en", "subscriptions.header", 2, 2),
+		i18n.T("en", "subscriptions.item", "$ZRA+0000", "proposal"),
+		i18n.T("en", "subscriptions.item", "$ZIP+0001", "proposal"),
+	)
+
+	bot.expectReply(t, chat, chat, "/proposalUnsubscribe $ZIP+0001", i18n.T("en", "unsubscribe.success_one", "$ZIP+0001"))
+	bot.expectReply(t, chat, chat, "/proposalUnsubscribe $ZIP+0001", i18n.T("en", "unsubscribe.not_subscribed", "$ZIP+0001"))
+
+	bot.expectReply(t, chat, chat, "/proposalUnsubscribe all", i18n.T("en", "unsubscribe.all_success"))
+	bot.expectReply(t, chat, chat, "/mySubscriptions", i18n.T("en", "subscriptions.empty"))
+}
+
+func TestGroupCommandsNeedAdmin(t *testing.T) {
+	bot := newTestBot(t)
+	const group, member, admin = -100, 5, 6
+	bot.api.SetChatMember(group, admin, "administrator")
+
+	bot.expectReply(t, group, member, "/proposalSubscribe $ZRA+0000", i18n.T("en", "admin.required"))
+	bot.expectReply(t, group, member, "/settings mute on", i18n.T("en", "admin.required"))
+	bot.expectReply(t, group, member, "/mySubscriptions", i18n.T("en", "subscriptions.empty"))
+
+	bot.expectReply(t, group, admin, "/proposalSubscribe $ZRA+0000", i18n.T("en", "subscribe.success_one", "$ZRA+0000"))
+
+	// Once admins allow it, anyone in the group may change subscriptions
+	bot.command(group, admin, "/settings adminonly off")
+	bot.expectReply(t, group, member, "/proposalSubscribe $ZIP+0001", i18n.T("en", "subscribe.success_one", "$ZIP+0001"))
+}
+
+func TestSettingsCommands(t *testing.T) {
+	bot := newTestBot(t)
+	const chat = 2
+
+	// Without arguments the settings are shown with a keyboard to change them
+	reply := bot.expectReply(t, chat, chat, "/settings")
+	if !strings.Contains(reply.ReplyMarkup, `"callback_data":"settings:`) {
+		t.Errorf("/settings keyboard = %q, want settings buttons", reply.ReplyMarkup)
+	}
+
+	bot.expectReply(t, chat, chat, "/settings format compact", i18n.T("en", "settings.format.compact"))
+	bot.expectReply(t, chat, chat, "/settings delivery daily", i18n.T("en", "settings.delivery.daily"))
+	bot.expectReply(t, chat, chat, "/settings format huge", i18n.T("en", "settings.invalid", "format", "huge"))
+	bot.expectReply(t, chat, chat, "/settings format", i18n.T("en", "settings.usage"))
+
+	settings := bot.settings(t, chat)
+	if settings.MessageFormat != db.CompactFormat || settings.DeliveryMode != db.DailyDigest {
+		t.Errorf("stored format %q and delivery %q, want compact and daily", settings.MessageFormat, settings.DeliveryMode)
+	}
+
+	// Replies follow the chat's language once it is set
+	bot.command(chat, chat, "/language es")
+	if got := bot.settings(t, chat).Language; got != "es" {
+		t.Errorf("stored language %q, want es", got)
+	}
+	bot.expectReply(t, chat, chat, "/mySubscriptions", i18n.T("es", "subscriptions.empty"))
+}
+
+// press sends a settings keyboard button press and returns the edited settings message and the callback answer
+func (b *testBot) press(t *testing.T, chatID, userID int64, data string) (*telegramtest.Message, string) {
+	t.Helper()
+
+	before, answered := len(b.api.MessagesTo(chatID)), len(b.api.CallbackAnswers())
+	b.WebhookHandler(httptest.NewRecorder(), telegramtest.NewUpdateRequest(telegramtest.CallbackUpdate(chatID, userID, 1, data)))
+
+	answers := b.api.CallbackAnswers()[answered:]
+	if len(answers) != 1 {
+		t.Fatalf("%s: got %d callback answers, want 1", data, len(answers))
+	}
+	var edit *telegramtest.Message
+	if messages := b.api.MessagesTo(chatID)[before:]; len(messages) > 0 {
+		edit = &messages[len(messages)-1]
+		if !edit.Edited {
+			t.Errorf("%s: sent a new message instead of editing the settings", data)
+		}
+	}
+	return edit, answers[0].Text
+}
+
+func TestSettingsKeyboard(t *testing.T) {
+	bot := newTestBot(t)
+	const chat, group, member = 3, -300, 7
+
+	cases := []struct {
+		data  string
+		check func(s *db.ChatSettings) bool
+		next  string // A button the keyboard should offer afterwards, if any
+	}{
+		{"settings:mute:toggle", func(s *db.ChatSettings) bool { return s.Muted }, ""},
+		{"settings:mute:toggle", func(s *db.ChatSettings) bool { return !s.Muted }, ""},
+		{"settings:previews:toggle", func(s *db.ChatSettings) bool { return !s.LinkPreviews }, ""},
+		{"settings:adminonly:toggle", func(s *db.ChatSettings) bool { return !s.AdminOnly }, ""},
+		{"settings:adminonly:toggle", func(s *db.ChatSettings) bool { return s.AdminOnly }, ""},
+		{"settings:format:compact", func(s *db.ChatSettings) bool { return s.MessageFormat == db.CompactFormat }, "settings:format:full"},
+		{"settings:delivery:hourly", func(s *db.ChatSettings) bool { return s.DeliveryMode == db.HourlyDigest }, "settings:delivery:daily"},
+		{"settings:delivery:daily", func(s *db.ChatSettings) bool { return s.DeliveryMode == db.DailyDigest }, "settings:delivery:instant"},
+		{"settings:delivery:instant", func(s *db.ChatSettings) bool { return s.DeliveryMode == db.InstantDelivery }, "settings:delivery:hourly"},
+		{"settings:timezone:Asia/Singapore", func(s *db.ChatSettings) bool { return s.Timezone == "Asia/Singapore" }, "settings:menu:timezone"},
+		{"settings:language:es", func(s *db.ChatSettings) bool { return s.Language == "es" }, "settings:menu:language"},
+	}
+	for _, c := range cases {
+		edit, answer := bot.press(t, chat, chat, c.data)
+		if edit == nil || answer != "" {
+			t.Errorf("%s: edited %v and answered %q, want the settings updated silently", c.data, edit != nil, answer)
+			continue
+		}
+		if settings := bot.settings(t, chat); !c.check(settings) {
+			t.Errorf("%s: stored %+v", c.data, settings)
+		}
+		if c.next != "" && !strings.Contains(edit.ReplyMarkup, `"`+c.next+`"`) {
+			t.Errorf("%s: keyboard %s has no %s button", c.data, edit.ReplyMarkup, c.next)
+		}
+	}
+
+	// After switching to Spanish the settings are shown in Spanish
+	edit, _ := bot.press(t, chat, chat, "settings:menu:main")
+	if edit == nil || !strings.Contains(edit.Text, i18n.T("es", "language.name")) || !strings.Contains(edit.ReplyMarkup, i18n.T("es", "settings.button.language")) {
+		t.Errorf("main menu in Spanish = %+v", edit)
+	}
+
+	// Menus only switch the keyboard
+	edit, _ = bot.press(t, chat, chat, "settings:menu:timezone")
+	if edit == nil || !strings.Contains(edit.ReplyMarkup, `"settings:timezone:Europe/Madrid"`) || !strings.Contains(edit.ReplyMarkup, `"settings:menu:main"`) {
+		t.Errorf("timezone menu = %+v, want timezone buttons and a back button", edit)
+	}
+
+	// Invalid values are refused without editing the message
+	if edit, answer := bot.press(t, chat, chat, "settings:delivery:weekly"); edit != nil || answer != i18n.T("es", "settings.error") {
+		t.Errorf("invalid delivery mode: edited %v and answered %q", edit != nil, answer)
+	}
+
+	// In groups only admins may press the buttons, as long as adminonly is on
+	if edit, answer := bot.press(t, group, member, "settings:mute:toggle"); edit != nil || answer != i18n.T("en", "admin.required") {
+		t.Errorf("member pressing mute: edited %v and answered %q", edit != nil, answer)
+	}
+	if bot.settings(t, group).Muted {
+		t.Error("a member muted the group")
+	}
+}
+
+func TestHelpCommands(t *testing.T) {
+	bot := newTestBot(t)
+	const chat, group, member = 3, -101, 7
+	help := i18n.T("en", "help.text")
+
+	bot.expectReply(t, chat, chat, "/start", help)
+	bot.expectReply(t, chat, chat, "/help", help)
+	bot.expectReply(t, group, member, "/start", help)
+
+	// In groups /help is only answered when addressed to this bot
+	if replies := bot.command(group, member, "/help"); len(replies) != 0 {
+		t.Errorf("/help in a group got %d replies, want none", len(replies))
+	}
+	if replies := bot.command(group, member, "/help@SomeOtherBot"); len(replies) != 0 {
+		t.Errorf("/help@SomeOtherBot got %d replies, want none", len(replies))
+	}
+	bot.expectReply(t, group, member, "/help@"+telegramtest.BotUser.UserName, help)
+}
+
+func TestQuietAndSnoozeCommands(t *testing.T) {
+	bot := newTestBot(t)
+	const chat, group, member = 4, -102, 8
+
+	bot.expectReply(t, chat, chat, "/quiet", i18n.T("en", "quiet.off"), i18n.T("en", "quiet.usage"))
+	bot.expectReply(t, chat, chat, "/quiet 23:00-07:00",
+		i18n.T("en", "quiet.on", "23:00", "07:00", "UTC", i18n.T("en", "quiet.mode.hold")))
+	bot.expectReply(t, chat, chat, "/quiet silent",
+		i18n.T("en", "quiet.on", "23:00", "07:00", "UTC", i18n.T("en", "quiet.mode.silent")))
+	bot.expectReply(t, chat, chat, "/quiet 25:00-07:00", i18n.T("en", "quiet.usage"))
+	bot.expectReply(t, chat, chat, "/quiet 07:00-07:00", i18n.T("en", "quiet.usage"))
+
+	settings := bot.settings(t, chat)
+	if settings.QuietStart != 23*60 || settings.QuietEnd != 7*60 || settings.QuietMode != db.SilentAlerts {
+		t.Errorf("stored quiet hours %d-%d %s, want 1380-420 silent", settings.QuietStart, settings.QuietEnd, settings.QuietMode)
+	}
+
+	bot.expectReply(t, chat, chat, "/snooze", i18n.T("en", "snooze.off"), i18n.T("en", "snooze.usage"))
+	before := time.Now()
+	bot.expectReply(t, chat, chat, "/snooze 2h", "Snoozed until")
+	if until := bot.settings(t, chat).SnoozedUntil; until.Before(before.Add(2*time.Hour-time.Minute)) || until.After(time.Now().Add(2*time.Hour)) {
+		t.Errorf("stored snooze until %s, want two hours from now", until)
+	}
+	bot.expectReply(t, chat, chat, "/snooze 8d", i18n.T("en", "snooze.usage"))
+	bot.expectReply(t, chat, chat, "/snooze off", i18n.T("en", "snooze.off"))
+	if until := bot.settings(t, chat).SnoozedUntil; until.After(time.Now()) {
+		t.Errorf("stored snooze until %s after /snooze off", until)
+	}
+
+	// Members may look at a group's quiet hours but only admins change them
+	bot.expectReply(t, group, member, "/quiet", i18n.T("en", "quiet.off"))
+	bot.expectReply(t, group, member, "/quiet 22:00-06:00", i18n.T("en", "admin.required"))
+	bot.expectReply(t, group, member, "/snooze 1h", i18n.T("en", "admin.required"))
+}
+
+func TestWebhookCommand(t *testing.T) {
+	bot := newTestBot(t)
+	const chat, group, admin = 5, -103, 9
+	bot.api.SetChatMember(group, admin, "administrator")
+
+	// Secrets are shown when adding, so groups are refused even for admins
+	bot.expectReply(t, group, admin, "/webhook", i18n.T("en", "webhook.private_only"))
+	bot.expectReply(t, group, admin, "/webhook add https://example.com/hook", i18n.T("en", "webhook.private_only"))
+
+	bot.expectReply(t, chat, chat, "/webhook", i18n.T("en", "webhook.none"))
+	bot.expectReply(t, chat, chat, "/webhook add", i18n.T("en", "webhook.usage"))
+	bot.expectReply(t, chat, chat, "/webhook add http://example.com/hook", "Invalid webhook URL")
+	bot.expectReply(t, chat, chat, "/webhook add https://example.com/hook proposal.vote",
+		i18n.T("en", "webhook.invalid_event", strings.Join(webhook.EventTypes, ", ")))
+	bot.expectReply(t, chat, chat, "/webhook add https://example.com/hook $ZRA+0000 proposal.created", "whsec_")
+
+	repo := db.NewWebhookRepository(bot.database)
+	webhooks, err := repo.List(context.Background(), chat)
+	if err != nil || len(webhooks) != 1 {
+		t.Fatalf("stored webhooks = %v, %v, want the one added", webhooks, err)
+	}
+	w := webhooks[0]
+	if w.URL != "https://example.com/hook" || strings.Join(w.Symbols, ",") != "$ZRA+0000" || strings.Join(w.EventTypes, ",") != webhook.EventProposalCreated {
+		t.Errorf("stored webhook %+v, want the URL, symbol and event type given", w)
+	}
+	if others, _ := repo.List(context.Background(), group); len(others) != 0 {
+		t.Errorf("group has %d webhooks, want none", len(others))
+	}
+
+	id := fmt.Sprint(w.ID)
+	bot.expectReply(t, chat, chat, "/webhook", i18n.T("en", "webhook.list_header"), i18n.T("en", "webhook.status_enabled"))
+	bot.expectReply(t, chat, chat, "/webhook disable "+id, i18n.T("en", "webhook.disabled", w.ID))
+	bot.expectReply(t, chat, chat, "/webhook", i18n.T("en", "webhook.status_disabled"))
+	bot.expectReply(t, chat, chat, "/webhook enable #"+id, i18n.T("en", "webhook.enabled", w.ID))
+	bot.expectReply(t, chat, chat, "/webhook remove "+id, i18n.T("en", "webhook.removed", w.ID))
+	bot.expectReply(t, chat, chat, "/webhook remove "+id, i18n.T("en", "webhook.not_found", w.ID))
+	bot.expectReply(t, chat, chat, "/webhook remove abc", i18n.T("en", "webhook.usage"))
+}
+
+func TestEmailCommand(t *testing.T) {
+	bot := newTestBot(t)
+	const chat, group, admin = 6, -104, 10
+	bot.api.SetChatMember(group, admin, "administrator")
+
+	bot.expectReply(t, group, admin, "/email add group@example.com", i18n.T("en", "email.private_only"))
+	if n := len(bot.mail.Messages()); n != 0 {
+		t.Fatalf("%d emails sent for a group, want none", n)
+	}
+
+	bot.expectReply(t, chat, chat, "/email", i18n.T("en", "email.none"))
+	bot.expectReply(t, chat, chat, "/email add not-an-address", i18n.T("en", "email.invalid_address"))
+	bot.expectReply(t, chat, chat, "/email add", i18n.T("en", "email.usage"))
+	bot.expectReply(t, chat, chat, "/email add User@Example.com", "A confirmation link was sent to user@example")
+	if n := len(bot.mail.MessagesTo("user@example.com")); n != 1 {
+		t.Fatalf("user@example.com got %d emails, want the confirmation", n)
+	}
+	bot.expectReply(t, chat, chat, "/email add user@example.com", "A confirmation link was sent to user@example")
+	if n := len(bot.mail.MessagesTo("user@example.com")); n != 1 {
+		t.Errorf("user@example.com got %d emails after adding it again, want no second confirmation yet", n)
+	}
+
+	recipients, err := db.NewEmailRepository(bot.database).List(context.Background(), chat)
+	if err != nil || len(recipients) != 1 {
+		t.Fatalf("stored addresses = %v, %v, want the one added", recipients, err)
+	}
+	e := recipients[0]
+
+	bot.expectReply(t, chat, chat, "/email", i18n.T("en", "email.list_header"), i18n.T("en", "email.status_pending"), i18n.T("en", "email.mode_instant"))
+	bot.expectReply(t, chat, chat, "/email daily "+fmt.Sprint(e.ID), i18n.T("en", "email.daily", e.ID))
+	bot.expectReply(t, chat, chat, "/email", i18n.T("en", "email.mode_daily"))
+	bot.expectReply(t, chat, chat, "/email remove "+fmt.Sprint(e.ID), i18n.T("en", "email.removed", e.ID))
+	bot.expectReply(t, chat, chat, "/email instant "+fmt.Sprint(e.ID), i18n.T("en", "email.not_found", e.ID))
+}
+
+// inline sends an inline query through the webhook handler and returns the bot's answer
+func (b *testBot) inline(t *testing.T, userID int64, query string) url.Values {
+	t.Helper()
+
+	before := len(b.api.InlineAnswers())
+	b.WebhookHandler(httptest.NewRecorder(), telegramtest.NewUpdateRequest(telegramtest.InlineUpdate(userID, query)))
+	answers := b.api.InlineAnswers()[before:]
+	if len(answers) != 1 {
+		t.Fatalf("%q: got %d inline answers, want 1", query, len(answers))
+	}
+	return answers[0].Params
+}
+
+func TestInlineQuery(t *testing.T) {
+	bot := newTestBot(t)
+	const english, spanish = 11, 12
+
+	proposal := &db.Proposal{ProposalID: "inline-1", ContractID: "$ZRA+0000", Title: "Raise the fee"}
+	if err := db.NewProposalRepository(bot.database).Save(context.Background(), proposal); err != nil {
+		t.Fatalf("failed to save proposal: %v", err)
+	}
+
+	answer := bot.inline(t, english, "zra")
+	if got := answer.Get("inline_query_id"); got != "inline-zra" {
+		t.Errorf("answered inline query %q, want inline-zra", got)
+	}
+	// Results are in the user's language, so they mustn't be cached for everyone
+	if answer.Get("is_personal") != "true" || answer.Get("cache_time") != "60" {
+		t.Errorf("is_personal %q and cache_time %q, want true and 60", answer.Get("is_personal"), answer.Get("cache_time"))
+	}
+	results := answer.Get("results")
+	for _, want := range []string{"$ZRA+0000 · Zera", "/proposalSubscribe $ZRA+0000", i18n.N("en", "inline.description", 1, 1)} {
+		if !strings.Contains(results, want) {
+			t.Errorf("results %s don't contain %q", results, want)
+		}
+	}
+	if strings.Contains(results, "$ZIP+0001") {
+		t.Errorf("results %s contain $ZIP+0001, which doesn't match zra", results)
+	}
+
+	// A user's language is taken from their private chat's settings
+	bot.command(spanish, spanish, "/language es")
+	if results := bot.inline(t, spanish, "zip").Get("results"); !strings.Contains(results, i18n.N("es", "inline.description", 0, 0)) {
+		t.Errorf("results %s aren't described in Spanish", results)
+	}
+}
+
+func TestNotifySubscribersFanOut(t *testing.T) {
+	bot := newTestBot(t)
+
+	// Each chat is a private chat set up through commands
+	const (
+		exact    = 11 // Subscribed to the proposal's symbol
+		compact  = 12 // Subscribed to everything, in the compact format
+		muted    = 13 // Subscribed through a pattern, but muted
+		digest   = 14 // Subscribed to everything, with daily digests
+		other    = 15 // Subscribed to another symbol only
+		filtered = 16 // Subscribed to the symbol with a filter the proposal fails
+	)
+	setup := map[int64][]string{
+		exact:    {"/proposalSubscribe $ZRA+0000"},
+		compact:  {"/proposalSubscribe all", "/settings format compact"},
+		muted:    {"/proposalSubscribe $ZRA+*", "/settings mute on"},
+		digest:   {"/proposalSubscribe all", "/settings delivery daily"},
+		other:    {"/proposalSubscribe $ZIP+0001"},
+		filtered: {"/proposalSubscribe $ZRA+0000", "/proposalFilter $ZRA+0000 burn"},
+	}
+	for chat, commands := range setup {
+		for _, command := range commands {
+			bot.command(chat, chat, command)
+		}
+	}
+	bot.api.Reset()
+
+	alert := &notify.ProposalAlert{
+		Symbol:     "$ZRA+0000",
+		ProposalID: "abc123",
+		Title:      "Fund the treasury",
+		Synopsis:   "Move 1000 ZRA to the community treasury.",
+		Types:      []string{filter.TypeYesNo},
+	}
+	full := i18n.T("en", "proposal.new", alert.Symbol, alert.Title, alert.Synopsis, alert.ProposalID, alert.URL())
+	short := i18n.T("en", "proposal.new_compact", alert.Symbol, alert.Title, alert.Synopsis, alert.ProposalID, alert.URL())
+
+	// The first send fails on its Markdown, which must not cost the chat its alert
+	bot.api.FailNext("sendMessage", 400, "Bad Request: can't parse entities")
+
+	if err := notify.NewFanout(bot).NotifySubscribers(alert); err != nil {
+		t.Fatalf("NotifySubscribers: %v", err)
+	}
+
+	want := map[int64]string{exact: full, compact: short}
+	for _, chat := range []int64{exact, compact, muted, digest, other, filtered} {
+		messages := bot.api.MessagesTo(chat)
+		text, notified := want[chat]
+		if !notified {
+			if len(messages) != 0 {
+				t.Errorf("chat %d got %d messages, want none", chat, len(messages))
+			}
+			continue
+		}
+
+		if len(messages) != 1 {
+			t.Errorf("chat %d got %d messages, want 1", chat, len(messages))
+			continue
+		}
+		if messages[0].Text != text {
+			t.Errorf("chat %d got %q, want %q", chat, messages[0].Text, text)
+		}
+	}
+
+	pending, err := db.NewPendingAlertRepository(bot.database).List(context.Background(), digest)
+	if err != nil {
+		t.Fatalf("failed to list pending alerts: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ProposalID != alert.ProposalID {
+		t.Errorf("chat with daily digests has %d pending alerts, want the proposal queued", len(pending))
+	}
+}
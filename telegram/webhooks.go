@@ -0,0 +1,158 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/i18n"
+	"github.com/ZeraVision/ZeraBot/util"
+	"github.com/ZeraVision/ZeraBot/webhook"
+)
+
+// handleWebhook handles the /webhook command, which manages outbound webhooks:
+// "/webhook [list]", "/webhook add URL [symbols] [event types]",
+// "/webhook remove|enable|disable ID". Secrets are shown, so it only works in private chats.
+func (b *Bot) handleWebhook(chatID, userID int64, lang string, args string) error {
+	if b.webhooks == nil {
+		return b.SendToChatID(chatID, i18n.T(lang, "command.unknown"))
+	}
+	if chatID != userID {
+		return b.SendToChatID(chatID, i18n.T(lang, "webhook.private_only"))
+	}
+
+	fields := strings.Fields(args)
+	action := "list"
+	if len(fields) > 0 {
+		action = strings.ToLower(fields[0])
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "list":
+		return b.listWebhooks(ctx, chatID, lang)
+	case "add":
+		if len(fields) < 2 {
+			return b.SendToChatID(chatID, i18n.T(lang, "webhook.usage"))
+		}
+		return b.addWebhook(ctx, chatID, lang, fields[1], fields[2:])
+	case "remove", "enable", "disable":
+		if len(fields) != 2 {
+			return b.SendToChatID(chatID, i18n.T(lang, "webhook.usage"))
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(fields[1], "#"), 10, 64)
+		if err != nil {
+			return b.SendToChatID(chatID, i18n.T(lang, "webhook.usage"))
+		}
+		return b.changeWebhook(ctx, chatID, lang, action, id)
+	default:
+		return b.SendToChatID(chatID, i18n.T(lang, "webhook.usage"))
+	}
+}
+
+// addWebhook registers a webhook. Terms after the URL are comma separated symbols
+// and event types, told apart by the dot in event types.
+func (b *Bot) addWebhook(ctx context.Context, chatID int64, lang string, url string, terms []string) error {
+	var symbols, eventTypes []string
+	for _, term := range terms {
+		for _, item := range strings.Split(term, ",") {
+			item = strings.TrimSpace(item)
+			switch {
+			case item == "":
+			case strings.Contains(item, "."):
+				eventTypes = append(eventTypes, strings.ToLower(item))
+			default:
+				symbols = append(symbols, processSymbols(item)...)
+			}
+		}
+	}
+
+	w, err := b.webhooks.Register(ctx, chatID, url, eventTypes, symbols)
+	switch {
+	case errors.Is(err, webhook.ErrInvalidURL):
+		return b.SendToChatID(chatID, i18n.T(lang, "webhook.invalid_url", util.EscapeMarkdown(err.Error())))
+	case errors.Is(err, webhook.ErrInvalidEventType):
+		return b.SendToChatID(chatID, i18n.T(lang, "webhook.invalid_event", util.EscapeMarkdown(strings.Join(webhook.EventTypes, ", "))))
+	case errors.Is(err, webhook.ErrInvalidSymbol):
+		return b.SendToChatID(chatID, i18n.T(lang, "symbol.invalid_format"))
+	case errors.Is(err, webhook.ErrLimitReached):
+		return b.SendToChatID(chatID, i18n.T(lang, "webhook.limit_reached"))
+	case err != nil:
+		return fmt.Errorf("failed to register webhook: %w", err)
+	}
+
+	return b.SendToChatID(chatID, i18n.T(lang, "webhook.added", w.ID, util.EscapeMarkdown(w.URL), w.Secret))
+}
+
+// listWebhooks lists a chat's webhooks with their status
+func (b *Bot) listWebhooks(ctx context.Context, chatID int64, lang string) error {
+	webhooks, err := b.webhooks.Repository().List(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return b.SendToChatID(chatID, i18n.T(lang, "webhook.none"))
+	}
+
+	lines := []string{i18n.T(lang, "webhook.list_header")}
+	for _, w := range webhooks {
+		lines = append(lines, formatWebhook(lang, w))
+	}
+	return b.SendToChatID(chatID, strings.Join(lines, "\n\n"))
+}
+
+// changeWebhook removes, enables or disables one of a chat's webhooks
+func (b *Bot) changeWebhook(ctx context.Context, chatID int64, lang string, action string, id int64) error {
+	repo := b.webhooks.Repository()
+
+	var err error
+	switch action {
+	case "remove":
+		err = repo.Delete(ctx, chatID, id)
+	case "enable":
+		err = repo.SetEnabled(ctx, chatID, id, true)
+	case "disable":
+		err = repo.SetEnabled(ctx, chatID, id, false)
+	}
+	if errors.Is(err, db.ErrWebhookNotFound) {
+		return b.SendToChatID(chatID, i18n.T(lang, "webhook.not_found", id))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s webhook: %w", action, err)
+	}
+
+	return b.SendToChatID(chatID, i18n.T(lang, "webhook."+action+"d", id))
+}
+
+// NotifyWebhookDisabled tells the owner of a webhook that it was disabled after repeated failures
+func (b *Bot) NotifyWebhookDisabled(w *db.Webhook) {
+	lang := chatLanguage(b.chatSettings(w.ChatID))
+	b.SendMessage(w.ChatID, i18n.T(lang, "webhook.auto_disabled", w.ID, util.EscapeMarkdown(w.URL), util.EscapeMarkdown(w.LastError), w.ID))
+}
+
+// formatWebhook describes a webhook in a list
+func formatWebhook(lang string, w *db.Webhook) string {
+	status := i18n.T(lang, "webhook.status_enabled")
+	if !w.Enabled {
+		status = i18n.T(lang, "webhook.status_disabled")
+	}
+
+	events := i18n.T(lang, "webhook.any")
+	if len(w.EventTypes) > 0 {
+		events = strings.Join(w.EventTypes, ", ")
+	}
+	symbols := i18n.T(lang, "webhook.any")
+	if len(w.Symbols) > 0 {
+		symbols = strings.Join(w.Symbols, ", ")
+	}
+
+	text := i18n.T(lang, "webhook.item", w.ID, util.EscapeMarkdown(w.URL), status,
+		util.EscapeMarkdown(events), util.EscapeMarkdown(symbols))
+	if w.ConsecutiveFailures > 0 {
+		text += "\n" + i18n.T(lang, "webhook.failures", w.ConsecutiveFailures, util.EscapeMarkdown(w.LastError))
+	}
+	return text
+}
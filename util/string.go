@@ -14,23 +14,18 @@ func Truncate(s string, maxLen int) string {
 	return s[:maxLen-3] + "..."
 }
 
+// markdownEscaper escapes the Markdown characters in a single pass, so the backslashes
+// it adds are never escaped again
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\", // Backslash must always be escaped
+	"*", "\\*", // Asterisk for bold/italic
+	"_", "\\_", // Underscore for italic
+	"[", "\\[", // Square bracket for links
+	"]", "\\]", // Square bracket for links
+	"`", "\\`", // Backtick for code
+)
+
 // EscapeMarkdown escapes only the essential Markdown characters that could break parsing
 func EscapeMarkdown(s string) string {
-	// Only escape characters that are commonly used in Markdown formatting
-	// and could cause parsing issues if not escaped
-	replacements := map[string]string{
-		"\\": "\\\\", // Backslash must always be escaped
-		"*":  "\\*",  // Asterisk for bold/italic
-		"_":  "\\_",  // Underscore for italic
-		"[":  "\\[",  // Square bracket for links
-		"]":  "\\]",  // Square bracket for links
-		"`":  "\\`",  // Backtick for code
-	}
-
-	result := s
-	for old, new := range replacements {
-		result = strings.ReplaceAll(result, old, new)
-	}
-
-	return result
+	return markdownEscaper.Replace(s)
 }
@@ -0,0 +1,72 @@
+// Package webhook delivers proposal events to outbound webhooks registered by integrators,
+// as versioned JSON payloads signed with HMAC-SHA256
+package webhook
+
+import (
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/notify"
+)
+
+// Version is the version of the event payload format. It changes when fields are
+// removed or change meaning; new fields may be added without changing it.
+const Version = 1
+
+// EventProposalCreated is the event sent when a governance proposal is accepted on chain
+const EventProposalCreated = "proposal.created"
+
+// EventTypes lists the event types webhooks can subscribe to. Only proposal creation is
+// sent: blocks are read for governance proposals alone, not for votes or results.
+var EventTypes = []string{EventProposalCreated}
+
+// Event is the JSON payload posted to webhooks
+type Event struct {
+	ID        string      `json:"id"` // Unique per event, the same across retries
+	Type      string      `json:"type"`
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+
+	symbol string // Symbol webhooks are matched on
+}
+
+// ProposalData is the data of proposal events
+type ProposalData struct {
+	Symbol     string   `json:"symbol"`
+	ProposalID string   `json:"proposal_id"`
+	Title      string   `json:"title"`
+	Synopsis   string   `json:"synopsis"`
+	Types      []string `json:"types"`
+	Proposer   string   `json:"proposer"`
+	URL        string   `json:"url"`
+}
+
+// NewProposalCreatedEvent builds the event announcing a new proposal
+func NewProposalCreatedEvent(alert *notify.ProposalAlert, now time.Time) *Event {
+	return &Event{
+		ID:        EventProposalCreated + ":" + alert.ProposalID,
+		Type:      EventProposalCreated,
+		Version:   Version,
+		CreatedAt: now.UTC(),
+		Data: ProposalData{
+			Symbol:     alert.Symbol,
+			ProposalID: alert.ProposalID,
+			Title:      alert.Title,
+			Synopsis:   alert.Synopsis,
+			Types:      alert.Types,
+			Proposer:   alert.Proposer,
+			URL:        alert.URL(),
+		},
+		symbol: alert.Symbol,
+	}
+}
+
+// isEventType reports whether t is a known event type
+func isEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
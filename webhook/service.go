@@ -0,0 +1,480 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/db"
+	"github.com/ZeraVision/ZeraBot/notify"
+	"github.com/ZeraVision/ZeraBot/symbol"
+)
+
+// batchSize is how many due deliveries are attempted per run
+const batchSize = 50
+
+var (
+	// ErrInvalidURL is returned when registering a webhook with a URL that can't receive events
+	ErrInvalidURL = errors.New("invalid webhook URL")
+	// ErrInvalidEventType is returned when registering a webhook for an unknown event type
+	ErrInvalidEventType = errors.New("unknown event type")
+	// ErrInvalidSymbol is returned when registering a webhook for an invalid symbol or pattern
+	ErrInvalidSymbol = errors.New("invalid symbol")
+	// ErrLimitReached is returned when a chat already has the maximum number of webhooks
+	ErrLimitReached = errors.New("webhook limit reached")
+
+	errPrivateAddress = errors.New("webhooks can't be delivered to private or loopback addresses")
+)
+
+// Config controls delivery and registration
+type Config struct {
+	MaxPerChat   int           // Webhooks a chat may register, 0 for no limit
+	MaxAttempts  int           // Attempts before a delivery is given up on
+	BaseBackoff  time.Duration // Wait before the first retry, doubled for each further one
+	MaxBackoff   time.Duration // Longest wait between retries
+	DisableAfter int           // Consecutive failed attempts before a webhook is disabled, 0 never disables
+	Timeout      time.Duration // Timeout of a single attempt
+	BatchTimeout time.Duration // Longest a run of deliveries may take, 0 for no limit; the rest wait for the next run
+	AllowPrivate bool          // Allow http and private or loopback addresses (development and tests)
+}
+
+// DefaultConfig returns the default delivery settings
+func DefaultConfig() Config {
+	return Config{
+		MaxPerChat:   5,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+		DisableAfter: 10,
+		Timeout:      10 * time.Second,
+		BatchTimeout: time.Minute,
+	}
+}
+
+// Service registers outbound webhooks, queues events for them and delivers them
+// with retries. It is a notification channel, queuing an event for every alert.
+type Service struct {
+	cfg    Config
+	repo   *db.WebhookRepository
+	client *http.Client
+	wake   chan struct{}
+
+	// OnDisabled is called when a webhook is disabled after repeated failures, e.g. to tell its owner
+	OnDisabled func(w *db.Webhook)
+}
+
+// NewService creates a webhook service storing webhooks and deliveries in repo
+func NewService(cfg Config, repo *db.WebhookRepository) *Service {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !cfg.AllowPrivate {
+		// Checked after name resolution, so DNS can't point a webhook at internal services
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); err != nil || !isPublic(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &Service{
+		cfg:  cfg,
+		repo: repo,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 5 * time.Second},
+			// Redirects are not followed, receivers must answer at the registered URL
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Register validates and creates a webhook for a chat with a new random secret.
+// Empty event types or symbols match everything; "all" is the same as no symbols.
+func (s *Service) Register(ctx context.Context, chatID int64, rawURL string, eventTypes, symbols []string) (*db.Webhook, error) {
+	if err := s.ValidateURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	for _, t := range eventTypes {
+		if !isEventType(t) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidEventType, t)
+		}
+	}
+
+	var patterns []string
+	for _, sym := range symbols {
+		if strings.EqualFold(sym, "all") {
+			patterns = nil
+			break
+		}
+		if !symbol.IsValid(sym) && !symbol.IsPattern(sym) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSymbol, sym)
+		}
+		patterns = append(patterns, sym)
+	}
+
+	if s.cfg.MaxPerChat > 0 {
+		count, err := s.repo.CountByChat(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= s.cfg.MaxPerChat {
+			return nil, ErrLimitReached
+		}
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &db.Webhook{
+		ChatID:     chatID,
+		URL:        rawURL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		Symbols:    patterns,
+	}
+	if err := s.repo.Create(ctx, w); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// ValidateURL checks that a URL can be registered: absolute https without credentials,
+// and not pointing at a private or loopback address
+func (s *Service) ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: not an absolute URL", ErrInvalidURL)
+	}
+	if u.Scheme != "https" && !(s.cfg.AllowPrivate && u.Scheme == "http") {
+		return fmt.Errorf("%w: must use https", ErrInvalidURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: must not contain credentials", ErrInvalidURL)
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !isPublic(ip) && !s.cfg.AllowPrivate {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, errPrivateAddress)
+	}
+	return nil
+}
+
+// Repository returns the repository webhooks are stored in
+func (s *Service) Repository() *db.WebhookRepository {
+	return s.repo
+}
+
+// Name identifies webhooks among notification channels
+func (s *Service) Name() string {
+	return "webhooks"
+}
+
+// NotifySubscribers queues a proposal.created event for every matching webhook
+func (s *Service) NotifySubscribers(alert *notify.ProposalAlert) error {
+	return s.Publish(context.Background(), NewProposalCreatedEvent(alert, time.Now()))
+}
+
+// Publish queues an event for every enabled webhook subscribed to its type and symbol
+func (s *Service) Publish(ctx context.Context, event *Event) error {
+	webhooks, err := s.repo.ListEnabled(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	queued := false
+	for _, w := range webhooks {
+		if !matches(w, event) {
+			continue
+		}
+
+		delivery := &db.WebhookDelivery{WebhookID: w.ID, EventID: event.ID, EventType: event.Type, Payload: payload}
+		if err := s.repo.EnqueueDelivery(ctx, delivery); err != nil {
+			log.Printf("Failed to queue event %s for webhook %d: %v", event.ID, w.ID, err)
+			continue
+		}
+		queued = true
+	}
+
+	// Deliver right away rather than at the next tick
+	if queued {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Run delivers due events every interval, and as soon as new ones are published,
+// until the context is cancelled
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		s.DeliverDue(ctx, time.Now())
+	}
+}
+
+// DeliverDue attempts every delivery that is due. Each webhook's deliveries are sent in
+// order, while webhooks are delivered to concurrently so a slow receiver only holds up its own.
+func (s *Service) DeliverDue(ctx context.Context, now time.Time) {
+	deliveries, err := s.repo.DueDeliveries(ctx, now, batchSize)
+	if err != nil {
+		log.Printf("Error getting due webhook deliveries: %v", err)
+		return
+	}
+
+	if s.cfg.BatchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.BatchTimeout)
+		defer cancel()
+	}
+
+	var order []int64
+	byWebhook := make(map[int64][]*db.WebhookDelivery)
+	for _, d := range deliveries {
+		if _, ok := byWebhook[d.WebhookID]; !ok {
+			order = append(order, d.WebhookID)
+		}
+		byWebhook[d.WebhookID] = append(byWebhook[d.WebhookID], d)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range order {
+		wg.Add(1)
+		go func(id int64, deliveries []*db.WebhookDelivery) {
+			defer wg.Done()
+			s.deliverTo(ctx, id, deliveries, now)
+		}(id, byWebhook[id])
+	}
+	wg.Wait()
+}
+
+// deliverTo attempts a webhook's due deliveries in order, until the batch runs out of time
+func (s *Service) deliverTo(ctx context.Context, webhookID int64, deliveries []*db.WebhookDelivery, now time.Time) {
+	w, err := s.repo.Get(ctx, webhookID)
+	if err != nil {
+		log.Printf("Error getting webhook %d: %v", webhookID, err)
+		return
+	}
+
+	for _, d := range deliveries {
+		// A webhook disabled earlier in this batch gets no further attempts
+		if !w.Enabled || ctx.Err() != nil {
+			return
+		}
+		s.attempt(ctx, w, d, now)
+	}
+}
+
+// attempt sends a delivery once and records the outcome
+func (s *Service) attempt(ctx context.Context, w *db.Webhook, d *db.WebhookDelivery, now time.Time) {
+	sendErr := s.send(ctx, w, d)
+
+	// An attempt cut short by the end of the batch or shutdown isn't the receiver's fault
+	if sendErr != nil && ctx.Err() != nil {
+		log.Printf("Webhook %d delivery %d attempt interrupted, retrying next run: %v", w.ID, d.ID, sendErr)
+		return
+	}
+	// The outcome is recorded even if the batch ends meanwhile
+	ctx = context.WithoutCancel(ctx)
+
+	if sendErr == nil {
+		if err := s.repo.MarkDelivered(ctx, d.ID, now); err != nil {
+			log.Printf("Error recording webhook delivery %d: %v", d.ID, err)
+		}
+		if w.ConsecutiveFailures > 0 || w.LastError != "" {
+			if err := s.repo.RecordSuccess(ctx, w.ID); err != nil {
+				log.Printf("Error recording webhook %d success: %v", w.ID, err)
+			}
+			w.ConsecutiveFailures, w.LastError = 0, ""
+		}
+		return
+	}
+
+	log.Printf("Webhook %d delivery %d attempt %d failed: %v", w.ID, d.ID, d.Attempts+1, sendErr)
+
+	var next time.Time
+	if d.Attempts+1 < s.cfg.MaxAttempts {
+		next = now.Add(s.backoff(d.Attempts + 1))
+	}
+	if err := s.repo.MarkAttemptFailed(ctx, d.ID, sendErr.Error(), next); err != nil {
+		log.Printf("Error recording webhook delivery %d failure: %v", d.ID, err)
+	}
+
+	disabled, err := s.repo.RecordFailure(ctx, w.ID, sendErr.Error(), s.cfg.DisableAfter)
+	if err != nil {
+		log.Printf("Error recording webhook %d failure: %v", w.ID, err)
+		return
+	}
+	w.ConsecutiveFailures++
+	w.LastError = sendErr.Error()
+
+	if disabled {
+		w.Enabled = false
+		log.Printf("Webhook %d disabled after %d consecutive failures", w.ID, w.ConsecutiveFailures)
+		if s.OnDisabled != nil {
+			s.OnDisabled(w)
+		}
+	}
+}
+
+// send posts a delivery's payload, signed as of when it is sent so receivers checking
+// the timestamp don't reject deliveries late in a batch
+func (s *Service) send(ctx context.Context, w *db.Webhook, d *db.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ZeraBot-Webhook/1")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, fmt.Sprint(d.ID))
+	req.Header.Set(SignatureHeader, Sign(w.Secret, time.Now(), d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// backoff returns the wait before retry number n (starting at 1): exponential
+// from BaseBackoff up to MaxBackoff, with up to 20% jitter
+func (s *Service) backoff(n int) time.Duration {
+	wait := s.cfg.BaseBackoff
+	for i := 1; i < n && wait < s.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, s.cfg.MaxBackoff)
+
+	if jitter := int64(wait / 5); jitter > 0 {
+		wait += time.Duration(rand.Int64N(jitter))
+	}
+	return wait
+}
+
+// matches reports whether a webhook is subscribed to an event's type and symbol
+func matches(w *db.Webhook, event *Event) bool {
+	if len(w.EventTypes) > 0 && !contains(w.EventTypes, event.Type) {
+		return false
+	}
+	if len(w.Symbols) == 0 {
+		return true
+	}
+	for _, pattern := range w.Symbols {
+		if symbol.Match(pattern, event.symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// nonPublic are the address ranges webhooks may not be delivered to: private, loopback,
+// link-local, shared (CGNAT), reserved, documentation, benchmarking and multicast ranges
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fec0::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// nat64 is the well-known NAT64 prefix, whose addresses embed an IPv4 address
+var nat64 = netip.MustParsePrefix("64:ff9b::/96")
+
+// isPublic reports whether an IP address is routable on the public internet. IPv4-mapped
+// and NAT64 addresses are judged by the IPv4 address they embed.
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap().WithZone("")
+	if nat64.Contains(ip) {
+		b := ip.As16()
+		ip = netip.AddrFrom4([4]byte(b[12:]))
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return ip.IsValid()
+}
+
+// newSecret returns a random signing secret
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,53 @@
+package webhook_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZeraVision/ZeraBot/webhook"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"proposal.created:abc"}`)
+	sent := time.Unix(1_700_000_000, 0)
+	header := webhook.Sign(secret, sent, body)
+
+	if !strings.HasPrefix(header, "t=1700000000,v1=") {
+		t.Fatalf("Sign = %q, want the timestamp followed by the signature", header)
+	}
+
+	// A receiver may be sent several signatures while secrets are rotated
+	rotated := header + ",v1=" + strings.Repeat("00", 32)
+
+	cases := []struct {
+		name      string
+		secret    string
+		header    string
+		body      []byte
+		tolerance time.Duration
+		now       time.Time
+		wantErr   bool
+	}{
+		{"Valid", secret, header, body, 5 * time.Minute, sent.Add(time.Minute), false},
+		{"AnyOfSeveralSignatures", secret, rotated, body, 5 * time.Minute, sent, false},
+		{"ToleranceDisabled", secret, header, body, 0, sent.Add(24 * time.Hour), false},
+		{"WrongSecret", "whsec_other", header, body, 5 * time.Minute, sent, true},
+		{"ChangedBody", secret, header, []byte(`{"id":"proposal.created:xyz"}`), 5 * time.Minute, sent, true},
+		{"Replayed", secret, header, body, 5 * time.Minute, sent.Add(10 * time.Minute), true},
+		{"FromTheFuture", secret, header, body, 5 * time.Minute, sent.Add(-10 * time.Minute), true},
+		{"ChangedTimestamp", secret, strings.Replace(header, "t=1700000000", "t=1700000001", 1), body, 0, sent, true},
+		{"NoSignature", secret, "t=1700000000", body, 0, sent, true},
+		{"Malformed", secret, "garbage", body, 0, sent, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := webhook.Verify(c.secret, c.header, c.body, c.tolerance, c.now)
+			if (err != nil) != c.wantErr {
+				t.Errorf("Verify = %v, want error %v", err, c.wantErr)
+			}
+		})
+	}
+}